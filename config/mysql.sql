@@ -66,3 +66,412 @@ CREATE TABLE user (
     PRIMARY KEY (id)
 );
 
+
+CREATE TABLE uploaded_files (
+    id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    file_name VARCHAR(255) NOT NULL,
+    file_type VARCHAR(50) NOT NULL,
+    user_id INT UNSIGNED NOT NULL,
+    category VARCHAR(50) NOT NULL DEFAULT 'other',
+    description VARCHAR(1024) NOT NULL DEFAULT '',
+    size BIGINT UNSIGNED NOT NULL DEFAULT 0,
+    checksum CHAR(64) NOT NULL DEFAULT '',
+    scan_status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    scan_signature VARCHAR(255) NOT NULL DEFAULT '',
+    
+    key_version INT UNSIGNED NOT NULL,
+    wrapped_key VARBINARY(128) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    deleted TINYINT(1) UNSIGNED NOT NULL DEFAULT 0,
+    deleted_at TIMESTAMP NULL DEFAULT NULL,
+    
+    INDEX (key_version),
+    INDEX (deleted, deleted_at),
+    INDEX (user_id, category),
+    INDEX (scan_status),
+    CONSTRAINT `f_uploaded_files_user` FOREIGN KEY (`user_id`) REFERENCES `user` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+    
+    PRIMARY KEY (id)
+);
+
+CREATE TABLE uploaded_file_tag (
+    file_id INT UNSIGNED NOT NULL,
+    tag VARCHAR(64) NOT NULL,
+
+    INDEX (tag),
+    CONSTRAINT `f_uploaded_file_tag_file` FOREIGN KEY (`file_id`) REFERENCES `uploaded_files` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+
+    PRIMARY KEY (file_id, tag)
+);
+
+CREATE TABLE staff_assignment (
+    staff_id INT UNSIGNED NOT NULL,
+    customer_id INT UNSIGNED NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    INDEX (customer_id),
+    CONSTRAINT `f_staff_assignment_staff` FOREIGN KEY (`staff_id`) REFERENCES `user` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT `f_staff_assignment_customer` FOREIGN KEY (`customer_id`) REFERENCES `user` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+
+    PRIMARY KEY (staff_id, customer_id)
+);
+
+CREATE TABLE document_request (
+    id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    customer_id INT UNSIGNED NOT NULL,
+    staff_id INT UNSIGNED NOT NULL,
+    category VARCHAR(50) NOT NULL,
+    note VARCHAR(1024) NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    file_id INT UNSIGNED NULL DEFAULT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    INDEX (customer_id, status),
+    CONSTRAINT `f_document_request_customer` FOREIGN KEY (`customer_id`) REFERENCES `user` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT `f_document_request_staff` FOREIGN KEY (`staff_id`) REFERENCES `user` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT `f_document_request_file` FOREIGN KEY (`file_id`) REFERENCES `uploaded_files` (`id`) ON DELETE SET NULL ON UPDATE CASCADE,
+
+    PRIMARY KEY (id)
+);
+
+/* Append-only audit: no foreign keys, so records outlive users and files */
+CREATE TABLE file_access_log (
+    id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    actor_id INT UNSIGNED NOT NULL,
+    actor_role TINYINT(1) UNSIGNED NOT NULL,
+    customer_id INT UNSIGNED NOT NULL,
+    file_id INT UNSIGNED NULL DEFAULT NULL,
+    action VARCHAR(20) NOT NULL,
+    detail VARCHAR(255) NOT NULL DEFAULT '',
+    remote_addr VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    INDEX (actor_id, created_at),
+    INDEX (customer_id, created_at),
+
+    PRIMARY KEY (id)
+);
+
+CREATE TRIGGER file_access_log_no_update BEFORE UPDATE ON file_access_log
+    FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'file_access_log is append-only';
+
+CREATE TRIGGER file_access_log_no_delete BEFORE DELETE ON file_access_log
+    FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'file_access_log is append-only';
+
+CREATE TABLE message_thread (
+    id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    to_user_id INT UNSIGNED NOT NULL,
+    from_user_id INT UNSIGNED NOT NULL,
+    title VARCHAR(255) NOT NULL DEFAULT '',
+    status VARCHAR(16) NOT NULL DEFAULT 'open',
+    assigned_staff_id INT UNSIGNED NOT NULL DEFAULT 0,
+    first_response_at TIMESTAMP NULL DEFAULT NULL,
+    resolved_at TIMESTAMP NULL DEFAULT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    deleted TINYINT(1) UNSIGNED NOT NULL DEFAULT 0,
+
+    INDEX (assigned_staff_id, status),
+    CONSTRAINT `f_message_thread_to_user` FOREIGN KEY (`to_user_id`) REFERENCES `user` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT `f_message_thread_from_user` FOREIGN KEY (`from_user_id`) REFERENCES `user` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+
+    PRIMARY KEY (id)
+);
+
+CREATE TABLE customer_message (
+    id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    thread_id INT UNSIGNED NOT NULL,
+    to_user_id INT UNSIGNED NOT NULL,
+    from_user_id INT UNSIGNED NOT NULL,
+    content TEXT NOT NULL,
+    thread_title VARCHAR(255) NOT NULL DEFAULT '', -- copy of thread title for full-text search
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    edited TINYINT(1) UNSIGNED NOT NULL DEFAULT 0,
+    deleted TINYINT(1) UNSIGNED NOT NULL DEFAULT 0,
+    deleted_by INT UNSIGNED NOT NULL DEFAULT 0,
+    deleted_at TIMESTAMP NULL DEFAULT NULL,
+
+    INDEX (thread_id, created_at),
+    FULLTEXT INDEX (thread_title, content),
+    CONSTRAINT `f_customer_message_thread` FOREIGN KEY (`thread_id`) REFERENCES `message_thread` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT `f_customer_message_to_user` FOREIGN KEY (`to_user_id`) REFERENCES `user` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT `f_customer_message_from_user` FOREIGN KEY (`from_user_id`) REFERENCES `user` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+
+    PRIMARY KEY (id)
+);
+
+/* Previous contents of edited messages, edited_by is user who replaced content */
+CREATE TABLE message_revision (
+    id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    message_id INT UNSIGNED NOT NULL,
+    content TEXT NOT NULL,
+    edited_by INT UNSIGNED NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    INDEX (message_id),
+    CONSTRAINT `f_message_revision_message` FOREIGN KEY (`message_id`) REFERENCES `customer_message` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+
+    PRIMARY KEY (id)
+);
+
+/* Users who can read and post to thread, added_by is 0 for system */
+CREATE TABLE message_thread_participant (
+    thread_id INT UNSIGNED NOT NULL,
+    user_id INT UNSIGNED NOT NULL,
+    added_by INT UNSIGNED NOT NULL DEFAULT 0,
+    last_read_message_id INT UNSIGNED NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    INDEX (user_id),
+    CONSTRAINT `f_message_thread_participant_thread` FOREIGN KEY (`thread_id`) REFERENCES `message_thread` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT `f_message_thread_participant_user` FOREIGN KEY (`user_id`) REFERENCES `user` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+
+    PRIMARY KEY (thread_id, user_id)
+);
+
+/* Per participant read state of messages */
+CREATE TABLE message_read (
+    message_id INT UNSIGNED NOT NULL,
+    user_id INT UNSIGNED NOT NULL,
+    read_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    INDEX (user_id),
+    CONSTRAINT `f_message_read_message` FOREIGN KEY (`message_id`) REFERENCES `customer_message` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT `f_message_read_user` FOREIGN KEY (`user_id`) REFERENCES `user` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+
+    PRIMARY KEY (message_id, user_id)
+);
+
+/* Uploaded files attached to messages, readable by all thread participants */
+CREATE TABLE message_attachment (
+    message_id INT UNSIGNED NOT NULL,
+    file_id INT UNSIGNED NOT NULL,
+
+    INDEX (file_id),
+    CONSTRAINT `f_message_attachment_message` FOREIGN KEY (`message_id`) REFERENCES `customer_message` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT `f_message_attachment_file` FOREIGN KEY (`file_id`) REFERENCES `uploaded_files` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+
+    PRIMARY KEY (message_id, file_id)
+);
+
+/* How user is notified about new messages: immediate, digest or off. Users without row get immediate emails */
+CREATE TABLE notification_preference (
+    user_id INT UNSIGNED NOT NULL,
+    mode VARCHAR(16) NOT NULL DEFAULT 'immediate',
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    CONSTRAINT `f_notification_preference_user` FOREIGN KEY (`user_id`) REFERENCES `user` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+
+    PRIMARY KEY (user_id)
+);
+
+/* New messages waiting for hourly digest email */
+CREATE TABLE notification_digest_item (
+    user_id INT UNSIGNED NOT NULL,
+    message_id INT UNSIGNED NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    INDEX (message_id),
+    CONSTRAINT `f_notification_digest_item_user` FOREIGN KEY (`user_id`) REFERENCES `user` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT `f_notification_digest_item_message` FOREIGN KEY (`message_id`) REFERENCES `customer_message` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+
+    PRIMARY KEY (user_id, message_id)
+);
+
+/* Emails waiting for delivery, status is pending, sent or failed */
+CREATE TABLE email_outbox (
+    id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    user_id INT UNSIGNED NOT NULL,
+    to_email VARCHAR(100) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    text_body TEXT NOT NULL,
+    html_body TEXT NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    attempts INT UNSIGNED NOT NULL DEFAULT 0,
+    last_error VARCHAR(255) NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    sent_at TIMESTAMP NULL DEFAULT NULL,
+
+    INDEX (status, next_attempt_at),
+    CONSTRAINT `f_email_outbox_user` FOREIGN KEY (`user_id`) REFERENCES `user` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+
+    PRIMARY KEY (id)
+);
+
+/* Lenders offering mortgage rates */
+CREATE TABLE lender (
+    id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    name VARCHAR(100) NOT NULL,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    address VARCHAR(255) NOT NULL DEFAULT '',
+    email VARCHAR(100) NOT NULL DEFAULT '',
+    phone VARCHAR(30) NOT NULL DEFAULT '',
+    contact VARCHAR(100) NOT NULL DEFAULT '',
+    status_id TINYINT(1) UNSIGNED NOT NULL DEFAULT 1,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    UNIQUE INDEX (name),
+
+    PRIMARY KEY (id)
+);
+
+/* Lender rates of loan products, interest and apr are percents */
+CREATE TABLE lender_rate (
+    id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    lender_id INT UNSIGNED NOT NULL,
+    product VARCHAR(50) NOT NULL,
+    term_years TINYINT UNSIGNED NOT NULL,
+    interest DECIMAL(6,3) NOT NULL,
+    apr DECIMAL(6,3) NOT NULL,
+    begin_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    INDEX (product, apr),
+    CONSTRAINT `f_lender_rate_lender` FOREIGN KEY (`lender_id`) REFERENCES `lender` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+
+    PRIMARY KEY (id)
+);
+
+/* Log of rate feed ingestion runs, the last successful run tells if feed rates are stale */
+CREATE TABLE rate_feed_run (
+    id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    source VARCHAR(20) NOT NULL,
+    succeeded TINYINT(1) UNSIGNED NOT NULL DEFAULT 0,
+    fetched INT UNSIGNED NOT NULL DEFAULT 0,
+    inserted INT UNSIGNED NOT NULL DEFAULT 0,
+    updated INT UNSIGNED NOT NULL DEFAULT 0,
+    rejected INT UNSIGNED NOT NULL DEFAULT 0,
+    error VARCHAR(1024) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    INDEX (succeeded, created_at),
+
+    PRIMARY KEY (id)
+);
+
+/* Customer alerts on best rate of product crossing threshold, direction is below or above.
+   Triggered alert is not sent again until rate crosses threshold back. */
+CREATE TABLE rate_alert (
+    id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    user_id INT UNSIGNED NOT NULL,
+    product VARCHAR(50) NOT NULL,
+    threshold DECIMAL(6,3) NOT NULL,
+    direction VARCHAR(8) NOT NULL,
+    token CHAR(32) NOT NULL,
+    triggered TINYINT(1) UNSIGNED NOT NULL DEFAULT 0,
+    notified_rate DECIMAL(6,3) NULL DEFAULT NULL,
+    notified_at TIMESTAMP NULL DEFAULT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE INDEX (user_id, product, direction, threshold),
+    UNIQUE INDEX (token),
+    CONSTRAINT `f_rate_alert_user` FOREIGN KEY (`user_id`) REFERENCES `user` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+
+    PRIMARY KEY (id)
+);
+
+/* Customer loan applications (leads). State is draft, submitted, in_review, approved, declined or funded.
+   Chosen lender rate is copied, so application keeps its terms after rate sheet import replaces rates. */
+CREATE TABLE loan_application (
+    id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    customer_id INT UNSIGNED NOT NULL,
+    staff_id INT UNSIGNED NULL DEFAULT NULL,
+    lender_rate_id INT UNSIGNED NULL DEFAULT NULL,
+    lender_id INT UNSIGNED NOT NULL,
+    product VARCHAR(50) NOT NULL,
+    term_years TINYINT UNSIGNED NOT NULL,
+    interest DECIMAL(6,3) NOT NULL,
+    apr DECIMAL(6,3) NOT NULL,
+    loan_amount DECIMAL(12,2) NOT NULL,
+    state VARCHAR(20) NOT NULL DEFAULT 'draft',
+    submitted_at TIMESTAMP NULL DEFAULT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    INDEX (customer_id),
+    INDEX (state),
+    CONSTRAINT `f_loan_application_customer` FOREIGN KEY (`customer_id`) REFERENCES `user` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT `f_loan_application_staff` FOREIGN KEY (`staff_id`) REFERENCES `user` (`id`) ON DELETE SET NULL ON UPDATE CASCADE,
+    CONSTRAINT `f_loan_application_rate` FOREIGN KEY (`lender_rate_id`) REFERENCES `lender_rate` (`id`) ON DELETE SET NULL ON UPDATE CASCADE,
+    CONSTRAINT `f_loan_application_lender` FOREIGN KEY (`lender_id`) REFERENCES `lender` (`id`) ON DELETE RESTRICT ON UPDATE CASCADE,
+
+    PRIMARY KEY (id)
+);
+
+/* Customer's uploaded files attached to loan application */
+CREATE TABLE loan_application_document (
+    application_id INT UNSIGNED NOT NULL,
+    file_id INT UNSIGNED NOT NULL,
+
+    INDEX (file_id),
+    CONSTRAINT `f_loan_application_document_application` FOREIGN KEY (`application_id`) REFERENCES `loan_application` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT `f_loan_application_document_file` FOREIGN KEY (`file_id`) REFERENCES `uploaded_files` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+
+    PRIMARY KEY (application_id, file_id)
+);
+
+/* Append-only history of loan application state changes, from_state is empty for created application */
+CREATE TABLE loan_application_history (
+    id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    application_id INT UNSIGNED NOT NULL,
+    from_state VARCHAR(20) NOT NULL DEFAULT '',
+    to_state VARCHAR(20) NOT NULL,
+    actor_id INT UNSIGNED NOT NULL,
+    actor_role TINYINT(1) UNSIGNED NOT NULL,
+    note VARCHAR(1024) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    INDEX (application_id),
+    CONSTRAINT `f_loan_application_history_application` FOREIGN KEY (`application_id`) REFERENCES `loan_application` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+
+    PRIMARY KEY (id)
+);
+
+/* Fast quotes with their inputs and returned rates (JSON). Anonymous quote is attached to customer
+   by its token after registration, user_id is empty until then. */
+CREATE TABLE fast_quote (
+    id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    token CHAR(32) NOT NULL,
+    user_id INT UNSIGNED NULL DEFAULT NULL,
+    loan_amount DECIMAL(12,2) NOT NULL,
+    term_years TINYINT UNSIGNED NOT NULL,
+    credit_band VARCHAR(20) NOT NULL,
+    product VARCHAR(50) NOT NULL DEFAULT '',
+    rates TEXT NOT NULL,
+    claimed_at TIMESTAMP NULL DEFAULT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE INDEX (token),
+    INDEX (user_id),
+    INDEX (created_at),
+    CONSTRAINT `f_fast_quote_user` FOREIGN KEY (`user_id`) REFERENCES `user` (`id`) ON DELETE SET NULL ON UPDATE CASCADE,
+
+    PRIMARY KEY (id)
+);
+
+/* *****************************************************************************
+// Seed data
+// ****************************************************************************/
+INSERT INTO lender (id, name, description, email, phone, contact) VALUES
+    (1, 'First National Mortgage', 'Retail mortgage lender', 'rates@firstnational.example', '800-555-0101', 'Rate desk'),
+    (2, 'Union Home Lending', 'Wholesale mortgage lender', 'rates@unionhome.example', '800-555-0102', 'Rate desk'),
+    (3, 'Coastal Credit Union', 'Member owned credit union', 'rates@coastalcu.example', '800-555-0103', 'Rate desk');
+
+INSERT INTO lender_rate (lender_id, product, term_years, interest, apr, begin_date, end_date) VALUES
+    (1, '15 Year Fixed', 15, 3.23, 3.35, '2018-01-01', '2099-12-31'),
+    (2, '15 Year Fixed', 15, 3.38, 3.46, '2018-01-01', '2099-12-31'),
+    (3, '15 Year Fixed', 15, 3.45, 3.52, '2018-01-01', '2099-12-31'),
+    (1, '30 Year Fixed', 30, 3.75, 3.84, '2018-01-01', '2099-12-31'),
+    (2, '30 Year Fixed', 30, 3.63, 3.71, '2018-01-01', '2099-12-31'),
+    (3, '30 Year Fixed', 30, 3.70, 3.79, '2018-01-01', '2099-12-31'),
+    (1, '5/1 ARM', 30, 3.05, 3.52, '2018-01-01', '2099-12-31'),
+    (2, '5/1 ARM', 30, 2.95, 3.48, '2018-01-01', '2099-12-31'),
+    (3, '5/1 ARM', 30, 3.10, 3.60, '2018-01-01', '2099-12-31'),
+    (2, '30 Year Fixed', 30, 3.45, 3.52, '2017-01-01', '2017-12-31'),
+    (1, '30 Year Fixed', 30, 3.40, 3.48, '2017-07-01', '2017-12-31');
@@ -0,0 +1,116 @@
+/* Upgrade of database created before mysql.sql had application tables.
+   Run sections in order on existing database; new tables are created with
+   their CREATE TABLE statements from mysql.sql. */
+
+USE puma;
+
+/* Envelope encryption of uploaded files.
+   Existing files get key_version 0: their stored objects are plain and are read as is
+   until "app encrypt-legacy-files" encrypts them. */
+ALTER TABLE uploaded_files
+    ADD key_version INT UNSIGNED NOT NULL DEFAULT 0 AFTER user_id,
+    ADD wrapped_key VARBINARY(128) NOT NULL DEFAULT '' AFTER key_version,
+    ADD INDEX (key_version);
+
+/* Trash of uploaded files */
+ALTER TABLE uploaded_files
+    ADD deleted_at TIMESTAMP NULL DEFAULT NULL AFTER deleted,
+    ADD INDEX (deleted, deleted_at);
+
+UPDATE uploaded_files SET deleted_at = updated_at WHERE deleted = 1;
+
+/* Categories, descriptions, size and checksum of uploaded files, tags are in uploaded_file_tag */
+ALTER TABLE uploaded_files
+    ADD category VARCHAR(50) NOT NULL DEFAULT 'other' AFTER user_id,
+    ADD description VARCHAR(1024) NOT NULL DEFAULT '' AFTER category,
+    ADD size BIGINT UNSIGNED NOT NULL DEFAULT 0 AFTER description,
+    ADD checksum CHAR(64) NOT NULL DEFAULT '' AFTER size,
+    ADD INDEX (user_id, category);
+
+/* Antivirus scan of uploaded files, existing files are scanned by "app scan-files" */
+ALTER TABLE uploaded_files
+    ADD scan_status VARCHAR(20) NOT NULL DEFAULT 'pending' AFTER checksum,
+    ADD scan_signature VARCHAR(255) NOT NULL DEFAULT '' AFTER scan_status,
+    ADD INDEX (scan_status);
+
+/* Group message threads with participants and per-user read state.
+   Both users of existing threads become participants, readed flag of message becomes read by recipient. */
+CREATE TABLE IF NOT EXISTS message_thread_participant (
+    thread_id INT UNSIGNED NOT NULL,
+    user_id INT UNSIGNED NOT NULL,
+    added_by INT UNSIGNED NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    INDEX (user_id),
+    CONSTRAINT `f_message_thread_participant_thread` FOREIGN KEY (`thread_id`) REFERENCES `message_thread` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT `f_message_thread_participant_user` FOREIGN KEY (`user_id`) REFERENCES `user` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+
+    PRIMARY KEY (thread_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS message_read (
+    message_id INT UNSIGNED NOT NULL,
+    user_id INT UNSIGNED NOT NULL,
+    read_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    INDEX (user_id),
+    CONSTRAINT `f_message_read_message` FOREIGN KEY (`message_id`) REFERENCES `customer_message` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT `f_message_read_user` FOREIGN KEY (`user_id`) REFERENCES `user` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+
+    PRIMARY KEY (message_id, user_id)
+);
+
+INSERT IGNORE INTO message_thread_participant (thread_id, user_id, added_by, created_at)
+    SELECT id, from_user_id, 0, created_at FROM message_thread;
+
+INSERT IGNORE INTO message_thread_participant (thread_id, user_id, added_by, created_at)
+    SELECT id, to_user_id, 0, created_at FROM message_thread;
+
+INSERT IGNORE INTO message_read (message_id, user_id, read_at)
+    SELECT id, to_user_id, updated_at FROM customer_message WHERE readed = 1;
+
+ALTER TABLE customer_message DROP COLUMN readed;
+
+/* Per-thread read cursors, messages read before upgrade stay in message_read */
+ALTER TABLE message_thread_participant
+    ADD last_read_message_id INT UNSIGNED NOT NULL DEFAULT 0 AFTER added_by;
+
+/* Full-text search over thread titles and message contents */
+ALTER TABLE customer_message
+    ADD thread_title VARCHAR(255) NOT NULL DEFAULT '' AFTER content;
+
+UPDATE customer_message JOIN message_thread ON message_thread.id = customer_message.thread_id
+    SET customer_message.thread_title = message_thread.title;
+
+ALTER TABLE customer_message ADD FULLTEXT INDEX (thread_title, content);
+
+/* Message edit history and moderation, revisions are in message_revision */
+ALTER TABLE customer_message
+    ADD edited TINYINT(1) UNSIGNED NOT NULL DEFAULT 0 AFTER updated_at,
+    ADD deleted_by INT UNSIGNED NOT NULL DEFAULT 0 AFTER deleted,
+    ADD deleted_at TIMESTAMP NULL DEFAULT NULL AFTER deleted_by;
+
+/* Thread status, staff assignment and SLA timestamps */
+ALTER TABLE message_thread
+    ADD status VARCHAR(16) NOT NULL DEFAULT 'open' AFTER title,
+    ADD assigned_staff_id INT UNSIGNED NOT NULL DEFAULT 0 AFTER status,
+    ADD first_response_at TIMESTAMP NULL DEFAULT NULL AFTER assigned_staff_id,
+    ADD resolved_at TIMESTAMP NULL DEFAULT NULL AFTER first_response_at,
+    ADD INDEX (assigned_staff_id, status);
+
+/* Lender rates with terms and three decimals, lender name is read from lender.
+   Existing rates get 30 years term, correct other terms with admin rate update.
+   Unique lender name fails on duplicate names, rename them first. */
+ALTER TABLE lender
+    ADD updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP AFTER created_at,
+    ADD UNIQUE INDEX (name);
+
+ALTER TABLE lender_rate
+    ADD term_years TINYINT UNSIGNED NOT NULL DEFAULT 30 AFTER product,
+    DROP COLUMN lender_name,
+    MODIFY interest DECIMAL(6,3) NOT NULL,
+    MODIFY apr DECIMAL(6,3) NOT NULL,
+    ADD INDEX (product, apr),
+    ADD CONSTRAINT `f_lender_rate_lender` FOREIGN KEY (`lender_id`) REFERENCES `lender` (`id`) ON DELETE CASCADE ON UPDATE CASCADE;
+
+ALTER TABLE lender_rate ALTER term_years DROP DEFAULT;
@@ -1,15 +1,25 @@
 package main
 
 import (
+	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
 	"runtime"
+	"strconv"
 
 	"app/provider"
 	"app/route"
 	"app/shared/config"
 	"app/shared/database"
+	"app/shared/email"
+	"app/shared/events"
+	"app/shared/filecategory"
 	"app/shared/jsonconfig"
+	"app/shared/keyring"
+	"app/shared/ratefeed"
+	"app/shared/ratesheet"
+	"app/shared/scanner"
 	"app/shared/server"
 	"app/shared/session"
 	"app/shared/view"
@@ -34,12 +44,70 @@ func main() {
 	// Configure the session cookie store
 	session.Configure(config.Session())
 
+	// Print a new random key for the keyring config, it doesn't need other settings
+	if len(os.Args) > 1 && os.Args[1] == "generate-key" {
+		key, err := keyring.GenerateKey()
+		if err != nil {
+			log.Fatalln(err)
+		}
+		fmt.Println(key)
+		return
+	}
+
+	// Load the encryption keys, they are not shipped in config.json:
+	// set KeyFile or APP_KEYRING_* environment variables
+	if err := keyring.Configure(config.Keyring()); err != nil {
+		log.Fatalln(err)
+	}
+
+	// Configure the antivirus scanner for uploaded files
+	if err := scanner.Configure(config.Scanner()); err != nil {
+		log.Fatalln(err)
+	}
+
+	// Configure the broker for real-time events
+	if err := events.Configure(config.Events()); err != nil {
+		log.Fatalln(err)
+	}
+
+	// Configure the email sender
+	if err := email.Configure(config.Email()); err != nil {
+		log.Fatalln(err)
+	}
+
+	// Load the document categories
+	filecategory.Configure(config.FileCategories())
+
+	// Load the column mappings of lender rate sheets
+	ratesheet.Configure(config.RateSheets())
+
+	// Configure the external rate feed
+	if err := ratefeed.Configure(config.RateFeed()); err != nil {
+		log.Fatalln(err)
+	}
+
+	// Set the public site URL for links in emails
+	provider.ConfigureSiteURL(config.SiteURL())
+
 	// Connect to database
 	database.Connect(config.Database())
 
+	// Run the maintenance command instead of the listener, e.g. "app rotate-keys"
+	if len(os.Args) > 1 {
+		runCommand(os.Args[1], os.Args[2:])
+		return
+	}
+
 	// After database connect - init application cache
 	provider.InitAppCache()
 
+	// Start periodic tasks
+	provider.StartFilesPurgeSheduler()
+	provider.StartFilesScanSheduler()
+	provider.StartNotificationsSheduler()
+	provider.StartFastQuotesPurgeSheduler()
+	provider.StartSheduler()
+
 	// Setup the views
 	view.Configure(config.View())
 	view.LoadTemplates(config.Template().Root, config.Template().Children)
@@ -51,3 +119,83 @@ func main() {
 	// Start the listener
 	server.Run(route.LoadHTTP(), route.LoadHTTPS(), config.Server())
 }
+
+// runCommand runs a maintenance command with its arguments
+func runCommand(command string, args []string) {
+	switch command {
+	case "rotate-keys":
+		rotated, err := provider.RotateFileKeys()
+		if err != nil {
+			log.Fatalln("rotate-keys failed after", rotated, "files:", err)
+		}
+		log.Println("rotate-keys: rewrapped data keys of", rotated, "files")
+	case "encrypt-legacy-files":
+		encrypted, err := provider.EncryptLegacyFiles()
+		if err != nil {
+			log.Fatalln("encrypt-legacy-files failed after", encrypted, "files:", err)
+		}
+		log.Println("encrypt-legacy-files: encrypted", encrypted, "files stored before encryption")
+	case "purge-files":
+		report, err := provider.PurgeDeletedFiles(provider.TrashRetentionPeriod())
+		if err != nil {
+			log.Fatalln("purge-files failed:", err)
+		}
+		log.Println("purge-files: purged", report.PurgedFiles, "files, failed:", report.FailedFiles)
+		log.Println("purge-files: orphaned storage objects without DB rows:", report.OrphanedObjects)
+	case "scan-files":
+		scanned, err := provider.ScanPendingFiles()
+		if err != nil {
+			log.Fatalln("scan-files: scanned", scanned, "pending files,", err)
+		}
+		log.Println("scan-files: scanned", scanned, "pending files")
+	case "import-rates":
+		importRates(args)
+	case "refresh-rates":
+		report, err := provider.RefreshRates()
+		if report != nil && report.Run != nil {
+			log.Println("refresh-rates: fetched", report.Run.Fetched, "inserted", report.Run.Inserted,
+				"updated", report.Run.Updated, "rejected", report.Run.Rejected)
+		}
+		if err != nil {
+			log.Fatalln("refresh-rates failed:", err)
+		}
+		log.Println("refresh-rates: best rates of", report.BestRates, "products reloaded")
+	default:
+		log.Fatalln("unknown command:", command)
+	}
+}
+
+// importRates imports lender rate sheet file: "app import-rates <lender_id> <file.csv|file.xlsx> [--dry-run]"
+func importRates(args []string) {
+	if len(args) < 2 || (len(args) > 2 && args[2] != "--dry-run") {
+		log.Fatalln("usage: import-rates <lender_id> <file.csv|file.xlsx> [--dry-run]")
+	}
+
+	lenderID, err := strconv.ParseUint(args[0], 10, 32)
+	if err != nil {
+		log.Fatalln("import-rates: bad lender_id:", args[0])
+	}
+
+	format, err := ratesheet.FormatByName(args[1])
+	if err != nil {
+		log.Fatalln("import-rates:", err)
+	}
+
+	data, err := ioutil.ReadFile(args[1])
+	if err != nil {
+		log.Fatalln("import-rates:", err)
+	}
+
+	report, err := provider.ImportRateSheet(uint32(lenderID), format, data, len(args) > 2)
+	if report != nil {
+		for _, v := range report.Rejected {
+			log.Println("import-rates: line", v.Line, "rejected:", v.Error)
+		}
+		log.Println("import-rates: rows", report.Rows, "added", len(report.Added), "changed", len(report.Changed),
+			"removed", len(report.Removed), "unchanged", report.Unchanged, "applied", report.Applied)
+	}
+
+	if err != nil {
+		log.Fatalln("import-rates failed:", err)
+	}
+}
@@ -19,6 +19,9 @@ const (
 	StatusCode_401 uint16 = 401
 	Msg_401        string = "Unauthorized"
 
+	StatusCode_403 uint16 = 403
+	Msg_403        string = "Forbidden"
+
 	StatusCode_404 uint16 = 404
 	Msg_404        string = "Not found"
 
@@ -34,6 +37,9 @@ const (
 	StatusCode_500 uint16 = 500
 	Msg_500        string = "Internal server error"
 
+	StatusCode_502 uint16 = 502
+	Msg_502        string = "Bad gateway"
+
 	StatusCode_505 uint16 = 505
 	Msg_505        string = "Version not supported"
 
@@ -46,20 +52,20 @@ const (
 	CustomerRole   = 4
 	DefaultRole    = 0
 
-	// AESEncryptKey
-	AESEncryptKey = "hiuh8ab324ddwp"
-	// SSEKMSKeyID need for getting encryption/decryption key
-	SSEKMSKeyID = "arn:aws:kms:us-east-1:832507808273:key/a9cfda3b1-583c-43sa-3982-9bbad16ee28c4b"
 	// ServerSideEncryptionType specified encryption type
 	ServerSideEncryptionType = "aws:kms"
-	// FilesCryptoKey using for aes files encryption
-	FilesCryptoKey = "FRG563KJH$61GhGTAFDADSAYAFAD16632423431"
 	// ConfigFilePath get a way to simple config file getting
 	ConfigFilePath = "../../../config" + string(os.PathSeparator) + "config.json"
+	// TemplateFolderPath get a way to templates folder from tests
+	TemplateFolderPath = "../../../template"
 	// TestUserEmail = random email just for testing
 	TestUserEmail = "johndoe@gmail.com"
 	// TestOtherUserEmail = random email just for testing
 	TestOtherUserEmail = "johndoecool@gmail.com"
+	// TestStaffEmail = random staff email just for testing
+	TestStaffEmail = "janedoestaff@gmail.com"
+	// TestProcessorEmail = random loan processor email just for testing
+	TestProcessorEmail = "janedoeprocessor@gmail.com"
 	// FilesIDLifePeriodMin is expired period for files ids what is send to frontend
 	FilesIDLifePeriodMin = 5
 	// AWSS3PresigmLinkLifePeriodMin is expired time for links
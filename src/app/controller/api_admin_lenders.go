@@ -0,0 +1,300 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+
+	"app/constants"
+	"app/provider"
+	"app/shared/ratesheet"
+	"app/webpojo"
+)
+
+const (
+	rateSheetFormName = "ratesheet"
+	maxRateSheetSize  = 10 << 20
+)
+
+// returnLenderError writes response for lender or lender rate change error
+func returnLenderError(w http.ResponseWriter, err error) {
+	switch err {
+	case provider.ErrLenderNotExist, provider.ErrLenderRateNotExist:
+		ReturnCodeError(w, err, http.StatusNotFound, constants.Msg_404)
+	case provider.ErrLenderNameTaken, provider.ErrLenderInUse:
+		ReturnCodeError(w, err, http.StatusConflict, constants.Msg_409)
+	default:
+		log.Println("error while change lender: " + err.Error())
+		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
+	}
+}
+
+// readLenderReq reads JSON request body to req, writes error response and return false on failure
+func readLenderReq(w http.ResponseWriter, r *http.Request, req interface{}) bool {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Println("error while read lender request: " + err.Error())
+		ReturnCodeError(w, errors.New("can't read request body"), http.StatusInternalServerError, constants.Msg_500)
+		return false
+	}
+
+	if len(body) == 0 {
+		ReturnCodeError(w, errors.New("emtpy json payload"), http.StatusBadRequest, constants.Msg_400)
+		return false
+	}
+
+	err = json.Unmarshal(body, req)
+	if err != nil {
+		log.Println("error while read lender request: can't unmarshall request: " + err.Error())
+		ReturnCodeError(w, errors.New("can't parse request"), http.StatusBadRequest, constants.Msg_400)
+		return false
+	}
+
+	return true
+}
+
+// AdminLendersGet return all lenders
+func AdminLendersGet(w http.ResponseWriter, r *http.Request) {
+	lenders, err := provider.GetLenders()
+	if err != nil {
+		log.Println("error while get lenders: " + err.Error())
+		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
+		return
+	}
+
+	err = ReturnNoEscapeCodeJSONResp(w, &webpojo.LenderListResp{StatusCode: constants.StatusCode_200, Message: constants.Msg_200, Lenders: lenders}, http.StatusOK)
+	if err != nil {
+		log.Println("error while return JSON response: " + err.Error())
+	}
+}
+
+// AdminLenderPost adds a new lender, return its ID
+func AdminLenderPost(w http.ResponseWriter, r *http.Request) {
+	lenderReq := &webpojo.LenderPojo{}
+	if !readLenderReq(w, r, lenderReq) {
+		return
+	}
+
+	if valid, errText := validateLender(lenderReq); !valid {
+		ReturnCodeError(w, errors.New(errText), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	id, err := provider.CreateLender(lenderReq)
+	if err != nil {
+		returnLenderError(w, err)
+		return
+	}
+
+	ReturnCodeJSONResponse(w, http.StatusOK, webpojo.IDResponse{ID: id})
+}
+
+// AdminLenderPut changes lender found by ID
+func AdminLenderPut(w http.ResponseWriter, r *http.Request) {
+	lenderReq := &webpojo.LenderPojo{}
+	if !readLenderReq(w, r, lenderReq) {
+		return
+	}
+
+	if lenderReq.ID == 0 {
+		ReturnCodeError(w, errors.New("id is empty"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	if valid, errText := validateLender(lenderReq); !valid {
+		ReturnCodeError(w, errors.New(errText), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	err := provider.UpdateLender(lenderReq)
+	if err != nil {
+		returnLenderError(w, err)
+		return
+	}
+
+	ReturnCodeError(w, errors.New(""), http.StatusOK, constants.Msg_200)
+}
+
+// AdminLenderDelete removes lender with all its rates, lender with loan applications can't be removed
+func AdminLenderDelete(w http.ResponseWriter, r *http.Request) {
+	idReq := &webpojo.IDRequest{}
+	if !readLenderReq(w, r, idReq) {
+		return
+	}
+
+	if idReq.ID <= 0 {
+		ReturnCodeError(w, errors.New("id is empty"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	err := provider.DeleteLender(uint32(idReq.ID))
+	if err != nil {
+		returnLenderError(w, err)
+		return
+	}
+
+	ReturnCodeError(w, errors.New(""), http.StatusOK, constants.Msg_200)
+}
+
+// AdminLenderRatesGet return rate sheet of lender.
+// Query param: lender_id.
+func AdminLenderRatesGet(w http.ResponseWriter, r *http.Request) {
+	lenderID, err := strconv.ParseUint(r.URL.Query().Get("lender_id"), 10, 32)
+	if err != nil {
+		ReturnCodeError(w, errors.New("bad lender_id"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	rates, err := provider.GetLenderRates(uint32(lenderID))
+	if err != nil {
+		returnLenderError(w, err)
+		return
+	}
+
+	err = ReturnNoEscapeCodeJSONResp(w, &webpojo.LenderRateListResp{StatusCode: constants.StatusCode_200, Message: constants.Msg_200, LenderRates: rates}, http.StatusOK)
+	if err != nil {
+		log.Println("error while return JSON response: " + err.Error())
+	}
+}
+
+// AdminLenderRatePost adds a new rate to lender's rate sheet, return its ID
+func AdminLenderRatePost(w http.ResponseWriter, r *http.Request) {
+	rateReq := &webpojo.LenderRatePojo{}
+	if !readLenderReq(w, r, rateReq) {
+		return
+	}
+
+	if errText := provider.CheckLenderRate(rateReq); errText != "" {
+		ReturnCodeError(w, errors.New(errText), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	id, err := provider.CreateLenderRate(rateReq)
+	if err != nil {
+		returnLenderError(w, err)
+		return
+	}
+
+	ReturnCodeJSONResponse(w, http.StatusOK, webpojo.IDResponse{ID: id})
+}
+
+// AdminLenderRatePut changes lender rate found by ID
+func AdminLenderRatePut(w http.ResponseWriter, r *http.Request) {
+	rateReq := &webpojo.LenderRatePojo{}
+	if !readLenderReq(w, r, rateReq) {
+		return
+	}
+
+	if rateReq.ID == 0 {
+		ReturnCodeError(w, errors.New("id is empty"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	if errText := provider.CheckLenderRate(rateReq); errText != "" {
+		ReturnCodeError(w, errors.New(errText), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	err := provider.UpdateLenderRate(rateReq)
+	if err != nil {
+		returnLenderError(w, err)
+		return
+	}
+
+	ReturnCodeError(w, errors.New(""), http.StatusOK, constants.Msg_200)
+}
+
+// AdminLenderRateDelete removes rate from lender's rate sheet
+func AdminLenderRateDelete(w http.ResponseWriter, r *http.Request) {
+	idReq := &webpojo.IDRequest{}
+	if !readLenderReq(w, r, idReq) {
+		return
+	}
+
+	if idReq.ID <= 0 {
+		ReturnCodeError(w, errors.New("id is empty"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	err := provider.DeleteLenderRate(uint32(idReq.ID))
+	if err != nil {
+		returnLenderError(w, err)
+		return
+	}
+
+	ReturnCodeError(w, errors.New(""), http.StatusOK, constants.Msg_200)
+}
+
+// AdminRateSheetImportPost imports lender rate sheet uploaded as CSV or XLSX file, return import report.
+// Form fields: lender_id, dry_run (true to get report without import) and ratesheet file.
+func AdminRateSheetImportPost(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRateSheetSize)
+	if err := r.ParseMultipartForm(maxRateSheetSize); err != nil {
+		log.Println("error while import rate sheet: can't parse form: " + err.Error())
+		ReturnCodeError(w, errors.New("can't parse rate sheet form"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	lenderID, err := strconv.ParseUint(r.FormValue("lender_id"), 10, 32)
+	if err != nil || lenderID == 0 {
+		ReturnCodeError(w, errors.New("bad lender_id"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	dryRun, err := strconv.ParseBool(r.FormValue("dry_run"))
+	if err != nil && r.FormValue("dry_run") != "" {
+		ReturnCodeError(w, errors.New("bad dry_run"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	file, handler, err := r.FormFile(rateSheetFormName)
+	if err != nil {
+		log.Println("error while import rate sheet: can't get form file: " + err.Error())
+		ReturnCodeError(w, errors.New("can't get rate sheet file"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+	defer file.Close()
+
+	format, err := ratesheet.FormatByName(handler.Filename)
+	if err != nil {
+		ReturnCodeError(w, err, http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		log.Println("error while import rate sheet: " + err.Error())
+		ReturnCodeError(w, errors.New("can't read rate sheet file"), http.StatusInternalServerError, constants.Msg_500)
+		return
+	}
+
+	report, err := provider.ImportRateSheet(uint32(lenderID), format, data, dryRun)
+	switch err {
+	case nil:
+		report.StatusCode = constants.StatusCode_200
+		report.Message = constants.Msg_200
+		err = ReturnNoEscapeCodeJSONResp(w, report, http.StatusOK)
+	case provider.ErrRateSheetRejected:
+		report.StatusCode = constants.StatusCode_400
+		report.Message = err.Error()
+		err = ReturnNoEscapeCodeJSONResp(w, report, http.StatusBadRequest)
+	case provider.ErrRateSheetEmpty:
+		ReturnCodeError(w, err, http.StatusBadRequest, constants.Msg_400)
+		return
+	default:
+		if _, ok := err.(*ratesheet.SheetError); ok {
+			ReturnCodeError(w, err, http.StatusBadRequest, constants.Msg_400)
+			return
+		}
+
+		returnLenderError(w, err)
+		return
+	}
+
+	if err != nil {
+		log.Println("error while return JSON response: " + err.Error())
+	}
+}
@@ -0,0 +1,52 @@
+package controller
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"app/constants"
+	"app/provider"
+)
+
+// AdminRateFeedRefreshPost ingests external rate feed now and reloads best rates, return refresh report.
+// Best rates are reloaded even if feed fails, then report has feed error and status is 502.
+func AdminRateFeedRefreshPost(w http.ResponseWriter, r *http.Request) {
+	report, err := provider.RefreshRates()
+	if report == nil {
+		log.Println("error while refresh rates: " + err.Error())
+		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
+		return
+	}
+
+	code := http.StatusOK
+	report.StatusCode = constants.StatusCode_200
+	report.Message = constants.Msg_200
+	if err != nil {
+		code = http.StatusBadGateway
+		report.StatusCode = constants.StatusCode_502
+		report.Message = err.Error()
+	}
+
+	err = ReturnNoEscapeCodeJSONResp(w, report, code)
+	if err != nil {
+		log.Println("error while return JSON response: " + err.Error())
+	}
+}
+
+// AdminRateFeedStatusGet return the latest rate feed ingestions and tells if feed rates are stale
+func AdminRateFeedStatusGet(w http.ResponseWriter, r *http.Request) {
+	status, err := provider.GetRateFeedStatus()
+	if err != nil {
+		log.Println("error while get rate feed status: " + err.Error())
+		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
+		return
+	}
+
+	status.StatusCode = constants.StatusCode_200
+	status.Message = constants.Msg_200
+	err = ReturnNoEscapeCodeJSONResp(w, status, http.StatusOK)
+	if err != nil {
+		log.Println("error while return JSON response: " + err.Error())
+	}
+}
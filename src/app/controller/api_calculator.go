@@ -0,0 +1,149 @@
+package controller
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"app/constants"
+	"app/provider"
+	"app/webpojo"
+)
+
+// formatCSV is value of format query param for CSV response
+const formatCSV = "csv"
+
+// returnCalculatorError writes response for calculator error
+func returnCalculatorError(w http.ResponseWriter, err error) {
+	switch err {
+	case provider.ErrWrongLoanAmount, provider.ErrWrongTerm, provider.ErrWrongInterest, provider.ErrWrongExtraPayment:
+		ReturnCodeError(w, err, http.StatusBadRequest, constants.Msg_400)
+	case provider.ErrLenderRateNotExist, provider.ErrNoQuote:
+		ReturnCodeError(w, err, http.StatusNotFound, constants.Msg_404)
+	default:
+		log.Println("error while calculate loan: ", err)
+		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
+	}
+}
+
+// readCalculatorReq reads JSON request body to req, writes error response and return false on failure
+func readCalculatorReq(w http.ResponseWriter, r *http.Request, req interface{}) bool {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Println("error while calculate loan: can't read request body: ", err)
+		ReturnCodeError(w, errors.New("error while read body"), http.StatusInternalServerError, constants.Msg_500)
+		return false
+	}
+
+	err = json.Unmarshal(body, req)
+	if err != nil {
+		log.Println("error while calculate loan: error while unmarshal request body: ", err)
+		ReturnCodeError(w, errors.New("can't unmarshal request body"), http.StatusBadRequest, constants.Msg_400)
+		return false
+	}
+
+	return true
+}
+
+// returnCSV writes records as CSV file attachment
+func returnCSV(w http.ResponseWriter, fileName string, records [][]string) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fileName}))
+	w.WriteHeader(http.StatusOK)
+
+	cw := csv.NewWriter(w)
+	err := cw.WriteAll(records)
+	if err != nil {
+		log.Println("error while write CSV response: " + err.Error())
+	}
+}
+
+// formatMoney formats dollars for CSV
+func formatMoney(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+// CalculatorPaymentPost return monthly payment, total payment and total interest of loan
+func CalculatorPaymentPost(w http.ResponseWriter, r *http.Request) {
+	calcReq := &webpojo.CalculatorReq{}
+	if !readCalculatorReq(w, r, calcReq) {
+		return
+	}
+
+	payment, err := provider.CalculatePayment(calcReq)
+	if err != nil {
+		returnCalculatorError(w, err)
+		return
+	}
+
+	payment.StatusCode = constants.StatusCode_200
+	payment.Message = constants.Msg_200
+	ReturnCodeJSONResp(w, payment, http.StatusOK)
+}
+
+// CalculatorSchedulePost return amortization schedule of loan with extra payments.
+// Query param format=csv returns schedule as CSV file.
+func CalculatorSchedulePost(w http.ResponseWriter, r *http.Request) {
+	calcReq := &webpojo.CalculatorReq{}
+	if !readCalculatorReq(w, r, calcReq) {
+		return
+	}
+
+	schedule, err := provider.CalculateAmortization(calcReq)
+	if err != nil {
+		returnCalculatorError(w, err)
+		return
+	}
+
+	if r.URL.Query().Get("format") == formatCSV {
+		records := [][]string{{"month", "payment", "principal", "interest", "extra", "balance"}}
+		for _, v := range schedule.Schedule {
+			records = append(records, []string{strconv.Itoa(v.Month), formatMoney(v.Payment), formatMoney(v.Principal),
+				formatMoney(v.Interest), formatMoney(v.Extra), formatMoney(v.Balance)})
+		}
+
+		returnCSV(w, "amortization_schedule.csv", records)
+		return
+	}
+
+	schedule.StatusCode = constants.StatusCode_200
+	schedule.Message = constants.Msg_200
+	ReturnCodeJSONResp(w, schedule, http.StatusOK)
+}
+
+// CalculatorComparePost return loan cost with every lender rate effective today, the cheapest by APR first.
+// Query param format=csv returns comparison as CSV file.
+func CalculatorComparePost(w http.ResponseWriter, r *http.Request) {
+	compareReq := &webpojo.RateComparisonReq{}
+	if !readCalculatorReq(w, r, compareReq) {
+		return
+	}
+
+	comparison, err := provider.CompareLenderRates(compareReq)
+	if err != nil {
+		returnCalculatorError(w, err)
+		return
+	}
+
+	if r.URL.Query().Get("format") == formatCSV {
+		records := [][]string{{"rate_id", "lender", "product", "term_years", "interest", "apr",
+			"monthly_payment", "total_interest", "apr_cost", "above_best"}}
+		for _, v := range comparison.Comparisons {
+			records = append(records, []string{strconv.FormatUint(uint64(v.ID), 10), v.LenderName, v.Product,
+				strconv.Itoa(v.TermYears), strconv.FormatFloat(v.Interest, 'f', -1, 64), strconv.FormatFloat(v.Apr, 'f', -1, 64),
+				formatMoney(v.MonthlyPayment), formatMoney(v.TotalInterest), formatMoney(v.AprCost), formatMoney(v.AboveBest)})
+		}
+
+		returnCSV(w, "rate_comparison.csv", records)
+		return
+	}
+
+	comparison.StatusCode = constants.StatusCode_200
+	comparison.Message = constants.Msg_200
+	ReturnNoEscapeCodeJSONResp(w, comparison, http.StatusOK)
+}
@@ -6,14 +6,18 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"mime"
 	"net/http"
 	"net/url"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"app/constants"
 	"app/model"
 	"app/provider"
+	"app/shared/cursor"
+	"app/shared/filecategory"
 	fas "app/shared/files_id_storage"
 	"app/shared/passhash"
 	"app/shared/session"
@@ -347,32 +351,83 @@ func CustomerUploadFilePost(w http.ResponseWriter, r *http.Request) {
 	}
 	escapedFileName := strings.Replace(url.QueryEscape(handler.Filename), "+", "%20", -1)
 
-	lastID, err := provider.SaveFileDataInDB(escapedFileName, "raw", getUserID(sess))
+	meta := webpojo.UserFileMeta{
+		Category:    r.FormValue("category"),
+		Description: r.FormValue("description"),
+	}
+
+	if tags := r.FormValue("tags"); tags != "" {
+		meta.Tags = strings.Split(tags, ",")
+	}
+
+	if valid, message := validateFileMeta(&meta); !valid {
+		log.Println("error while upload customer file: invalid metadata: " + message)
+		ReturnCodeError(w, errors.New(message), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	// Upload can fulfill document request from staff
+	requestID := r.FormValue("request_id")
+	if requestID != "" {
+		docRequest, err := provider.GetPendingDocumentRequest(getUserID(sess), requestID)
+		switch err {
+		case nil:
+		case model.ErrNoResult:
+			ReturnCodeError(w, errors.New("document request not found"), http.StatusNotFound, constants.Msg_404)
+			return
+		default:
+			log.Println("error while get document request: " + err.Error())
+			ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
+			return
+		}
+
+		if meta.Category == "" {
+			meta.Category = docRequest.Category
+		}
+	}
+
+	lastID, err := provider.SaveFileDataInDB(escapedFileName, "raw", getUserID(sess), &meta)
 	if err != nil {
 		log.Println("error while add new file info to DB: " + err.Error())
 		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
 		return
 	}
 
-	userName := getUserName(sess)
-	err = provider.UploadFile(&file, handler, fmt.Sprint(lastID)+filepath.Ext(handler.Filename), userName)
+	err = provider.UploadFile(&file, handler, getUserID(sess), lastID)
 	if err != nil {
 		log.Println("error while upload new users file: " + err.Error())
 		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
 		return
 	}
 
+	if requestID != "" {
+		err = provider.FulfillDocumentRequest(getUserID(sess), requestID, lastID)
+		if err != nil {
+			log.Println("error while fulfill document request: " + err.Error())
+			ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
+			return
+		}
+	}
+
 	fakeFileID := fas.GetNewFileID(int(lastID), sess.ID)
 	ReturnCodeJSONResponse(w, http.StatusOK, webpojo.FileIDResponse{FileID: fakeFileID})
 }
 
-// CustomerFilesListGet handler handle all uploads request
+// CustomerFilesListGet return page of customer files.
+// Query params: category, tag, q (search in names and tags), sort (created, name, size, category),
+// order (asc, desc), limit and offset.
 func CustomerFilesListGet(w http.ResponseWriter, r *http.Request) {
 	// Get session
 	sess := session.Instance(r)
 	userID := getUserID(sess)
 
-	list, err := provider.GetFileList(userID, sess.ID)
+	listReq, message := parseFileListReq(r)
+	if listReq == nil {
+		ReturnCodeError(w, errors.New(message), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	list, err := provider.SearchFileList(userID, sess.ID, listReq)
 	if err != nil {
 		log.Println("error while get user files list: " + err.Error())
 		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
@@ -387,7 +442,111 @@ func CustomerFilesListGet(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// CustomerFileDelete not implemented yet
+// parseFileListReq reads file list params from query. Returns nil request and validation error text for bad params.
+func parseFileListReq(r *http.Request) (*webpojo.FileListReq, string) {
+	query := r.URL.Query()
+	listReq := &webpojo.FileListReq{
+		Category: filecategory.Normalize(query.Get("category")),
+		Tag:      strings.ToLower(strings.TrimSpace(query.Get("tag"))),
+		Query:    strings.TrimSpace(query.Get("q")),
+		Sort:     query.Get("sort"),
+	}
+
+	switch query.Get("order") {
+	case "", "asc":
+	case "desc":
+		listReq.Desc = true
+	default:
+		return nil, "order should be asc or desc"
+	}
+
+	if !validateFileSort(listReq.Sort) {
+		return nil, "unknown sort field"
+	}
+
+	if listReq.Category != "" && !filecategory.IsValid(listReq.Category) {
+		return nil, "unknown category"
+	}
+
+	var err error
+	if limit := query.Get("limit"); limit != "" {
+		listReq.Limit, err = strconv.Atoi(limit)
+		if err != nil || listReq.Limit < 0 {
+			return nil, "bad limit"
+		}
+	}
+
+	listReq.Cursor = query.Get("cursor")
+	if _, err = cursor.Decode(listReq.Cursor); err != nil {
+		return nil, "bad cursor"
+	}
+
+	return listReq, ""
+}
+
+// CustomerFilePatch changes category, description and tags of customer file
+func CustomerFilePatch(w http.ResponseWriter, r *http.Request) {
+	sess := session.Instance(r)
+
+	body, readErr := ioutil.ReadAll(r.Body)
+	if readErr != nil {
+		log.Println("error while patch customer file: " + readErr.Error())
+		ReturnCodeError(w, errors.New("can't read request body"), http.StatusInternalServerError, constants.Msg_500)
+		return
+	}
+
+	if len(body) == 0 {
+		log.Println("error while patch customer file: empty json payoload")
+		ReturnCodeError(w, errors.New("emtpy json payload"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	log.Println("customer file patch request: ", string(body))
+	patchReq := webpojo.UserFilePatchReq{}
+	jsonErr := json.Unmarshal(body, &patchReq)
+	if jsonErr != nil {
+		log.Println("error while patch customer file: can't unmarshall request")
+		ReturnCodeError(w, errors.New("can't parse request"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	if valid, message := validateFileMetaPatch(&patchReq.UserFileMetaPatch); !valid {
+		log.Println("error while patch customer file: invalid metadata: " + message)
+		ReturnCodeError(w, errors.New(message), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	realFileID, err := fas.GetRealID(patchReq.ID, sess.ID)
+	if err != nil {
+		log.Println("error while patch customer file: error while get real file ID: id not found")
+		ReturnCodeError(w, errors.New("bad file id"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	err = provider.UpdateFileMeta(getUserID(sess), realFileID, &patchReq.UserFileMetaPatch)
+	switch err {
+	case model.ErrNoResult:
+		ReturnCodeError(w, errors.New("not_found"), http.StatusNotFound, constants.Msg_404)
+		return
+	case nil:
+		ReturnCodeError(w, errors.New(""), http.StatusOK, constants.Msg_200)
+		return
+	default:
+		log.Println("error while patch customer file: " + err.Error())
+		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
+	}
+}
+
+// CustomerFileCategoriesGet return list of document categories
+func CustomerFileCategoriesGet(w http.ResponseWriter, r *http.Request) {
+	err := ReturnNoEscapeCodeJSONResp(w, provider.GetFileCategories(), http.StatusOK)
+	if err != nil {
+		log.Println("error while return JSON response: " + err.Error())
+		return
+	}
+}
+
+// CustomerFileDelete moves customer file to trash
 func CustomerFileDelete(w http.ResponseWriter, r *http.Request) {
 	sess := session.Instance(r)
 
@@ -441,6 +600,91 @@ func CustomerFileDelete(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// CustomerFilesTrashGet return list of files in customer's trash
+func CustomerFilesTrashGet(w http.ResponseWriter, r *http.Request) {
+	// Get session
+	sess := session.Instance(r)
+	userID := getUserID(sess)
+
+	list, err := provider.GetTrashFileList(userID, sess.ID)
+	if err != nil {
+		log.Println("error while get user trash files list: " + err.Error())
+		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
+		return
+	}
+
+	err = ReturnNoEscapeCodeJSONResp(w, list, http.StatusOK)
+	if err != nil {
+		log.Println("error while return JSON response: " + err.Error())
+		return
+	}
+}
+
+// CustomerFileRestorePost moves file back from customer's trash
+func CustomerFileRestorePost(w http.ResponseWriter, r *http.Request) {
+	sess := session.Instance(r)
+
+	body, readErr := ioutil.ReadAll(r.Body)
+	if readErr != nil {
+		log.Println("error while restore customer file: " + readErr.Error())
+		ReturnCodeError(w, errors.New("can't read request body"), http.StatusInternalServerError, constants.Msg_500)
+		return
+	}
+
+	if len(body) == 0 {
+		log.Println("error while restore customer file: empty json payoload")
+		ReturnCodeError(w, errors.New("emtpy json payload"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	log.Println("customer file restore request: ", string(body))
+	idReq := webpojo.IDRequestString{}
+	jsonErr := json.Unmarshal(body, &idReq)
+	if jsonErr != nil {
+		log.Println("error while restore customer file: can't unmarshall request")
+		ReturnCodeError(w, errors.New("can't parse request"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	realFileID, err := fas.GetRealID(idReq.ID, sess.ID)
+	if err != nil {
+		log.Println("error while restore customer file: error while get real file ID: id not found")
+		ReturnCodeError(w, errors.New("bad file id"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	err = provider.FileRestore(getUserID(sess), realFileID)
+	switch err {
+	case model.ErrNoResult:
+		ReturnCodeError(w, errors.New("not_found"), http.StatusNotFound, constants.Msg_404)
+		return
+	case nil:
+		ReturnCodeError(w, errors.New(""), http.StatusOK, constants.Msg_200)
+		return
+	default:
+		log.Println("error while restore customer file: " + err.Error())
+		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
+	}
+}
+
+// CustomerDocumentRequestsGet return list of documents requested from customer by staff
+func CustomerDocumentRequestsGet(w http.ResponseWriter, r *http.Request) {
+	sess := session.Instance(r)
+
+	requests, err := provider.GetDocumentRequests(getUserID(sess), sess.ID)
+	if err != nil {
+		log.Println("error while get customer document requests: " + err.Error())
+		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
+		return
+	}
+
+	err = ReturnNoEscapeCodeJSONResp(w, requests, http.StatusOK)
+	if err != nil {
+		log.Println("error while return JSON response: " + err.Error())
+		return
+	}
+}
+
 // CustomerServeFileGet return one file info
 func CustomerServeFileGet(w http.ResponseWriter, r *http.Request) {
 	sess := session.Instance(r)
@@ -491,6 +735,60 @@ func CustomerServeFileGet(w http.ResponseWriter, r *http.Request) {
 	ReturnNoEscapeCodeJSONResp(w, userFile, http.StatusOK)
 }
 
+// CustomerFileDownloadGet decrypts and serves user file content
+func CustomerFileDownloadGet(w http.ResponseWriter, r *http.Request) {
+	sess := session.Instance(r)
+
+	fakeFileID := r.URL.Query().Get("id")
+	if fakeFileID == "" {
+		log.Println("error while download customer file: id is empty")
+		ReturnCodeError(w, errors.New("bad file id"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	realFileID, err := fas.GetRealID(fakeFileID, sess.ID)
+	if err != nil {
+		log.Println("error while download customer file: error while get real file ID: id not found")
+		ReturnCodeError(w, errors.New("bad file id"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	data, fileInfo, err := provider.GetFileContent(getUserID(sess), realFileID)
+	switch err {
+	case nil:
+	case model.ErrNoResult:
+		ReturnCodeError(w, errors.New("not_found"), http.StatusNotFound, constants.Msg_404)
+		return
+	case provider.ErrFileNotScanned, provider.ErrFileInfected:
+		ReturnCodeError(w, err, http.StatusConflict, constants.Msg_409)
+		return
+	default:
+		log.Println("error while download customer file: " + err.Error())
+		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
+		return
+	}
+
+	serveFileContent(w, data, fileInfo)
+}
+
+// serveFileContent writes decrypted file content as attachment
+func serveFileContent(w http.ResponseWriter, data []byte, fileInfo *model.UserFile) {
+	fileName, err := url.QueryUnescape(fileInfo.FileName)
+	if err != nil {
+		fileName = fileInfo.FileName
+	}
+
+	contentType := mime.TypeByExtension(filepath.Ext(fileName))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fileName}))
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
 // // CustomerMessageByID returns single customer message
 // func CustomerMessageByID(w http.ResponseWriter, r *http.Request) {
 // 	sess := session.Instance(r)
@@ -0,0 +1,161 @@
+package controller
+
+import (
+	"app/constants"
+	"app/shared/events"
+	"app/shared/session"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	eventsHeartbeatPeriod = 25 * time.Second
+	eventsWriteTimeout    = 10 * time.Second
+	eventsPongTimeout     = 60 * time.Second
+	eventsRetryMillis     = 3000
+)
+
+// Browser sends session cookie with websocket handshake, so only same origin connections are accepted
+var eventsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// EventsGet streams user's real-time events. WebSocket is used when client requests upgrade, Server-Sent Events otherwise.
+func EventsGet(w http.ResponseWriter, r *http.Request) {
+	sess := session.Instance(r)
+
+	if sess == nil {
+		log.Println("error while open events stream: sess is nil")
+		ReturnCodeError(w, errors.New("unauthorized"), http.StatusUnauthorized, constants.Msg_401)
+		return
+	}
+
+	userID, err := strconv.ParseUint(getUserID(sess), 10, 32)
+	if err != nil {
+		log.Println("error while open events stream: can't parse user ID: ", err)
+		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
+		return
+	}
+
+	if websocket.IsWebSocketUpgrade(r) {
+		serveEventsWebSocket(w, r, uint32(userID))
+		return
+	}
+
+	serveEventsSSE(w, r, uint32(userID))
+}
+
+// serveEventsSSE writes events as text/event-stream until client disconnects
+func serveEventsSSE(w http.ResponseWriter, r *http.Request, userID uint32) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		log.Println("error while open events stream: response writer doesn't support flush")
+		ReturnCodeError(w, errors.New("streaming unsupported"), http.StatusInternalServerError, constants.Msg_500)
+		return
+	}
+
+	sub := events.Subscribe(userID)
+	defer events.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "retry: %d\n\n", eventsRetryMillis)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(eventsHeartbeatPeriod)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case e, ok := <-sub.Events():
+			if !ok {
+				// Subscription was dropped as too slow, client reconnects after retry period
+				return
+			}
+
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, e.Data); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// serveEventsWebSocket writes events as JSON text frames until client disconnects
+func serveEventsWebSocket(w http.ResponseWriter, r *http.Request, userID uint32) {
+	conn, err := eventsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrader already replied with error
+		log.Println("error while upgrade events connection: ", err)
+		return
+	}
+	defer conn.Close()
+
+	sub := events.Subscribe(userID)
+	defer events.Unsubscribe(sub)
+
+	// Client doesn't send anything except control frames, reading is needed to process pongs and close
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+
+		conn.SetReadLimit(512)
+		conn.SetReadDeadline(time.Now().Add(eventsPongTimeout))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(eventsPongTimeout))
+		})
+
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	heartbeat := time.NewTicker(eventsHeartbeatPeriod)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case <-heartbeat.C:
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(eventsWriteTimeout))
+		case e, ok := <-sub.Events():
+			if !ok {
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseGoingAway, "too slow"), time.Now().Add(eventsWriteTimeout))
+				return
+			}
+
+			var msg []byte
+			msg, err = json.Marshal(e)
+			if err == nil {
+				conn.SetWriteDeadline(time.Now().Add(eventsWriteTimeout))
+				err = conn.WriteMessage(websocket.TextMessage, msg)
+			}
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
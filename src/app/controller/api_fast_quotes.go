@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+
+	"app/constants"
+	"app/provider"
+	"app/shared/session"
+	"app/webpojo"
+)
+
+// FastQuoteClaimPost attaches anonymous fast quote to signed in customer
+func FastQuoteClaimPost(w http.ResponseWriter, r *http.Request) {
+	sess := session.Instance(r)
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Println("error while claim fast quote: " + err.Error())
+		ReturnCodeError(w, errors.New("can't read request body"), http.StatusInternalServerError, constants.Msg_500)
+		return
+	}
+
+	claimReq := &webpojo.FastQuoteClaimReq{}
+	jsonErr := json.Unmarshal(body, claimReq)
+	if jsonErr != nil {
+		log.Println("error while claim fast quote: can't unmarshall request: " + jsonErr.Error())
+		ReturnCodeError(w, errors.New("can't parse request"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	err = provider.ClaimFastQuote(getUserID(sess), claimReq.QuoteID)
+	switch err {
+	case nil:
+		ReturnCodeError(w, errors.New(""), http.StatusOK, constants.Msg_200)
+	case provider.ErrFastQuoteNotExist:
+		ReturnCodeError(w, err, http.StatusNotFound, constants.Msg_404)
+	case provider.ErrFastQuoteClaimed:
+		ReturnCodeError(w, err, http.StatusConflict, constants.Msg_409)
+	default:
+		log.Println("error while claim fast quote: " + err.Error())
+		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
+	}
+}
+
+// FastQuoteListGet return fast quotes of customer
+func FastQuoteListGet(w http.ResponseWriter, r *http.Request) {
+	sess := session.Instance(r)
+
+	quotes, err := provider.GetFastQuotes(getUserID(sess))
+	if err != nil {
+		log.Println("error while get fast quotes: " + err.Error())
+		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
+		return
+	}
+
+	err = ReturnNoEscapeCodeJSONResp(w, &webpojo.FastQuoteListResp{StatusCode: constants.StatusCode_200, Message: constants.Msg_200, Quotes: quotes}, http.StatusOK)
+	if err != nil {
+		log.Println("error while return JSON response: " + err.Error())
+	}
+}
+
+// AdminFastQuoteReportGet return fast quote volume and conversion to customers and loan applications by day.
+// Query params: from and to (YYYY-MM-DD), last 30 days if empty.
+func AdminFastQuoteReportGet(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	report, err := provider.GetFastQuoteReport(query.Get("from"), query.Get("to"))
+	switch err {
+	case nil:
+	case provider.ErrWrongRateDate, provider.ErrWrongRateRange:
+		ReturnCodeError(w, err, http.StatusBadRequest, constants.Msg_400)
+		return
+	default:
+		log.Println("error while get fast quote report: " + err.Error())
+		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
+		return
+	}
+
+	report.StatusCode = constants.StatusCode_200
+	report.Message = constants.Msg_200
+	err = ReturnNoEscapeCodeJSONResp(w, report, http.StatusOK)
+	if err != nil {
+		log.Println("error while return JSON response: " + err.Error())
+	}
+}
@@ -0,0 +1,205 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+
+	"app/constants"
+	"app/model"
+	"app/provider"
+	"app/shared/session"
+	"app/webpojo"
+)
+
+// returnLoanApplicationError writes response for loan application error
+func returnLoanApplicationError(w http.ResponseWriter, err error) {
+	switch err {
+	case provider.ErrLoanApplicationNotExist, provider.ErrLenderRateNotExist, provider.ErrAttachmentNotFound:
+		ReturnCodeError(w, err, http.StatusNotFound, constants.Msg_404)
+	case model.ErrUnauthorized:
+		ReturnCodeError(w, errors.New("customer is not assigned"), http.StatusForbidden, constants.Msg_403)
+	case provider.ErrWrongLoanAmount, provider.ErrWrongLoanState, provider.ErrLoanNoteTooLong, provider.ErrTooManyAttachments:
+		ReturnCodeError(w, err, http.StatusBadRequest, constants.Msg_400)
+	case provider.ErrWrongLoanTransition, provider.ErrLenderRateNotEffective:
+		ReturnCodeError(w, err, http.StatusConflict, constants.Msg_409)
+	default:
+		log.Println("error while process loan application: " + err.Error())
+		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
+	}
+}
+
+// readLoanApplicationReq return loan application request from body, writes error response if it can't be read
+func readLoanApplicationReq(w http.ResponseWriter, r *http.Request) (*webpojo.LoanApplicationReq, bool) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Println("error while read loan application: " + err.Error())
+		ReturnCodeError(w, errors.New("can't read request body"), http.StatusInternalServerError, constants.Msg_500)
+		return nil, false
+	}
+
+	appReq := &webpojo.LoanApplicationReq{}
+	jsonErr := json.Unmarshal(body, appReq)
+	if jsonErr != nil || appReq.LenderRateID == 0 {
+		log.Println("error while read loan application: can't unmarshall request")
+		ReturnCodeError(w, errors.New("can't parse request"), http.StatusBadRequest, constants.Msg_400)
+		return nil, false
+	}
+
+	return appReq, true
+}
+
+// getLoanApplicationIDParam return id query param if it's a valid ID
+func getLoanApplicationIDParam(r *http.Request) (uint32, bool) {
+	id, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 32)
+	if err != nil || id == 0 {
+		return 0, false
+	}
+
+	return uint32(id), true
+}
+
+// LoanApplicationPost creates customer's draft loan application and records it as session lead, return application ID
+func LoanApplicationPost(w http.ResponseWriter, r *http.Request) {
+	sess := session.Instance(r)
+
+	appReq, ok := readLoanApplicationReq(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := provider.CreateLoanApplication(getUserID(sess), appReq)
+	if err != nil {
+		returnLoanApplicationError(w, err)
+		return
+	}
+
+	RecordLeadID(sess, fmt.Sprint(id))
+	sess.Save(r, w)
+
+	ReturnCodeJSONResponse(w, http.StatusOK, webpojo.IDResponse{ID: id})
+}
+
+// LoanApplicationPut changes rate, loan amount and documents of customer's draft loan application
+func LoanApplicationPut(w http.ResponseWriter, r *http.Request) {
+	sess := session.Instance(r)
+
+	appReq, ok := readLoanApplicationReq(w, r)
+	if !ok {
+		return
+	}
+
+	if appReq.ID == 0 {
+		ReturnCodeError(w, errors.New("id is empty"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	err := provider.UpdateLoanApplication(getUserID(sess), appReq)
+	if err != nil {
+		returnLoanApplicationError(w, err)
+		return
+	}
+
+	ReturnCodeError(w, errors.New(""), http.StatusOK, constants.Msg_200)
+}
+
+// LoanApplicationListGet return customer's loan applications
+func LoanApplicationListGet(w http.ResponseWriter, r *http.Request) {
+	sess := session.Instance(r)
+
+	apps, err := provider.GetLoanApplications(getUserID(sess))
+	if err != nil {
+		returnLoanApplicationError(w, err)
+		return
+	}
+
+	err = ReturnNoEscapeCodeJSONResp(w, &webpojo.LoanApplicationListResp{StatusCode: constants.StatusCode_200, Message: constants.Msg_200, Applications: apps}, http.StatusOK)
+	if err != nil {
+		log.Println("error while return JSON response: " + err.Error())
+	}
+}
+
+// LoanApplicationGet return loan application with documents and state history to its customer,
+// assigned staff, supervisors and admins. Query param: id.
+func LoanApplicationGet(w http.ResponseWriter, r *http.Request) {
+	sess := session.Instance(r)
+
+	id, ok := getLoanApplicationIDParam(r)
+	if !ok {
+		ReturnCodeError(w, errors.New("bad id"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	app, err := provider.GetLoanApplication(getUserID(sess), getUserRole(sess), id)
+	if err != nil {
+		returnLoanApplicationError(w, err)
+		return
+	}
+
+	err = ReturnNoEscapeCodeJSONResp(w, &webpojo.LoanApplicationResp{StatusCode: constants.StatusCode_200, Message: constants.Msg_200, Application: *app}, http.StatusOK)
+	if err != nil {
+		log.Println("error while return JSON response: " + err.Error())
+	}
+}
+
+// LoanApplicationStatePost moves loan application to requested state. Customers submit and withdraw
+// their applications, staff reviews, approves, declines and funds them.
+func LoanApplicationStatePost(w http.ResponseWriter, r *http.Request) {
+	sess := session.Instance(r)
+
+	body, readErr := ioutil.ReadAll(r.Body)
+	if readErr != nil {
+		log.Println("error while change loan application state: " + readErr.Error())
+		ReturnCodeError(w, errors.New("can't read request body"), http.StatusInternalServerError, constants.Msg_500)
+		return
+	}
+
+	stateReq := &webpojo.LoanApplicationStateReq{}
+	jsonErr := json.Unmarshal(body, stateReq)
+	if jsonErr != nil || stateReq.ID == 0 {
+		log.Println("error while change loan application state: can't unmarshall request")
+		ReturnCodeError(w, errors.New("can't parse request"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	err := provider.ChangeLoanApplicationState(getUserID(sess), getUserRole(sess), stateReq)
+	if err != nil {
+		returnLoanApplicationError(w, err)
+		return
+	}
+
+	ReturnCodeError(w, errors.New(""), http.StatusOK, constants.Msg_200)
+}
+
+// StaffLoanApplicationListGet return loan applications of assigned customers, supervisors and admins get all
+// applications. Query params: state and customer_id, both optional.
+func StaffLoanApplicationListGet(w http.ResponseWriter, r *http.Request) {
+	sess := session.Instance(r)
+
+	var customerID uint32
+	if r.URL.Query().Get("customer_id") != "" {
+		id, ok := getCustomerIDParam(r)
+		if !ok {
+			ReturnCodeError(w, errors.New("bad customer id"), http.StatusBadRequest, constants.Msg_400)
+			return
+		}
+
+		uintID, _ := strconv.ParseUint(id, 10, 32)
+		customerID = uint32(uintID)
+	}
+
+	apps, err := provider.StaffGetLoanApplications(getUserID(sess), getUserRole(sess), r.URL.Query().Get("state"), customerID)
+	if err != nil {
+		returnLoanApplicationError(w, err)
+		return
+	}
+
+	err = ReturnNoEscapeCodeJSONResp(w, &webpojo.LoanApplicationListResp{StatusCode: constants.StatusCode_200, Message: constants.Msg_200, Applications: apps}, http.StatusOK)
+	if err != nil {
+		log.Println("error while return JSON response: " + err.Error())
+	}
+}
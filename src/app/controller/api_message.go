@@ -4,15 +4,18 @@ import (
 	"app/constants"
 	"app/model"
 	"app/provider"
+	"app/shared/cursor"
 	"app/shared/session"
 	"app/webpojo"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"io/ioutil"
 	"log"
+	"mime/multipart"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 )
 
 // UserMessagePost send new message to user
@@ -25,7 +28,20 @@ func UserMessagePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	body, err := ioutil.ReadAll(r.Body)
+	// Message with inline attachments is sent as multipart form: JSON in "message" field and files in "uploadfile" fields
+	var body []byte
+	var err error
+	var uploads []*multipart.FileHeader
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		err = r.ParseMultipartForm(32 << 20)
+		if err == nil {
+			body = []byte(r.FormValue("message"))
+			uploads = r.MultipartForm.File[uploadFileFormName]
+		}
+	} else {
+		body, err = ioutil.ReadAll(r.Body)
+	}
+
 	if err != nil {
 		log.Println("error while post new user's message: " + err.Error())
 		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
@@ -48,13 +64,24 @@ func UserMessagePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if getUserID(sess) != fmt.Sprint(messagePostReq.FromUserID) {
-		log.Println("error while post new user's message: user ID from session and in JSON request is not equal")
-		ReturnCodeError(w, errors.New("wrong user ID"), http.StatusBadRequest, constants.Msg_400)
-		return
+	// Inline uploads are removed if message is not posted
+	var uploadedIDs []uint32
+	if len(uploads) > 0 {
+		uploadedIDs, err = saveMessageUploads(getUserID(sess), uploads)
+		if err != nil {
+			provider.DiscardFiles(getUserID(sess), uploadedIDs)
+			log.Println("error while post new user's message: can't save attachment: " + err.Error())
+			ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
+			return
+		}
+		messagePostReq.FileIDs = append(messagePostReq.FileIDs, uploadedIDs...)
 	}
 
 	resp, err := provider.PostNewMessage(getUserID(sess), messagePostReq)
+	if err != nil {
+		provider.DiscardFiles(getUserID(sess), uploadedIDs)
+	}
+
 	switch err {
 	case nil:
 		ReturnCodeJSONResponse(w, http.StatusOK, resp)
@@ -67,6 +94,23 @@ func UserMessagePost(w http.ResponseWriter, r *http.Request) {
 		log.Println("error while post new user's message: user not exist")
 		ReturnCodeError(w, errors.New("can't create message: user not exist"), http.StatusNotFound, constants.Msg_404)
 		return
+	case model.ErrThreadNotExist:
+		log.Println("error while post new user's message: thread not exist")
+		ReturnCodeError(w, errors.New("can't create message: thread not exist"), http.StatusNotFound, constants.Msg_404)
+		return
+	case model.ErrUnauthorized:
+		log.Println("error while post new user's message: user is not thread participant or can't add participants")
+		ReturnCodeError(w, errors.New("can't create message: not thread participant or not allowed to add participants"), http.StatusForbidden, constants.Msg_403)
+		return
+	case provider.ErrAttachmentNotFound:
+		ReturnCodeError(w, err, http.StatusNotFound, constants.Msg_404)
+		return
+	case provider.ErrTooManyAttachments:
+		ReturnCodeError(w, err, http.StatusBadRequest, constants.Msg_400)
+		return
+	case provider.ErrThreadArchived:
+		ReturnCodeError(w, errors.New("can't create message: thread is archived"), http.StatusConflict, constants.Msg_409)
+		return
 	default:
 		log.Println("error while post new user's message: " + err.Error())
 		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
@@ -109,20 +153,23 @@ func MessageThreads(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if messagesReq.Offset < 0 {
-		log.Println("error while get customer's message threads list: offset < 0")
-		ReturnCodeError(w, errors.New("bad_request: offset less then 0"), http.StatusBadRequest, constants.Msg_400)
+	list, cursors, err := provider.ThreadsByUserID(userID, &messagesReq.ThreadFilter, messagesReq.Count, messagesReq.Cursor)
+	switch err {
+	case nil:
+	case provider.ErrWrongThreadStatus:
+		ReturnCodeError(w, errors.New("bad_request: unknown thread status"), http.StatusBadRequest, constants.Msg_400)
 		return
-	}
-
-	list, err := provider.ThreadsByUserID(userID, messagesReq.Count, messagesReq.Offset)
-	if err != nil {
+	case cursor.ErrInvalid:
+		log.Println("error while get customer's message threads list: bad cursor")
+		ReturnCodeError(w, errors.New("bad_request: bad cursor"), http.StatusBadRequest, constants.Msg_400)
+		return
+	default:
 		log.Println("error while get customer's messages threads: " + err.Error())
 		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
 		return
 	}
 
-	err = ReturnCodeJSONResp(w, list, http.StatusOK)
+	err = ReturnCodeJSONResp(w, &webpojo.MessagesThreadsListResp{Threads: list, PageCursors: *cursors}, http.StatusOK)
 	if err != nil {
 		log.Println("error while get customer's messages threads: error while return JSON response: " + err.Error())
 		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
@@ -185,6 +232,10 @@ func MarkMessageAsReaded(w http.ResponseWriter, r *http.Request) {
 		log.Println("error while patch customer message's message (set readed): user not exist")
 		ReturnCodeError(w, errors.New("can't patch message: user not exist"), http.StatusNotFound, constants.Msg_404)
 		return
+	case model.ErrUnauthorized:
+		log.Println("error while patch customer message (set readed): user is not thread participant")
+		ReturnCodeError(w, errors.New("can't patch message: not thread participant"), http.StatusForbidden, constants.Msg_403)
+		return
 	default:
 		log.Println("error while patch customer message's message (set readed): " + err.Error())
 		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
@@ -234,23 +285,95 @@ func UserMessageList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if messagesReq.Offset < 0 {
-		log.Println("error while get customer's message list: offset < 0")
-		ReturnCodeError(w, errors.New("bad_request: offset less then 0"), http.StatusBadRequest, constants.Msg_400)
+	list, cursors, err := provider.GetCustomerMessagesList(messagesReq.ThreadID, uint32(iUserID), messagesReq.Count, messagesReq.Cursor)
+	switch err {
+	case nil:
+	case cursor.ErrInvalid:
+		log.Println("error while get customer's message list: bad cursor")
+		ReturnCodeError(w, errors.New("bad_request: bad cursor"), http.StatusBadRequest, constants.Msg_400)
 		return
-	}
-
-	list, err := provider.GetCustomerMessagesList(messagesReq.ThreadID, uint32(iUserID), messagesReq.Count, messagesReq.Offset)
-	if err != nil {
+	case model.ErrThreadNotExist:
+		log.Println("error while get customer's messages list: thread not exist")
+		ReturnCodeError(w, errors.New("thread not exist"), http.StatusNotFound, constants.Msg_404)
+		return
+	case model.ErrUnauthorized:
+		log.Println("error while get customer's messages list: user is not thread participant")
+		ReturnCodeError(w, errors.New("not thread participant"), http.StatusForbidden, constants.Msg_403)
+		return
+	default:
 		log.Println("error while get customer's messages list: " + err.Error())
 		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
 		return
 	}
 
-	err = ReturnCodeJSONResp(w, list, http.StatusOK)
+	err = ReturnCodeJSONResp(w, &webpojo.MessagesListResp{Messages: list, PageCursors: *cursors}, http.StatusOK)
 	if err != nil {
 		log.Println("error while get customer's messages list: error while return JSON response: " + err.Error())
 		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
 		return
 	}
 }
+
+// saveMessageUploads stores inline message attachments as user's files with default category and return their IDs.
+// On error returned IDs contain files saved before it, including partly saved file.
+func saveMessageUploads(userID string, uploads []*multipart.FileHeader) ([]uint32, error) {
+	fileIDs := []uint32{}
+	for _, fileHeader := range uploads {
+		file, err := fileHeader.Open()
+		if err != nil {
+			return fileIDs, err
+		}
+
+		escapedFileName := strings.Replace(url.QueryEscape(fileHeader.Filename), "+", "%20", -1)
+		lastID, err := provider.SaveFileDataInDB(escapedFileName, "raw", userID, nil)
+		if err == nil {
+			fileIDs = append(fileIDs, uint32(lastID))
+			err = provider.UploadFile(&file, fileHeader, userID, lastID)
+		}
+		file.Close()
+
+		if err != nil {
+			return fileIDs, err
+		}
+	}
+
+	return fileIDs, nil
+}
+
+// MessageAttachmentGet serves content of file attached to message to thread participants.
+// Query params: message_id and file_id.
+func MessageAttachmentGet(w http.ResponseWriter, r *http.Request) {
+	sess := session.Instance(r)
+
+	messageID, err := strconv.ParseUint(r.URL.Query().Get("message_id"), 10, 32)
+	if err != nil || messageID == 0 {
+		ReturnCodeError(w, errors.New("bad message id"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	fileID, err := strconv.ParseUint(r.URL.Query().Get("file_id"), 10, 32)
+	if err != nil || fileID == 0 {
+		ReturnCodeError(w, errors.New("bad file id"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	data, fileInfo, err := provider.GetMessageAttachmentContent(getStaffActor(sess, r), uint32(messageID), uint32(fileID))
+	switch err {
+	case nil:
+	case model.ErrNoResult:
+		ReturnCodeError(w, errors.New("not_found"), http.StatusNotFound, constants.Msg_404)
+		return
+	case model.ErrUnauthorized:
+		ReturnCodeError(w, errors.New("not thread participant"), http.StatusForbidden, constants.Msg_403)
+		return
+	case provider.ErrFileNotScanned, provider.ErrFileInfected:
+		ReturnCodeError(w, err, http.StatusConflict, constants.Msg_409)
+		return
+	default:
+		log.Println("error while download message attachment: " + err.Error())
+		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
+		return
+	}
+
+	serveFileContent(w, data, fileInfo)
+}
@@ -8,13 +8,13 @@ import (
 	"app/webpojo"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"strconv"
 )
 
-// AdminMessagePatch edit user's message
+// AdminMessagePatch edit user's message, previous content is kept in revision history
 func AdminMessagePatch(w http.ResponseWriter, r *http.Request) {
 	sess := session.Instance(r)
 
@@ -47,13 +47,7 @@ func AdminMessagePatch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if getUserID(sess) != fmt.Sprint(messagePatchReq.FromUserID) {
-		log.Println("error while patch user's message: user ID from session and in JSON request is not equal")
-		ReturnCodeError(w, errors.New("wrong user ID"), http.StatusConflict, constants.Msg_409)
-		return
-	}
-
-	err = provider.PatchMessage(messagePatchReq)
+	err = provider.PatchMessage(getUserID(sess), messagePatchReq)
 	switch err {
 	case nil:
 		ReturnCodeError(w, errors.New(""), http.StatusOK, constants.Msg_200)
@@ -77,7 +71,7 @@ func AdminMessagePatch(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// AdminMessageDelete delete user's message
+// AdminMessageDelete mark user's message deleted
 func AdminMessageDelete(w http.ResponseWriter, r *http.Request) {
 	sess := session.Instance(r)
 
@@ -110,13 +104,7 @@ func AdminMessageDelete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if fmt.Sprint(idReq.FromUserID) != getUserID(sess) {
-		log.Println("error while delete customer's message: from user ID and user ID from session is not equal: ", idReq.FromUserID, " - ", getUserID(sess))
-		ReturnCodeError(w, errors.New("wrong from user ID"), http.StatusConflict, constants.Msg_409)
-		return
-	}
-
-	err := provider.DeleteMessage(idReq.FromUserID, idReq.MessageID, idReq.ThreadID)
+	err := provider.DeleteMessage(getUserID(sess), idReq.FromUserID, idReq.MessageID, idReq.ThreadID)
 	switch err {
 	case nil:
 		ReturnCodeError(w, errors.New(""), http.StatusOK, constants.Msg_200)
@@ -138,3 +126,73 @@ func AdminMessageDelete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+// AdminMessageHistoryGet return message with its original content and revision history.
+// Query param: message_id.
+func AdminMessageHistoryGet(w http.ResponseWriter, r *http.Request) {
+	messageID, err := strconv.ParseUint(r.URL.Query().Get("message_id"), 10, 32)
+	if err != nil {
+		ReturnCodeError(w, errors.New("bad message_id"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	history, err := provider.GetMessageHistory(uint32(messageID))
+	switch err {
+	case nil:
+		err = ReturnNoEscapeCodeJSONResp(w, history, http.StatusOK)
+		if err != nil {
+			log.Println("error while return JSON response: " + err.Error())
+			ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
+		}
+	case model.ErrMessageNotExist:
+		ReturnCodeError(w, errors.New("message not found"), http.StatusNotFound, constants.Msg_404)
+	default:
+		log.Println("error while get message history: " + err.Error())
+		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
+	}
+}
+
+// AdminMessageRestorePost undelete user's message, optionally bring back content of its revision
+func AdminMessageRestorePost(w http.ResponseWriter, r *http.Request) {
+	sess := session.Instance(r)
+
+	if sess == nil {
+		log.Println("error while restore user's message: sess is nil")
+		ReturnCodeError(w, errors.New("unauthorized"), http.StatusUnauthorized, constants.Msg_401)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Println("error while restore user's message: " + err.Error())
+		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
+		return
+	}
+
+	if len(body) == 0 {
+		log.Println("error while restore user's message: empty json payoload")
+		ReturnCodeError(w, errors.New("emtpy json payload"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	restoreReq := &webpojo.MessageRestoreReq{}
+	jsonErr := json.Unmarshal(body, restoreReq)
+	if jsonErr != nil || restoreReq.MessageID == 0 {
+		log.Println("error while restore user's message: can't unmarshall request")
+		ReturnCodeError(w, errors.New("can't parse request"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	err = provider.RestoreMessage(getUserID(sess), restoreReq)
+	switch err {
+	case nil:
+		ReturnCodeError(w, errors.New(""), http.StatusOK, constants.Msg_200)
+	case model.ErrMessageNotExist:
+		ReturnCodeError(w, errors.New("message not found"), http.StatusNotFound, constants.Msg_404)
+	case model.ErrNoResult:
+		ReturnCodeError(w, errors.New("revision not found"), http.StatusNotFound, constants.Msg_404)
+	default:
+		log.Println("error while restore user's message: " + err.Error())
+		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
+	}
+}
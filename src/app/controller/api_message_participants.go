@@ -0,0 +1,121 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+
+	"app/constants"
+	"app/model"
+	"app/provider"
+	"app/shared/session"
+	"app/webpojo"
+)
+
+// readThreadParticipantReq read and validate thread participant request body
+func readThreadParticipantReq(w http.ResponseWriter, r *http.Request) (*webpojo.ThreadParticipantReq, bool) {
+	body, readErr := ioutil.ReadAll(r.Body)
+	if readErr != nil {
+		log.Println("error while read thread participant request: " + readErr.Error())
+		ReturnCodeError(w, errors.New("can't read request body"), http.StatusInternalServerError, constants.Msg_500)
+		return nil, false
+	}
+
+	if len(body) == 0 {
+		log.Println("error while read thread participant request: empty json payoload")
+		ReturnCodeError(w, errors.New("emtpy json payload"), http.StatusBadRequest, constants.Msg_400)
+		return nil, false
+	}
+
+	participantReq := &webpojo.ThreadParticipantReq{}
+	jsonErr := json.Unmarshal(body, participantReq)
+	if jsonErr != nil {
+		log.Println("error while read thread participant request: can't unmarshall request")
+		ReturnCodeError(w, errors.New("can't parse request"), http.StatusBadRequest, constants.Msg_400)
+		return nil, false
+	}
+
+	if participantReq.ThreadID == 0 || participantReq.UserID == 0 {
+		ReturnCodeError(w, errors.New("thread_id and user_id are required"), http.StatusBadRequest, constants.Msg_400)
+		return nil, false
+	}
+
+	return participantReq, true
+}
+
+// returnThreadParticipantError writes response for thread participant operation error
+func returnThreadParticipantError(w http.ResponseWriter, err error) {
+	switch err {
+	case model.ErrUnauthorized:
+		ReturnCodeError(w, errors.New("not allowed for thread"), http.StatusForbidden, constants.Msg_403)
+	case model.ErrThreadNotExist:
+		ReturnCodeError(w, errors.New("thread not exist"), http.StatusNotFound, constants.Msg_404)
+	case model.ErrUserNotExist, model.ErrNoResult:
+		ReturnCodeError(w, errors.New("participant not found"), http.StatusNotFound, constants.Msg_404)
+	default:
+		log.Println("error while change thread participants: " + err.Error())
+		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
+	}
+}
+
+// MessageThreadParticipantsGet return participants of thread from thread_id query param
+func MessageThreadParticipantsGet(w http.ResponseWriter, r *http.Request) {
+	sess := session.Instance(r)
+
+	threadID, err := strconv.ParseUint(r.URL.Query().Get("thread_id"), 10, 32)
+	if err != nil || threadID == 0 {
+		ReturnCodeError(w, errors.New("bad thread id"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	participants, err := provider.GetThreadParticipants(getUserID(sess), uint32(threadID))
+	if err != nil {
+		returnThreadParticipantError(w, err)
+		return
+	}
+
+	err = ReturnNoEscapeCodeJSONResp(w, participants, http.StatusOK)
+	if err != nil {
+		log.Println("error while return JSON response: " + err.Error())
+		return
+	}
+}
+
+// MessageThreadParticipantPost adds participant to thread
+func MessageThreadParticipantPost(w http.ResponseWriter, r *http.Request) {
+	sess := session.Instance(r)
+
+	participantReq, ok := readThreadParticipantReq(w, r)
+	if !ok {
+		return
+	}
+
+	err := provider.AddThreadParticipant(getUserID(sess), getUserRole(sess), participantReq)
+	if err != nil {
+		returnThreadParticipantError(w, err)
+		return
+	}
+
+	ReturnCodeError(w, errors.New(""), http.StatusOK, constants.Msg_200)
+}
+
+// MessageThreadParticipantDelete removes participant from thread
+func MessageThreadParticipantDelete(w http.ResponseWriter, r *http.Request) {
+	sess := session.Instance(r)
+
+	participantReq, ok := readThreadParticipantReq(w, r)
+	if !ok {
+		return
+	}
+
+	err := provider.RemoveThreadParticipant(getUserID(sess), getUserRole(sess), participantReq)
+	if err != nil {
+		returnThreadParticipantError(w, err)
+		return
+	}
+
+	ReturnCodeError(w, errors.New(""), http.StatusOK, constants.Msg_200)
+}
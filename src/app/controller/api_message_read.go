@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+
+	"app/constants"
+	"app/model"
+	"app/provider"
+	"app/shared/session"
+	"app/webpojo"
+)
+
+// MessageUnreadGet return numbers of user's unread messages, total and per thread
+func MessageUnreadGet(w http.ResponseWriter, r *http.Request) {
+	sess := session.Instance(r)
+
+	unread, err := provider.GetUnreadCounts(getUserID(sess))
+	if err != nil {
+		log.Println("error while get unread counts: " + err.Error())
+		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
+		return
+	}
+
+	err = ReturnNoEscapeCodeJSONResp(w, unread, http.StatusOK)
+	if err != nil {
+		log.Println("error while return JSON response: " + err.Error())
+		return
+	}
+}
+
+// MessageThreadReadPost marks thread messages readed up to message_id, or all of them if it is omitted
+func MessageThreadReadPost(w http.ResponseWriter, r *http.Request) {
+	sess := session.Instance(r)
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Println("error while mark thread read: " + err.Error())
+		ReturnCodeError(w, errors.New("can't read request body"), http.StatusInternalServerError, constants.Msg_500)
+		return
+	}
+
+	if len(body) == 0 {
+		log.Println("error while mark thread read: empty json payoload")
+		ReturnCodeError(w, errors.New("emtpy json payload"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	threadReadReq := &webpojo.ThreadReadReq{}
+	jsonErr := json.Unmarshal(body, threadReadReq)
+	if jsonErr != nil {
+		log.Println("error while mark thread read: can't unmarshall request: " + jsonErr.Error())
+		ReturnCodeError(w, errors.New("can't parse request"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	if threadReadReq.ThreadID == 0 {
+		ReturnCodeError(w, errors.New("thread_id is required"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	cursor, err := provider.MarkThreadRead(getUserID(sess), threadReadReq)
+	switch err {
+	case nil:
+	case model.ErrUnauthorized:
+		ReturnCodeError(w, errors.New("can't mark thread read: not thread participant"), http.StatusForbidden, constants.Msg_403)
+		return
+	case model.ErrThreadNotExist:
+		ReturnCodeError(w, errors.New("can't mark thread read: thread not exist"), http.StatusNotFound, constants.Msg_404)
+		return
+	case model.ErrMessageNotExist:
+		ReturnCodeError(w, errors.New("can't mark thread read: message not found in thread"), http.StatusNotFound, constants.Msg_404)
+		return
+	default:
+		log.Println("error while mark thread read: " + err.Error())
+		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
+		return
+	}
+
+	err = ReturnNoEscapeCodeJSONResp(w, &webpojo.ThreadReadResp{ThreadID: threadReadReq.ThreadID, LastReadMessageID: cursor}, http.StatusOK)
+	if err != nil {
+		log.Println("error while return JSON response: " + err.Error())
+		return
+	}
+}
@@ -0,0 +1,53 @@
+package controller
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"app/constants"
+	"app/provider"
+	"app/shared/session"
+)
+
+// MessageSearchGet finds user's messages by words from q query param, count limits number of results
+func MessageSearchGet(w http.ResponseWriter, r *http.Request) {
+	sess := session.Instance(r)
+	query := r.URL.Query()
+
+	q := strings.TrimSpace(query.Get("q"))
+	if q == "" {
+		ReturnCodeError(w, errors.New("q is required"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	count := 0
+	if c := query.Get("count"); c != "" {
+		var err error
+		count, err = strconv.Atoi(c)
+		if err != nil || count < 1 {
+			ReturnCodeError(w, errors.New("bad count"), http.StatusBadRequest, constants.Msg_400)
+			return
+		}
+	}
+
+	found, err := provider.SearchMessages(getUserID(sess), q, count)
+	switch err {
+	case nil:
+	case provider.ErrEmptySearchQuery:
+		ReturnCodeError(w, errors.New("search query has no words"), http.StatusBadRequest, constants.Msg_400)
+		return
+	default:
+		log.Println("error while search messages: " + err.Error())
+		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
+		return
+	}
+
+	err = ReturnNoEscapeCodeJSONResp(w, found, http.StatusOK)
+	if err != nil {
+		log.Println("error while return JSON response: " + err.Error())
+		return
+	}
+}
@@ -0,0 +1,132 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+
+	"app/constants"
+	"app/model"
+	"app/provider"
+	"app/shared/cursor"
+	"app/shared/session"
+	"app/webpojo"
+)
+
+// returnThreadLifecycleError writes response for thread status or assignment error
+func returnThreadLifecycleError(w http.ResponseWriter, err error) {
+	switch err {
+	case model.ErrUnauthorized:
+		ReturnCodeError(w, errors.New("not allowed for thread"), http.StatusForbidden, constants.Msg_403)
+	case model.ErrThreadNotExist:
+		ReturnCodeError(w, errors.New("thread not exist"), http.StatusNotFound, constants.Msg_404)
+	case model.ErrNoResult:
+		ReturnCodeError(w, errors.New("staff member not found"), http.StatusNotFound, constants.Msg_404)
+	case provider.ErrWrongThreadStatus:
+		ReturnCodeError(w, errors.New("status should be open, waiting_customer, closed or archived"), http.StatusBadRequest, constants.Msg_400)
+	case provider.ErrWrongUserRole:
+		ReturnCodeError(w, errors.New("staff_id should be staff or supervisor"), http.StatusBadRequest, constants.Msg_400)
+	case provider.ErrThreadAssigned:
+		ReturnCodeError(w, err, http.StatusConflict, constants.Msg_409)
+	default:
+		log.Println("error while change thread: " + err.Error())
+		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
+	}
+}
+
+// MessageThreadStatusPatch changes thread status
+func MessageThreadStatusPatch(w http.ResponseWriter, r *http.Request) {
+	sess := session.Instance(r)
+
+	body, readErr := ioutil.ReadAll(r.Body)
+	if readErr != nil {
+		log.Println("error while change thread status: " + readErr.Error())
+		ReturnCodeError(w, errors.New("can't read request body"), http.StatusInternalServerError, constants.Msg_500)
+		return
+	}
+
+	statusReq := &webpojo.ThreadStatusReq{}
+	jsonErr := json.Unmarshal(body, statusReq)
+	if jsonErr != nil || statusReq.ThreadID == 0 {
+		log.Println("error while change thread status: can't unmarshall request")
+		ReturnCodeError(w, errors.New("can't parse request"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	err := provider.SetThreadStatus(getUserID(sess), getUserRole(sess), statusReq)
+	if err != nil {
+		returnThreadLifecycleError(w, err)
+		return
+	}
+
+	ReturnCodeError(w, errors.New(""), http.StatusOK, constants.Msg_200)
+}
+
+// StaffThreadAssignPost assigns thread to staff member or returns it to queue
+func StaffThreadAssignPost(w http.ResponseWriter, r *http.Request) {
+	sess := session.Instance(r)
+
+	body, readErr := ioutil.ReadAll(r.Body)
+	if readErr != nil {
+		log.Println("error while assign thread: " + readErr.Error())
+		ReturnCodeError(w, errors.New("can't read request body"), http.StatusInternalServerError, constants.Msg_500)
+		return
+	}
+
+	assignReq := &webpojo.ThreadAssignReq{}
+	jsonErr := json.Unmarshal(body, assignReq)
+	if jsonErr != nil || assignReq.ThreadID == 0 {
+		log.Println("error while assign thread: can't unmarshall request")
+		ReturnCodeError(w, errors.New("can't parse request"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	err := provider.AssignThread(getUserID(sess), getUserRole(sess), assignReq)
+	if err != nil {
+		returnThreadLifecycleError(w, err)
+		return
+	}
+
+	ReturnCodeError(w, errors.New(""), http.StatusOK, constants.Msg_200)
+}
+
+// StaffThreadQueueGet return page of unassigned open and waiting threads, oldest first.
+// Staff get threads of their assigned customers only. Query params: count (default 20) and cursor.
+func StaffThreadQueueGet(w http.ResponseWriter, r *http.Request) {
+	sess := session.Instance(r)
+	query := r.URL.Query()
+
+	count := 20
+	if c := query.Get("count"); c != "" {
+		var err error
+		count, err = strconv.Atoi(c)
+		if err != nil || count < 1 {
+			ReturnCodeError(w, errors.New("bad count"), http.StatusBadRequest, constants.Msg_400)
+			return
+		}
+	}
+
+	list, cursors, err := provider.GetThreadQueue(getUserID(sess), getUserRole(sess), count, query.Get("cursor"))
+	switch err {
+	case nil:
+	case cursor.ErrInvalid:
+		ReturnCodeError(w, errors.New("bad cursor"), http.StatusBadRequest, constants.Msg_400)
+		return
+	case model.ErrUnauthorized:
+		ReturnCodeError(w, errors.New("not allowed to see thread queue"), http.StatusForbidden, constants.Msg_403)
+		return
+	default:
+		log.Println("error while get thread queue: " + err.Error())
+		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
+		return
+	}
+
+	err = ReturnNoEscapeCodeJSONResp(w, &webpojo.MessagesThreadsListResp{Threads: list, PageCursors: *cursors}, http.StatusOK)
+	if err != nil {
+		log.Println("error while return JSON response: " + err.Error())
+		return
+	}
+}
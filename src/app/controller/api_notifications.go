@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+
+	"app/constants"
+	"app/provider"
+	"app/shared/session"
+	"app/webpojo"
+)
+
+// NotificationPreferenceGet return how user is notified about new messages
+func NotificationPreferenceGet(w http.ResponseWriter, r *http.Request) {
+	sess := session.Instance(r)
+
+	pref, err := provider.GetNotificationPreference(getUserID(sess))
+	if err != nil {
+		log.Println("error while get notification preference: " + err.Error())
+		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
+		return
+	}
+
+	err = ReturnCodeJSONResp(w, pref, http.StatusOK)
+	if err != nil {
+		log.Println("error while return JSON response: " + err.Error())
+		return
+	}
+}
+
+// NotificationPreferencePut changes how user is notified about new messages: immediate, digest or off
+func NotificationPreferencePut(w http.ResponseWriter, r *http.Request) {
+	sess := session.Instance(r)
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Println("error while set notification preference: " + err.Error())
+		ReturnCodeError(w, errors.New("can't read request body"), http.StatusInternalServerError, constants.Msg_500)
+		return
+	}
+
+	if len(body) == 0 {
+		log.Println("error while set notification preference: empty json payoload")
+		ReturnCodeError(w, errors.New("emtpy json payload"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	pref := &webpojo.NotificationPreference{}
+	jsonErr := json.Unmarshal(body, pref)
+	if jsonErr != nil {
+		log.Println("error while set notification preference: can't unmarshall request: " + jsonErr.Error())
+		ReturnCodeError(w, errors.New("can't parse request"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	err = provider.SetNotificationPreference(getUserID(sess), pref)
+	switch err {
+	case nil:
+		ReturnCodeError(w, errors.New(""), http.StatusOK, constants.Msg_200)
+	case provider.ErrWrongNotificationMode:
+		ReturnCodeError(w, errors.New("mode should be immediate, digest or off"), http.StatusBadRequest, constants.Msg_400)
+	default:
+		log.Println("error while set notification preference: " + err.Error())
+		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
+	}
+}
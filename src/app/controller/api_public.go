@@ -2,18 +2,19 @@ package controller
 
 import (
 	"app/constants"
-	"app/model"
 	"app/provider"
+	"app/shared/cursor"
+	"app/shared/session"
 	"app/webpojo"
 	"encoding/json"
 	"errors"
 	"io/ioutil"
 	"log"
 	"net/http"
-	"strconv"
 )
 
-//LenderRateList get a list of lender rates based on the input criteria
+//LenderRateList get a list of lender rates based on the input criteria: best rates or page of all rates,
+//filtered by product, lender, rate bounds and validity, sorted by apr or interest and grouped by product
 func LenderRateList(w http.ResponseWriter, r *http.Request) {
 
 	body, readErr := ioutil.ReadAll(r.Body)
@@ -32,14 +33,21 @@ func LenderRateList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var rates []model.LenderRate
-	var dbErr error
+	lenderRateListResp, dbErr := provider.SearchLenderRates(&lenderRateListReq)
+	if dbErr == cursor.ErrInvalid {
+		ReturnCodeError(w, errors.New("bad cursor"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
 
-	switch lenderRateListReq.Criteria {
-	case "best":
-		rates, dbErr = provider.GetBestLenderRatesList()
-	default:
-		rates, dbErr = model.LenderRatesListAll()
+	if dbErr == provider.ErrWrongRateDate {
+		ReturnCodeError(w, errors.New("as_of should be YYYY-MM-DD"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	switch dbErr {
+	case provider.ErrWrongRateSort, provider.ErrWrongRateValidity, provider.ErrWrongRateField, provider.ErrWrongRateBounds, provider.ErrWrongRateGroup:
+		ReturnCodeError(w, dbErr, http.StatusBadRequest, constants.Msg_400)
+		return
 	}
 
 	if dbErr != nil {
@@ -48,38 +56,53 @@ func LenderRateList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	lenderRateListResp := new(webpojo.LenderRateListResp)
-	var convErr error
-	for _, v := range rates {
-		rate := new(webpojo.LenderRatePojo)
-		rate.Apr, convErr = strconv.ParseFloat(v.Apr, 64)
-		if convErr != nil {
-			log.Println(convErr)
-			ReturnError(w, convErr)
-			return
-		}
-		rate.BeginDate = v.BeginDate
-		rate.EndDate = v.EndDate
-		rate.ID = v.ID
-		rate.Interest, convErr = strconv.ParseFloat(v.Interest, 64)
-		if convErr != nil {
-			log.Println(convErr)
-			ReturnError(w, convErr)
-			return
-		}
-		rate.LenderID = v.LenderID
-		rate.LenderName = v.LenderName
-		rate.Product = v.Product
-		lenderRateListResp.LenderRates = append(lenderRateListResp.LenderRates, *rate)
-	}
-
 	lenderRateListResp.StatusCode = constants.StatusCode_200
 	lenderRateListResp.Message = constants.Msg_200
 	ReturnJsonResp(w, lenderRateListResp)
 }
 
-// FastQuotePost return info about best 30yrs rates for now
+// LenderRateHistoryPost return best rate of product for every day of date range for charting
+func LenderRateHistoryPost(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Println("error while get rate history: can't read request body: ", err)
+		ReturnCodeError(w, errors.New("error while read body"), http.StatusInternalServerError, constants.Msg_500)
+		return
+	}
+
+	historyReq := &webpojo.LenderRateHistoryReq{}
+	err = json.Unmarshal(body, historyReq)
+	if err != nil {
+		log.Println("error while get rate history: error while unmarshal request body: ", err)
+		ReturnCodeError(w, errors.New("can't unmarshal request body"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	history, err := provider.GetLenderRateHistory(historyReq)
+	switch err {
+	case nil:
+	case provider.ErrWrongRateProduct, provider.ErrWrongRateDate, provider.ErrWrongRateRange:
+		ReturnCodeError(w, err, http.StatusBadRequest, constants.Msg_400)
+		return
+	default:
+		log.Println("error while get rate history: ", err)
+		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
+		return
+	}
+
+	history.StatusCode = constants.StatusCode_200
+	history.Message = constants.Msg_200
+	err = ReturnNoEscapeCodeJSONResp(w, history, http.StatusOK)
+	if err != nil {
+		log.Println("error while return JSON response: " + err.Error())
+	}
+}
+
+// FastQuotePost return best rates of products adjusted for loan amount, term, credit band and product.
+// Quote is stored, quote of signed in customer is attached to their account.
 func FastQuotePost(w http.ResponseWriter, r *http.Request) {
+	sess := session.Instance(r)
+
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		log.Println("error while get fast quote: can't read request body: ", err)
@@ -97,8 +120,22 @@ func FastQuotePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	fastQuote, err := provider.GetFastQuote(fastQuoteReq)
-	if err != nil {
+	// Quote is attached to signed in customer only, quotes of anonymous visitors and staff stay anonymous
+	userID := ""
+	if getUserRole(sess) == constants.CustomerRole {
+		userID, _ = sess.Values[UserID].(string)
+	}
+
+	fastQuote, err := provider.CreateFastQuote(userID, fastQuoteReq)
+	switch err {
+	case nil:
+	case provider.ErrWrongLoanAmount, provider.ErrWrongCreditBand:
+		ReturnCodeError(w, err, http.StatusBadRequest, constants.Msg_400)
+		return
+	case provider.ErrNoQuote:
+		ReturnCodeError(w, err, http.StatusNotFound, constants.Msg_404)
+		return
+	default:
 		log.Println("error while get fast quote: ", err)
 		ReturnCodeError(w, errors.New("intenrnal server error"), http.StatusInternalServerError, constants.Msg_500)
 		return
@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+
+	"app/constants"
+	"app/provider"
+	"app/shared/session"
+	"app/webpojo"
+)
+
+// RateAlertListGet return customer's rate alerts
+func RateAlertListGet(w http.ResponseWriter, r *http.Request) {
+	sess := session.Instance(r)
+
+	alerts, err := provider.GetRateAlerts(getUserID(sess))
+	if err != nil {
+		log.Println("error while get rate alerts: " + err.Error())
+		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
+		return
+	}
+
+	err = ReturnNoEscapeCodeJSONResp(w, &webpojo.RateAlertListResp{StatusCode: constants.StatusCode_200, Message: constants.Msg_200, Alerts: alerts}, http.StatusOK)
+	if err != nil {
+		log.Println("error while return JSON response: " + err.Error())
+	}
+}
+
+// RateAlertPost subscribes customer to alert on best rate of product crossing threshold, return alert ID
+func RateAlertPost(w http.ResponseWriter, r *http.Request) {
+	sess := session.Instance(r)
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Println("error while create rate alert: " + err.Error())
+		ReturnCodeError(w, errors.New("can't read request body"), http.StatusInternalServerError, constants.Msg_500)
+		return
+	}
+
+	alertReq := &webpojo.RateAlertReq{}
+	jsonErr := json.Unmarshal(body, alertReq)
+	if jsonErr != nil {
+		log.Println("error while create rate alert: can't unmarshall request: " + jsonErr.Error())
+		ReturnCodeError(w, errors.New("can't parse request"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	id, err := provider.CreateRateAlert(getUserID(sess), alertReq)
+	switch err {
+	case nil:
+		ReturnCodeJSONResponse(w, http.StatusOK, webpojo.IDResponse{ID: id})
+	case provider.ErrWrongAlertDirection, provider.ErrWrongAlertThreshold, provider.ErrWrongAlertProduct, provider.ErrTooManyRateAlerts:
+		ReturnCodeError(w, err, http.StatusBadRequest, constants.Msg_400)
+	case provider.ErrRateAlertExists:
+		ReturnCodeError(w, err, http.StatusConflict, constants.Msg_409)
+	default:
+		log.Println("error while create rate alert: " + err.Error())
+		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
+	}
+}
+
+// RateAlertDelete removes customer's rate alert
+func RateAlertDelete(w http.ResponseWriter, r *http.Request) {
+	sess := session.Instance(r)
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Println("error while delete rate alert: " + err.Error())
+		ReturnCodeError(w, errors.New("can't read request body"), http.StatusInternalServerError, constants.Msg_500)
+		return
+	}
+
+	idReq := &webpojo.IDRequest{}
+	jsonErr := json.Unmarshal(body, idReq)
+	if jsonErr != nil || idReq.ID <= 0 {
+		log.Println("error while delete rate alert: can't unmarshall request")
+		ReturnCodeError(w, errors.New("can't parse request"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	err = provider.DeleteRateAlert(getUserID(sess), uint32(idReq.ID))
+	switch err {
+	case nil:
+		ReturnCodeError(w, errors.New(""), http.StatusOK, constants.Msg_200)
+	case provider.ErrRateAlertNotExist:
+		ReturnCodeError(w, err, http.StatusNotFound, constants.Msg_404)
+	default:
+		log.Println("error while delete rate alert: " + err.Error())
+		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
+	}
+}
+
+// RateAlertUnsubscribeGet removes rate alert by token of unsubscribe link from alert email.
+// Query param: token.
+func RateAlertUnsubscribeGet(w http.ResponseWriter, r *http.Request) {
+	err := provider.UnsubscribeRateAlert(r.URL.Query().Get("token"))
+	switch err {
+	case nil:
+		ReturnCodeError(w, errors.New(""), http.StatusOK, constants.Msg_200)
+	case provider.ErrRateAlertNotExist:
+		ReturnCodeError(w, errors.New("alert not found, it may be unsubscribed already"), http.StatusNotFound, constants.Msg_404)
+	default:
+		log.Println("error while unsubscribe rate alert: " + err.Error())
+		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
+	}
+}
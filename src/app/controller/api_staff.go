@@ -0,0 +1,210 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+
+	"app/constants"
+	"app/model"
+	"app/provider"
+	"app/shared/filecategory"
+	fas "app/shared/files_id_storage"
+	"app/shared/session"
+	"app/webpojo"
+
+	"github.com/gorilla/sessions"
+)
+
+// getStaffActor return staff member info for access checks and audit
+func getStaffActor(sess *sessions.Session, r *http.Request) *provider.StaffActor {
+	return &provider.StaffActor{
+		UserID:     getUserID(sess),
+		Role:       getUserRole(sess),
+		RemoteAddr: r.RemoteAddr,
+	}
+}
+
+// getCustomerIDParam return customer_id query param if it's a valid ID
+func getCustomerIDParam(r *http.Request) (string, bool) {
+	customerID := r.URL.Query().Get("customer_id")
+	id, err := strconv.ParseUint(customerID, 10, 32)
+	if err != nil || id == 0 {
+		return "", false
+	}
+
+	return customerID, true
+}
+
+// StaffCustomersGet return list of customers assigned to staff member
+func StaffCustomersGet(w http.ResponseWriter, r *http.Request) {
+	sess := session.Instance(r)
+
+	customers, err := provider.GetAssignedCustomers(getUserID(sess))
+	if err != nil {
+		log.Println("error while get assigned customers: " + err.Error())
+		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
+		return
+	}
+
+	err = ReturnNoEscapeCodeJSONResp(w, customers, http.StatusOK)
+	if err != nil {
+		log.Println("error while return JSON response: " + err.Error())
+		return
+	}
+}
+
+// StaffCustomerFilesListGet return page of assigned customer files.
+// Query params are the same as in CustomerFilesListGet plus customer_id.
+func StaffCustomerFilesListGet(w http.ResponseWriter, r *http.Request) {
+	sess := session.Instance(r)
+
+	customerID, ok := getCustomerIDParam(r)
+	if !ok {
+		ReturnCodeError(w, errors.New("bad customer id"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	listReq, message := parseFileListReq(r)
+	if listReq == nil {
+		ReturnCodeError(w, errors.New(message), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	list, err := provider.StaffGetCustomerFileList(getStaffActor(sess, r), customerID, sess.ID, listReq)
+	switch err {
+	case nil:
+	case model.ErrUnauthorized:
+		ReturnCodeError(w, errors.New("customer is not assigned"), http.StatusForbidden, constants.Msg_403)
+		return
+	default:
+		log.Println("error while get customer files list for staff: " + err.Error())
+		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
+		return
+	}
+
+	err = ReturnNoEscapeCodeJSONResp(w, list, http.StatusOK)
+	if err != nil {
+		log.Println("error while return JSON response: " + err.Error())
+		return
+	}
+}
+
+// StaffCustomerFileDownloadGet serve decrypted content of assigned customer file
+func StaffCustomerFileDownloadGet(w http.ResponseWriter, r *http.Request) {
+	sess := session.Instance(r)
+
+	customerID, ok := getCustomerIDParam(r)
+	if !ok {
+		ReturnCodeError(w, errors.New("bad customer id"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	realFileID, err := fas.GetRealID(r.URL.Query().Get("id"), sess.ID)
+	if err != nil {
+		log.Println("error while download customer file for staff: error while get real file ID: id not found")
+		ReturnCodeError(w, errors.New("bad file id"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	data, fileInfo, err := provider.StaffGetFileContent(getStaffActor(sess, r), customerID, realFileID)
+	switch err {
+	case nil:
+	case model.ErrUnauthorized:
+		ReturnCodeError(w, errors.New("customer is not assigned"), http.StatusForbidden, constants.Msg_403)
+		return
+	case model.ErrNoResult:
+		ReturnCodeError(w, errors.New("not_found"), http.StatusNotFound, constants.Msg_404)
+		return
+	case provider.ErrFileNotScanned, provider.ErrFileInfected:
+		ReturnCodeError(w, err, http.StatusConflict, constants.Msg_409)
+		return
+	default:
+		log.Println("error while download customer file for staff: " + err.Error())
+		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
+		return
+	}
+
+	serveFileContent(w, data, fileInfo)
+}
+
+// StaffDocumentRequestPost asks assigned customer to upload document
+func StaffDocumentRequestPost(w http.ResponseWriter, r *http.Request) {
+	sess := session.Instance(r)
+
+	body, readErr := ioutil.ReadAll(r.Body)
+	if readErr != nil {
+		log.Println("error while request document: " + readErr.Error())
+		ReturnCodeError(w, errors.New("can't read request body"), http.StatusInternalServerError, constants.Msg_500)
+		return
+	}
+
+	if len(body) == 0 {
+		log.Println("error while request document: empty json payoload")
+		ReturnCodeError(w, errors.New("emtpy json payload"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	log.Println("document request: ", string(body))
+	docReq := webpojo.DocumentRequestReq{}
+	jsonErr := json.Unmarshal(body, &docReq)
+	if jsonErr != nil {
+		log.Println("error while request document: can't unmarshall request")
+		ReturnCodeError(w, errors.New("can't parse request"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	docReq.Category = filecategory.Normalize(docReq.Category)
+	if !filecategory.IsValid(docReq.Category) {
+		ReturnCodeError(w, errors.New("unknown category"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	if len(docReq.Note) > maxFileDescriptionLen {
+		ReturnCodeError(w, errors.New("note is too long"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	id, err := provider.StaffRequestDocument(getStaffActor(sess, r), &docReq)
+	switch err {
+	case nil:
+		ReturnCodeJSONResponse(w, http.StatusOK, webpojo.IDResponse{ID: uint32(id)})
+	case model.ErrUnauthorized:
+		ReturnCodeError(w, errors.New("customer is not assigned"), http.StatusForbidden, constants.Msg_403)
+	default:
+		log.Println("error while request document: " + err.Error())
+		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
+	}
+}
+
+// StaffDocumentRequestsGet return document requests of assigned customer
+func StaffDocumentRequestsGet(w http.ResponseWriter, r *http.Request) {
+	sess := session.Instance(r)
+
+	customerID, ok := getCustomerIDParam(r)
+	if !ok {
+		ReturnCodeError(w, errors.New("bad customer id"), http.StatusBadRequest, constants.Msg_400)
+		return
+	}
+
+	requests, err := provider.StaffGetDocumentRequests(getStaffActor(sess, r), customerID, sess.ID)
+	switch err {
+	case nil:
+	case model.ErrUnauthorized:
+		ReturnCodeError(w, errors.New("customer is not assigned"), http.StatusForbidden, constants.Msg_403)
+		return
+	default:
+		log.Println("error while get document requests for staff: " + err.Error())
+		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
+		return
+	}
+
+	err = ReturnNoEscapeCodeJSONResp(w, requests, http.StatusOK)
+	if err != nil {
+		log.Println("error while return JSON response: " + err.Error())
+		return
+	}
+}
@@ -0,0 +1,144 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"app/constants"
+	"app/model"
+	"app/provider"
+	"app/webpojo"
+)
+
+// readStaffAssignmentReq read and validate staff assignment request body
+func readStaffAssignmentReq(w http.ResponseWriter, r *http.Request) (*webpojo.StaffAssignmentReq, bool) {
+	body, readErr := ioutil.ReadAll(r.Body)
+	if readErr != nil {
+		log.Println("error while read staff assignment request: " + readErr.Error())
+		ReturnCodeError(w, errors.New("can't read request body"), http.StatusInternalServerError, constants.Msg_500)
+		return nil, false
+	}
+
+	if len(body) == 0 {
+		log.Println("error while read staff assignment request: empty json payoload")
+		ReturnCodeError(w, errors.New("emtpy json payload"), http.StatusBadRequest, constants.Msg_400)
+		return nil, false
+	}
+
+	log.Println("staff assignment request: ", string(body))
+	assignReq := &webpojo.StaffAssignmentReq{}
+	jsonErr := json.Unmarshal(body, assignReq)
+	if jsonErr != nil {
+		log.Println("error while read staff assignment request: can't unmarshall request")
+		ReturnCodeError(w, errors.New("can't parse request"), http.StatusBadRequest, constants.Msg_400)
+		return nil, false
+	}
+
+	if assignReq.StaffID == 0 || assignReq.CustomerID == 0 {
+		ReturnCodeError(w, errors.New("staff_id and customer_id are required"), http.StatusBadRequest, constants.Msg_400)
+		return nil, false
+	}
+
+	return assignReq, true
+}
+
+// AdminStaffAssignmentPost assigns customer to staff member
+func AdminStaffAssignmentPost(w http.ResponseWriter, r *http.Request) {
+	assignReq, ok := readStaffAssignmentReq(w, r)
+	if !ok {
+		return
+	}
+
+	err := provider.AssignCustomerToStaff(fmt.Sprint(assignReq.StaffID), fmt.Sprint(assignReq.CustomerID))
+	switch err {
+	case nil:
+		ReturnCodeError(w, errors.New(""), http.StatusOK, constants.Msg_200)
+	case model.ErrNoResult:
+		ReturnCodeError(w, errors.New("user not found"), http.StatusNotFound, constants.Msg_404)
+	case provider.ErrWrongUserRole:
+		ReturnCodeError(w, errors.New("staff_id should be staff or supervisor and customer_id should be customer"), http.StatusBadRequest, constants.Msg_400)
+	default:
+		log.Println("error while assign customer to staff: " + err.Error())
+		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
+	}
+}
+
+// AdminStaffAssignmentDelete removes customer assignment from staff member
+func AdminStaffAssignmentDelete(w http.ResponseWriter, r *http.Request) {
+	assignReq, ok := readStaffAssignmentReq(w, r)
+	if !ok {
+		return
+	}
+
+	err := provider.UnassignCustomerFromStaff(fmt.Sprint(assignReq.StaffID), fmt.Sprint(assignReq.CustomerID))
+	if err != nil {
+		log.Println("error while unassign customer from staff: " + err.Error())
+		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
+		return
+	}
+
+	ReturnCodeError(w, errors.New(""), http.StatusOK, constants.Msg_200)
+}
+
+// AdminFileAccessLogGet return page of file access log.
+// Query params: actor_id, customer_id, action, from and to (RFC 3339), limit and offset.
+func AdminFileAccessLogGet(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	logReq := webpojo.FileAccessLogReq{
+		ActorID:    query.Get("actor_id"),
+		CustomerID: query.Get("customer_id"),
+		Action:     query.Get("action"),
+	}
+
+	var err error
+	if from := query.Get("from"); from != "" {
+		logReq.From, err = time.Parse(time.RFC3339, from)
+		if err != nil {
+			ReturnCodeError(w, errors.New("bad from date"), http.StatusBadRequest, constants.Msg_400)
+			return
+		}
+	}
+
+	if to := query.Get("to"); to != "" {
+		logReq.To, err = time.Parse(time.RFC3339, to)
+		if err != nil {
+			ReturnCodeError(w, errors.New("bad to date"), http.StatusBadRequest, constants.Msg_400)
+			return
+		}
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		logReq.Limit, err = strconv.Atoi(limit)
+		if err != nil || logReq.Limit < 0 {
+			ReturnCodeError(w, errors.New("bad limit"), http.StatusBadRequest, constants.Msg_400)
+			return
+		}
+	}
+
+	if offset := query.Get("offset"); offset != "" {
+		logReq.Offset, err = strconv.Atoi(offset)
+		if err != nil || logReq.Offset < 0 {
+			ReturnCodeError(w, errors.New("bad offset"), http.StatusBadRequest, constants.Msg_400)
+			return
+		}
+	}
+
+	resp, err := provider.GetFileAccessLog(&logReq)
+	if err != nil {
+		log.Println("error while get file access log: " + err.Error())
+		ReturnCodeError(w, errors.New("internal server error"), http.StatusInternalServerError, constants.Msg_500)
+		return
+	}
+
+	err = ReturnNoEscapeCodeJSONResp(w, resp, http.StatusOK)
+	if err != nil {
+		log.Println("error while return JSON response: " + err.Error())
+		return
+	}
+}
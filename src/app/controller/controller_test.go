@@ -24,10 +24,14 @@ import (
 	"github.com/julienschmidt/httprouter"
 
 	"app/constants"
+	"app/model"
 	"app/provider"
 	"app/shared/database"
+	"app/shared/events"
 	fas "app/shared/files_id_storage"
 	"app/shared/jsonconfig"
+	"app/shared/keyring"
+	"app/shared/scanner"
 	"app/shared/session"
 
 	"app/shared/config"
@@ -46,6 +50,9 @@ func TestController(t *testing.T) {
 	jsonconfig.Load(constants.ConfigFilePath, config.Config)
 	database.Connect(config.Database())
 	session.Configure(config.Session())
+	configureTestKeyring(t)
+	scanner.SetInstance(&scanner.Fake{}) // tests don't need clamd
+	events.Configure(config.Events())
 
 	t.Run("TestBestRates", func(t *testing.T) {
 		criterias := [2]string{"best", ""} // test for all items and best items
@@ -246,6 +253,41 @@ func TestController(t *testing.T) {
 			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
 			return
 		}
+
+		listResp := webpojo.FileListResp{}
+		if err = json.Unmarshal(body, &listResp); err != nil {
+			t.Error("fail TestUserFileList: can't parse response: " + err.Error())
+			return
+		}
+
+		if listResp.Total == 0 || len(listResp.Files) == 0 {
+			t.Error("fail TestUserFileList: file list is empty")
+		}
+	})
+
+	t.Run("TestUserFileListBadParams", func(f *testing.T) {
+		for _, query := range []string{"sort=owner", "order=up", "category=unknown", "limit=x", "cursor=x"} {
+			req, err := http.NewRequest("GET", "/api/customer/file/list?"+query, nil)
+			if err != nil {
+				t.Fatal("fail TestUserFileListBadParams: ", err)
+				return
+			}
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(CustomerFilesListGet)
+			setSession(req, rr)
+
+			handler.ServeHTTP(rr, req)
+			body, err := ioutil.ReadAll(rr.Body)
+			if err != nil {
+				t.Error("fail TestUserFileListBadParams: " + err.Error())
+				return
+			}
+
+			if status := getStatusCode(body, rr.Code); status != http.StatusBadRequest {
+				t.Errorf("handler returned wrong status code for %v: got %v want %v", query, status, http.StatusBadRequest)
+			}
+		}
 	})
 
 	t.Run("UserGetFile", func(f *testing.T) {
@@ -303,31 +345,39 @@ func TestController(t *testing.T) {
 		}
 
 		req, err := http.NewRequest("GET", fileList[0].Link, nil)
+		if err != nil {
+			t.Fatal("fail TestServeContent: ", err)
+			return
+		}
 
-		// don't delete it please. This header should be set if we using SSE with customer keys
-		// encryption model.
-		//
-		// req.Header.Set("x-amz-server-side-encryption-customer-key", provider.CreateUsersFileKey(TestUserName))
-		// req.Header.Set("x-amz-server-side-encryption-customer-key-MD5", provider.GetMD5Hash(provider.CreateUsersFileKey(TestUserName)))
-		// req.Header.Set("x-amz-server-side-encryption-customer-algorithm", "AES256")
+		rr := httptest.NewRecorder()
+		handler := hr.Handler(http.HandlerFunc(CustomerFileDownloadGet))
 
-		client := &http.Client{}
-		response, err := client.Do(req)
+		router := httprouter.New()
+		router.GET("/api/customer/file/download", handler)
+		setSession(req, rr)
+		router.ServeHTTP(rr, req)
 
-		if err != nil {
-			t.Error("error while try to download file by link: " + err.Error())
+		if rr.Code != http.StatusOK {
+			t.Errorf("fail TestServeContent: handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
 			return
 		}
 
-		defer response.Body.Close()
-		contents, err := ioutil.ReadAll(response.Body)
+		contents, err := ioutil.ReadAll(rr.Body)
 		if err != nil {
 			t.Error("fail TestServeContent: error while read file body: " + err.Error())
 			return
 		}
 
-		log.Printf("%s\n", string(contents))
+		original, err := ioutil.ReadFile("../../../config" + string(os.PathSeparator) + "encryption_test.txt")
+		if err != nil {
+			t.Error("fail TestServeContent: error while read original file: " + err.Error())
+			return
+		}
 
+		if !bytes.Equal(contents, original) {
+			t.Error("fail TestServeContent: decrypted content is not equal to uploaded file")
+		}
 	})
 
 	t.Run("DeleteCustomerFile", func(f *testing.T) {
@@ -463,7 +513,7 @@ func TestController(t *testing.T) {
 	})
 
 	t.Run("TestCustomerThreadsList", func(f *testing.T) {
-		req, err := http.NewRequest("POST", "/api/user/message/threads", bytes.NewBuffer([]byte(`{"offset":0, "count":10}`)))
+		req, err := http.NewRequest("POST", "/api/user/message/threads", bytes.NewBuffer([]byte(`{"count":10}`)))
 		if err != nil {
 			t.Fatal("fail TestCustomerMessageList: ", err)
 			return
@@ -484,13 +534,19 @@ func TestController(t *testing.T) {
 		}
 
 		log.Println(string(body))
-		resp := []*webpojo.MessagesThreadsResp{}
-		err = json.Unmarshal(body, &resp)
+		page := &webpojo.MessagesThreadsListResp{}
+		err = json.Unmarshal(body, page)
 		if err != nil {
 			t.Error("error while test TestCustomerThreadsList: can't unmarshal threads response: ", err)
 			return
 		}
 
+		if page.NextCursor != "" || page.PrevCursor == "" {
+			t.Error("error while test TestCustomerThreadsList: wrong page cursors")
+			return
+		}
+
+		resp := page.Threads
 		if len(resp) != 1 {
 			t.Error("error while test TestCustomerThreadsList: response list should be 1")
 			return
@@ -523,7 +579,7 @@ func TestController(t *testing.T) {
 	})
 
 	t.Run("TestCustomerMessageList", func(f *testing.T) {
-		threads, err := provider.ThreadsByUserID(TestUserID, 10, 0)
+		threads, _, err := provider.ThreadsByUserID(TestUserID, nil, 10, "")
 		if err != nil {
 			t.Error("error while post new message: can't get threads by user ID: ", err)
 			return
@@ -532,8 +588,7 @@ func TestController(t *testing.T) {
 		req, err := http.NewRequest("POST", "/api/customer/message/list", bytes.NewBuffer([]byte(`
 			{
 				"thread_id":`+fmt.Sprint(threads[0].ID)+`,
-				"count":10,
-				"offseet":0
+				"count":10
 			}`)))
 		if err != nil {
 			t.Fatal("fail TestCustomerMessageList: ", err)
@@ -555,13 +610,14 @@ func TestController(t *testing.T) {
 		}
 
 		log.Println(string(body))
-		resp := []*webpojo.MessageListResp{}
-		err = json.Unmarshal(body, &resp)
+		page := &webpojo.MessagesListResp{}
+		err = json.Unmarshal(body, page)
 		if err != nil {
 			t.Error("error while TestCustomerMessageList: can't unmarshal response: ", err)
 			return
 		}
 
+		resp := page.Messages
 		if len(resp) != 1 {
 			t.Error("error while TestCustomerMessageList: can't unmarshal response")
 			return
@@ -584,7 +640,7 @@ func TestController(t *testing.T) {
 	})
 
 	t.Run("TestUserMessagePatch", func(f *testing.T) {
-		threads, err := provider.ThreadsByUserID(TestUserID, 10, 0)
+		threads, _, err := provider.ThreadsByUserID(TestUserID, nil, 10, "")
 		if err != nil {
 			t.Error("error while TestUserMessagePatch: can't get threads by user ID: ", err)
 			return
@@ -596,7 +652,7 @@ func TestController(t *testing.T) {
 			return
 		}
 
-		messages, err := provider.GetCustomerMessagesList(threads[0].ID, uint32(iUserID), 100, 0)
+		messages, _, err := provider.GetCustomerMessagesList(threads[0].ID, uint32(iUserID), 100, "")
 		if err != nil {
 			t.Fatal("fail TestUserMessagePatch: error while get user's messages list: " + err.Error())
 			return
@@ -637,7 +693,7 @@ func TestController(t *testing.T) {
 			return
 		}
 
-		messages, err = provider.GetCustomerMessagesList(threads[0].ID, uint32(iUserID), 100, 0)
+		messages, _, err = provider.GetCustomerMessagesList(threads[0].ID, uint32(iUserID), 100, "")
 		if err != nil {
 			t.Fatal("fail TestUserMessagePatch: error while get user's messages list: " + err.Error())
 			return
@@ -650,7 +706,7 @@ func TestController(t *testing.T) {
 	})
 
 	t.Run("TestSetMessageReaded", func(f *testing.T) {
-		threads, err := provider.ThreadsByUserID(TestUserID, 10, 0)
+		threads, _, err := provider.ThreadsByUserID(TestUserID, nil, 10, "")
 		if err != nil {
 			t.Error("error while TestSetMessageReaded: can't get threads by user ID: ", err)
 			return
@@ -662,7 +718,7 @@ func TestController(t *testing.T) {
 			return
 		}
 
-		messages, err := provider.GetCustomerMessagesList(threads[0].ID, uint32(iUserID), 100, 0)
+		messages, _, err := provider.GetCustomerMessagesList(threads[0].ID, uint32(iUserID), 100, "")
 		if err != nil {
 			t.Fatal("fail TestSetMessageReaded: error while get user's messages list: " + err.Error())
 			return
@@ -695,7 +751,7 @@ func TestController(t *testing.T) {
 			return
 		}
 
-		messages, err = provider.GetCustomerMessagesList(threads[0].ID, uint32(iUserID), 100, 0)
+		messages, _, err = provider.GetCustomerMessagesList(threads[0].ID, uint32(iUserID), 100, "")
 		if err != nil {
 			t.Fatal("fail TestCustomerMessagePatch: error while get user's messages list: " + err.Error())
 		}
@@ -706,7 +762,7 @@ func TestController(t *testing.T) {
 	})
 
 	t.Run("TestUserMessageDelete", func(f *testing.T) {
-		threads, err := provider.ThreadsByUserID(TestUserID, 10, 0)
+		threads, _, err := provider.ThreadsByUserID(TestUserID, nil, 10, "")
 		if err != nil {
 			t.Error("error while TestUserMessageDelete: can't get threads by user ID: ", err)
 			return
@@ -718,7 +774,7 @@ func TestController(t *testing.T) {
 			return
 		}
 
-		messages, err := provider.GetCustomerMessagesList(threads[0].ID, uint32(iUserID), 100, 0)
+		messages, _, err := provider.GetCustomerMessagesList(threads[0].ID, uint32(iUserID), 100, "")
 		if err != nil {
 			t.Fatal("fail TestUserMessageDelete: error while get user's messages list: " + err.Error())
 			return
@@ -757,16 +813,72 @@ func TestController(t *testing.T) {
 			return
 		}
 
-		messages, err = provider.GetCustomerMessagesList(threads[0].ID, uint32(iUserID), 100, 0)
+		messages, _, err = provider.GetCustomerMessagesList(threads[0].ID, uint32(iUserID), 100, "")
 		if err != nil {
 			t.Fatal("error while get user's messages list: " + err.Error())
 		}
 
-		if len(messages) != 0 {
+		if len(messages) == 0 || !messages[0].Deleted {
 			t.Fatal("message not deleted")
 		}
 	})
 
+	t.Run("TestThreadOutsiderForbidden", func(f *testing.T) {
+		model.UserRemoveByEmail(constants.TestStaffEmail)
+		defer model.UserRemoveByEmail(constants.TestStaffEmail)
+
+		err := model.UserCreateWithRole("Jane", "Doe", constants.TestStaffEmail, "1qazxsw2", constants.CustomerRole)
+		if err != nil {
+			t.Fatal("fail TestThreadOutsiderForbidden: " + err.Error())
+		}
+
+		outsider, err := provider.GetUserByEmail(constants.TestStaffEmail)
+		if err != nil {
+			t.Fatal("fail TestThreadOutsiderForbidden: " + err.Error())
+		}
+
+		thread, err := provider.PostNewMessage(outsider.UserID(), &webpojo.MessagePostReq{
+			ToUserID: outsider.ID,
+			Subject:  "private",
+			Content:  "private",
+		})
+		if err != nil {
+			t.Fatal("fail TestThreadOutsiderForbidden: " + err.Error())
+		}
+
+		requests := []struct {
+			path    string
+			body    string
+			handler http.HandlerFunc
+		}{
+			{"/api/user/message/list", `{"thread_id":` + fmt.Sprint(thread.ThreadID) + `,"count":10}`, UserMessageList},
+			{"/api/user/message", `{"thread_id":` + fmt.Sprint(thread.ThreadID) + `,"from_user_id":` + fmt.Sprint(outsider.ID) + `,"content":"intrusion"}`, UserMessagePost},
+		}
+
+		for _, v := range requests {
+			req, err := http.NewRequest("POST", v.path, bytes.NewBuffer([]byte(v.body)))
+			if err != nil {
+				t.Fatal("fail TestThreadOutsiderForbidden: ", err)
+			}
+
+			rr := httptest.NewRecorder()
+			router := httprouter.New()
+			router.POST(v.path, hr.Handler(v.handler))
+			setSession(req, rr)
+			router.ServeHTTP(rr, req)
+
+			body, err := ioutil.ReadAll(rr.Body)
+			if err != nil {
+				t.Error("fail TestThreadOutsiderForbidden: " + err.Error())
+				return
+			}
+
+			if status := getStatusCode(body, rr.Code); status != http.StatusForbidden {
+				t.Errorf("fail TestThreadOutsiderForbidden: %v returned wrong status code: got %v want %v", v.path, status, http.StatusForbidden)
+			}
+		}
+	})
+
 	t.Run("TestPatchUserProfile", func(f *testing.T) {
 		req, err := http.NewRequest("PATCH", "/api/customer/profile", bytes.NewBuffer([]byte(`{"user_id": "`+TestUserID+`", "username":"wer", "password":"opopop", "email":"ivanov@gmail.com", "mailing_address":"kirovohrad", "phone":"0999858858"}`)))
 		if err != nil {
@@ -823,7 +935,7 @@ func TestController(t *testing.T) {
 	})
 
 	t.Run("TestFastQuotePost", func(f *testing.T) {
-		req, err := http.NewRequest("POST", "/api/public/fast_quote", bytes.NewBuffer([]byte(`{"loan_amounte":12, "property_value":12, "property_zip":12, "loan_purpose":"hello", "credit_score":"world"}`)))
+		req, err := http.NewRequest("POST", "/api/public/fast_quote", bytes.NewBuffer([]byte(`{"loan_amount":250000, "term_years":15, "credit_band":"excellent", "product":""}`)))
 		if err != nil {
 			t.Fatal("fail TestFastQuotePost: ", err)
 			return
@@ -850,12 +962,47 @@ func TestController(t *testing.T) {
 			return
 		}
 
-		rate := &[]*webpojo.LenderRatePojo{}
+		rate := &[]*webpojo.FastQuoteResp{}
 		err = json.Unmarshal(body, rate)
 		if err != nil {
 			log.Println("fail TestFastQuotePost: can't unmarshal request: ", err)
 			t.Error("fail TestFastQuotePost: can't unmarshal request: ", err)
 		}
+
+		if len(*rate) == 0 || (*rate)[0].MonthlyPayment == 0 {
+			t.Error("fail TestFastQuotePost: quote with monthly payment expected")
+		}
+	})
+
+	t.Run("TestCalculatorSchedulePostCSV", func(f *testing.T) {
+		req, err := http.NewRequest("POST", "/api/public/calculator/schedule?format=csv", bytes.NewBuffer([]byte(`{"loan_amount":12000, "term_years":1, "interest":0}`)))
+		if err != nil {
+			t.Fatal("fail TestCalculatorSchedulePostCSV: ", err)
+			return
+		}
+
+		rr := httptest.NewRecorder()
+		handler := hr.Handler(http.HandlerFunc(CalculatorSchedulePost))
+
+		router := httprouter.New()
+		router.POST("/api/public/calculator/schedule", handler)
+		router.ServeHTTP(rr, req)
+
+		body, err := ioutil.ReadAll(rr.Body)
+		if err != nil {
+			t.Error("fail TestCalculatorSchedulePostCSV: " + err.Error())
+			return
+		}
+
+		if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "text/csv; charset=utf-8" {
+			t.Errorf("fail TestCalculatorSchedulePostCSV: CSV expected, got %v: %s", rr.Code, body)
+			return
+		}
+
+		lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+		if len(lines) != 13 || lines[12] != "12,1000.00,1000.00,0.00,0.00,0.00" {
+			t.Error("fail TestCalculatorSchedulePostCSV: header and 12 payments expected, got ", string(body))
+		}
 	})
 }
 
@@ -924,6 +1071,10 @@ func getStatusCode(body []byte, code int) int {
 		return 401
 	}
 
+	if strings.Contains(strBody, "403") && strings.Contains(strBody, "statusCode") {
+		return 403
+	}
+
 	if strings.Contains(strBody, "404") && strings.Contains(strBody, "statusCode") {
 		return 404
 	}
@@ -942,3 +1093,26 @@ func getStatusCode(body []byte, code int) int {
 
 	return code
 }
+
+// configureTestKeyring loads the configured keys, or random keys when the config ships none
+func configureTestKeyring(t *testing.T) {
+	if keyring.Configure(config.Keyring()) == nil {
+		return
+	}
+
+	masterKey, err := keyring.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileIDKey, err := keyring.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config.Config.Keyring.ActiveVersion = 1
+	config.Config.Keyring.MasterKeys = map[string]string{"1": masterKey}
+	config.Config.Keyring.FileIDKey = fileIDKey
+	if err = keyring.Configure(config.Keyring()); err != nil {
+		t.Fatal(err)
+	}
+}
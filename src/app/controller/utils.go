@@ -2,8 +2,11 @@ package controller
 
 import (
 	"errors"
+	"fmt"
 	"strconv"
 
+	"app/constants"
+
 	"github.com/gorilla/sessions"
 )
 
@@ -18,6 +21,16 @@ func getUserID(sess *sessions.Session) string {
 	return sess.Values[UserID].(string)
 }
 
+// getUserRole return session user role. Role type depends on the login handler.
+func getUserRole(sess *sessions.Session) int {
+	role, err := strconv.Atoi(fmt.Sprint(sess.Values[UserRole]))
+	if err != nil {
+		return constants.DefaultRole
+	}
+
+	return role
+}
+
 func getUserName(sess *sessions.Session) string {
 	return sess.Values[UserName].(string)
 }
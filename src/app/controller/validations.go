@@ -7,9 +7,19 @@ import (
 	"strings"
 
 	"app/constants"
+	"app/model"
+	"app/shared/filecategory"
 	"app/webpojo"
 )
 
+const (
+	maxFileDescriptionLen = 1024
+	maxFileTags           = 20
+	maxFileTagLen         = 64
+
+	maxLenderNameLen = 100
+)
+
 // validateEmail using regexp for check is email valid. Bad practice always trust to front-end info
 func validateEmail(email string) bool {
 	Re := regexp.MustCompile(`^[a-z0-9._%+\-]+@[a-z0-9.\-]+\.[a-z]{2,4}$`)
@@ -75,3 +85,123 @@ func trimUserRegisterInfo(userCreateRequest *webpojo.UserCreateReq) error {
 
 	return nil
 }
+
+// validateFileMetaPatch normalizes present fields of file metadata patch like validateFileMeta
+func validateFileMetaPatch(patch *webpojo.UserFileMetaPatch) (bool, string) {
+	if patch == nil {
+		return false, "file metadata is empty"
+	}
+
+	meta := webpojo.UserFileMeta{Tags: patch.Tags}
+	if patch.Category != nil {
+		meta.Category = *patch.Category
+	}
+	if patch.Description != nil {
+		meta.Description = *patch.Description
+	}
+
+	if valid, message := validateFileMeta(&meta); !valid {
+		return false, message
+	}
+
+	if patch.Category != nil {
+		patch.Category = &meta.Category
+	}
+	if patch.Description != nil {
+		patch.Description = &meta.Description
+	}
+	patch.Tags = meta.Tags
+
+	return true, ""
+}
+
+// validateFileMeta normalizes file category and tags and returns true if metadata is valid. Return validation error text.
+func validateFileMeta(meta *webpojo.UserFileMeta) (bool, string) {
+	if meta == nil {
+		return false, "file metadata is empty"
+	}
+
+	meta.Category = filecategory.Normalize(meta.Category)
+	if meta.Category != "" && !filecategory.IsValid(meta.Category) {
+		return false, "unknown category"
+	}
+
+	meta.Description = strings.TrimSpace(meta.Description)
+	if len(meta.Description) > maxFileDescriptionLen {
+		return false, "description is too long"
+	}
+
+	// Missing tags keep file tags unchanged
+	if meta.Tags == nil {
+		return true, ""
+	}
+
+	tags := []string{}
+	seen := map[string]bool{}
+	for _, v := range meta.Tags {
+		tag := strings.ToLower(strings.TrimSpace(v))
+		if tag == "" || seen[tag] {
+			continue
+		}
+
+		if len(tag) > maxFileTagLen {
+			return false, "tag is too long"
+		}
+
+		if strings.Contains(tag, ",") {
+			return false, "tag can't contain comma"
+		}
+
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+
+	if len(tags) > maxFileTags {
+		return false, "too many tags"
+	}
+
+	meta.Tags = tags
+	return true, ""
+}
+
+// validateFileSort returns true if file list can be sorted by field
+func validateFileSort(field string) bool {
+	switch field {
+	case "", "created", "name", "size", "category":
+		return true
+	}
+
+	return false
+}
+
+// validateLender trims lender fields and returns true if lender is valid. Return validation error text.
+func validateLender(lender *webpojo.LenderPojo) (bool, string) {
+	lender.Name = strings.TrimSpace(lender.Name)
+	lender.Description = strings.TrimSpace(lender.Description)
+	lender.Address = strings.TrimSpace(lender.Address)
+	lender.Email = strings.ToLower(strings.TrimSpace(lender.Email))
+	lender.Phone = strings.TrimSpace(lender.Phone)
+	lender.Contact = strings.TrimSpace(lender.Contact)
+
+	if lender.Name == "" {
+		return false, "name is empty"
+	}
+
+	if len(lender.Name) > maxLenderNameLen {
+		return false, "name is too long"
+	}
+
+	if lender.Email != "" && !validateEmail(lender.Email) {
+		return false, "bad email format"
+	}
+
+	if lender.StatusID == 0 {
+		lender.StatusID = model.LenderActive
+	}
+
+	if lender.StatusID != model.LenderActive && lender.StatusID != model.LenderInactive {
+		return false, "status_id should be 1 (active) or 2 (inactive)"
+	}
+
+	return true, ""
+}
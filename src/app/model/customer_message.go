@@ -14,21 +14,34 @@ import (
 // Customer message
 // *****************************************************************************
 
+// messageReaders selects thread participants who read message: by thread read cursor or by mark on message
+const messageReaders = `FROM message_thread_participant reader
+	WHERE reader.thread_id = customer_message.thread_id AND (reader.last_read_message_id >= customer_message.id
+		OR EXISTS (SELECT 1 FROM message_read WHERE message_read.message_id = customer_message.id AND message_read.user_id = reader.user_id))`
+
+// messageReadStateColumns selects readed for user passed twice as argument and read_by list
+const messageReadStateColumns = `EXISTS (SELECT 1 ` + messageReaders + ` AND (reader.user_id = ? OR from_user_id = ?)) AS readed,
+	IFNULL((SELECT GROUP_CONCAT(reader.user_id ORDER BY reader.user_id) ` + messageReaders + `), '') AS read_by`
+
 // Message table contains the information for each user message
 type Message struct {
-	ID                uint32    `db:"id"`
-	ThreadID          uint32    `db:"thread_id"`
-	ToUserID          uint32    `db:"to_user_id"`
-	ToUserFirstName   string    `db:"to_first_name"`
-	ToUserLastName    string    `db:"to_last_name"`
-	FromUserID        uint32    `db:"from_user_id"`
-	FromUserFirstName string    `db:"from_first_name"`
-	FromUserLastName  string    `db:"from_last_name"`
-	Content           string    `db:"content"`
-	Readed            bool      `db:"readed"`
-	CreatedAt         time.Time `db:"created_at"`
-	UpdatedAt         time.Time `db:"updated_at"`
-	Deleted           uint8     `db:"deleted"`
+	ID                uint32     `db:"id"`
+	ThreadID          uint32     `db:"thread_id"`
+	ToUserID          uint32     `db:"to_user_id"`
+	ToUserFirstName   string     `db:"to_first_name"`
+	ToUserLastName    string     `db:"to_last_name"`
+	FromUserID        uint32     `db:"from_user_id"`
+	FromUserFirstName string     `db:"from_first_name"`
+	FromUserLastName  string     `db:"from_last_name"`
+	Content           string     `db:"content"`
+	Readed            bool       `db:"readed"`
+	ReadBy            string     `db:"read_by"` // comma separated IDs of users who read message
+	CreatedAt         time.Time  `db:"created_at"`
+	UpdatedAt         time.Time  `db:"updated_at"`
+	Edited            bool       `db:"edited"`
+	Deleted           uint8      `db:"deleted"`
+	DeletedBy         uint32     `db:"deleted_by"` // 0 while message is not deleted
+	DeletedAt         *time.Time `db:"deleted_at"`
 }
 
 // MessageID returns the message id
@@ -43,8 +56,9 @@ func (m *Message) MessageID() string {
 	return r
 }
 
-// MessageByID gets message by ID
-func MessageByID(toUserID string, messageID string) (*Message, error) {
+// MessageByID gets message by ID if user is participant of its thread.
+// Readed is set for user: own message is readed when any participant read it.
+func MessageByID(userID string, messageID string) (*Message, error) {
 	var err error
 
 	result := &Message{}
@@ -63,19 +77,23 @@ func MessageByID(toUserID string, messageID string) (*Message, error) {
 			from_user_info.last_name as from_last_name, 
 			
             content,
-            readed,
+            `+messageReadStateColumns+`,
 				
 			customer_message.created_at, 
  			customer_message.updated_at, 
- 			customer_message.deleted 
+ 			customer_message.edited, 
+ 			customer_message.deleted, 
+ 			customer_message.deleted_by, 
+ 			customer_message.deleted_at 
  
 			FROM customer_message 
 
 			LEFT JOIN user from_user_info ON from_user_info.id=from_user_id
 			LEFT JOIN user to_user_info ON to_user_info.id=to_user_id 
 
-			WHERE customer_message.id = ? AND to_user_id = ? LIMIT 1;
-			`, messageID, toUserID)
+			WHERE customer_message.id = ? AND EXISTS (SELECT 1 FROM message_thread_participant participant
+				WHERE participant.thread_id = customer_message.thread_id AND participant.user_id = ?) LIMIT 1;
+			`, userID, userID, messageID, userID)
 	default:
 		err = ErrCode
 	}
@@ -83,13 +101,19 @@ func MessageByID(toUserID string, messageID string) (*Message, error) {
 	return result, standardizeError(err)
 }
 
-// MessagesByUserID gets messages for a user
-func MessagesByUserID(threadID, userID uint32, count, offset int) ([]*Message, error) {
-	log.Println(threadID, " ", userID, " ", count, " ", offset)
+// MessagesByUserID gets page of thread messages, newest messages first, readed is set for user.
+// Also return are there more messages in page direction. Caller must check user is thread participant.
+func MessagesByUserID(threadID, userID uint32, page *Page) ([]*Message, bool, error) {
 	var err error
+	var more bool
 
 	var result []*Message
 
+	cond, args, orderBy := page.keyset("", "customer_message.id", true)
+	if cond != "" {
+		cond = "AND " + cond
+	}
+
 	switch database.ReadConfig().Type {
 	case database.TypeMySQL:
 		err = database.SQL.Select(&result, `
@@ -105,53 +129,96 @@ func MessagesByUserID(threadID, userID uint32, count, offset int) ([]*Message, e
 			from_user_info.last_name as from_last_name, 
 			
             content,
-            readed,
+            `+messageReadStateColumns+`,
 				
 			customer_message.created_at, 
  			customer_message.updated_at, 
- 			customer_message.deleted 
+ 			customer_message.edited, 
+ 			customer_message.deleted, 
+ 			customer_message.deleted_by, 
+ 			customer_message.deleted_at 
  
 			FROM customer_message 
 
 			LEFT JOIN user from_user_info ON from_user_info.id=from_user_id
 			LEFT JOIN user to_user_info ON to_user_info.id=to_user_id 
 
-			WHERE thread_id = ? AND (to_user_id=? OR from_user_id=?) ORDER BY created_at DESC LIMIT ? OFFSET ?;`, threadID, userID, userID, count, offset)
+			WHERE thread_id = ? `+cond+` ORDER BY `+orderBy+` LIMIT ?;`,
+			append(append([]interface{}{userID, userID, threadID}, args...), page.limit())...)
+		if err != nil {
+			break
+		}
+
+		var n int
+		n, more = page.trim(len(result), func(i, j int) { result[i], result[j] = result[j], result[i] })
+		result = result[:n]
 	default:
 		err = ErrCode
 	}
 
-	return result, standardizeError(err)
+	return result, more, standardizeError(err)
 }
 
-// MessageCreate creates a message
-func MessageCreate(toUserID, fromUserID, threadID uint32, content string) error {
+// MessageCreate creates a message with attached files in one transaction and return its ID
+func MessageCreate(toUserID, fromUserID, threadID uint32, content string, fileIDs ...uint32) (uint32, error) {
 	var err error
+	var lastID uint32
 
 	switch database.ReadConfig().Type {
 	case database.TypeMySQL:
-		_, err = database.SQL.Exec("INSERT INTO customer_message (to_user_id, from_user_id, content, thread_id) VALUES (?, ?, ?, ?);", toUserID, fromUserID, content, threadID)
-		if err != nil {
-			return standardizeError(err)
-		}
-
+		lastID, err = messageCreateMySQL(toUserID, fromUserID, threadID, content, fileIDs)
 	default:
 		err = ErrCode
 	}
 
-	return standardizeError(err)
+	return lastID, standardizeError(err)
+}
+
+// messageCreateMySQL inserts message and links attached files to it
+func messageCreateMySQL(toUserID, fromUserID, threadID uint32, content string, fileIDs []uint32) (uint32, error) {
+	tx, err := database.SQL.Beginx()
+	if err != nil {
+		return 0, err
+	}
+
+	// Thread title is copied to message for full-text search over title and content
+	result, err := tx.Exec(`INSERT INTO customer_message (to_user_id, from_user_id, content, thread_id, thread_title)
+		SELECT ?, ?, ?, id, title FROM message_thread WHERE id = ?`, toUserID, fromUserID, content, threadID)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	if n, _ := result.RowsAffected(); n == 0 {
+		tx.Rollback()
+		return 0, ErrThreadNotExist
+	}
+
+	lastID, err := result.LastInsertId()
+	if err != nil {
+		log.Println("error while get last inserted id: ", err)
+		tx.Rollback()
+		return 0, err
+	}
+
+	for _, fileID := range fileIDs {
+		_, err = tx.Exec("INSERT IGNORE INTO message_attachment (message_id, file_id) VALUES (?,?)", lastID, fileID)
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+
+	return uint32(lastID), tx.Commit()
 }
 
-// MessageUpdate updates a message
-func MessageUpdate(content string, fromUserID, messageID, threadID uint32) error {
+// MessageUpdate replaces content of not deleted message, previous content is kept in revision history
+func MessageUpdate(content string, editorID, fromUserID, messageID, threadID uint32) error {
 	var err error
 
 	switch database.ReadConfig().Type {
 	case database.TypeMySQL:
-		_, err := database.SQL.Exec("UPDATE customer_message SET content=?  WHERE id = ? AND thread_id = ? AND from_user_id = ? LIMIT 1", content, messageID, threadID, fromUserID)
-		if err != nil {
-			return standardizeError(err)
-		}
+		err = messageUpdateMySQL(content, editorID, fromUserID, messageID, threadID)
 
 	default:
 		err = ErrCode
@@ -160,28 +227,65 @@ func MessageUpdate(content string, fromUserID, messageID, threadID uint32) error
 	return standardizeError(err)
 }
 
-// MarkMessageReadedUpdate set messages readed mark
-func MarkMessageReadedUpdate(readed bool, toUserID, messageID uint32, threadID uint32) error {
+func messageUpdateMySQL(content string, editorID, fromUserID, messageID, threadID uint32) error {
+	tx, err := database.SQL.Beginx()
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.Exec(`INSERT INTO message_revision (message_id, content, edited_by)
+		SELECT id, content, ? FROM customer_message WHERE id = ? AND thread_id = ? AND from_user_id = ? AND deleted = 0`,
+		editorID, messageID, threadID, fromUserID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		tx.Rollback()
+		return ErrNoResult
+	}
+
+	_, err = tx.Exec("UPDATE customer_message SET content = ?, edited = 1 WHERE id = ?", content, messageID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// MarkMessageReadedUpdate set or clear user's read mark of thread message.
+// Clearing mark also moves user's thread read cursor before message, so it becomes unread.
+func MarkMessageReadedUpdate(readed bool, userID, messageID uint32, threadID uint32) error {
 	var err error
 
 	switch database.ReadConfig().Type {
 	case database.TypeMySQL:
-		res, err := database.SQL.Exec("UPDATE customer_message SET readed=?  WHERE id = ? AND thread_id = ? AND to_user_id = ? LIMIT 1", readed, messageID, threadID, toUserID)
+		var exists bool
+		err = database.SQL.Get(&exists, "SELECT EXISTS (SELECT 1 FROM customer_message WHERE id = ? AND thread_id = ?)", messageID, threadID)
 		if err != nil {
 			return standardizeError(err)
 		}
 
-		affected, err := res.RowsAffected()
-		if err != nil {
-			log.Println("error while mark message readed: ", err)
-			return standardizeError(err)
+		if !exists {
+			log.Println("error while mark message readed: message not found in thread")
+			return ErrNoResult
 		}
 
-		if affected == 0 {
-			log.Println("error while mark message readed: 0 rows affected")
+		if readed {
+			_, err = database.SQL.Exec("INSERT IGNORE INTO message_read (message_id, user_id) VALUES (?,?)", messageID, userID)
+			break
+		}
+
+		_, err = database.SQL.Exec("DELETE FROM message_read WHERE message_id = ? AND user_id = ?", messageID, userID)
+		if err != nil {
 			return standardizeError(err)
 		}
 
+		_, err = database.SQL.Exec(`UPDATE message_thread_participant SET last_read_message_id = ?
+			WHERE thread_id = ? AND user_id = ? AND last_read_message_id >= ?`, messageID-1, threadID, userID, messageID)
+
 	default:
 		err = ErrCode
 	}
@@ -189,13 +293,27 @@ func MarkMessageReadedUpdate(readed bool, toUserID, messageID uint32, threadID u
 	return standardizeError(err)
 }
 
-// MessageDelete deletes a message
-func MessageDelete(fromUserID, messageID, threadID uint32) error {
+// ReadByIDs return IDs of users who read message
+func (m *Message) ReadByIDs() []uint32 {
+	return splitIDs(m.ReadBy)
+}
+
+// MessageDelete marks message deleted, its content is kept for moderation
+func MessageDelete(deletedBy, fromUserID, messageID, threadID uint32) error {
 	var err error
+	var res sql.Result
 
 	switch database.ReadConfig().Type {
 	case database.TypeMySQL:
-		_, err = database.SQL.Exec("DELETE FROM customer_message WHERE id = ? AND thread_id = ? AND from_user_id = ?", messageID, threadID, fromUserID)
+		res, err = database.SQL.Exec(`UPDATE customer_message SET deleted = 1, deleted_by = ?, deleted_at = NOW()
+			WHERE id = ? AND thread_id = ? AND from_user_id = ? AND deleted = 0`, deletedBy, messageID, threadID, fromUserID)
+		if err != nil {
+			break
+		}
+
+		if n, _ := res.RowsAffected(); n == 0 {
+			err = ErrNoResult
+		}
 
 	default:
 		err = ErrCode
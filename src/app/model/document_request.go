@@ -0,0 +1,106 @@
+package model
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"app/shared/database"
+)
+
+// *****************************************************************************
+// Document request
+// *****************************************************************************
+
+const (
+	// DocumentRequestPending is waiting for customer upload
+	DocumentRequestPending = "pending"
+	// DocumentRequestFulfilled is closed by customer upload
+	DocumentRequestFulfilled = "fulfilled"
+)
+
+// DocumentRequest table contains documents which staff asked customer to upload
+type DocumentRequest struct {
+	ID         uint32        `db:"id"`
+	CustomerID uint32        `db:"customer_id"`
+	StaffID    uint32        `db:"staff_id"`
+	Category   string        `db:"category"`
+	Note       string        `db:"note"`
+	Status     string        `db:"status"`
+	FileID     sql.NullInt64 `db:"file_id"` // uploaded file which fulfilled request
+	CreatedAt  time.Time     `db:"created_at"`
+	UpdatedAt  time.Time     `db:"updated_at"`
+}
+
+// DocumentRequestCreate creates a new pending document request
+func DocumentRequestCreate(customerID, staffID, category, note string) (int, error) {
+	var err error
+	var res sql.Result
+	var lastID int64
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		res, err = database.SQL.Exec("INSERT INTO document_request (customer_id, staff_id, category, note, status) VALUES (?,?,?,?,?)",
+			customerID, staffID, category, note, DocumentRequestPending)
+
+	default:
+		err = ErrCode
+	}
+
+	if err != nil {
+		return 0, standardizeError(err)
+	}
+
+	lastID, err = res.LastInsertId()
+	if err != nil {
+		return 0, standardizeError(err)
+	}
+
+	return int(lastID), nil
+}
+
+// DocumentRequestsByCustomerID gets all document requests of customer, newest first
+func DocumentRequestsByCustomerID(customerID string) ([]*DocumentRequest, error) {
+	var err error
+
+	var result []*DocumentRequest
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Select(&result, "SELECT id, customer_id, staff_id, category, note, status, file_id, created_at, updated_at "+
+			"FROM document_request WHERE customer_id = ? ORDER BY id DESC", customerID)
+	default:
+		err = ErrCode
+	}
+
+	return result, standardizeError(err)
+}
+
+// DocumentRequestFulfill links uploaded file to pending customer's document request
+func DocumentRequestFulfill(customerID, requestID string, fileID int) error {
+	var err error
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		var res sql.Result
+		res, err = database.SQL.Exec("UPDATE document_request SET status = ?, file_id = ? WHERE id = ? AND customer_id = ? AND status = ?",
+			DocumentRequestFulfilled, fileID, requestID, customerID, DocumentRequestPending)
+		if err != nil {
+			return standardizeError(err)
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			log.Println("error while get rows affected: " + err.Error())
+			return standardizeError(err)
+		}
+
+		if rows == 0 {
+			return ErrNoResult
+		}
+	default:
+		err = ErrCode
+	}
+
+	return standardizeError(err)
+}
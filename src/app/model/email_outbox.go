@@ -0,0 +1,157 @@
+package model
+
+import (
+	"time"
+
+	"app/shared/database"
+)
+
+// *****************************************************************************
+// Email outbox
+// *****************************************************************************
+
+// Outbox email statuses
+const (
+	EmailPending = "pending"
+	EmailSent    = "sent"
+	EmailFailed  = "failed"
+)
+
+// OutboxEmail is email waiting for delivery
+type OutboxEmail struct {
+	ID            uint32     `db:"id"`
+	UserID        uint32     `db:"user_id"`
+	ToEmail       string     `db:"to_email"`
+	Subject       string     `db:"subject"`
+	TextBody      string     `db:"text_body"`
+	HTMLBody      string     `db:"html_body"`
+	Status        string     `db:"status"` // EmailPending, EmailSent or EmailFailed
+	Attempts      int        `db:"attempts"`
+	LastError     string     `db:"last_error"`
+	NextAttemptAt time.Time  `db:"next_attempt_at"`
+	CreatedAt     time.Time  `db:"created_at"`
+	SentAt        *time.Time `db:"sent_at"`
+}
+
+const outboxEmailColumns = `id, user_id, to_email, subject, text_body, html_body, status, attempts, last_error,
+	next_attempt_at, created_at, sent_at`
+
+// OutboxEmailCreate puts email to outbox and return its ID
+func OutboxEmailCreate(userID uint32, to, subject, textBody, htmlBody string) (uint32, error) {
+	var err error
+	var lastID int64
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		res, err := database.SQL.Exec("INSERT INTO email_outbox (user_id, to_email, subject, text_body, html_body) VALUES (?,?,?,?,?)",
+			userID, to, subject, textBody, htmlBody)
+		if err != nil {
+			return 0, standardizeError(err)
+		}
+
+		lastID, err = res.LastInsertId()
+		if err != nil {
+			return 0, standardizeError(err)
+		}
+	default:
+		err = ErrCode
+	}
+
+	return uint32(lastID), standardizeError(err)
+}
+
+// OutboxEmailByID return outbox email
+func OutboxEmailByID(id uint32) (*OutboxEmail, error) {
+	var err error
+
+	result := OutboxEmail{}
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Get(&result, "SELECT "+outboxEmailColumns+" FROM email_outbox WHERE id = ?", id)
+	default:
+		err = ErrCode
+	}
+
+	return &result, standardizeError(err)
+}
+
+// OutboxEmailsDue return pending emails which attempt time has come, oldest first
+func OutboxEmailsDue(limit int) ([]*OutboxEmail, error) {
+	var err error
+
+	var result []*OutboxEmail
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Select(&result, "SELECT "+outboxEmailColumns+` FROM email_outbox
+			WHERE status = ? AND next_attempt_at <= NOW() ORDER BY id LIMIT ?`, EmailPending, limit)
+	default:
+		err = ErrCode
+	}
+
+	return result, standardizeError(err)
+}
+
+// OutboxEmailClaim postpones next attempt of due email by lease, so other workers skip it while it is sent.
+// Return false if email is claimed already.
+func OutboxEmailClaim(id uint32, lease time.Duration) (bool, error) {
+	var err error
+	var affected int64
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		res, err := database.SQL.Exec(`UPDATE email_outbox SET next_attempt_at = DATE_ADD(NOW(), INTERVAL ? SECOND)
+			WHERE id = ? AND status = ? AND next_attempt_at <= NOW()`, int(lease.Seconds()), id, EmailPending)
+		if err != nil {
+			return false, standardizeError(err)
+		}
+
+		affected, err = res.RowsAffected()
+		if err != nil {
+			return false, standardizeError(err)
+		}
+	default:
+		err = ErrCode
+	}
+
+	return affected == 1, standardizeError(err)
+}
+
+// OutboxEmailSent marks email delivered
+func OutboxEmailSent(id uint32) error {
+	var err error
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		_, err = database.SQL.Exec("UPDATE email_outbox SET status = ?, attempts = attempts + 1, sent_at = NOW() WHERE id = ?", EmailSent, id)
+	default:
+		err = ErrCode
+	}
+
+	return standardizeError(err)
+}
+
+// OutboxEmailFailed records failed attempt. Email is retried after delay or gets EmailFailed status if giveUp is set.
+func OutboxEmailFailed(id uint32, lastError string, delay time.Duration, giveUp bool) error {
+	var err error
+
+	status := EmailPending
+	if giveUp {
+		status = EmailFailed
+	}
+
+	if len(lastError) > 255 {
+		lastError = lastError[:255]
+	}
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		_, err = database.SQL.Exec(`UPDATE email_outbox SET status = ?, attempts = attempts + 1, last_error = ?,
+			next_attempt_at = DATE_ADD(NOW(), INTERVAL ? SECOND) WHERE id = ?`, status, lastError, int(delay.Seconds()), id)
+	default:
+		err = ErrCode
+	}
+
+	return standardizeError(err)
+}
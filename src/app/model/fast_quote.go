@@ -0,0 +1,163 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+
+	"app/shared/database"
+)
+
+// *****************************************************************************
+// Fast quote
+// *****************************************************************************
+
+// fastQuoteColumns is the column list for FastQuote selects
+const fastQuoteColumns = `id, token, IFNULL(user_id, 0) AS user_id, loan_amount, term_years, credit_band, product, rates,
+	claimed_at, created_at`
+
+// FastQuote table contains fast quote inputs and returned rates as JSON, loan amount is decimal
+type FastQuote struct {
+	ID         uint32     `db:"id"`
+	Token      string     `db:"token"`   // public quote ID
+	UserID     uint32     `db:"user_id"` // 0 while quote is anonymous
+	LoanAmount string     `db:"loan_amount"`
+	TermYears  int        `db:"term_years"`
+	CreditBand string     `db:"credit_band"`
+	Product    string     `db:"product"`
+	Rates      string     `db:"rates"`
+	ClaimedAt  *time.Time `db:"claimed_at"` // nil while quote is not attached to customer
+	CreatedAt  time.Time  `db:"created_at"`
+}
+
+// FastQuoteReportDay contains quote volume of day and its conversion
+type FastQuoteReportDay struct {
+	Day     string `db:"day"` // YYYY-MM-DD
+	Quotes  int    `db:"quotes"`
+	Claimed int    `db:"claimed"` // quotes attached to customers
+	Applied int    `db:"applied"` // claimed quotes followed by loan application of customer
+}
+
+// FastQuoteCreate inserts a new fast quote and return its ID. Quote of signed in customer is claimed at once.
+func FastQuoteCreate(quote *FastQuote) (uint32, error) {
+	var err error
+	var res sql.Result
+	var id int64
+
+	var userID interface{}
+	if quote.UserID != 0 {
+		userID = quote.UserID
+	}
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		res, err = database.SQL.Exec(`INSERT INTO fast_quote (token, user_id, loan_amount, term_years, credit_band, product, rates, claimed_at)
+			VALUES (?,?,?,?,?,?,?,IF(? IS NULL, NULL, CURRENT_TIMESTAMP))`,
+			quote.Token, userID, quote.LoanAmount, quote.TermYears, quote.CreditBand, quote.Product, quote.Rates, userID)
+		if err != nil {
+			break
+		}
+
+		id, err = res.LastInsertId()
+	default:
+		err = ErrCode
+	}
+
+	return uint32(id), standardizeError(err)
+}
+
+// FastQuoteByToken gets fast quote by its token
+func FastQuoteByToken(token string) (*FastQuote, error) {
+	var err error
+	result := &FastQuote{}
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Get(result, "SELECT "+fastQuoteColumns+" FROM fast_quote WHERE token = ? LIMIT 1", token)
+	default:
+		err = ErrCode
+	}
+
+	return result, standardizeError(err)
+}
+
+// FastQuoteClaim attaches anonymous fast quote created after since to customer.
+// Return ErrNoResult if there is no such anonymous quote.
+func FastQuoteClaim(token string, userID uint32, since time.Time) error {
+	var err error
+	var res sql.Result
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		res, err = database.SQL.Exec(`UPDATE fast_quote SET user_id = ?, claimed_at = CURRENT_TIMESTAMP
+			WHERE token = ? AND user_id IS NULL AND claimed_at IS NULL AND created_at >= ?`, userID, token, since)
+		if err != nil {
+			break
+		}
+
+		if n, _ := res.RowsAffected(); n == 0 {
+			err = ErrNoResult
+		}
+	default:
+		err = ErrCode
+	}
+
+	return standardizeError(err)
+}
+
+// FastQuotesByUserID gets fast quotes of customer, newest first
+func FastQuotesByUserID(userID uint32) ([]FastQuote, error) {
+	var err error
+	var result []FastQuote
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Select(&result, "SELECT "+fastQuoteColumns+" FROM fast_quote WHERE user_id = ? ORDER BY id DESC", userID)
+	default:
+		err = ErrCode
+	}
+
+	return result, standardizeError(err)
+}
+
+// FastQuoteReport gets quote volume and conversion of every day with quotes from first to last day (YYYY-MM-DD).
+// Claimed quote is converted to application if its customer created loan application after the quote.
+func FastQuoteReport(from, to string) ([]FastQuoteReportDay, error) {
+	var err error
+	var result []FastQuoteReportDay
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Select(&result, `SELECT DATE_FORMAT(fast_quote.created_at, '%Y-%m-%d') AS day, COUNT(*) AS quotes,
+			COUNT(fast_quote.user_id) AS claimed,
+			IFNULL(SUM(EXISTS (SELECT 1 FROM loan_application WHERE loan_application.customer_id = fast_quote.user_id
+				AND loan_application.created_at >= fast_quote.created_at)), 0) AS applied
+			FROM fast_quote
+			WHERE fast_quote.created_at >= ? AND fast_quote.created_at < DATE_ADD(?, INTERVAL 1 DAY)
+			GROUP BY day ORDER BY day`, from, to)
+	default:
+		err = ErrCode
+	}
+
+	return result, standardizeError(err)
+}
+
+// FastQuotesPurge removes anonymous not claimed fast quotes created before time, return number of removed quotes
+func FastQuotesPurge(before time.Time) (int, error) {
+	var err error
+	var res sql.Result
+	var n int64
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		res, err = database.SQL.Exec("DELETE FROM fast_quote WHERE user_id IS NULL AND claimed_at IS NULL AND created_at < ?", before)
+		if err != nil {
+			break
+		}
+
+		n, err = res.RowsAffected()
+	default:
+		err = ErrCode
+	}
+
+	return int(n), standardizeError(err)
+}
@@ -0,0 +1,114 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+
+	"app/shared/database"
+)
+
+// *****************************************************************************
+// File access log
+// *****************************************************************************
+
+// File access log actions
+const (
+	FileAccessList     = "list"
+	FileAccessDownload = "download"
+	FileAccessRequest  = "request"
+	FileAccessDenied   = "denied"
+)
+
+// FileAccessLog table is an append-only audit of staff access to customer files.
+// There are no update or delete functions, DB triggers reject them too.
+type FileAccessLog struct {
+	ID         uint32        `db:"id"`
+	ActorID    uint32        `db:"actor_id"`
+	ActorRole  uint8         `db:"actor_role"`
+	CustomerID uint32        `db:"customer_id"`
+	FileID     sql.NullInt64 `db:"file_id"`
+	Action     string        `db:"action"`
+	Detail     string        `db:"detail"`
+	RemoteAddr string        `db:"remote_addr"`
+	CreatedAt  time.Time     `db:"created_at"`
+}
+
+// FileAccessFilter contains params for file access log search. Zero values are ignored.
+type FileAccessFilter struct {
+	ActorID    string
+	CustomerID string
+	Action     string
+	From       time.Time
+	To         time.Time
+	Limit      int
+	Offset     int
+}
+
+// FileAccessLogCreate appends record to file access log. Zero fileID means action is not related to one file.
+func FileAccessLogCreate(actorID string, actorRole int, customerID string, fileID uint32, action, detail, remoteAddr string) error {
+	var err error
+
+	file := sql.NullInt64{Int64: int64(fileID), Valid: fileID != 0}
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		_, err = database.SQL.Exec("INSERT INTO file_access_log (actor_id, actor_role, customer_id, file_id, action, detail, remote_addr) VALUES (?,?,?,?,?,?,?)",
+			actorID, actorRole, customerID, file, action, detail, remoteAddr)
+
+	default:
+		err = ErrCode
+	}
+
+	return standardizeError(err)
+}
+
+// FileAccessLogSearch gets file access log records matched to filter, newest first, and total count of matched records
+func FileAccessLogSearch(filter *FileAccessFilter) ([]*FileAccessLog, int, error) {
+	var err error
+
+	var result []*FileAccessLog
+	var total int
+
+	where := "WHERE 1 = 1"
+	args := []interface{}{}
+
+	if filter.ActorID != "" {
+		where += " AND actor_id = ?"
+		args = append(args, filter.ActorID)
+	}
+
+	if filter.CustomerID != "" {
+		where += " AND customer_id = ?"
+		args = append(args, filter.CustomerID)
+	}
+
+	if filter.Action != "" {
+		where += " AND action = ?"
+		args = append(args, filter.Action)
+	}
+
+	if !filter.From.IsZero() {
+		where += " AND created_at >= ?"
+		args = append(args, filter.From)
+	}
+
+	if !filter.To.IsZero() {
+		where += " AND created_at < ?"
+		args = append(args, filter.To)
+	}
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Get(&total, "SELECT COUNT(*) FROM file_access_log "+where, args...)
+		if err != nil {
+			break
+		}
+
+		err = database.SQL.Select(&result, "SELECT id, actor_id, actor_role, customer_id, file_id, action, detail, remote_addr, created_at "+
+			"FROM file_access_log "+where+" ORDER BY id DESC LIMIT ? OFFSET ?", append(args, filter.Limit, filter.Offset)...)
+	default:
+		err = ErrCode
+	}
+
+	return result, total, standardizeError(err)
+}
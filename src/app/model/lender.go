@@ -1,10 +1,7 @@
 package model
 
 import (
-	"app/webpojo"
-	"errors"
-	"fmt"
-	"log"
+	"database/sql"
 	"time"
 
 	"app/shared/database"
@@ -14,7 +11,17 @@ import (
 // Lender CRUD
 // *****************************************************************************
 
-// Lender table contains the information for each lead
+const (
+	// LenderActive is status of lender whose rates are offered
+	LenderActive = 1
+	// LenderInactive is status of lender whose rates are hidden from best rates
+	LenderInactive = 2
+)
+
+// lenderColumns selects lender
+const lenderColumns = "id, name, description, address, email, phone, contact, status_id, created_at"
+
+// Lender table contains the information for each lender
 type Lender struct {
 	ID          uint32    `db:"id"`
 	Name        string    `db:"name"`
@@ -27,56 +34,38 @@ type Lender struct {
 	CreatedAt   time.Time `db:"created_at"`
 }
 
-// LenderCreate inserts a new lender
-func LenderCreate(name string, description string, address string, email string,
-	phone string, contact string, statusId int) error {
+// LenderCreate inserts a new lender and return its ID
+func LenderCreate(lender *Lender) (uint32, error) {
 	var err error
+	var res sql.Result
+	var id int64
 
 	switch database.ReadConfig().Type {
 	case database.TypeMySQL:
-		result := false
-		retries := 0
-		for !result && retries < 5 {
-			_, err = database.SQL.Exec("INSERT INTO lender (name, description, address, email, phone, contact, status_id)"+
-				" VALUES (?,?,?,?,?,?,?)",
-				name, description, address, email, phone, contact, statusId)
-
-			if err == nil {
-				result = true
-				log.Println("Inserted lender " + name + " successfully")
-			} else {
-				log.Println(fmt.Sprintf("%v has error %v", name, err))
-			}
-			retries = retries + 1
+		res, err = database.SQL.Exec("INSERT INTO lender (name, description, address, email, phone, contact, status_id)"+
+			" VALUES (?,?,?,?,?,?,?)",
+			lender.Name, lender.Description, lender.Address, lender.Email, lender.Phone, lender.Contact, lender.StatusID)
+		if err != nil {
+			break
 		}
+
+		id, err = res.LastInsertId()
 	default:
 		err = ErrCode
 	}
 
-	return standardizeError(err)
+	return uint32(id), standardizeError(err)
 }
 
-// LenderUpdate updates an existing lender
-func LenderUpdate(name string, description string, address string, email string,
-	phone string, contact string, statusId int) error {
+// LenderUpdate updates an existing lender found by its ID
+func LenderUpdate(lender *Lender) error {
 	var err error
-	log.Println("Status: " + string(statusId))
+
 	switch database.ReadConfig().Type {
 	case database.TypeMySQL:
-		result := false
-		retries := 0
-		for !result && retries < 5 {
-			_, err = database.SQL.Exec("UPDATE lender SET description = ?, address = ?, email = ?, phone = ?, contact = ?, "+
-				"status_id = ? WHERE name = ?", description, address, email, phone, contact, statusId, name)
-
-			if err == nil {
-				result = true
-				log.Println("Updated lender " + name + " successfully")
-			} else {
-				log.Println(fmt.Sprintf("%v has error %v", name, err))
-			}
-			retries = retries + 1
-		}
+		_, err = database.SQL.Exec("UPDATE lender SET name = ?, description = ?, address = ?, email = ?, phone = ?, contact = ?, "+
+			"status_id = ? WHERE id = ?",
+			lender.Name, lender.Description, lender.Address, lender.Email, lender.Phone, lender.Contact, lender.StatusID, lender.ID)
 	default:
 		err = ErrCode
 	}
@@ -84,35 +73,29 @@ func LenderUpdate(name string, description string, address string, email string,
 	return standardizeError(err)
 }
 
-//LeaderByName gets Lender information from leader name
-func LeaderByName(lenderName string) (Lender, error) {
+// LenderByID gets lender by its ID
+func LenderByID(id uint32) (Lender, error) {
 	var err error
 	result := Lender{}
 
 	switch database.ReadConfig().Type {
 	case database.TypeMySQL:
-		err = database.SQL.Get(&result, "SELECT id, name, description, address, email, phone, "+
-			"contact, status_id, created_at FROM lender WHERE name = ? LIMIT 1", lenderName)
+		err = database.SQL.Get(&result, "SELECT "+lenderColumns+" FROM lender WHERE id = ? LIMIT 1", id)
 	default:
 		err = ErrCode
 	}
-	fmt.Printf("Email: %s\n", result.Email)
-
-	if err != nil {
-		fmt.Printf("%s", err)
-	}
 
 	return result, standardizeError(err)
 }
 
-// LendersListAll gets all lenders
+// LendersListAll gets all lenders ordered by name
 func LendersListAll() ([]Lender, error) {
 	var err error
 	var lenders []Lender
 
 	switch database.ReadConfig().Type {
 	case database.TypeMySQL:
-		err = database.SQL.Select(&lenders, "SELECT id, name, description, address, email, phone, contact, status_id, created_at from lender")
+		err = database.SQL.Select(&lenders, "SELECT "+lenderColumns+" FROM lender ORDER BY name")
 	default:
 		err = ErrCode
 	}
@@ -120,86 +103,25 @@ func LendersListAll() ([]Lender, error) {
 	return lenders, standardizeError(err)
 }
 
-// LenderRatesListBest gets best lender rates (min apr+interest from group of products)
-func LenderRatesListBest() ([]LenderRate, error) {
-	var err error
-	var lenderRates []LenderRate
-
-	switch database.ReadConfig().Type {
-	case database.TypeMySQL:
-		err = database.SQL.Select(&lenderRates,
-			`SELECT id,
-			lender_id,
-			lender_name,
-			product,
-			interest,
-			apr,
-			begin_date,
-			end_date,
-			created_at 
-				FROM lender_rate WHERE (interest, product) IN (
-                SELECT
-				max(r2.interest), 
-                r2.product
-					FROM lender_rate r2 
-					GROUP BY r2.product)
-                GROUP BY product`)
-	default:
-		err = ErrCode
-	}
-
-	return lenderRates, standardizeError(err)
-}
-
-// FastQuoteRate return info for fast quote
-func FastQuoteRate(fastQuoteRequest *webpojo.FastQuoteReq) ([]*LenderRate, error) {
+// LenderDelete deletes a lender by its ID, lender rates are deleted by cascade.
+// Return ErrNoResult if lender not exist.
+func LenderDelete(id uint32) error {
 	var err error
-	lenderRate := []*LenderRate{}
+	var res sql.Result
 
 	switch database.ReadConfig().Type {
 	case database.TypeMySQL:
-		err = database.SQL.Select(&lenderRate,
-			`SELECT id,
-			lender_id,
-			lender_name,
-			product,
-			interest,
-			apr,
-			begin_date,
-			end_date,
-			created_at 
-				FROM lender_rate WHERE (interest, product) IN (
-                SELECT
-				max(r2.interest), 
-                r2.product
-					FROM lender_rate r2 
-					GROUP BY r2.product)
-				GROUP BY product;`)
-	default:
-		err = ErrCode
-	}
-
-	if len(lenderRate) == 0 {
-		return nil, standardizeError(errors.New("lenders list is empty"))
-	}
-
-	return lenderRate, standardizeError(err)
-}
-
-//LenderDeleteByName deletes a lender by its name
-func LenderDeleteByName(lenderName string) error {
-	var err error
+		res, err = database.SQL.Exec("DELETE FROM lender WHERE id = ?", id)
+		if err != nil {
+			break
+		}
 
-	switch database.ReadConfig().Type {
-	case database.TypeMySQL:
-		_, err = database.SQL.Exec("DELETE FROM lender WHERE name = ?", lenderName)
+		if n, _ := res.RowsAffected(); n == 0 {
+			err = ErrNoResult
+		}
 	default:
 		err = ErrCode
 	}
 
-	if err != nil {
-		fmt.Printf("%s", err)
-	}
-
 	return standardizeError(err)
 }
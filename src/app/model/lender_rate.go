@@ -0,0 +1,479 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"app/shared/database"
+)
+
+// *****************************************************************************
+// Lender rate
+// *****************************************************************************
+
+// RateDateLayout is layout of lender rate begin and end dates
+const RateDateLayout = "2006-01-02"
+
+// lenderRateColumns selects lender rate with lender name, needs lender_rate joined with lender
+const lenderRateColumns = `lender_rate.id, lender_rate.lender_id, lender.name AS lender_name, lender_rate.product, lender_rate.term_years,
+	lender_rate.interest, lender_rate.apr,
+	DATE_FORMAT(lender_rate.begin_date, '%Y-%m-%d') AS begin_date, DATE_FORMAT(lender_rate.end_date, '%Y-%m-%d') AS end_date,
+	lender_rate.created_at`
+
+// LenderRate table contains rate of lender's loan product, interest and apr are decimal percents
+type LenderRate struct {
+	ID         uint32    `db:"id"`
+	LenderID   uint32    `db:"lender_id"`
+	LenderName string    `db:"lender_name"`
+	Product    string    `db:"product"`
+	TermYears  int       `db:"term_years"`
+	Interest   string    `db:"interest"`
+	Apr        string    `db:"apr"`
+	BeginDate  string    `db:"begin_date"` // YYYY-MM-DD
+	EndDate    string    `db:"end_date"`   // YYYY-MM-DD
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+// LenderRatesListAll gets page of all lender rates in order they were added.
+// Also return are there more rates in page direction.
+func LenderRatesListAll(page *Page) ([]LenderRate, bool, error) {
+	var err error
+	var more bool
+
+	var result []LenderRate
+
+	cond, args, orderBy := page.keyset("", "lender_rate.id", false)
+	if cond != "" {
+		cond = "WHERE " + cond
+	}
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Select(&result, `SELECT `+lenderRateColumns+`
+			FROM lender_rate JOIN lender ON lender.id = lender_rate.lender_id
+			`+cond+` ORDER BY `+orderBy+` LIMIT ?`, append(args, page.limit())...)
+		if err != nil {
+			break
+		}
+
+		var n int
+		n, more = page.trim(len(result), func(i, j int) { result[i], result[j] = result[j], result[i] })
+		result = result[:n]
+	default:
+		err = ErrCode
+	}
+
+	return result, more, standardizeError(err)
+}
+
+// LenderRateFilter contains params for lender rates search
+type LenderRateFilter struct {
+	Product   string
+	LenderID  uint32
+	SortBy    string  // apr, interest or empty for order rates were added
+	MinRate   float64 // bounds of RateField, zero for no bound
+	MaxRate   float64
+	RateField string // apr or interest, apr if empty
+	Validity  string // effective, upcoming or expired on Day, empty for any rate. Effective rates are of active lenders only.
+	Day       string // YYYY-MM-DD
+	Desc      bool
+	Page      *Page
+}
+
+// lenderRateSortColumns maps LenderRateFilter.SortBy values to columns.
+// Rates are added in ID order, so default order uses ID only.
+var lenderRateSortColumns = map[string]string{
+	"":         "",
+	"apr":      "lender_rate.apr",
+	"interest": "lender_rate.interest",
+}
+
+// SortKey return value of rate sort column, it is cursor key of rate in list sorted by sortBy
+func (r *LenderRate) SortKey(sortBy string) string {
+	switch sortBy {
+	case "apr":
+		return r.Apr
+	case "interest":
+		return r.Interest
+	}
+
+	return ""
+}
+
+// LenderRatesSearch gets page of lender rates matched to filter.
+// Also return are there more rates in page direction.
+func LenderRatesSearch(filter *LenderRateFilter) ([]LenderRate, bool, error) {
+	var err error
+	var more bool
+
+	var result []LenderRate
+
+	sortColumn, ok := lenderRateSortColumns[filter.SortBy]
+	if !ok {
+		return nil, false, fmt.Errorf("unknown sort field %q", filter.SortBy)
+	}
+
+	rateColumn := "lender_rate.apr"
+	if filter.RateField == "interest" {
+		rateColumn = "lender_rate.interest"
+	}
+
+	join := "JOIN lender ON lender.id = lender_rate.lender_id"
+	where := []string{}
+	args := []interface{}{}
+
+	if filter.Product != "" {
+		where = append(where, "lender_rate.product = ?")
+		args = append(args, filter.Product)
+	}
+
+	if filter.LenderID != 0 {
+		where = append(where, "lender_rate.lender_id = ?")
+		args = append(args, filter.LenderID)
+	}
+
+	if filter.MinRate != 0 {
+		where = append(where, rateColumn+" >= ?")
+		args = append(args, filter.MinRate)
+	}
+
+	if filter.MaxRate != 0 {
+		where = append(where, rateColumn+" <= ?")
+		args = append(args, filter.MaxRate)
+	}
+
+	switch filter.Validity {
+	case "":
+	case "effective":
+		join += " AND lender.status_id = ?"
+		where = append(where, "lender_rate.begin_date <= ? AND lender_rate.end_date >= ?")
+		args = append([]interface{}{LenderActive}, append(args, filter.Day, filter.Day)...)
+	case "upcoming":
+		where = append(where, "lender_rate.begin_date > ?")
+		args = append(args, filter.Day)
+	case "expired":
+		where = append(where, "lender_rate.end_date < ?")
+		args = append(args, filter.Day)
+	default:
+		return nil, false, fmt.Errorf("unknown rate validity %q", filter.Validity)
+	}
+
+	cond, pageArgs, orderBy := filter.Page.keyset(sortColumn, "lender_rate.id", filter.Desc)
+	if cond != "" {
+		where = append(where, cond)
+		args = append(args, pageArgs...)
+	}
+
+	whereClause := ""
+	if len(where) != 0 {
+		whereClause = "WHERE " + strings.Join(where, " AND ")
+	}
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Select(&result, `SELECT `+lenderRateColumns+`
+			FROM lender_rate `+join+`
+			`+whereClause+` ORDER BY `+orderBy+` LIMIT ?`, append(args, filter.Page.limit())...)
+		if err != nil {
+			break
+		}
+
+		var n int
+		n, more = filter.Page.trim(len(result), func(i, j int) { result[i], result[j] = result[j], result[i] })
+		result = result[:n]
+	default:
+		err = ErrCode
+	}
+
+	return result, more, standardizeError(err)
+}
+
+// LenderRatesListBest gets best rate of every product among rates effective today
+func LenderRatesListBest() ([]LenderRate, error) {
+	return LenderRatesListBestAsOf(time.Now().Format(RateDateLayout))
+}
+
+// LenderRatesListBestAsOf gets best rate of every product of active lenders among rates effective on day
+// (YYYY-MM-DD), ordered by product: rate with min apr, lower interest wins on equal apr
+func LenderRatesListBestAsOf(day string) ([]LenderRate, error) {
+	var err error
+	var result []LenderRate
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Select(&result, `SELECT `+lenderRateColumns+`
+			FROM lender_rate JOIN lender ON lender.id = lender_rate.lender_id
+			WHERE lender_rate.id = (SELECT best.id FROM lender_rate best
+				JOIN lender best_lender ON best_lender.id = best.lender_id AND best_lender.status_id = ?
+				WHERE best.product = lender_rate.product AND best.begin_date <= ? AND best.end_date >= ?
+				ORDER BY best.apr, best.interest, best.id LIMIT 1)
+			ORDER BY lender_rate.product`, LenderActive, day, day)
+	default:
+		err = ErrCode
+	}
+
+	return result, standardizeError(err)
+}
+
+// LenderRatesEffectiveAsOf gets all rates of active lenders effective on day (YYYY-MM-DD),
+// ordered from best to worst rate
+func LenderRatesEffectiveAsOf(day string) ([]LenderRate, error) {
+	var err error
+	var result []LenderRate
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Select(&result, `SELECT `+lenderRateColumns+`
+			FROM lender_rate JOIN lender ON lender.id = lender_rate.lender_id AND lender.status_id = ?
+			WHERE lender_rate.begin_date <= ? AND lender_rate.end_date >= ?
+			ORDER BY lender_rate.apr, lender_rate.interest, lender_rate.id`, LenderActive, day, day)
+	default:
+		err = ErrCode
+	}
+
+	return result, standardizeError(err)
+}
+
+// LenderRatesByProductBetween gets rates of product of active lenders effective on any day from first to last day
+// (YYYY-MM-DD), ordered from best to worst rate
+func LenderRatesByProductBetween(product, from, to string) ([]LenderRate, error) {
+	var err error
+	var result []LenderRate
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Select(&result, `SELECT `+lenderRateColumns+`
+			FROM lender_rate JOIN lender ON lender.id = lender_rate.lender_id AND lender.status_id = ?
+			WHERE lender_rate.product = ? AND lender_rate.begin_date <= ? AND lender_rate.end_date >= ?
+			ORDER BY lender_rate.apr, lender_rate.interest, lender_rate.id`, LenderActive, product, to, from)
+	default:
+		err = ErrCode
+	}
+
+	return result, standardizeError(err)
+}
+
+// LenderRatesByLenderID gets all rates of lender ordered by product and term
+func LenderRatesByLenderID(lenderID uint32) ([]LenderRate, error) {
+	var err error
+	var result []LenderRate
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Select(&result, `SELECT `+lenderRateColumns+`
+			FROM lender_rate JOIN lender ON lender.id = lender_rate.lender_id
+			WHERE lender_rate.lender_id = ? ORDER BY lender_rate.product, lender_rate.term_years, lender_rate.id`, lenderID)
+	default:
+		err = ErrCode
+	}
+
+	return result, standardizeError(err)
+}
+
+// LenderRateByID gets lender rate by its ID
+func LenderRateByID(id uint32) (LenderRate, error) {
+	var err error
+	result := LenderRate{}
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Get(&result, `SELECT `+lenderRateColumns+`
+			FROM lender_rate JOIN lender ON lender.id = lender_rate.lender_id
+			WHERE lender_rate.id = ? LIMIT 1`, id)
+	default:
+		err = ErrCode
+	}
+
+	return result, standardizeError(err)
+}
+
+// LenderRateCreate inserts a new lender rate and return its ID.
+// Return ErrConstraintFails if lender not exist.
+func LenderRateCreate(rate *LenderRate) (uint32, error) {
+	var err error
+	var res sql.Result
+	var id int64
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		res, err = database.SQL.Exec(`INSERT INTO lender_rate (lender_id, product, term_years, interest, apr, begin_date, end_date)
+			VALUES (?,?,?,?,?,?,?)`,
+			rate.LenderID, rate.Product, rate.TermYears, rate.Interest, rate.Apr, rate.BeginDate, rate.EndDate)
+		if err != nil {
+			break
+		}
+
+		id, err = res.LastInsertId()
+	default:
+		err = ErrCode
+	}
+
+	return uint32(id), standardizeError(err)
+}
+
+// LenderRateUpdate updates an existing lender rate found by its ID.
+// Return ErrConstraintFails if lender not exist.
+func LenderRateUpdate(rate *LenderRate) error {
+	var err error
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		_, err = database.SQL.Exec(`UPDATE lender_rate SET lender_id = ?, product = ?, term_years = ?, interest = ?, apr = ?,
+			begin_date = ?, end_date = ? WHERE id = ?`,
+			rate.LenderID, rate.Product, rate.TermYears, rate.Interest, rate.Apr, rate.BeginDate, rate.EndDate, rate.ID)
+	default:
+		err = ErrCode
+	}
+
+	return standardizeError(err)
+}
+
+// LenderRateDelete deletes a lender rate by its ID. Return ErrNoResult if rate not exist.
+func LenderRateDelete(id uint32) error {
+	var err error
+	var res sql.Result
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		res, err = database.SQL.Exec("DELETE FROM lender_rate WHERE id = ?", id)
+		if err != nil {
+			break
+		}
+
+		if n, _ := res.RowsAffected(); n == 0 {
+			err = ErrNoResult
+		}
+	default:
+		err = ErrCode
+	}
+
+	return standardizeError(err)
+}
+
+// LenderRatesActiveByLenderID gets rates of lender which are not expired yet, ordered by product and term
+func LenderRatesActiveByLenderID(lenderID uint32) ([]LenderRate, error) {
+	var err error
+	var result []LenderRate
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Select(&result, `SELECT `+lenderRateColumns+`
+			FROM lender_rate JOIN lender ON lender.id = lender_rate.lender_id
+			WHERE lender_rate.lender_id = ? AND lender_rate.end_date >= CURDATE()
+			ORDER BY lender_rate.product, lender_rate.term_years, lender_rate.id`, lenderID)
+	default:
+		err = ErrCode
+	}
+
+	return result, standardizeError(err)
+}
+
+// LenderRatesReplace replaces not expired rates of lender with new rates in one transaction.
+// Rates which began before today are ended yesterday and kept as history with expired rates,
+// rates which begin today or later were never quoted for a full day and are deleted.
+func LenderRatesReplace(lenderID uint32, rates []LenderRate) error {
+	var err error
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = lenderRatesReplaceMySQL(lenderID, rates)
+	default:
+		err = ErrCode
+	}
+
+	return standardizeError(err)
+}
+
+func lenderRatesReplaceMySQL(lenderID uint32, rates []LenderRate) error {
+	tx, err := database.SQL.Beginx()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`UPDATE lender_rate SET end_date = CURDATE() - INTERVAL 1 DAY
+		WHERE lender_id = ? AND begin_date < CURDATE() AND end_date >= CURDATE()`, lenderID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM lender_rate WHERE lender_id = ? AND begin_date >= CURDATE()", lenderID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for _, rate := range rates {
+		_, err = tx.Exec(`INSERT INTO lender_rate (lender_id, product, term_years, interest, apr, begin_date, end_date)
+			VALUES (?,?,?,?,?,?,?)`,
+			lenderID, rate.Product, rate.TermYears, rate.Interest, rate.Apr, rate.BeginDate, rate.EndDate)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// LenderRatesUpsert updates interest, apr and end date of rates found by lender, product, term and begin date,
+// other rates are inserted. All rates are saved in one transaction. Return numbers of inserted and changed rates.
+func LenderRatesUpsert(rates []LenderRate) (int, int, error) {
+	var err error
+	var inserted, updated int
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		inserted, updated, err = lenderRatesUpsertMySQL(rates)
+	default:
+		err = ErrCode
+	}
+
+	return inserted, updated, standardizeError(err)
+}
+
+func lenderRatesUpsertMySQL(rates []LenderRate) (int, int, error) {
+	tx, err := database.SQL.Beginx()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var inserted, updated int
+	for _, rate := range rates {
+		var id uint32
+		err = tx.Get(&id, `SELECT id FROM lender_rate WHERE lender_id = ? AND product = ? AND term_years = ? AND begin_date = ?
+			ORDER BY id LIMIT 1 FOR UPDATE`, rate.LenderID, rate.Product, rate.TermYears, rate.BeginDate)
+		if err == sql.ErrNoRows {
+			_, err = tx.Exec(`INSERT INTO lender_rate (lender_id, product, term_years, interest, apr, begin_date, end_date)
+				VALUES (?,?,?,?,?,?,?)`,
+				rate.LenderID, rate.Product, rate.TermYears, rate.Interest, rate.Apr, rate.BeginDate, rate.EndDate)
+			if err != nil {
+				tx.Rollback()
+				return 0, 0, err
+			}
+
+			inserted++
+			continue
+		}
+		if err != nil {
+			tx.Rollback()
+			return 0, 0, err
+		}
+
+		var res sql.Result
+		res, err = tx.Exec("UPDATE lender_rate SET interest = ?, apr = ?, end_date = ? WHERE id = ?",
+			rate.Interest, rate.Apr, rate.EndDate, id)
+		if err != nil {
+			tx.Rollback()
+			return 0, 0, err
+		}
+
+		if n, _ := res.RowsAffected(); n != 0 {
+			updated++
+		}
+	}
+
+	return inserted, updated, tx.Commit()
+}
@@ -0,0 +1,335 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+
+	"app/shared/database"
+)
+
+// *****************************************************************************
+// Loan application
+// *****************************************************************************
+
+// Loan application states
+const (
+	LoanDraft     = "draft"
+	LoanSubmitted = "submitted"
+	LoanInReview  = "in_review"
+	LoanApproved  = "approved"
+	LoanDeclined  = "declined"
+	LoanFunded    = "funded"
+)
+
+// loanApplicationColumns selects loan application with lender name, needs loan_application joined with lender
+const loanApplicationColumns = `loan_application.id, loan_application.customer_id, IFNULL(loan_application.staff_id, 0) AS staff_id,
+	IFNULL(loan_application.lender_rate_id, 0) AS lender_rate_id, loan_application.lender_id, lender.name AS lender_name,
+	loan_application.product, loan_application.term_years, loan_application.interest, loan_application.apr,
+	loan_application.loan_amount, loan_application.state, loan_application.submitted_at,
+	loan_application.created_at, loan_application.updated_at`
+
+// LoanApplication table contains customer's application for loan at chosen lender rate.
+// Rate terms are copied from lender rate, interest, apr and loan amount are decimals.
+type LoanApplication struct {
+	ID           uint32     `db:"id"`
+	CustomerID   uint32     `db:"customer_id"`
+	StaffID      uint32     `db:"staff_id"`       // staff member who took application in review, 0 before review
+	LenderRateID uint32     `db:"lender_rate_id"` // 0 if rate is deleted
+	LenderID     uint32     `db:"lender_id"`
+	LenderName   string     `db:"lender_name"`
+	Product      string     `db:"product"`
+	TermYears    int        `db:"term_years"`
+	Interest     string     `db:"interest"`
+	Apr          string     `db:"apr"`
+	LoanAmount   string     `db:"loan_amount"`
+	State        string     `db:"state"`
+	SubmittedAt  *time.Time `db:"submitted_at"` // nil while application is not submitted
+	CreatedAt    time.Time  `db:"created_at"`
+	UpdatedAt    time.Time  `db:"updated_at"`
+}
+
+// LoanApplicationFilter selects loan applications, zero fields match any application
+type LoanApplicationFilter struct {
+	CustomerID      uint32
+	AssignedStaffID uint32 // customers assigned to staff member
+	State           string
+}
+
+// LoanApplicationDocument is uploaded file attached to loan application
+type LoanApplicationDocument struct {
+	ApplicationID uint32 `db:"application_id"`
+	FileID        uint32 `db:"file_id"`
+	FileName      string `db:"file_name"`
+	Category      string `db:"category"`
+	Size          int64  `db:"size"`
+	ScanStatus    string `db:"scan_status"`
+}
+
+// LoanApplicationChange is record of loan application state history
+type LoanApplicationChange struct {
+	ID            uint32    `db:"id"`
+	ApplicationID uint32    `db:"application_id"`
+	FromState     string    `db:"from_state"` // empty for created application
+	ToState       string    `db:"to_state"`
+	ActorID       uint32    `db:"actor_id"`
+	ActorRole     int       `db:"actor_role"`
+	Note          string    `db:"note"`
+	CreatedAt     time.Time `db:"created_at"`
+}
+
+// LoanApplicationCreate inserts a new draft application with its documents and first history record,
+// return application ID
+func LoanApplicationCreate(app *LoanApplication, fileIDs []uint32, actorRole int) (uint32, error) {
+	var err error
+	var id uint32
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		id, err = loanApplicationCreateMySQL(app, fileIDs, actorRole)
+	default:
+		err = ErrCode
+	}
+
+	return id, standardizeError(err)
+}
+
+func loanApplicationCreateMySQL(app *LoanApplication, fileIDs []uint32, actorRole int) (uint32, error) {
+	tx, err := database.SQL.Beginx()
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := tx.Exec(`INSERT INTO loan_application (customer_id, lender_rate_id, lender_id, product, term_years, interest, apr, loan_amount, state)
+		VALUES (?,?,?,?,?,?,?,?,?)`,
+		app.CustomerID, app.LenderRateID, app.LenderID, app.Product, app.TermYears, app.Interest, app.Apr, app.LoanAmount, LoanDraft)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	lastID, err := res.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	id := uint32(lastID)
+	for _, fileID := range fileIDs {
+		_, err = tx.Exec("INSERT INTO loan_application_document (application_id, file_id) VALUES (?,?)", id, fileID)
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+
+	_, err = tx.Exec(`INSERT INTO loan_application_history (application_id, from_state, to_state, actor_id, actor_role)
+		VALUES (?,?,?,?,?)`, id, "", LoanDraft, app.CustomerID, actorRole)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	return id, tx.Commit()
+}
+
+// LoanApplicationUpdate changes rate, loan amount and documents of customer's draft application.
+// Return ErrNoResult if there is no draft application with ID of customer.
+func LoanApplicationUpdate(app *LoanApplication, fileIDs []uint32) error {
+	var err error
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = loanApplicationUpdateMySQL(app, fileIDs)
+	default:
+		err = ErrCode
+	}
+
+	return standardizeError(err)
+}
+
+func loanApplicationUpdateMySQL(app *LoanApplication, fileIDs []uint32) error {
+	tx, err := database.SQL.Beginx()
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.Exec(`UPDATE loan_application SET lender_rate_id = ?, lender_id = ?, product = ?, term_years = ?, interest = ?, apr = ?,
+		loan_amount = ? WHERE id = ? AND customer_id = ? AND state = ?`,
+		app.LenderRateID, app.LenderID, app.Product, app.TermYears, app.Interest, app.Apr, app.LoanAmount,
+		app.ID, app.CustomerID, LoanDraft)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// Unchanged row is not affected, so draft is checked by select
+	if n, _ := res.RowsAffected(); n == 0 {
+		var count int
+		err = tx.Get(&count, "SELECT COUNT(*) FROM loan_application WHERE id = ? AND customer_id = ? AND state = ?",
+			app.ID, app.CustomerID, LoanDraft)
+		if err == nil && count == 0 {
+			err = ErrNoResult
+		}
+
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	_, err = tx.Exec("DELETE FROM loan_application_document WHERE application_id = ?", app.ID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for _, fileID := range fileIDs {
+		_, err = tx.Exec("INSERT INTO loan_application_document (application_id, file_id) VALUES (?,?)", app.ID, fileID)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// LoanApplicationByID gets loan application by its ID
+func LoanApplicationByID(id uint32) (*LoanApplication, error) {
+	var err error
+	result := &LoanApplication{}
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Get(result, `SELECT `+loanApplicationColumns+`
+			FROM loan_application JOIN lender ON lender.id = loan_application.lender_id
+			WHERE loan_application.id = ? LIMIT 1`, id)
+	default:
+		err = ErrCode
+	}
+
+	return result, standardizeError(err)
+}
+
+// LoanApplicationsSearch gets loan applications matched to filter, newest first
+func LoanApplicationsSearch(filter *LoanApplicationFilter) ([]LoanApplication, error) {
+	var err error
+	var result []LoanApplication
+
+	cond := "1 = 1"
+	args := []interface{}{}
+
+	if filter.CustomerID != 0 {
+		cond += " AND loan_application.customer_id = ?"
+		args = append(args, filter.CustomerID)
+	}
+
+	if filter.AssignedStaffID != 0 {
+		cond += ` AND loan_application.customer_id IN (SELECT customer_id FROM staff_assignment WHERE staff_id = ?)`
+		args = append(args, filter.AssignedStaffID)
+	}
+
+	if filter.State != "" {
+		cond += " AND loan_application.state = ?"
+		args = append(args, filter.State)
+	}
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Select(&result, `SELECT `+loanApplicationColumns+`
+			FROM loan_application JOIN lender ON lender.id = loan_application.lender_id
+			WHERE `+cond+` ORDER BY loan_application.id DESC`, args...)
+	default:
+		err = ErrCode
+	}
+
+	return result, standardizeError(err)
+}
+
+// LoanApplicationTransition moves application from state to next state and writes history record.
+// Reviewer becomes staff member of application if it is not zero.
+// Return ErrNoResult if application is not in from state, so concurrent changes can't skip states.
+func LoanApplicationTransition(id uint32, from, to string, reviewerID uint32, change *LoanApplicationChange) error {
+	var err error
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = loanApplicationTransitionMySQL(id, from, to, reviewerID, change)
+	default:
+		err = ErrCode
+	}
+
+	return standardizeError(err)
+}
+
+func loanApplicationTransitionMySQL(id uint32, from, to string, reviewerID uint32, change *LoanApplicationChange) error {
+	tx, err := database.SQL.Beginx()
+	if err != nil {
+		return err
+	}
+
+	var res sql.Result
+	switch {
+	case to == LoanSubmitted:
+		res, err = tx.Exec("UPDATE loan_application SET state = ?, submitted_at = CURRENT_TIMESTAMP WHERE id = ? AND state = ?", to, id, from)
+	case reviewerID != 0:
+		res, err = tx.Exec("UPDATE loan_application SET state = ?, staff_id = ? WHERE id = ? AND state = ?", to, reviewerID, id, from)
+	default:
+		res, err = tx.Exec("UPDATE loan_application SET state = ? WHERE id = ? AND state = ?", to, id, from)
+	}
+
+	if err == nil {
+		if n, _ := res.RowsAffected(); n == 0 {
+			err = ErrNoResult
+		}
+	}
+
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	_, err = tx.Exec(`INSERT INTO loan_application_history (application_id, from_state, to_state, actor_id, actor_role, note)
+		VALUES (?,?,?,?,?,?)`, id, from, to, change.ActorID, change.ActorRole, change.Note)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// LoanApplicationHistory gets state changes of application in order they were made
+func LoanApplicationHistory(id uint32) ([]LoanApplicationChange, error) {
+	var err error
+	var result []LoanApplicationChange
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Select(&result, `SELECT id, application_id, from_state, to_state, actor_id, actor_role, note, created_at
+			FROM loan_application_history WHERE application_id = ? ORDER BY id`, id)
+	default:
+		err = ErrCode
+	}
+
+	return result, standardizeError(err)
+}
+
+// LoanApplicationDocuments gets not deleted files attached to application
+func LoanApplicationDocuments(id uint32) ([]LoanApplicationDocument, error) {
+	var err error
+	var result []LoanApplicationDocument
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Select(&result, `SELECT loan_application_document.application_id, loan_application_document.file_id,
+			uploaded_files.file_name, uploaded_files.category, uploaded_files.size, uploaded_files.scan_status
+			FROM loan_application_document
+			JOIN uploaded_files ON uploaded_files.id = loan_application_document.file_id AND uploaded_files.deleted = 0
+			WHERE loan_application_document.application_id = ? ORDER BY loan_application_document.file_id`, id)
+	default:
+		err = ErrCode
+	}
+
+	return result, standardizeError(err)
+}
@@ -0,0 +1,84 @@
+package model
+
+import (
+	"app/shared/database"
+)
+
+// *****************************************************************************
+// Message attachment
+// *****************************************************************************
+
+// MessageAttachment is uploaded file referenced by message
+type MessageAttachment struct {
+	MessageID  uint32 `db:"message_id"`
+	FileID     uint32 `db:"file_id"`
+	OwnerID    uint32 `db:"user_id"`
+	FileName   string `db:"file_name"`
+	Category   string `db:"category"`
+	Size       int64  `db:"size"`
+	ScanStatus string `db:"scan_status"`
+}
+
+const messageAttachmentColumns = `message_attachment.message_id, message_attachment.file_id, uploaded_files.user_id,
+	uploaded_files.file_name, uploaded_files.category, uploaded_files.size, uploaded_files.scan_status`
+
+// MessageAttachmentsByMessageID return not deleted files attached to message
+func MessageAttachmentsByMessageID(messageID uint32) ([]*MessageAttachment, error) {
+	var err error
+
+	var result []*MessageAttachment
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Select(&result, "SELECT "+messageAttachmentColumns+` FROM message_attachment
+			JOIN uploaded_files ON uploaded_files.id = message_attachment.file_id AND uploaded_files.deleted = 0
+			WHERE message_attachment.message_id = ? ORDER BY message_attachment.file_id`, messageID)
+	default:
+		err = ErrCode
+	}
+
+	return result, standardizeError(err)
+}
+
+// MessageAttachmentsByThreadID return not deleted files attached to any message of thread
+func MessageAttachmentsByThreadID(threadID uint32) ([]*MessageAttachment, error) {
+	var err error
+
+	var result []*MessageAttachment
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Select(&result, "SELECT "+messageAttachmentColumns+` FROM message_attachment
+			JOIN customer_message ON customer_message.id = message_attachment.message_id
+			JOIN uploaded_files ON uploaded_files.id = message_attachment.file_id AND uploaded_files.deleted = 0
+			WHERE customer_message.thread_id = ? ORDER BY message_attachment.file_id`, threadID)
+	default:
+		err = ErrCode
+	}
+
+	return result, standardizeError(err)
+}
+
+// MessageAttachmentFile return not deleted file attached to message and thread ID of message
+func MessageAttachmentFile(messageID, fileID uint32) (*UserFile, uint32, error) {
+	var err error
+	var threadID uint32
+
+	result := UserFile{}
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Get(&threadID, `SELECT customer_message.thread_id FROM message_attachment
+			JOIN customer_message ON customer_message.id = message_attachment.message_id
+			WHERE message_attachment.message_id = ? AND message_attachment.file_id = ? LIMIT 1`, messageID, fileID)
+		if err != nil {
+			return nil, 0, standardizeError(err)
+		}
+
+		err = database.SQL.Get(&result, "SELECT "+userFileColumns+" FROM uploaded_files WHERE id = ? AND deleted = 0 LIMIT 1", fileID)
+	default:
+		err = ErrCode
+	}
+
+	return &result, threadID, standardizeError(err)
+}
@@ -0,0 +1,210 @@
+package model
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"app/shared/database"
+)
+
+// *****************************************************************************
+// Message thread participant
+// *****************************************************************************
+
+// ThreadParticipant is a user who can read and post to the thread
+type ThreadParticipant struct {
+	ThreadID  uint32    `db:"thread_id"`
+	UserID    uint32    `db:"user_id"`
+	FirstName string    `db:"first_name"`
+	LastName  string    `db:"last_name"`
+	UserRole  uint8     `db:"user_role"`
+	AddedBy   uint32    `db:"added_by"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// ThreadUnread is number of thread messages unread by participant
+type ThreadUnread struct {
+	ThreadID uint32 `db:"thread_id"`
+	Unread   int    `db:"unread"`
+}
+
+// unreadMessageCondition selects unread_message not read by participant: it is after participant's
+// read cursor, is not marked readed and is not sent by participant
+const unreadMessageCondition = `unread_message.thread_id = participant.thread_id
+	AND unread_message.id > participant.last_read_message_id
+	AND unread_message.from_user_id <> participant.user_id
+	AND unread_message.deleted = 0
+	AND NOT EXISTS (SELECT 1 FROM message_read WHERE message_read.message_id = unread_message.id AND message_read.user_id = participant.user_id)`
+
+// ParticipantIDs return IDs of thread participants
+func (t *MessageThread) ParticipantIDs() []uint32 {
+	return splitIDs(t.Participants)
+}
+
+// ThreadParticipants return participants of thread
+func ThreadParticipants(threadID uint32) ([]*ThreadParticipant, error) {
+	var err error
+
+	var result []*ThreadParticipant
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Select(&result, `SELECT participant.thread_id, participant.user_id,
+			user.first_name, user.last_name, user.user_role, participant.added_by, participant.created_at
+			FROM message_thread_participant participant
+			JOIN user ON user.id = participant.user_id
+			WHERE participant.thread_id = ?
+			ORDER BY participant.created_at, participant.user_id`, threadID)
+	default:
+		err = ErrCode
+	}
+
+	return result, standardizeError(err)
+}
+
+// ThreadParticipantIDs return IDs of thread participants
+func ThreadParticipantIDs(threadID uint32) ([]uint32, error) {
+	var err error
+
+	var result []uint32
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Select(&result, "SELECT user_id FROM message_thread_participant WHERE thread_id = ?", threadID)
+	default:
+		err = ErrCode
+	}
+
+	return result, standardizeError(err)
+}
+
+// IsThreadParticipant check is user takes part in thread
+func IsThreadParticipant(threadID, userID uint32) (bool, error) {
+	var err error
+	var exists bool
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Get(&exists, "SELECT EXISTS (SELECT 1 FROM message_thread_participant WHERE thread_id = ? AND user_id = ?)", threadID, userID)
+	default:
+		err = ErrCode
+	}
+
+	return exists, standardizeError(err)
+}
+
+// ThreadParticipantAdd adds user to thread, adding existing participant does nothing
+func ThreadParticipantAdd(threadID, userID, addedBy uint32) error {
+	var err error
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		_, err = database.SQL.Exec("INSERT IGNORE INTO message_thread_participant (thread_id, user_id, added_by) VALUES (?,?,?)", threadID, userID, addedBy)
+	default:
+		err = ErrCode
+	}
+
+	return standardizeError(err)
+}
+
+// ThreadParticipantRemove removes user from thread
+func ThreadParticipantRemove(threadID, userID uint32) error {
+	var err error
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		res, err := database.SQL.Exec("DELETE FROM message_thread_participant WHERE thread_id = ? AND user_id = ?", threadID, userID)
+		if err != nil {
+			return standardizeError(err)
+		}
+
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return standardizeError(err)
+		}
+
+		if affected == 0 {
+			return ErrNoResult
+		}
+
+	default:
+		err = ErrCode
+	}
+
+	return standardizeError(err)
+}
+
+// UnreadCounts return numbers of unread messages in user's threads, threads without unread messages are skipped
+func UnreadCounts(userID uint32) ([]*ThreadUnread, error) {
+	var err error
+
+	var result []*ThreadUnread
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Select(&result, `SELECT participant.thread_id, COUNT(*) AS unread
+			FROM message_thread_participant participant
+			JOIN customer_message unread_message ON `+unreadMessageCondition+`
+			WHERE participant.user_id = ?
+			GROUP BY participant.thread_id
+			ORDER BY participant.thread_id`, userID)
+	default:
+		err = ErrCode
+	}
+
+	return result, standardizeError(err)
+}
+
+// ThreadReadCursorUpdate marks thread messages up to messageID readed by participant and return new read cursor.
+// Zero messageID means the last message of thread. Cursor never moves back.
+func ThreadReadCursorUpdate(threadID, userID, messageID uint32) (uint32, error) {
+	var err error
+	var cursor uint32
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		if messageID == 0 {
+			err = database.SQL.Get(&messageID, "SELECT IFNULL(MAX(id), 0) FROM customer_message WHERE thread_id = ?", threadID)
+		} else {
+			var exists bool
+			err = database.SQL.Get(&exists, "SELECT EXISTS (SELECT 1 FROM customer_message WHERE id = ? AND thread_id = ?)", messageID, threadID)
+			if err == nil && !exists {
+				return 0, ErrMessageNotExist
+			}
+		}
+
+		if err != nil {
+			return 0, standardizeError(err)
+		}
+
+		_, err = database.SQL.Exec(`UPDATE message_thread_participant SET last_read_message_id = GREATEST(last_read_message_id, ?)
+			WHERE thread_id = ? AND user_id = ?`, messageID, threadID, userID)
+		if err != nil {
+			return 0, standardizeError(err)
+		}
+
+		err = database.SQL.Get(&cursor, "SELECT last_read_message_id FROM message_thread_participant WHERE thread_id = ? AND user_id = ?", threadID, userID)
+	default:
+		err = ErrCode
+	}
+
+	return cursor, standardizeError(err)
+}
+
+// splitIDs parse comma separated IDs from GROUP_CONCAT
+func splitIDs(s string) []uint32 {
+	ids := []uint32{}
+	if s == "" {
+		return ids
+	}
+
+	for _, v := range strings.Split(s, ",") {
+		id, err := strconv.ParseUint(v, 10, 32)
+		if err == nil {
+			ids = append(ids, uint32(id))
+		}
+	}
+
+	return ids
+}
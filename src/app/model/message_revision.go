@@ -0,0 +1,130 @@
+package model
+
+import (
+	"time"
+
+	"app/shared/database"
+)
+
+// *****************************************************************************
+// Message revision
+// *****************************************************************************
+
+// MessageRevision is previous content of edited message, EditedBy is user who replaced it
+type MessageRevision struct {
+	ID              uint32    `db:"id"`
+	MessageID       uint32    `db:"message_id"`
+	Content         string    `db:"content"`
+	EditedBy        uint32    `db:"edited_by"`
+	EditorFirstName string    `db:"first_name"`
+	EditorLastName  string    `db:"last_name"`
+	CreatedAt       time.Time `db:"created_at"`
+}
+
+// MessageModerationByID gets message by ID including deleted one, without user's read state
+func MessageModerationByID(messageID uint32) (*Message, error) {
+	var err error
+
+	result := &Message{}
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Get(result, `
+			SELECT customer_message.id, customer_message.thread_id,
+			to_user_id, to_user_info.first_name AS to_first_name, to_user_info.last_name AS to_last_name,
+			from_user_id, from_user_info.first_name AS from_first_name, from_user_info.last_name AS from_last_name,
+			content, customer_message.created_at, customer_message.updated_at,
+			customer_message.edited, customer_message.deleted, customer_message.deleted_by, customer_message.deleted_at
+			FROM customer_message
+			LEFT JOIN user from_user_info ON from_user_info.id = from_user_id
+			LEFT JOIN user to_user_info ON to_user_info.id = to_user_id
+			WHERE customer_message.id = ? LIMIT 1`, messageID)
+	default:
+		err = ErrCode
+	}
+
+	return result, standardizeError(err)
+}
+
+// MessageRevisions return previous contents of message, oldest first
+func MessageRevisions(messageID uint32) ([]*MessageRevision, error) {
+	var err error
+
+	result := []*MessageRevision{}
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Select(&result, `SELECT message_revision.id, message_id, content, edited_by,
+			IFNULL(user.first_name, '') AS first_name, IFNULL(user.last_name, '') AS last_name, message_revision.created_at
+			FROM message_revision
+			LEFT JOIN user ON user.id = edited_by
+			WHERE message_id = ? ORDER BY message_revision.id`, messageID)
+	default:
+		err = ErrCode
+	}
+
+	return result, standardizeError(err)
+}
+
+// MessageRestore undeletes message. Not zero revisionID also brings back content of message revision,
+// content it replaces is kept in revision history.
+func MessageRestore(restoredBy, messageID, revisionID uint32) error {
+	var err error
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = messageRestoreMySQL(restoredBy, messageID, revisionID)
+
+	default:
+		err = ErrCode
+	}
+
+	return standardizeError(err)
+}
+
+func messageRestoreMySQL(restoredBy, messageID, revisionID uint32) error {
+	tx, err := database.SQL.Beginx()
+	if err != nil {
+		return err
+	}
+
+	var exists bool
+	err = tx.Get(&exists, "SELECT EXISTS (SELECT 1 FROM customer_message WHERE id = ?)", messageID)
+	if err != nil || !exists {
+		tx.Rollback()
+		if err == nil {
+			err = ErrNoResult
+		}
+		return err
+	}
+
+	if revisionID != 0 {
+		var content string
+		err = tx.Get(&content, "SELECT content FROM message_revision WHERE id = ? AND message_id = ?", revisionID, messageID)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		_, err = tx.Exec(`INSERT INTO message_revision (message_id, content, edited_by)
+			SELECT id, content, ? FROM customer_message WHERE id = ?`, restoredBy, messageID)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		_, err = tx.Exec("UPDATE customer_message SET content = ?, edited = 1 WHERE id = ?", content, messageID)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	_, err = tx.Exec("UPDATE customer_message SET deleted = 0, deleted_by = 0, deleted_at = NULL WHERE id = ?", messageID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,48 @@
+package model
+
+import (
+	"time"
+
+	"app/shared/database"
+)
+
+// *****************************************************************************
+// Message search
+// *****************************************************************************
+
+// MessageSearchResult is message found by content or title of its thread
+type MessageSearchResult struct {
+	MessageID  uint32    `db:"message_id"`
+	ThreadID   uint32    `db:"thread_id"`
+	Title      string    `db:"title"`
+	Content    string    `db:"content"`
+	FromUserID uint32    `db:"from_user_id"`
+	CreatedAt  time.Time `db:"created_at"`
+	Score      float64   `db:"score"`
+}
+
+// MessagesSearch return most relevant messages of threads where user is a participant.
+// Query is MySQL boolean mode full-text query matched to message content and thread title together,
+// so query terms can be split between them.
+func MessagesSearch(userID uint32, query string, limit int) ([]*MessageSearchResult, error) {
+	var err error
+
+	var result []*MessageSearchResult
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Select(&result, `SELECT customer_message.id AS message_id, customer_message.thread_id,
+			message_thread.title, customer_message.content, customer_message.from_user_id, customer_message.created_at,
+			MATCH (customer_message.thread_title, customer_message.content) AGAINST (? IN BOOLEAN MODE) AS score
+			FROM customer_message
+			JOIN message_thread ON message_thread.id = customer_message.thread_id
+			JOIN message_thread_participant participant ON participant.thread_id = customer_message.thread_id AND participant.user_id = ?
+			WHERE customer_message.deleted = 0 AND message_thread.deleted = 0
+			AND MATCH (customer_message.thread_title, customer_message.content) AGAINST (? IN BOOLEAN MODE)
+			ORDER BY score DESC, customer_message.id DESC LIMIT ?`, query, userID, query, limit)
+	default:
+		err = ErrCode
+	}
+
+	return result, standardizeError(err)
+}
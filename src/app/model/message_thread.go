@@ -3,6 +3,7 @@ package model
 import (
 	"app/shared/database"
 	"log"
+	"strings"
 	"time"
 )
 
@@ -20,14 +21,41 @@ type MessageThread struct {
 	CreatedAt  time.Time `db:"created_at"`
 	UpdatedAt  time.Time `db:"updated_at"`
 	Deleted    uint8     `db:"deleted"`
+
+	Status          string     `db:"status"`
+	AssignedStaffID uint32     `db:"assigned_staff_id"` // 0 while thread is in staff queue
+	FirstResponseAt *time.Time `db:"first_response_at"` // first message not from thread opener
+	ResolvedAt      *time.Time `db:"resolved_at"`       // thread was closed
+
+	// Comma separated IDs of participants, filled in threads list only
+	Participants string `db:"participants"`
+	// Number of messages unread by user, filled in threads list only
+	Unread int `db:"unread"`
+	// Last message is deleted, filled in threads list only
+	LastDeleted bool `db:"last_deleted"`
 }
 
-// ThreadsByUserID return threads info for
-func ThreadsByUserID(toUserID string, limit, offset int) ([]*MessageThread, error) {
+// ThreadsByUserID return page of threads where user is a participant with their last message, newest threads first.
+// Nil filter selects all threads. Also return are there more threads in page direction.
+func ThreadsByUserID(userID string, filter *ThreadFilter, page *Page) ([]*MessageThread, bool, error) {
 	var err error
+	var more bool
 
 	var result []*MessageThread
 
+	where, args := filter.where()
+
+	cond, pageArgs, orderBy := page.keyset("", "message_thread.id", true)
+	if cond != "" {
+		where = append(where, cond)
+		args = append(args, pageArgs...)
+	}
+
+	cond = ""
+	if len(where) != 0 {
+		cond = "WHERE " + strings.Join(where, " AND ")
+	}
+
 	switch database.ReadConfig().Type {
 	case database.TypeMySQL:
 		err = database.SQL.Select(&result, `
@@ -36,9 +64,14 @@ func ThreadsByUserID(toUserID string, limit, offset int) ([]*MessageThread, erro
 	message_thread.from_user_id, 
 	message_thread.title,
 	last_message.content,
+	last_message.deleted AS last_deleted,
 	message_thread.created_at, 
 	message_thread.updated_at, 
-	message_thread.deleted
+	message_thread.deleted,
+	`+threadLifecycleColumns+`,
+	IFNULL((SELECT GROUP_CONCAT(p.user_id ORDER BY p.user_id) FROM message_thread_participant p WHERE p.thread_id = message_thread.id), '') AS participants,
+	(SELECT COUNT(*) FROM customer_message unread_message
+		WHERE `+unreadMessageCondition+`) AS unread
 
 	FROM message_thread
 	JOIN (select * from customer_message where `+"`created_at`"+` in (
@@ -46,37 +79,91 @@ func ThreadsByUserID(toUserID string, limit, offset int) ([]*MessageThread, erro
 	from customer_message
 	group by thread_id)) last_message ON last_message.thread_id=message_thread.id
 
-	WHERE message_thread.to_user_id = ? OR message_thread.from_user_id = ?
+	JOIN message_thread_participant participant ON participant.thread_id=message_thread.id AND participant.user_id = ?
+	`+cond+`
 	GROUP BY id
-	ORDER BY created_at DESC LIMIT ? OFFSET ?`, toUserID, toUserID, limit, offset)
+	ORDER BY `+orderBy+` LIMIT ?`, append(append([]interface{}{userID}, args...), page.limit())...)
+		if err != nil {
+			break
+		}
+
+		var n int
+		n, more = page.trim(len(result), func(i, j int) { result[i], result[j] = result[j], result[i] })
+		result = result[:n]
 	default:
 		err = ErrCode
 	}
 
-	return result, standardizeError(err)
+	return result, more, standardizeError(err)
 }
 
-// ThreadCreate create new messages thread
-func ThreadCreate(title string, toUserID, fromUserID uint32) (uint32, error) {
+// ThreadCreate create new messages thread, sender, recipient and additional participants become its participants
+// in one transaction
+func ThreadCreate(title string, toUserID, fromUserID uint32, participantIDs ...uint32) (uint32, error) {
 	var err error
-	var lastID int64
+	var lastID uint32
 
 	switch database.ReadConfig().Type {
 	case database.TypeMySQL:
-		result, err := database.SQL.Exec("INSERT INTO message_thread (to_user_id, from_user_id, title) VALUES (?,?,?)", toUserID, fromUserID, title)
-		if err != nil {
-			return 0, standardizeError(err)
-		}
+		lastID, err = threadCreateMySQL(title, toUserID, fromUserID, participantIDs)
+	default:
+		err = ErrCode
+	}
+
+	return lastID, standardizeError(err)
+}
 
-		lastID, err = result.LastInsertId()
+func threadCreateMySQL(title string, toUserID, fromUserID uint32, participantIDs []uint32) (uint32, error) {
+	tx, err := database.SQL.Beginx()
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec("INSERT INTO message_thread (to_user_id, from_user_id, title) VALUES (?,?,?)", toUserID, fromUserID, title)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	lastID, err := result.LastInsertId()
+	if err != nil {
+		log.Println("error while get last inserted id: ", err)
+		tx.Rollback()
+		return 0, err
+	}
+
+	_, err = tx.Exec("INSERT IGNORE INTO message_thread_participant (thread_id, user_id, added_by) VALUES (?,?,?), (?,?,?)",
+		lastID, fromUserID, fromUserID, lastID, toUserID, fromUserID)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	for _, participantID := range participantIDs {
+		_, err = tx.Exec("INSERT IGNORE INTO message_thread_participant (thread_id, user_id, added_by) VALUES (?,?,?)",
+			lastID, participantID, fromUserID)
 		if err != nil {
-			log.Println("error while get last inserted id: ", err)
-			return 0, standardizeError(err)
+			tx.Rollback()
+			return 0, err
 		}
+	}
 
+	return uint32(lastID), tx.Commit()
+}
+
+// ThreadByID return thread without last message
+func ThreadByID(threadID uint32) (*MessageThread, error) {
+	var err error
+
+	result := &MessageThread{}
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Get(result, `SELECT id, to_user_id, from_user_id, title, created_at, updated_at, deleted, `+threadLifecycleColumns+`
+			FROM message_thread WHERE id = ? LIMIT 1`, threadID)
 	default:
 		err = ErrCode
 	}
 
-	return uint32(lastID), standardizeError(err)
+	return result, standardizeError(err)
 }
@@ -0,0 +1,196 @@
+package model
+
+import (
+	"app/shared/database"
+)
+
+// *****************************************************************************
+// Customer message thread lifecycle
+// *****************************************************************************
+
+// Thread statuses
+const (
+	ThreadOpen            = "open"
+	ThreadWaitingCustomer = "waiting_customer"
+	ThreadClosed          = "closed"
+	ThreadArchived        = "archived"
+)
+
+// threadLifecycleColumns selects thread status, assignment and SLA timestamps
+const threadLifecycleColumns = `message_thread.status, message_thread.assigned_staff_id,
+	message_thread.first_response_at, message_thread.resolved_at`
+
+// ThreadFilter contains conditions of threads list, empty fields match any thread
+type ThreadFilter struct {
+	Status          string
+	AssignedStaffID uint32
+	Unassigned      bool // only threads in staff queue, AssignedStaffID is ignored
+}
+
+// where return conditions and their arguments for message_thread rows
+func (f *ThreadFilter) where() ([]string, []interface{}) {
+	where := []string{}
+	args := []interface{}{}
+
+	if f == nil {
+		return where, args
+	}
+
+	if f.Status != "" {
+		where = append(where, "message_thread.status = ?")
+		args = append(args, f.Status)
+	}
+
+	if f.Unassigned {
+		where = append(where, "message_thread.assigned_staff_id = 0")
+	} else if f.AssignedStaffID != 0 {
+		where = append(where, "message_thread.assigned_staff_id = ?")
+		args = append(args, f.AssignedStaffID)
+	}
+
+	return where, args
+}
+
+// ThreadQueue return page of unassigned open and waiting threads, oldest threads first.
+// Nonzero staffID limits queue to threads of customers assigned to staff member.
+// Also return are there more threads in page direction.
+func ThreadQueue(staffID uint32, page *Page) ([]*MessageThread, bool, error) {
+	var err error
+	var more bool
+
+	var result []*MessageThread
+
+	cond, args, orderBy := page.keyset("", "message_thread.id", false)
+	if cond != "" {
+		cond = "AND " + cond
+	}
+
+	queryArgs := []interface{}{ThreadOpen, ThreadWaitingCustomer}
+	if staffID != 0 {
+		cond = `AND EXISTS (SELECT 1 FROM staff_assignment
+			WHERE staff_assignment.staff_id = ? AND staff_assignment.customer_id IN (message_thread.from_user_id, message_thread.to_user_id)) ` + cond
+		queryArgs = append(queryArgs, staffID)
+	}
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Select(&result, `SELECT id, to_user_id, from_user_id, title, created_at, updated_at, deleted, `+threadLifecycleColumns+`,
+			IFNULL((SELECT GROUP_CONCAT(p.user_id ORDER BY p.user_id) FROM message_thread_participant p WHERE p.thread_id = message_thread.id), '') AS participants
+			FROM message_thread
+			WHERE assigned_staff_id = 0 AND status IN (?, ?) AND deleted = 0 `+cond+`
+			ORDER BY `+orderBy+` LIMIT ?`,
+			append(append(queryArgs, args...), page.limit())...)
+		if err != nil {
+			break
+		}
+
+		var n int
+		n, more = page.trim(len(result), func(i, j int) { result[i], result[j] = result[j], result[i] })
+		result = result[:n]
+	default:
+		err = ErrCode
+	}
+
+	return result, more, standardizeError(err)
+}
+
+// ThreadStatusUpdate sets thread status. Closing thread sets its resolution time, reopening clears it.
+func ThreadStatusUpdate(threadID uint32, status string) error {
+	var err error
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		_, err = database.SQL.Exec(`UPDATE message_thread SET
+			resolved_at = CASE WHEN ? = ? THEN IFNULL(resolved_at, NOW()) WHEN ? = ? THEN resolved_at ELSE NULL END,
+			status = ?
+			WHERE id = ?`, status, ThreadClosed, status, ThreadArchived, status, threadID)
+	default:
+		err = ErrCode
+	}
+
+	return standardizeError(err)
+}
+
+// ThreadAssign assigns thread to staff member who becomes its participant, zero staffID returns thread to queue.
+// Without override only unassigned thread or thread assigned to assignedBy is changed, otherwise return false.
+func ThreadAssign(threadID, staffID, assignedBy uint32, override bool) (bool, error) {
+	var err error
+	var assigned bool
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		assigned, err = threadAssignMySQL(threadID, staffID, assignedBy, override)
+	default:
+		err = ErrCode
+	}
+
+	return assigned, standardizeError(err)
+}
+
+func threadAssignMySQL(threadID, staffID, assignedBy uint32, override bool) (bool, error) {
+	tx, err := database.SQL.Beginx()
+	if err != nil {
+		return false, err
+	}
+
+	query := "UPDATE message_thread SET assigned_staff_id = ? WHERE id = ?"
+	args := []interface{}{staffID, threadID}
+	if !override {
+		query += " AND assigned_staff_id IN (0, ?)"
+		args = append(args, assignedBy)
+	}
+
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	// MySQL doesn't count rows with unchanged values, so thread already assigned to staffID is not a conflict
+	if n, _ := result.RowsAffected(); n == 0 {
+		var current uint32
+		err = tx.Get(&current, "SELECT assigned_staff_id FROM message_thread WHERE id = ?", threadID)
+		if err != nil {
+			tx.Rollback()
+			return false, err
+		}
+
+		if current != staffID {
+			tx.Rollback()
+			return false, nil
+		}
+	}
+
+	if staffID != 0 {
+		_, err = tx.Exec("INSERT IGNORE INTO message_thread_participant (thread_id, user_id, added_by) VALUES (?,?,?)", threadID, staffID, assignedBy)
+		if err != nil {
+			tx.Rollback()
+			return false, err
+		}
+	}
+
+	return true, tx.Commit()
+}
+
+// ThreadReplyUpdate tracks reply of sender to thread: first message not from thread opener is first response,
+// opener's reply reopens thread waiting on customer or closed thread.
+func ThreadReplyUpdate(threadID, senderID uint32) error {
+	var err error
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		_, err = database.SQL.Exec(`UPDATE message_thread SET
+			first_response_at = IF(first_response_at IS NULL AND from_user_id <> ?, NOW(), first_response_at),
+			resolved_at = IF(from_user_id = ? AND status IN (?, ?), NULL, resolved_at),
+			status = IF(from_user_id = ? AND status IN (?, ?), ?, status)
+			WHERE id = ?`,
+			senderID,
+			senderID, ThreadWaitingCustomer, ThreadClosed,
+			senderID, ThreadWaitingCustomer, ThreadClosed, ThreadOpen,
+			threadID)
+	default:
+		err = ErrCode
+	}
+
+	return standardizeError(err)
+}
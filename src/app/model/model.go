@@ -17,6 +17,10 @@ var (
 	ErrUnauthorized = errors.New("User does not have permission to perform this operation")
 	// ErrConstraintFails return is app fails DB constraint
 	ErrConstraintFails = errors.New("Constraint Fails")
+	// ErrDuplicateEntry return if app violates DB unique index
+	ErrDuplicateEntry = errors.New("Duplicate entry")
+	// ErrRowReferenced return if app deletes row referenced by other rows
+	ErrRowReferenced = errors.New("Row is referenced")
 	// ErrUserNotExist return if user not exist
 	ErrUserNotExist = errors.New("User not exist")
 	// ErrThreadNotExist return if thread not exist
@@ -39,5 +43,13 @@ func standardizeError(err error) error {
 		return ErrConstraintFails
 	}
 
+	if strings.HasPrefix(err.Error(), "Error 1062:") {
+		return ErrDuplicateEntry
+	}
+
+	if strings.HasPrefix(err.Error(), "Error 1451:") {
+		return ErrRowReferenced
+	}
+
 	return err
 }
@@ -6,9 +6,9 @@ import (
 	"fmt"
 
 	"app/shared/config"
+	"app/shared/cursor"
 	"app/shared/database"
 	"app/shared/jsonconfig"
-	"app/webpojo"
 	"testing"
 )
 
@@ -245,13 +245,18 @@ func TestModel(t *testing.T) {
 		t.Run("TestMessageCreate", func(t *testing.T) {
 			userID := getUserByEmail(t, testUserEmailChanged).ID
 
-			err := MessageCreate(userID, userID, threadID, customerMessageContent)
+			messageID, err := MessageCreate(userID, userID, threadID, customerMessageContent)
 			if err != nil {
 				t.Error("error while TestMessageCreate: error while message create: " + err.Error())
 				return
 			}
 
-			messages, err := MessagesByUserID(threadID, userID, 10, 0)
+			if messageID == 0 {
+				t.Error("error while TestMessageCreate: message ID is 0")
+				return
+			}
+
+			messages, _, err := MessagesByUserID(threadID, userID, &Page{Limit: 10})
 			if err != nil {
 				if err != nil {
 					t.Error("error while TestMessageCreate: error while get user's messages by ID: " + err.Error())
@@ -267,7 +272,28 @@ func TestModel(t *testing.T) {
 			AccertEqual(t, "TestMessageCreate - content", messages[0].Content, customerMessageContent)
 		})
 
-		threads, err := ThreadsByUserID(fmt.Sprint(userID), 10, 0)
+		t.Run("TestThreadAssign", func(t *testing.T) {
+			if assigned, err := ThreadAssign(threadID, userID, userID, false); err != nil || !assigned {
+				t.Error("error while TestThreadAssign: unassigned thread should be assigned: ", err)
+				return
+			}
+
+			if assigned, err := ThreadAssign(threadID, userID, userID, false); err != nil || !assigned {
+				t.Error("error while TestThreadAssign: assign to same staff should not conflict: ", err)
+				return
+			}
+
+			if assigned, err := ThreadAssign(threadID, userID+1, userID+1, false); err != nil || assigned {
+				t.Error("error while TestThreadAssign: thread assigned to other staff should not be taken: ", err)
+				return
+			}
+
+			if assigned, err := ThreadAssign(threadID, 0, userID+1, true); err != nil || !assigned {
+				t.Error("error while TestThreadAssign: override should reassign thread: ", err)
+			}
+		})
+
+		threads, _, err := ThreadsByUserID(fmt.Sprint(userID), nil, &Page{Limit: 10})
 		if err != nil {
 			t.Error("error while create new thread: can't get thread list: ", err)
 			return
@@ -286,24 +312,26 @@ func TestModel(t *testing.T) {
 	t.Run("TestMessageUpdate", func(t *testing.T) {
 		userID := getUserByEmail(t, testUserEmailChanged).ID
 
-		threads, err := ThreadsByUserID(fmt.Sprint(userID), 10, 0)
+		threads, _, err := ThreadsByUserID(fmt.Sprint(userID), nil, &Page{Limit: 10})
 		if err != nil {
 			t.Error("error while TestMessageUpdate: error while get threads: " + err.Error())
 		}
 
-		messages, err := MessagesByUserID(threads[0].ID, userID, 10, 0)
+		messages, _, err := MessagesByUserID(threads[0].ID, userID, &Page{Limit: 10})
 		if err != nil {
 			t.Error("error while TestMessageUpdate: error while get user's messages by ID: " + err.Error())
 			return
 		}
 
-		err = MessageUpdate(customerMessageContentChanged, userID, messages[0].ID, threads[0].ID)
+		originalContent := messages[0].Content
+
+		err = MessageUpdate(customerMessageContentChanged, userID, userID, messages[0].ID, threads[0].ID)
 		if err != nil {
 			t.Error("error while TestMessageUpdate: error while message create: " + err.Error())
 			return
 		}
 
-		messages, err = MessagesByUserID(threads[0].ID, userID, 10, 0)
+		messages, _, err = MessagesByUserID(threads[0].ID, userID, &Page{Limit: 10})
 		if err != nil {
 			if err != nil {
 				t.Error("error while TestMessageUpdate: error while get user's messages by ID: " + err.Error())
@@ -312,18 +340,38 @@ func TestModel(t *testing.T) {
 		}
 
 		AccertEqual(t, "TestMessageUpdate - content", messages[0].Content, customerMessageContentChanged)
+
+		if !messages[0].Edited {
+			t.Error("error while TestMessageUpdate: message should be marked edited")
+		}
+
+		revisions, err := MessageRevisions(messages[0].ID)
+		if err != nil {
+			t.Error("error while TestMessageUpdate: error while get message revisions: " + err.Error())
+			return
+		}
+
+		if len(revisions) != 1 {
+			t.Error("error while TestMessageUpdate: previous content should be kept in revision")
+			return
+		}
+
+		AccertEqual(t, "TestMessageUpdate - revision content", revisions[0].Content, originalContent)
+		if revisions[0].EditedBy != userID {
+			t.Error("error while TestMessageUpdate: revision should keep editor")
+		}
 	})
 
 	t.Run("TestMarkMessageReadedUpdate", func(t *testing.T) {
 		userID := getUserByEmail(t, testUserEmailChanged).ID
 
-		threads, err := ThreadsByUserID(fmt.Sprint(userID), 10, 0)
+		threads, _, err := ThreadsByUserID(fmt.Sprint(userID), nil, &Page{Limit: 10})
 		if err != nil {
 			t.Error("error while set message readed: error while get threads: ", err)
 			return
 		}
 
-		messages, err := MessagesByUserID(threads[0].ID, userID, 10, 0)
+		messages, _, err := MessagesByUserID(threads[0].ID, userID, &Page{Limit: 10})
 		if err != nil {
 			if err != nil {
 				t.Error("error while TestMarkMessageReadedUpdate: error while get user's messages by ID: " + err.Error())
@@ -337,7 +385,7 @@ func TestModel(t *testing.T) {
 			return
 		}
 
-		messages, err = MessagesByUserID(threads[0].ID, userID, 10, 0)
+		messages, _, err = MessagesByUserID(threads[0].ID, userID, &Page{Limit: 10})
 		if err != nil {
 			if err != nil {
 				t.Error("error while TestMarkMessageReadedUpdate: error while get user's messages by ID: " + err.Error())
@@ -356,12 +404,12 @@ func TestModel(t *testing.T) {
 			t.Error("error while TestMessageDelete: userID is empty")
 		}
 
-		threads, err := ThreadsByUserID(fmt.Sprint(userID), 10, 0)
+		threads, _, err := ThreadsByUserID(fmt.Sprint(userID), nil, &Page{Limit: 10})
 		if err != nil {
 			t.Error("error while TestMessageDelete: error while get threads: " + err.Error())
 		}
 
-		messages, err := MessagesByUserID(threads[0].ID, userID, 10, 0)
+		messages, _, err := MessagesByUserID(threads[0].ID, userID, &Page{Limit: 10})
 		if err != nil {
 			if err != nil {
 				t.Error("error while TestMessageDelete: error while get user's messages by ID: " + err.Error())
@@ -374,13 +422,21 @@ func TestModel(t *testing.T) {
 			return
 		}
 
-		err = MessageDelete(userID, messages[0].ID, threads[0].ID)
+		err = MessageDelete(userID, userID, messages[0].ID, threads[0].ID)
 		if err != nil {
 			t.Error("error while test TestMessageDelete: error while message delete: " + err.Error())
 			return
 		}
 
-		messages, err = MessagesByUserID(threads[0].ID, userID, 10, 0)
+		if err = MessageDelete(userID, userID, messages[0].ID, threads[0].ID); err != ErrNoResult {
+			t.Error("error while test TestMessageDelete: deleted message should not be deleted again")
+		}
+
+		if err = MessageUpdate(customerMessageContentChanged, userID, userID, messages[0].ID, threads[0].ID); err != ErrNoResult {
+			t.Error("error while test TestMessageDelete: deleted message should not be edited")
+		}
+
+		messages, _, err = MessagesByUserID(threads[0].ID, userID, &Page{Limit: 10})
 		if err != nil {
 			if err != nil {
 				t.Error("error while test TestMessageDelete: error while get user's messages by ID: " + err.Error())
@@ -388,23 +444,96 @@ func TestModel(t *testing.T) {
 			}
 		}
 
-		if len(messages) != 0 {
-			t.Error("error while test TestMessageDelete: error while get user's messages by ID: " + err.Error())
+		if len(messages) != 1 || messages[0].Deleted != 1 || messages[0].DeletedBy != userID {
+			t.Error("error while test TestMessageDelete: deleted message should stay in list marked deleted")
 			return
 		}
+
+		err = MessageRestore(userID, messages[0].ID, 0)
+		if err != nil {
+			t.Error("error while test TestMessageDelete: error while message restore: " + err.Error())
+			return
+		}
+
+		message, err := MessageModerationByID(messages[0].ID)
+		if err != nil {
+			t.Error("error while test TestMessageDelete: error while get message: " + err.Error())
+			return
+		}
+
+		if message.Deleted != 0 || message.DeletedAt != nil {
+			t.Error("error while test TestMessageDelete: message should be restored")
+		}
+	})
+
+	t.Run("TestLenderRatesListBest", func(t *testing.T) {
+		rates, err := LenderRatesListBest()
+		if err != nil {
+			t.Error("error while test TestLenderRatesListBest: error while get best rates: " + err.Error())
+			return
+		}
+
+		if len(rates) != 3 {
+			t.Error("error while test TestLenderRatesListBest: best rate of every product expected")
+			return
+		}
+
+		AccertEqual(t, "TestLenderRatesListBest - 15 year interest", rates[0].Interest, "3.230")
+		AccertEqual(t, "TestLenderRatesListBest - 30 year lender", rates[1].LenderName, "Union Home Lending")
+		AccertEqual(t, "TestLenderRatesListBest - begin date", rates[2].BeginDate, "2018-01-01")
+	})
+
+	t.Run("TestLenderRatesListBestAsOf", func(t *testing.T) {
+		rates, err := LenderRatesListBestAsOf("2017-06-30")
+		if err != nil {
+			t.Error("error while test TestLenderRatesListBestAsOf: " + err.Error())
+			return
+		}
+
+		if len(rates) != 1 {
+			t.Error("error while test TestLenderRatesListBestAsOf: only 30 year rate was effective")
+			return
+		}
+
+		AccertEqual(t, "TestLenderRatesListBestAsOf - interest", rates[0].Interest, "3.450")
+
+		rates, err = LenderRatesByProductBetween("30 Year Fixed", "2017-06-30", "2017-07-01")
+		if err != nil {
+			t.Error("error while test TestLenderRatesListBestAsOf: " + err.Error())
+			return
+		}
+
+		if len(rates) != 2 {
+			t.Error("error while test TestLenderRatesListBestAsOf: two 2017 rates expected")
+			return
+		}
+
+		AccertEqual(t, "TestLenderRatesListBestAsOf - best of range", rates[0].Interest, "3.400")
 	})
 
-	t.Run("TestFastQuoteRate", func(t *testing.T) {
-		rate, err := FastQuoteRate(&webpojo.FastQuoteReq{})
+	t.Run("TestLenderRatesListAll", func(t *testing.T) {
+		page := &Page{Limit: 5}
+		rates, more, err := LenderRatesListAll(page)
 		if err != nil {
-			t.Error("error while test TestFastQuoteRate: error while get fast quote rate: " + err.Error())
+			t.Error("error while test TestLenderRatesListAll: " + err.Error())
 			return
 		}
 
-		if rate == nil {
-			t.Error("error while test TestFastQuoteRate: rate is nil")
+		if len(rates) != 5 || !more {
+			t.Error("error while test TestLenderRatesListAll: first page should be full")
 			return
 		}
+
+		page.Cursor = &cursor.Cursor{ID: rates[4].ID}
+		next, _, err := LenderRatesListAll(page)
+		if err != nil {
+			t.Error("error while test TestLenderRatesListAll: " + err.Error())
+			return
+		}
+
+		if len(next) == 0 || next[0].ID <= rates[4].ID {
+			t.Error("error while test TestLenderRatesListAll: next page should continue list")
+		}
 	})
 
 }
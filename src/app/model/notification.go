@@ -0,0 +1,171 @@
+package model
+
+import (
+	"time"
+
+	"app/shared/database"
+)
+
+// *****************************************************************************
+// Message notifications
+// *****************************************************************************
+
+// Notification modes
+const (
+	NotifyImmediate = "immediate"
+	NotifyDigest    = "digest"
+	NotifyOff       = "off"
+)
+
+// NotificationRecipient is thread participant who should be told about new message
+type NotificationRecipient struct {
+	UserID    uint32 `db:"user_id"`
+	Email     string `db:"email"`
+	FirstName string `db:"first_name"`
+	Mode      string `db:"mode"` // NotifyImmediate, NotifyDigest or NotifyOff
+}
+
+// DigestUser is user having messages waiting for digest
+type DigestUser struct {
+	UserID        uint32 `db:"user_id"`
+	Email         string `db:"email"`
+	FirstName     string `db:"first_name"`
+	LastMessageID uint32 `db:"last_message_id"` // the newest waiting message
+}
+
+// DigestItem is unread message waiting for digest email
+type DigestItem struct {
+	MessageID     uint32    `db:"message_id"`
+	ThreadID      uint32    `db:"thread_id"`
+	Title         string    `db:"title"`
+	Content       string    `db:"content"`
+	FromFirstName string    `db:"from_first_name"`
+	FromLastName  string    `db:"from_last_name"`
+	CreatedAt     time.Time `db:"created_at"`
+}
+
+// NotificationMode return user's notification mode, NotifyImmediate if user has not chosen it
+func NotificationMode(userID uint32) (string, error) {
+	var err error
+	mode := NotifyImmediate
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Get(&mode, "SELECT mode FROM notification_preference WHERE user_id = ?", userID)
+		if standardizeError(err) == ErrNoResult {
+			return NotifyImmediate, nil
+		}
+	default:
+		err = ErrCode
+	}
+
+	return mode, standardizeError(err)
+}
+
+// NotificationModeUpdate sets user's notification mode
+func NotificationModeUpdate(userID uint32, mode string) error {
+	var err error
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		_, err = database.SQL.Exec(`INSERT INTO notification_preference (user_id, mode) VALUES (?,?)
+			ON DUPLICATE KEY UPDATE mode = VALUES(mode)`, userID, mode)
+	default:
+		err = ErrCode
+	}
+
+	return standardizeError(err)
+}
+
+// NotificationRecipients return thread participants except sender with their notification modes
+func NotificationRecipients(threadID, senderID uint32) ([]*NotificationRecipient, error) {
+	var err error
+
+	var result []*NotificationRecipient
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Select(&result, `SELECT user.id AS user_id, user.email, user.first_name,
+			IFNULL(notification_preference.mode, ?) AS mode
+			FROM message_thread_participant participant
+			JOIN user ON user.id = participant.user_id
+			LEFT JOIN notification_preference ON notification_preference.user_id = participant.user_id
+			WHERE participant.thread_id = ? AND participant.user_id <> ?`, NotifyImmediate, threadID, senderID)
+	default:
+		err = ErrCode
+	}
+
+	return result, standardizeError(err)
+}
+
+// DigestItemAdd puts message to user's next digest
+func DigestItemAdd(userID, messageID uint32) error {
+	var err error
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		_, err = database.SQL.Exec("INSERT IGNORE INTO notification_digest_item (user_id, message_id) VALUES (?,?)", userID, messageID)
+	default:
+		err = ErrCode
+	}
+
+	return standardizeError(err)
+}
+
+// DigestUsers return users having messages waiting for digest
+func DigestUsers() ([]*DigestUser, error) {
+	var err error
+
+	var result []*DigestUser
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Select(&result, `SELECT user.id AS user_id, user.email, user.first_name, MAX(item.message_id) AS last_message_id
+			FROM notification_digest_item item
+			JOIN user ON user.id = item.user_id
+			GROUP BY user.id, user.email, user.first_name
+			ORDER BY user.id`)
+	default:
+		err = ErrCode
+	}
+
+	return result, standardizeError(err)
+}
+
+// DigestItems return messages up to lastMessageID waiting for user's digest which user has not read yet, oldest first
+func DigestItems(userID, lastMessageID uint32) ([]*DigestItem, error) {
+	var err error
+
+	var result []*DigestItem
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Select(&result, `SELECT unread_message.id AS message_id, unread_message.thread_id, message_thread.title,
+			unread_message.content, sender.first_name AS from_first_name, sender.last_name AS from_last_name, unread_message.created_at
+			FROM notification_digest_item item
+			JOIN customer_message unread_message ON unread_message.id = item.message_id
+			JOIN message_thread ON message_thread.id = unread_message.thread_id
+			JOIN user sender ON sender.id = unread_message.from_user_id
+			JOIN message_thread_participant participant ON participant.thread_id = unread_message.thread_id AND participant.user_id = item.user_id
+			WHERE item.user_id = ? AND item.message_id <= ? AND `+unreadMessageCondition+`
+			ORDER BY unread_message.id`, userID, lastMessageID)
+	default:
+		err = ErrCode
+	}
+
+	return result, standardizeError(err)
+}
+
+// DigestItemsRemove removes user's digest items up to message, they are sent or read already
+func DigestItemsRemove(userID, lastMessageID uint32) error {
+	var err error
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		_, err = database.SQL.Exec("DELETE FROM notification_digest_item WHERE user_id = ? AND message_id <= ?", userID, lastMessageID)
+	default:
+		err = ErrCode
+	}
+
+	return standardizeError(err)
+}
@@ -0,0 +1,72 @@
+package model
+
+import (
+	"app/shared/cursor"
+)
+
+// *****************************************************************************
+// Keyset pagination
+// *****************************************************************************
+
+// Page contains params of list page: rows after or before cursor row in list order
+type Page struct {
+	Limit  int
+	Cursor *cursor.Cursor // nil for the first page
+}
+
+// before tells page goes before cursor row in list order
+func (p *Page) before() bool {
+	return p.Cursor != nil && p.Cursor.Before
+}
+
+// keyset return condition, its arguments and ORDER BY clause for page of list sorted by column and idColumn.
+// Empty column sorts list by idColumn only. Condition is empty for the first page.
+// Pages before cursor are selected in reversed order, use trim to restore list order.
+func (p *Page) keyset(column, idColumn string, desc bool) (string, []interface{}, string) {
+	if p.before() {
+		desc = !desc
+	}
+
+	cmp, order := ">", "ASC"
+	if desc {
+		cmp, order = "<", "DESC"
+	}
+
+	orderBy := idColumn + " " + order
+	if column != "" {
+		orderBy = column + " " + order + ", " + orderBy
+	}
+
+	if p.Cursor == nil {
+		return "", nil, orderBy
+	}
+
+	if column == "" {
+		return idColumn + " " + cmp + " ?", []interface{}{p.Cursor.ID}, orderBy
+	}
+
+	return "(" + column + " " + cmp + " ? OR (" + column + " = ? AND " + idColumn + " " + cmp + " ?))",
+		[]interface{}{p.Cursor.Key, p.Cursor.Key, p.Cursor.ID}, orderBy
+}
+
+// limit return number of rows to select: one more than page size to find out there are more rows
+func (p *Page) limit() int {
+	return p.Limit + 1
+}
+
+// trim drops extra row selected by limit and puts rows in list order.
+// n is number of selected rows, swap exchanges rows. Return page size and are there more rows in page direction.
+func (p *Page) trim(n int, swap func(i, j int)) (int, bool) {
+	more := n > p.Limit
+	if more {
+		n = p.Limit
+	}
+
+	if p.before() {
+		for i, j := 0, n-1; i < j; i, j = i+1, j-1 {
+			swap(i, j)
+		}
+	}
+
+	return n, more
+}
@@ -0,0 +1,213 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+
+	"app/shared/database"
+)
+
+// *****************************************************************************
+// Rate alerts
+// *****************************************************************************
+
+// Rate alert directions
+const (
+	AlertBelow = "below"
+	AlertAbove = "above"
+)
+
+// rateAlertColumns selects rate alert
+const rateAlertColumns = `rate_alert.id, rate_alert.user_id, rate_alert.product, rate_alert.threshold, rate_alert.direction,
+	rate_alert.token, rate_alert.triggered, rate_alert.notified_rate, rate_alert.notified_at, rate_alert.created_at`
+
+// RateAlert is customer's alert on best interest of product crossing threshold.
+// Triggered alert is not sent again until rate crosses threshold back.
+type RateAlert struct {
+	ID           uint32         `db:"id"`
+	UserID       uint32         `db:"user_id"`
+	Product      string         `db:"product"`
+	Threshold    string         `db:"threshold"`
+	Direction    string         `db:"direction"` // AlertBelow or AlertAbove
+	Token        string         `db:"token"`     // unsubscribe token
+	Triggered    bool           `db:"triggered"`
+	NotifiedRate sql.NullString `db:"notified_rate"`
+	NotifiedAt   *time.Time     `db:"notified_at"`
+	CreatedAt    time.Time      `db:"created_at"`
+}
+
+// RateAlertRecipient is rate alert with email of its customer
+type RateAlertRecipient struct {
+	RateAlert
+	Email     string `db:"email"`
+	FirstName string `db:"first_name"`
+}
+
+// RateAlertCreate adds alert and return its ID. Return ErrDuplicateEntry if user has the same alert.
+func RateAlertCreate(alert *RateAlert) (uint32, error) {
+	var err error
+	var res sql.Result
+	var id int64
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		res, err = database.SQL.Exec(`INSERT INTO rate_alert (user_id, product, threshold, direction, token)
+			VALUES (?,?,?,?,?)`, alert.UserID, alert.Product, alert.Threshold, alert.Direction, alert.Token)
+		if err != nil {
+			break
+		}
+
+		id, err = res.LastInsertId()
+	default:
+		err = ErrCode
+	}
+
+	return uint32(id), standardizeError(err)
+}
+
+// RateAlertsByUserID gets alerts of user in order they were added
+func RateAlertsByUserID(userID uint32) ([]RateAlert, error) {
+	var err error
+	var result []RateAlert
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Select(&result, "SELECT "+rateAlertColumns+" FROM rate_alert WHERE user_id = ? ORDER BY id", userID)
+	default:
+		err = ErrCode
+	}
+
+	return result, standardizeError(err)
+}
+
+// RateAlertsCount return number of user's alerts
+func RateAlertsCount(userID uint32) (int, error) {
+	var err error
+	var count int
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Get(&count, "SELECT COUNT(*) FROM rate_alert WHERE user_id = ?", userID)
+	default:
+		err = ErrCode
+	}
+
+	return count, standardizeError(err)
+}
+
+// RateAlertRecipients gets all alerts with emails of their customers
+func RateAlertRecipients() ([]RateAlertRecipient, error) {
+	var err error
+	var result []RateAlertRecipient
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Select(&result, `SELECT `+rateAlertColumns+`, user.email, user.first_name
+			FROM rate_alert JOIN user ON user.id = rate_alert.user_id ORDER BY rate_alert.id`)
+	default:
+		err = ErrCode
+	}
+
+	return result, standardizeError(err)
+}
+
+// RateAlertTrigger marks not triggered alert sent for rate and puts its email to outbox in one transaction.
+// Return false and enqueues nothing if alert is triggered already, e.g. by concurrent evaluation.
+func RateAlertTrigger(id uint32, rate string, email *OutboxEmail) (bool, error) {
+	var err error
+	var triggered bool
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		triggered, err = rateAlertTriggerMySQL(id, rate, email)
+	default:
+		err = ErrCode
+	}
+
+	return triggered, standardizeError(err)
+}
+
+func rateAlertTriggerMySQL(id uint32, rate string, email *OutboxEmail) (bool, error) {
+	tx, err := database.SQL.Beginx()
+	if err != nil {
+		return false, err
+	}
+
+	res, err := tx.Exec("UPDATE rate_alert SET triggered = 1, notified_rate = ?, notified_at = NOW() WHERE id = ? AND triggered = 0", rate, id)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		tx.Rollback()
+		return false, nil
+	}
+
+	_, err = tx.Exec("INSERT INTO email_outbox (user_id, to_email, subject, text_body, html_body) VALUES (?,?,?,?,?)",
+		email.UserID, email.ToEmail, email.Subject, email.TextBody, email.HTMLBody)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// RateAlertRearm lets alert be sent again when rate crosses threshold next time
+func RateAlertRearm(id uint32) error {
+	var err error
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		_, err = database.SQL.Exec("UPDATE rate_alert SET triggered = 0 WHERE id = ?", id)
+	default:
+		err = ErrCode
+	}
+
+	return standardizeError(err)
+}
+
+// RateAlertDelete removes user's alert. Return ErrNoResult if user has no such alert.
+func RateAlertDelete(id, userID uint32) error {
+	var err error
+	var res sql.Result
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		res, err = database.SQL.Exec("DELETE FROM rate_alert WHERE id = ? AND user_id = ?", id, userID)
+		if err != nil {
+			break
+		}
+
+		if n, _ := res.RowsAffected(); n == 0 {
+			err = ErrNoResult
+		}
+	default:
+		err = ErrCode
+	}
+
+	return standardizeError(err)
+}
+
+// RateAlertDeleteByToken removes alert by its unsubscribe token. Return ErrNoResult if token is unknown.
+func RateAlertDeleteByToken(token string) error {
+	var err error
+	var res sql.Result
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		res, err = database.SQL.Exec("DELETE FROM rate_alert WHERE token = ?", token)
+		if err != nil {
+			break
+		}
+
+		if n, _ := res.RowsAffected(); n == 0 {
+			err = ErrNoResult
+		}
+	default:
+		err = ErrCode
+	}
+
+	return standardizeError(err)
+}
@@ -0,0 +1,64 @@
+package model
+
+import (
+	"time"
+
+	"app/shared/database"
+)
+
+// *****************************************************************************
+// Rate feed run
+// *****************************************************************************
+
+// RateFeedRun table contains result of rate feed ingestion
+type RateFeedRun struct {
+	ID        uint32    `db:"id"`
+	Source    string    `db:"source"`
+	Succeeded bool      `db:"succeeded"`
+	Fetched   int       `db:"fetched"`
+	Inserted  int       `db:"inserted"`
+	Updated   int       `db:"updated"`
+	Rejected  int       `db:"rejected"`
+	Error     string    `db:"error"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// rateFeedRunColumns is the column list for RateFeedRun selects
+const rateFeedRunColumns = "id, source, succeeded, fetched, inserted, updated, rejected, error, created_at"
+
+// RateFeedRunCreate writes result of rate feed ingestion
+func RateFeedRunCreate(run *RateFeedRun) error {
+	var err error
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		_, err = database.SQL.Exec(`INSERT INTO rate_feed_run (source, succeeded, fetched, inserted, updated, rejected, error)
+			VALUES (?,?,?,?,?,?,?)`,
+			run.Source, run.Succeeded, run.Fetched, run.Inserted, run.Updated, run.Rejected, run.Error)
+	default:
+		err = ErrCode
+	}
+
+	return standardizeError(err)
+}
+
+// RateFeedRunLast gets the latest rate feed ingestion, the latest successful one if succeeded is true.
+// Return ErrNoResult if there is no such run.
+func RateFeedRunLast(succeeded bool) (*RateFeedRun, error) {
+	var err error
+	result := &RateFeedRun{}
+
+	cond := ""
+	if succeeded {
+		cond = "WHERE succeeded = 1"
+	}
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Get(result, "SELECT "+rateFeedRunColumns+" FROM rate_feed_run "+cond+" ORDER BY id DESC LIMIT 1")
+	default:
+		err = ErrCode
+	}
+
+	return result, standardizeError(err)
+}
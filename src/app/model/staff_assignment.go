@@ -0,0 +1,86 @@
+package model
+
+import (
+	"time"
+
+	"app/shared/database"
+)
+
+// *****************************************************************************
+// Staff assignment
+// *****************************************************************************
+
+// AssignedCustomer contains the information about customer assigned to staff member
+type AssignedCustomer struct {
+	CustomerID uint32    `db:"customer_id"`
+	FirstName  string    `db:"first_name"`
+	LastName   string    `db:"last_name"`
+	Email      string    `db:"email"`
+	AssignedAt time.Time `db:"created_at"`
+}
+
+// StaffAssignmentCreate assigns customer to staff member
+func StaffAssignmentCreate(staffID, customerID string) error {
+	var err error
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		_, err = database.SQL.Exec("INSERT IGNORE INTO staff_assignment (staff_id, customer_id) VALUES (?,?)", staffID, customerID)
+
+	default:
+		err = ErrCode
+	}
+
+	return standardizeError(err)
+}
+
+// StaffAssignmentDelete removes customer assignment from staff member
+func StaffAssignmentDelete(staffID, customerID string) error {
+	var err error
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		_, err = database.SQL.Exec("DELETE FROM staff_assignment WHERE staff_id = ? AND customer_id = ?", staffID, customerID)
+
+	default:
+		err = ErrCode
+	}
+
+	return standardizeError(err)
+}
+
+// IsStaffAssigned returns true if customer is assigned to staff member
+func IsStaffAssigned(staffID, customerID string) (bool, error) {
+	var err error
+	var count int
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Get(&count, "SELECT COUNT(*) FROM staff_assignment WHERE staff_id = ? AND customer_id = ?", staffID, customerID)
+	default:
+		err = ErrCode
+	}
+
+	return count > 0, standardizeError(err)
+}
+
+// CustomersByStaffID gets all customers assigned to staff member
+func CustomersByStaffID(staffID string) ([]*AssignedCustomer, error) {
+	var err error
+
+	var result []*AssignedCustomer
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Select(&result, `
+			SELECT staff_assignment.customer_id, user.first_name, user.last_name, user.email, staff_assignment.created_at
+			FROM staff_assignment
+			JOIN user ON user.id = staff_assignment.customer_id
+			WHERE staff_assignment.staff_id = ?
+			ORDER BY user.last_name, user.first_name`, staffID)
+	default:
+		err = ErrCode
+	}
+
+	return result, standardizeError(err)
+}
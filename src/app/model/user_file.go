@@ -4,24 +4,61 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"strconv"
+	"strings"
 	"time"
 
 	"app/shared/database"
+
+	"github.com/jmoiron/sqlx"
 )
 
 // *****************************************************************************
 // Note
 // *****************************************************************************
 
+const (
+	// userFileColumns is the column list for UserFile selects
+	userFileColumns = `id, file_name, file_type, user_id, category, description, size, checksum, scan_status, scan_signature, key_version, wrapped_key, created_at, updated_at, deleted, deleted_at,
+		IFNULL((SELECT GROUP_CONCAT(tag ORDER BY tag) FROM uploaded_file_tag WHERE file_id = uploaded_files.id), '') AS tags`
+)
+
+// File scan statuses
+const (
+	FileScanPending  = "pending"
+	FileScanClean    = "clean"
+	FileScanInfected = "infected"
+)
+
+// fileSortColumns maps FileFilter.SortBy values to columns.
+// Files are created in ID order, so sorting by creation time uses ID only.
+var fileSortColumns = map[string]string{
+	"":         "",
+	"created":  "",
+	"name":     "file_name",
+	"size":     "size",
+	"category": "category",
+}
+
 // UserFile table contains the information for each file
 type UserFile struct {
-	ID        uint32    `db:"id" bson:"id,omitempty"` // Don't use Id, use NoteID() instead for consistency with MongoDB
-	FileName  string    `db:"file_name" bson:"file_name"`
-	FileType  string    `db:"file_type" bson:"file_type"`
-	UserID    uint32    `db:"user_id"`
-	CreatedAt time.Time `db:"created_at" bson:"created_at"`
-	UpdatedAt time.Time `db:"updated_at" bson:"updated_at"`
-	Deleted   uint8     `db:"deleted" bson:"deleted"`
+	ID            uint32     `db:"id" bson:"id,omitempty"` // Don't use Id, use NoteID() instead for consistency with MongoDB
+	FileName      string     `db:"file_name" bson:"file_name"`
+	FileType      string     `db:"file_type" bson:"file_type"`
+	UserID        uint32     `db:"user_id"`
+	Category      string     `db:"category"`
+	Description   string     `db:"description"`
+	Tags          string     `db:"tags"`        // comma separated, read only: use FileTagsReplace to change
+	Size          int64      `db:"size"`        // size of original (not encrypted) content
+	Checksum      string     `db:"checksum"`    // hex SHA-256 of original content
+	ScanStatus    string     `db:"scan_status"` // FileScanPending, FileScanClean or FileScanInfected
+	ScanSignature string     `db:"scan_signature"`
+	KeyVersion    uint32     `db:"key_version"` // master key version of wrapped data key, FileKeyLegacy if file is not encrypted
+	WrappedKey    []byte     `db:"wrapped_key"` // file data key encrypted with master key
+	CreatedAt     time.Time  `db:"created_at" bson:"created_at"`
+	UpdatedAt     time.Time  `db:"updated_at" bson:"updated_at"`
+	Deleted       uint8      `db:"deleted" bson:"deleted"`
+	DeletedAt     *time.Time `db:"deleted_at"` // nil while file is not in trash
 }
 
 // FileID returns the file id
@@ -36,7 +73,7 @@ func (us *UserFile) FileID() string {
 	return r
 }
 
-// FileByID gets note by ID
+// FileByID gets not deleted file by ID
 func FileByID(userID string, fileID string) (*UserFile, error) {
 	var err error
 
@@ -44,7 +81,7 @@ func FileByID(userID string, fileID string) (*UserFile, error) {
 
 	switch database.ReadConfig().Type {
 	case database.TypeMySQL:
-		err = database.SQL.Get(&result, "SELECT id, file_name, file_type, user_id, created_at, updated_at, deleted FROM uploaded_files WHERE id = ? AND user_id = ? LIMIT 1", fileID, userID)
+		err = database.SQL.Get(&result, "SELECT "+userFileColumns+" FROM uploaded_files WHERE id = ? AND user_id = ? AND deleted = 0 LIMIT 1", fileID, userID)
 	default:
 		err = ErrCode
 	}
@@ -52,7 +89,7 @@ func FileByID(userID string, fileID string) (*UserFile, error) {
 	return &result, standardizeError(err)
 }
 
-// FilesByUserID gets all files for a user
+// FilesByUserID gets all not deleted files for a user
 func FilesByUserID(userID string) ([]*UserFile, error) {
 	var err error
 
@@ -60,7 +97,7 @@ func FilesByUserID(userID string) ([]*UserFile, error) {
 
 	switch database.ReadConfig().Type {
 	case database.TypeMySQL:
-		err = database.SQL.Select(&result, "SELECT id, file_name, file_type, user_id, created_at, updated_at, deleted FROM uploaded_files WHERE user_id = ?", userID)
+		err = database.SQL.Select(&result, "SELECT "+userFileColumns+" FROM uploaded_files WHERE user_id = ? AND deleted = 0", userID)
 	default:
 		err = ErrCode
 	}
@@ -68,15 +105,116 @@ func FilesByUserID(userID string) ([]*UserFile, error) {
 	return result, standardizeError(err)
 }
 
+// FileFilter contains params for files search
+type FileFilter struct {
+	Category string
+	Tag      string
+	Query    string // part of file name or tag
+	SortBy   string // created, name, size or category
+	Desc     bool
+	Page     *Page
+}
+
+// SortKey return value of file sort column, it is cursor key of file in list sorted by sortBy
+func (us *UserFile) SortKey(sortBy string) string {
+	switch sortBy {
+	case "name":
+		return us.FileName
+	case "size":
+		return strconv.FormatInt(us.Size, 10)
+	case "category":
+		return us.Category
+	}
+
+	return ""
+}
+
+// TagList return file tags as slice
+func (us *UserFile) TagList() []string {
+	if us.Tags == "" {
+		return []string{}
+	}
+
+	return strings.Split(us.Tags, ",")
+}
+
+// FilesSearch gets page of not deleted user files matched to filter, total count of matched files
+// and are there more files in page direction
+func FilesSearch(userID string, filter *FileFilter) ([]*UserFile, int, bool, error) {
+	var err error
+	var more bool
+
+	var result []*UserFile
+	var total int
+
+	sortColumn, ok := fileSortColumns[filter.SortBy]
+	if !ok {
+		return nil, 0, false, fmt.Errorf("unknown sort field %q", filter.SortBy)
+	}
+
+	where := "WHERE user_id = ? AND deleted = 0"
+	args := []interface{}{userID}
+
+	if filter.Category != "" {
+		where += " AND category = ?"
+		args = append(args, filter.Category)
+	}
+
+	if filter.Tag != "" {
+		where += " AND id IN (SELECT file_id FROM uploaded_file_tag WHERE tag = ?)"
+		args = append(args, filter.Tag)
+	}
+
+	if filter.Query != "" {
+		like := "%" + escapeLike(filter.Query) + "%"
+		where += " AND (file_name LIKE ? OR id IN (SELECT file_id FROM uploaded_file_tag WHERE tag LIKE ?))"
+		args = append(args, like, like)
+	}
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Get(&total, "SELECT COUNT(*) FROM uploaded_files "+where, args...)
+		if err != nil {
+			break
+		}
+
+		cond, pageArgs, orderBy := filter.Page.keyset(sortColumn, "id", filter.Desc)
+		if cond != "" {
+			where += " AND " + cond
+			args = append(args, pageArgs...)
+		}
+
+		err = database.SQL.Select(&result, "SELECT "+userFileColumns+" FROM uploaded_files "+where+
+			" ORDER BY "+orderBy+" LIMIT ?", append(args, filter.Page.limit())...)
+		if err != nil {
+			break
+		}
+
+		var n int
+		n, more = filter.Page.trim(len(result), func(i, j int) { result[i], result[j] = result[j], result[i] })
+		result = result[:n]
+	default:
+		err = ErrCode
+	}
+
+	return result, total, more, standardizeError(err)
+}
+
+// escapeLike escapes LIKE wildcards in user input
+func escapeLike(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(s)
+}
+
 // FileCreate creates a new file in DB
-func FileCreate(fileName, fileType, userID string) (int, error) {
+func FileCreate(fileName, fileType, userID, category, description string, keyVersion uint32, wrappedKey []byte) (int, error) {
 	var err error
 	var res sql.Result
 	var lastID int64
 
 	switch database.ReadConfig().Type {
 	case database.TypeMySQL:
-		res, err = database.SQL.Exec("INSERT INTO uploaded_files (file_name, file_type, user_id) VALUES (?,?,?)", fileName, fileType, userID)
+		res, err = database.SQL.Exec("INSERT INTO uploaded_files (file_name, file_type, user_id, category, description, key_version, wrapped_key) VALUES (?,?,?,?,?,?,?)",
+			fileName, fileType, userID, category, description, keyVersion, wrappedKey)
 
 	default:
 		err = ErrCode
@@ -94,19 +232,182 @@ func FileCreate(fileName, fileType, userID string) (int, error) {
 	return int(lastID), standardizeError(err)
 }
 
-// FileDelete deletes a file
-func FileDelete(userID string, fileID string) error {
+// FileContentUpdate sets size and checksum of uploaded file content
+func FileContentUpdate(fileID uint32, size int64, checksum string) error {
+	var err error
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		_, err = database.SQL.Exec("UPDATE uploaded_files SET size = ?, checksum = ? WHERE id = ? LIMIT 1", size, checksum, fileID)
+
+	default:
+		err = ErrCode
+	}
+
+	return standardizeError(err)
+}
+
+// FileScanStatusUpdate sets antivirus scan result of a file
+func FileScanStatusUpdate(fileID uint32, status, signature string) error {
+	var err error
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		_, err = database.SQL.Exec("UPDATE uploaded_files SET scan_status = ?, scan_signature = ? WHERE id = ? LIMIT 1", status, signature, fileID)
+
+	default:
+		err = ErrCode
+	}
+
+	return standardizeError(err)
+}
+
+// FilesByScanStatus gets not deleted files of all users with scan status
+func FilesByScanStatus(status string) ([]*UserFile, error) {
+	var err error
+
+	var result []*UserFile
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Select(&result, "SELECT "+userFileColumns+" FROM uploaded_files WHERE scan_status = ? AND deleted = 0", status)
+	default:
+		err = ErrCode
+	}
+
+	return result, standardizeError(err)
+}
+
+// FileMetaUpdate sets category, description and tags of not deleted user file in one transaction.
+// Nil fields keep file values unchanged, empty tags remove them.
+func FileMetaUpdate(userID string, fileID string, category, description *string, tags []string) error {
 	var err error
 
 	switch database.ReadConfig().Type {
 	case database.TypeMySQL:
-		res, err := database.SQL.Exec("DELETE FROM uploaded_files WHERE id = ? AND user_id = ?", fileID, userID)
+		err = fileMetaUpdateMySQL(userID, fileID, category, description, tags)
+	default:
+		err = ErrCode
+	}
+
+	return standardizeError(err)
+}
+
+// fileMetaUpdateMySQL locks file row, updates it and replaces its tags
+func fileMetaUpdateMySQL(userID string, fileID string, category, description *string, tags []string) error {
+	tx, err := database.SQL.Beginx()
+	if err != nil {
+		return err
+	}
+
+	// MySQL doesn't count rows with unchanged values, so file existence is checked by select
+	var id uint32
+	err = tx.Get(&id, "SELECT id FROM uploaded_files WHERE id = ? AND user_id = ? AND deleted = 0 FOR UPDATE", fileID, userID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	sets := []string{}
+	args := []interface{}{}
+	if category != nil {
+		sets = append(sets, "category = ?")
+		args = append(args, *category)
+	}
+	if description != nil {
+		sets = append(sets, "description = ?")
+		args = append(args, *description)
+	}
+
+	if len(sets) > 0 {
+		_, err = tx.Exec("UPDATE uploaded_files SET "+strings.Join(sets, ", ")+" WHERE id = ?", append(args, id)...)
 		if err != nil {
-			return standardizeError(err)
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if tags != nil {
+		err = fileTagsReplaceTx(tx, id, tags)
+		if err != nil {
+			tx.Rollback()
+			return err
 		}
+	}
+
+	return tx.Commit()
+}
+
+// FileTagsReplace replaces all tags of a file
+func FileTagsReplace(fileID uint32, tags []string) error {
+	var err error
 
-		if res == nil {
-			log.Println("file delete result is nil")
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = fileTagsReplaceMySQL(fileID, tags)
+	default:
+		err = ErrCode
+	}
+
+	return standardizeError(err)
+}
+
+// fileTagsReplaceMySQL deletes old and inserts new tags in one transaction
+func fileTagsReplaceMySQL(fileID uint32, tags []string) error {
+	tx, err := database.SQL.Beginx()
+	if err != nil {
+		return err
+	}
+
+	err = fileTagsReplaceTx(tx, fileID, tags)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// fileTagsReplaceTx deletes old and inserts new tags of a file in transaction
+func fileTagsReplaceTx(tx *sqlx.Tx, fileID uint32, tags []string) error {
+	_, err := tx.Exec("DELETE FROM uploaded_file_tag WHERE file_id = ?", fileID)
+	if err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		_, err = tx.Exec("INSERT INTO uploaded_file_tag (file_id, tag) VALUES (?,?)", fileID, tag)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FileDelete moves a file to trash
+func FileDelete(userID string, fileID string) error {
+	return fileDeletedUpdate(userID, fileID, true)
+}
+
+// FileRestore moves a file back from trash
+func FileRestore(userID string, fileID string) error {
+	return fileDeletedUpdate(userID, fileID, false)
+}
+
+// fileDeletedUpdate sets or clears soft delete mark of a file
+func fileDeletedUpdate(userID string, fileID string, deleted bool) error {
+	var err error
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		var res sql.Result
+		if deleted {
+			res, err = database.SQL.Exec("UPDATE uploaded_files SET deleted = 1, deleted_at = NOW() WHERE id = ? AND user_id = ? AND deleted = 0", fileID, userID)
+		} else {
+			res, err = database.SQL.Exec("UPDATE uploaded_files SET deleted = 0, deleted_at = NULL WHERE id = ? AND user_id = ? AND deleted = 1", fileID, userID)
+		}
+		if err != nil {
 			return standardizeError(err)
 		}
 
@@ -125,3 +426,116 @@ func FileDelete(userID string, fileID string) error {
 
 	return standardizeError(err)
 }
+
+// DeletedFilesByUserID gets all files in user's trash
+func DeletedFilesByUserID(userID string) ([]*UserFile, error) {
+	var err error
+
+	var result []*UserFile
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Select(&result, "SELECT "+userFileColumns+" FROM uploaded_files WHERE user_id = ? AND deleted = 1 ORDER BY deleted_at DESC", userID)
+	default:
+		err = ErrCode
+	}
+
+	return result, standardizeError(err)
+}
+
+// FilesDeletedBefore gets files of all users which are in trash since before time
+func FilesDeletedBefore(before time.Time) ([]*UserFile, error) {
+	var err error
+
+	var result []*UserFile
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Select(&result, "SELECT "+userFileColumns+" FROM uploaded_files WHERE deleted = 1 AND deleted_at < ?", before)
+	default:
+		err = ErrCode
+	}
+
+	return result, standardizeError(err)
+}
+
+// FilesAll gets files of all users including deleted
+func FilesAll() ([]*UserFile, error) {
+	var err error
+
+	var result []*UserFile
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Select(&result, "SELECT "+userFileColumns+" FROM uploaded_files")
+	default:
+		err = ErrCode
+	}
+
+	return result, standardizeError(err)
+}
+
+// FilePurge removes a deleted file row from DB
+func FilePurge(fileID uint32) error {
+	var err error
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		_, err = database.SQL.Exec("DELETE FROM uploaded_files WHERE id = ? AND deleted = 1", fileID)
+
+	default:
+		err = ErrCode
+	}
+
+	return standardizeError(err)
+}
+
+// FileKeyLegacy is key version of files stored before encryption, their content is plain
+const FileKeyLegacy uint32 = 0
+
+// FilesByKeyVersionNot gets all encrypted files which data keys are wrapped with another master key version
+func FilesByKeyVersionNot(keyVersion uint32) ([]*UserFile, error) {
+	var err error
+
+	var result []*UserFile
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Select(&result, "SELECT "+userFileColumns+" FROM uploaded_files WHERE key_version <> ? AND key_version <> ?", keyVersion, FileKeyLegacy)
+	default:
+		err = ErrCode
+	}
+
+	return result, standardizeError(err)
+}
+
+// FilesByKeyVersion gets all files which data keys are wrapped with master key version
+func FilesByKeyVersion(keyVersion uint32) ([]*UserFile, error) {
+	var err error
+
+	var result []*UserFile
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		err = database.SQL.Select(&result, "SELECT "+userFileColumns+" FROM uploaded_files WHERE key_version = ?", keyVersion)
+	default:
+		err = ErrCode
+	}
+
+	return result, standardizeError(err)
+}
+
+// FileKeyUpdate replaces wrapped data key of a file
+func FileKeyUpdate(fileID uint32, keyVersion uint32, wrappedKey []byte) error {
+	var err error
+
+	switch database.ReadConfig().Type {
+	case database.TypeMySQL:
+		_, err = database.SQL.Exec("UPDATE uploaded_files SET key_version = ?, wrapped_key = ? WHERE id = ? LIMIT 1", keyVersion, wrappedKey, fileID)
+
+	default:
+		err = ErrCode
+	}
+
+	return standardizeError(err)
+}
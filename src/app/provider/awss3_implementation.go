@@ -2,12 +2,13 @@ package provider
 
 import (
 	"errors"
+	"io"
+	"io/ioutil"
 	"log"
-	"mime/multipart"
-	"strconv"
-	"time"
+	"net/url"
 
 	"app/shared/config"
+	"app/shared/keyring"
 
 	"app/constants"
 
@@ -57,9 +58,9 @@ func getAmazonSession() (*session.Session, error) {
 }
 
 // uploadFileWithAWS send file to AWSS3
-func uploadFileWithAWS(file *multipart.File, fileID, userName string) (string, error) {
-	if file == nil {
-		return "", errors.New("error while upload files with amazon: multipart.File is nil")
+func uploadFileWithAWS(body io.Reader, fileID string) (string, error) {
+	if body == nil {
+		return "", errors.New("error while upload files with amazon: body is nil")
 	}
 
 	if fileID == "" || fileID == "0" {
@@ -74,24 +75,21 @@ func uploadFileWithAWS(file *multipart.File, fileID, userName string) (string, e
 	// Create an uploader with the session and default options
 	uploader := s3manager.NewUploader(sess)
 
-	// Upload the file to S3.
-	result, err := uploader.Upload(&s3manager.UploadInput{
-		// Bucket: aws.String(config.AWSS3().AWSBucketName),
-		// Key:    aws.String(fileID),
-		// Body:   *file,
-		// ACL:    aws.String(config.AWSS3().AWSAccessType),
-
-		// SSECustomerAlgorithm: aws.String("AES256"),
-		// SSECustomerKey:       aws.String(CreateUsersFileKey(userName)),
-		// SSECustomerKeyMD5:    aws.String(GetMD5Hash(CreateUsersFileKey(userName))),
+	input := &s3manager.UploadInput{
 		Bucket:               aws.String(config.AWSS3().AWSBucketName),
 		Key:                  aws.String(fileID),
-		Body:                 *file,
+		Body:                 body,
 		ACL:                  aws.String(config.AWSS3().AWSAccessType),
 		ServerSideEncryption: aws.String(constants.ServerSideEncryptionType),
-		SSEKMSKeyId:          aws.String(constants.SSEKMSKeyID),
-	})
+	}
 
+	// without key ID S3 uses account default KMS key
+	if keyID := keyring.SSEKMSKeyID(); keyID != "" {
+		input.SSEKMSKeyId = aws.String(keyID)
+	}
+
+	// Upload the file to S3.
+	result, err := uploader.Upload(input)
 	if err != nil {
 		return "", err
 	}
@@ -108,33 +106,86 @@ func uploadFileWithAWS(file *multipart.File, fileID, userName string) (string, e
 	return result.Location, nil
 }
 
-func getPresignLinkFromAWS(key string) (string, error) {
-	if key == "" {
-		return "", errors.New("error while getting presign link: bad file key")
+// downloadFileFromAWS return stored (encrypted) file content by fileID (Key)
+func downloadFileFromAWS(fileID string) ([]byte, error) {
+	if fileID == "" || fileID == "0" {
+		return nil, errors.New("error while download file from amazon: file id is empty or zero")
 	}
 
 	sess, err := getAmazonSession()
+	if err != nil {
+		return nil, err
+	}
+
+	svc := s3.New(sess)
+	result, err := svc.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(config.AWSS3().AWSBucketName),
+		Key:    aws.String(fileID),
+	})
+	if err != nil {
+		return nil, errors.New("error while download file from amazon: " + err.Error())
+	}
+
+	defer result.Body.Close()
+	return ioutil.ReadAll(result.Body)
+}
+
+// listFileKeysFromAWS return keys of all objects in bucket
+func listFileKeysFromAWS() ([]string, error) {
+	sess, err := getAmazonSession()
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+
 	svc := s3.New(sess)
-	req, _ := svc.GetObjectRequest(&s3.GetObjectInput{
-		// Bucket:               aws.String(config.AWSS3().AWSBucketName),
-		// Key:                  aws.String(key),
-		// SSECustomerAlgorithm: aws.String("AES256"),
+	err = svc.ListObjectsPages(&s3.ListObjectsInput{
 		Bucket: aws.String(config.AWSS3().AWSBucketName),
-		Key:    aws.String(key),
+	}, func(page *s3.ListObjectsOutput, lastPage bool) bool {
+		for _, v := range page.Contents {
+			keys = append(keys, aws.StringValue(v.Key))
+		}
+		return true
 	})
 
-	t, err := strconv.Atoi("10")
 	if err != nil {
-		return "", errors.New("error while parsing presign link life period: " + err.Error())
+		return nil, errors.New("error while list files in amazon bucket: " + err.Error())
 	}
 
-	urlStr, _, err := req.PresignRequest(time.Duration(t) * time.Minute)
+	return keys, nil
+}
 
+// moveFileWithAWS copy file to new key with the same encryption settings and remove old one
+func moveFileWithAWS(fromKey, toKey string) error {
+	if fromKey == "" || toKey == "" {
+		return errors.New("error while move file with amazon: key is empty")
+	}
+
+	sess, err := getAmazonSession()
+	if err != nil {
+		return err
+	}
+
+	input := &s3.CopyObjectInput{
+		Bucket:               aws.String(config.AWSS3().AWSBucketName),
+		Key:                  aws.String(toKey),
+		CopySource:           aws.String(url.PathEscape(config.AWSS3().AWSBucketName + "/" + fromKey)),
+		ACL:                  aws.String(config.AWSS3().AWSAccessType),
+		ServerSideEncryption: aws.String(constants.ServerSideEncryptionType),
+	}
+
+	if keyID := keyring.SSEKMSKeyID(); keyID != "" {
+		input.SSEKMSKeyId = aws.String(keyID)
+	}
+
+	svc := s3.New(sess)
+	_, err = svc.CopyObject(input)
 	if err != nil {
-		return "", errors.New("error while getting presign link: " + err.Error())
+		return errors.New("error while copy file with amazon: " + err.Error())
 	}
 
-	return urlStr, nil
+	return RemoveFileWithAWS(fromKey)
 }
 
 // RemoveFileWithAWS can remove file with fileID (Key).
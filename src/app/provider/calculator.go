@@ -0,0 +1,241 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+
+	"app/model"
+	"app/webpojo"
+)
+
+var (
+	// ErrWrongTerm returns if calculator loan term is out of range
+	ErrWrongTerm = errors.New("term_years should be from 1 to 40")
+	// ErrWrongInterest returns if calculator interest is out of range
+	ErrWrongInterest = errors.New("interest should be from 0 to 100")
+	// ErrWrongExtraPayment returns if extra payment is not positive, is above maxLoanAmount or is out of loan term
+	ErrWrongExtraPayment = errors.New("extra payments should be positive and within loan term")
+)
+
+// CalculatePayment return monthly payment and totals of fully amortized loan
+func CalculatePayment(req *webpojo.CalculatorReq) (*webpojo.CalculatorPaymentResp, error) {
+	return calculatorLoan(req)
+}
+
+// CalculateAmortization return amortization schedule of loan with extra payments and what they save
+func CalculateAmortization(req *webpojo.CalculatorReq) (*webpojo.AmortizationResp, error) {
+	loan, err := calculatorLoan(req)
+	if err != nil {
+		return nil, err
+	}
+
+	months := loan.TermYears * 12
+	if req.ExtraMonthly < 0 || req.ExtraMonthly > maxLoanAmount {
+		return nil, ErrWrongExtraPayment
+	}
+
+	extra := map[int]float64{}
+	for _, v := range req.ExtraPayments {
+		if v.Month < 1 || v.Month > months || v.Amount <= 0 || v.Amount > maxLoanAmount {
+			return nil, ErrWrongExtraPayment
+		}
+
+		extra[v.Month] += v.Amount
+	}
+
+	res := &webpojo.AmortizationResp{
+		CalculatorPaymentResp: *loan,
+		Schedule:              amortize(loan.LoanAmount, loan.Interest, loan.TermYears, req.ExtraMonthly, extra),
+	}
+
+	for _, v := range res.Schedule {
+		res.PaidTotal += v.Payment
+		res.PaidInterest += v.Interest
+	}
+
+	res.Months = len(res.Schedule)
+	res.PaidTotal = roundTo(res.PaidTotal, 2)
+	res.PaidInterest = roundTo(res.PaidInterest, 2)
+	res.InterestSaved = roundTo(loan.TotalInterest-res.PaidInterest, 2)
+	res.MonthsSaved = months - res.Months
+	return res, nil
+}
+
+// CompareLenderRates return cost of loan with every lender rate effective today matched to requested
+// product and term, the cheapest by APR cost first
+func CompareLenderRates(req *webpojo.RateComparisonReq) (*webpojo.RateComparisonResp, error) {
+	if err := checkLoanAmount(req.LoanAmount); err != nil {
+		return nil, err
+	}
+
+	rates, err := model.LenderRatesEffectiveAsOf(today())
+	if err != nil {
+		log.Println("error while compare lender rates: ", err)
+		return nil, err
+	}
+
+	product := strings.TrimSpace(req.Product)
+
+	res := &webpojo.RateComparisonResp{LoanAmount: req.LoanAmount, Comparisons: []webpojo.RateComparison{}}
+	best := map[string]float64{}
+	for i := range rates {
+		if product != "" && !strings.EqualFold(rates[i].Product, product) {
+			continue
+		}
+
+		if req.TermYears != 0 && rates[i].TermYears != req.TermYears {
+			continue
+		}
+
+		rate, err := lenderRatePojo(&rates[i])
+		if err != nil {
+			log.Println("error while compare lender rates: bad rate ", rates[i].ID, ": ", err)
+			return nil, err
+		}
+
+		_, totalInterest := amortizedTotals(req.LoanAmount, rate.Interest, rate.TermYears)
+		_, aprCost := amortizedTotals(req.LoanAmount, rate.Apr, rate.TermYears)
+
+		res.Comparisons = append(res.Comparisons, webpojo.RateComparison{
+			LenderRatePojo: *rate,
+			MonthlyPayment: monthlyPayment(req.LoanAmount, rate.Interest, rate.TermYears),
+			TotalInterest:  totalInterest,
+			AprCost:        aprCost,
+		})
+
+		key := comparisonKey(rate)
+		if cost, ok := best[key]; !ok || aprCost < cost {
+			best[key] = aprCost
+		}
+	}
+
+	if len(res.Comparisons) == 0 {
+		return nil, ErrNoQuote
+	}
+
+	for i := range res.Comparisons {
+		c := &res.Comparisons[i]
+		c.AboveBest = roundTo(c.AprCost-best[comparisonKey(&c.LenderRatePojo)], 2)
+	}
+
+	sort.SliceStable(res.Comparisons, func(i, j int) bool {
+		return res.Comparisons[i].AprCost < res.Comparisons[j].AprCost
+	})
+
+	return res, nil
+}
+
+// comparisonKey return key of rates compared with each other
+func comparisonKey(rate *webpojo.LenderRatePojo) string {
+	return fmt.Sprintf("%s|%d", strings.ToLower(rate.Product), rate.TermYears)
+}
+
+// calculatorLoan checks calculator loan params, takes rate and term of lender rate if request has rate ID.
+// Return loan payment without extra payments.
+func calculatorLoan(req *webpojo.CalculatorReq) (*webpojo.CalculatorPaymentResp, error) {
+	if err := checkLoanAmount(req.LoanAmount); err != nil {
+		return nil, err
+	}
+
+	res := &webpojo.CalculatorPaymentResp{
+		LoanAmount: roundTo(req.LoanAmount, 2),
+		TermYears:  req.TermYears,
+		Interest:   req.Interest,
+	}
+
+	if req.RateID != 0 {
+		rate, err := model.LenderRateByID(req.RateID)
+		if err == model.ErrNoResult {
+			return nil, ErrLenderRateNotExist
+		}
+		if err != nil {
+			log.Println("error while get calculator rate: ", err)
+			return nil, err
+		}
+
+		pojo, err := lenderRatePojo(&rate)
+		if err != nil {
+			log.Println("error while get calculator rate: ", err)
+			return nil, err
+		}
+
+		res.RateID = pojo.ID
+		res.Interest = pojo.Interest
+		res.Apr = pojo.Apr
+		if res.TermYears == 0 {
+			res.TermYears = pojo.TermYears
+		}
+	}
+
+	if res.TermYears < 1 || res.TermYears > maxLenderTermYears {
+		return nil, ErrWrongTerm
+	}
+
+	if res.Interest < 0 || res.Interest >= 100 {
+		return nil, ErrWrongInterest
+	}
+
+	res.MonthlyPayment = monthlyPayment(res.LoanAmount, res.Interest, res.TermYears)
+	res.TotalPayment, res.TotalInterest = amortizedTotals(res.LoanAmount, res.Interest, res.TermYears)
+	return res, nil
+}
+
+// amortizedTotals return total payment and total interest of loan without extra payments
+func amortizedTotals(amount, interest float64, termYears int) (float64, float64) {
+	var total, totalInterest float64
+	for _, v := range amortize(amount, interest, termYears, 0, nil) {
+		total += v.Payment
+		totalInterest += v.Interest
+	}
+
+	return roundTo(total, 2), roundTo(totalInterest, 2)
+}
+
+// amortize return monthly payments of loan until it is paid off. Payment is monthly payment of loan
+// plus extra principal, the last payment pays rest of balance. Sums are counted in cents.
+func amortize(amount, interest float64, termYears int, extraMonthly float64, extra map[int]float64) []webpojo.AmortizationRow {
+	months := termYears * 12
+	rate := interest / 100 / 12
+	payment := toCents(monthlyPayment(amount, interest, termYears))
+	balance := toCents(amount)
+
+	rows := []webpojo.AmortizationRow{}
+	for m := 1; m <= months && balance > 0; m++ {
+		interestPart := int64(math.Floor(float64(balance)*rate + 0.5))
+		principal := payment - interestPart
+		if m == months || principal > balance {
+			principal = balance
+		}
+
+		extraPart := toCents(extraMonthly + extra[m])
+		if extraPart > balance-principal {
+			extraPart = balance - principal
+		}
+
+		balance -= principal + extraPart
+		rows = append(rows, webpojo.AmortizationRow{
+			Month:     m,
+			Payment:   fromCents(principal + interestPart + extraPart),
+			Principal: fromCents(principal),
+			Interest:  fromCents(interestPart),
+			Extra:     fromCents(extraPart),
+			Balance:   fromCents(balance),
+		})
+	}
+
+	return rows
+}
+
+// toCents convert dollars to whole cents
+func toCents(v float64) int64 {
+	return int64(math.Floor(v*100 + 0.5))
+}
+
+// fromCents convert cents to dollars
+func fromCents(v int64) float64 {
+	return float64(v) / 100
+}
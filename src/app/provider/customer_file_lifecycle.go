@@ -0,0 +1,181 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"app/model"
+	"app/shared/config"
+	"app/webpojo"
+
+	"github.com/jasonlvhit/gocron"
+)
+
+const (
+	defaultTrashRetentionDays = 30
+)
+
+// GetTrashFileList return list of files in user's trash
+func GetTrashFileList(userID string, sessID string) ([]*webpojo.UserFile, error) {
+	if userID == "" || userID == "0" {
+		return nil, errors.New("error while get user's trash file list: user id empty or zero")
+	}
+
+	rawFileList, err := model.DeletedFilesByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	resultFilesList := []*webpojo.UserFile{}
+
+	for _, v := range rawFileList {
+		userFile := userFilePojo(v, sessID)
+		// deleted file can't be downloaded until restored
+		userFile.Link = ""
+
+		resultFilesList = append(resultFilesList, userFile)
+	}
+
+	return resultFilesList, nil
+}
+
+// FileRestore moves user file back from trash
+func FileRestore(userID string, fileID string) error {
+	if fileID == "" {
+		return errors.New("error while restore user's file: fileID is zero")
+	}
+
+	if userID == "" || userID == "0" {
+		return errors.New("error while restore user's file: user id is empty or zero")
+	}
+
+	err := model.FileRestore(userID, fileID)
+	if err != nil {
+		log.Println("error while restore user file: " + err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// DiscardFiles removes just uploaded user files from storage and DB, e.g. attachments of message which was not posted.
+// Errors are only logged, files which were not removed stay in trash and are purged later.
+func DiscardFiles(userID string, fileIDs []uint32) {
+	for _, id := range fileIDs {
+		fileID := fmt.Sprint(id)
+		fileInfo, err := model.FileByID(userID, fileID)
+		if err != nil {
+			log.Println("error while discard file ", id, ": ", err)
+			continue
+		}
+
+		err = model.FileDelete(userID, fileID)
+		if err != nil {
+			log.Println("error while discard file ", id, ": ", err)
+			continue
+		}
+
+		err = RemoveFileWithAWS(objectKey(fileInfo))
+		if err != nil {
+			log.Println("error while discard file ", id, " from storage: ", err)
+			continue
+		}
+
+		err = model.FilePurge(id)
+		if err != nil {
+			log.Println("error while discard file ", id, " from DB: ", err)
+		}
+	}
+}
+
+// TrashRetentionPeriod return period after which deleted files are purged
+func TrashRetentionPeriod() time.Duration {
+	days := config.AWSS3().TrashRetentionDays
+	if days == 0 {
+		days = defaultTrashRetentionDays
+	}
+
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// PurgeDeletedFiles removes from storage and DB files which are in trash longer than retention period,
+// and reports storage objects which have no DB rows. Orphaned objects are only reported, not removed.
+func PurgeDeletedFiles(retention time.Duration) (*webpojo.FilesPurgeReport, error) {
+	report := &webpojo.FilesPurgeReport{}
+
+	files, err := model.FilesDeletedBefore(time.Now().Add(-retention))
+	if err != nil {
+		log.Println("error while get deleted files for purge: " + err.Error())
+		return nil, err
+	}
+
+	for _, v := range files {
+		err = RemoveFileWithAWS(objectKey(v))
+		if err != nil {
+			log.Println("error while purge file ", v.ID, " from storage: ", err)
+			report.FailedFiles = append(report.FailedFiles, storageKey(v))
+			continue
+		}
+
+		err = model.FilePurge(v.ID)
+		if err != nil {
+			log.Println("error while purge file ", v.ID, " from DB: ", err)
+			report.FailedFiles = append(report.FailedFiles, storageKey(v))
+			continue
+		}
+
+		report.PurgedFiles++
+	}
+
+	report.OrphanedObjects, err = findOrphanedObjects()
+	if err != nil {
+		log.Println("error while find orphaned files: " + err.Error())
+		return report, err
+	}
+
+	return report, nil
+}
+
+// findOrphanedObjects return storage keys which have no file row in DB
+func findOrphanedObjects() ([]string, error) {
+	files, err := model.FilesAll()
+	if err != nil {
+		return nil, err
+	}
+
+	known := map[string]bool{}
+	for _, v := range files {
+		known[objectKey(v)] = true
+	}
+
+	keys, err := listFileKeysFromAWS()
+	if err != nil {
+		return nil, err
+	}
+
+	var orphaned []string
+	for _, key := range keys {
+		if !known[key] {
+			orphaned = append(orphaned, key)
+		}
+	}
+
+	return orphaned, nil
+}
+
+// StartFilesPurgeSheduler start gocron with daily purge of deleted files
+func StartFilesPurgeSheduler() {
+	log.Println("start deleted files purge sheduler")
+
+	gocron.Every(1).Day().Do(func() {
+		report, err := PurgeDeletedFiles(TrashRetentionPeriod())
+		if err != nil {
+			log.Println("error while purge deleted files: " + err.Error())
+			return
+		}
+
+		log.Println(fmt.Sprintf("deleted files purge: purged=%d failed=%v orphaned=%v", report.PurgedFiles, report.FailedFiles, report.OrphanedObjects))
+	})
+}
@@ -0,0 +1,137 @@
+package provider
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"app/model"
+	"app/shared/scanner"
+
+	"github.com/jasonlvhit/gocron"
+)
+
+const (
+	// quarantinePrefix is storage key prefix of infected files
+	quarantinePrefix = "quarantine/"
+
+	// uploadGracePeriod is how long file without checksum is considered uploading
+	uploadGracePeriod = time.Hour
+)
+
+var (
+	// ErrFileNotScanned returns if file content is requested before antivirus scan finished
+	ErrFileNotScanned = errors.New("file is not scanned yet")
+	// ErrFileInfected returns if file content is requested for infected file
+	ErrFileInfected = errors.New("file is infected")
+)
+
+// checkFileScanStatus returns error if file content can't be served
+func checkFileScanStatus(fileInfo *model.UserFile) error {
+	switch fileInfo.ScanStatus {
+	case model.FileScanClean:
+		return nil
+	case model.FileScanInfected:
+		return ErrFileInfected
+	default:
+		return ErrFileNotScanned
+	}
+}
+
+// scanFileData checks decrypted file content with antivirus and saves result.
+// Infected file is moved to quarantine. If scanner fails, file stays pending and will be rescanned by ScanPendingFiles.
+func scanFileData(fileInfo *model.UserFile, data []byte) error {
+	result, err := scanner.Scan(bytes.NewReader(data))
+	if err != nil {
+		return errors.New("error while scan file: " + err.Error())
+	}
+
+	if !result.Infected {
+		return model.FileScanStatusUpdate(fileInfo.ID, model.FileScanClean, "")
+	}
+
+	log.Println("file ", fileInfo.ID, " of user ", fileInfo.UserID, " is infected: ", result.Signature)
+
+	err = moveFileWithAWS(objectKey(fileInfo), quarantinePrefix+storageKey(fileInfo))
+	if err != nil {
+		return errors.New("error while move infected file to quarantine: " + err.Error())
+	}
+
+	return model.FileScanStatusUpdate(fileInfo.ID, model.FileScanInfected, result.Signature)
+}
+
+// ScanPendingFiles scans stored files which were not scanned after upload (e.g. scanner was unavailable).
+// Files uploaded before checksums were saved get their size and checksum on scan.
+// Failed files stay pending for next scan and don't stop the batch. Returns count of scanned files.
+func ScanPendingFiles() (int, error) {
+	files, err := model.FilesByScanStatus(model.FileScanPending)
+	if err != nil {
+		log.Println("error while get pending files for scan: " + err.Error())
+		return 0, err
+	}
+
+	scanned, failed := 0, 0
+	for _, v := range files {
+		// Row is created before upload, recent file without checksum is still uploading
+		if v.Checksum == "" && time.Since(v.CreatedAt) < uploadGracePeriod {
+			continue
+		}
+
+		encrypted, err := downloadFileFromAWS(objectKey(v))
+		if err != nil {
+			log.Println("error while download pending file ", v.ID, ": ", err)
+			failed++
+			continue
+		}
+
+		data, err := decryptFileData(v, encrypted)
+		if err != nil {
+			log.Println("error while decrypt pending file ", v.ID, ": ", err)
+			failed++
+			continue
+		}
+
+		if v.Checksum == "" {
+			checksum := sha256.Sum256(data)
+			err = model.FileContentUpdate(v.ID, int64(len(data)), hex.EncodeToString(checksum[:]))
+			if err != nil {
+				log.Println("error while save size and checksum of pending file ", v.ID, ": ", err)
+				failed++
+				continue
+			}
+		}
+
+		err = scanFileData(v, data)
+		if err != nil {
+			log.Println("error while scan pending file ", v.ID, ": ", err)
+			failed++
+			continue
+		}
+
+		scanned++
+	}
+
+	if failed > 0 {
+		return scanned, errors.New("error while scan pending files: " + strconv.Itoa(failed) + " files failed and stay pending")
+	}
+
+	return scanned, nil
+}
+
+// StartFilesScanSheduler start gocron with hourly scan of pending files
+func StartFilesScanSheduler() {
+	log.Println("start pending files scan sheduler")
+
+	gocron.Every(1).Hour().Do(func() {
+		scanned, err := ScanPendingFiles()
+		if err != nil {
+			log.Println("error while scan pending files: " + err.Error())
+		}
+
+		log.Println("pending files scan: scanned=", scanned)
+	})
+}
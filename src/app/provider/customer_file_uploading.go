@@ -1,23 +1,38 @@
 package provider
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"mime/multipart"
+	"net/url"
 	"os"
 	"path/filepath"
-	"strings"
 
-	"app/constants"
 	"app/model"
+	"app/shared/filecategory"
 	fas "app/shared/files_id_storage"
+	"app/shared/keyring"
 	"app/webpojo"
 )
 
-// UploadFile create new file and copy data from multipart reuqest
+const (
+	fileDownloadPath = "/api/customer/file/download"
+
+	// defaultFileListLimit is used when file list request has no limit
+	defaultFileListLimit = 50
+	// maxFileListLimit is the biggest page of file list
+	maxFileListLimit = 500
+)
+
+// UploadFile encrypts multipart file with data key of file DB row and sends it to storage.
+// Size and checksum of original content are saved in file DB row, then content is checked with antivirus.
 // This func is testing in controller test, because it need multipart form data
-func UploadFile(file *multipart.File, fileHeader *multipart.FileHeader, fileID, userName string) error {
+func UploadFile(file *multipart.File, fileHeader *multipart.FileHeader, userID string, fileID int) error {
 	if file == nil {
 		return errors.New("error while upload file: multipart.File is nil")
 	}
@@ -26,19 +41,81 @@ func UploadFile(file *multipart.File, fileHeader *multipart.FileHeader, fileID,
 		return errors.New("error while upload file: multipart.FileHeader is nil")
 	}
 
-	if fileID == "" || fileID == "0" {
-		return errors.New("error while upload file: file id is empty or zero")
+	if fileID == 0 {
+		return errors.New("error while upload file: file id is zero")
+	}
+
+	fileInfo, err := model.FileByID(userID, fmt.Sprint(fileID))
+	if err != nil {
+		return errors.New("error while upload file: can't get file info: " + err.Error())
+	}
+
+	data, err := ioutil.ReadAll(*file)
+	if err != nil {
+		return errors.New("error while upload file: can't read file: " + err.Error())
+	}
+
+	encrypted, err := encryptFileData(fileInfo, data)
+	if err != nil {
+		return errors.New("error while upload file: " + err.Error())
 	}
 
-	response, err := uploadFileWithAWS(file, fileID, userName)
+	response, err := uploadFileWithAWS(bytes.NewReader(encrypted), storageKey(fileInfo))
 	if err != nil {
 		return errors.New("error while upload files to AWSS3: " + err.Error())
 	}
 
 	log.Println("AWSS3 upload response: " + response)
+
+	checksum := sha256.Sum256(data)
+	err = model.FileContentUpdate(fileInfo.ID, int64(len(data)), hex.EncodeToString(checksum[:]))
+	if err != nil {
+		return errors.New("error while save file size and checksum: " + err.Error())
+	}
+
+	// Upload is done even if scan fails: file stays pending and can't be downloaded until rescan
+	err = scanFileData(fileInfo, data)
+	if err != nil {
+		log.Println("error while scan uploaded file: " + err.Error())
+	}
+
 	return nil
 }
 
+// GetFileContent return decrypted user file content and file info.
+// Returns ErrFileNotScanned or ErrFileInfected if file is not marked clean by antivirus.
+func GetFileContent(userID string, fileID string) ([]byte, *model.UserFile, error) {
+	if fileID == "" {
+		return nil, nil, errors.New("error while get file content: fileID is empty")
+	}
+
+	if userID == "" || userID == "0" {
+		return nil, nil, errors.New("error while get file content: user id is empty or zero")
+	}
+
+	fileInfo, err := model.FileByID(userID, fileID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = checkFileScanStatus(fileInfo)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	encrypted, err := downloadFileFromAWS(objectKey(fileInfo))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data, err := decryptFileData(fileInfo, encrypted)
+	if err != nil {
+		return nil, nil, errors.New("error while get file content: " + err.Error())
+	}
+
+	return data, fileInfo, nil
+}
+
 // GetDecryptedFileFata return decrypted file content
 // Using in intrnal files which storing in /upload directory of the project
 func GetDecryptedFileFata(fileID, userID string) ([]byte, error) {
@@ -52,7 +129,7 @@ func GetDecryptedFileFata(fileID, userID string) ([]byte, error) {
 		return nil, errors.New("error while read ecnrypted file: " + err.Error())
 	}
 
-	data, err = fas.Decrypt(data, constants.FilesCryptoKey)
+	data, err = decryptFileData(file, data)
 	if err != nil {
 		return nil, errors.New("error while decrypt file data: " + err.Error())
 	}
@@ -60,8 +137,9 @@ func GetDecryptedFileFata(fileID, userID string) ([]byte, error) {
 	return data, nil
 }
 
-// SaveFileDataInDB create new DB row with file id, name etc
-func SaveFileDataInDB(fileName, fileType, userID string) (int, error) {
+// SaveFileDataInDB create new DB row with file id, name, metadata and new wrapped data key for file content.
+// Metadata should be validated by caller, nil metadata means default category without tags.
+func SaveFileDataInDB(fileName, fileType, userID string, meta *webpojo.UserFileMeta) (int, error) {
 	if fileName == "" {
 		return 0, errors.New("error while save file data in DB: file name is empty")
 	}
@@ -74,12 +152,35 @@ func SaveFileDataInDB(fileName, fileType, userID string) (int, error) {
 		return 0, errors.New("error while save file data in DB: user id is empty or zero")
 	}
 
-	lastID, err := model.FileCreate(fileName, fileType, userID)
+	if meta == nil {
+		meta = &webpojo.UserFileMeta{}
+	}
+
+	category := meta.Category
+	if category == "" {
+		category = filecategory.Default()
+	}
+
+	_, wrappedKey, err := keyring.GenerateDataKey()
+	if err != nil {
+		log.Println("error while generate file data key: " + err.Error())
+		return 0, err
+	}
+
+	lastID, err := model.FileCreate(fileName, fileType, userID, category, meta.Description, wrappedKey.Version, wrappedKey.Ciphertext)
 	if err != nil {
 		log.Println("error while add new file info to DB: " + err.Error())
 		return 0, errors.New("error while add new file info to DB: " + err.Error())
 	}
 
+	if len(meta.Tags) != 0 {
+		err = model.FileTagsReplace(uint32(lastID), meta.Tags)
+		if err != nil {
+			log.Println("error while add new file tags to DB: " + err.Error())
+			return 0, errors.New("error while add new file tags to DB: " + err.Error())
+		}
+	}
+
 	return lastID, nil
 }
 
@@ -97,21 +198,97 @@ func GetFileList(userID string, sessID string) ([]*webpojo.UserFile, error) {
 	var resultFilesList []*webpojo.UserFile
 
 	for _, v := range rawFileList {
-		link, err := getPresignLinkFromAWS(fmt.Sprint(v.ID) + filepath.Ext(v.FileName))
-		if err != nil {
-			log.Println("error while get presign link from AWSS3: " + err.Error())
-			return nil, err
-		}
+		resultFilesList = append(resultFilesList, userFilePojo(v, sessID))
+	}
 
-		// in some reason, app escaping  & and / symbols, so, should replase to righ state
-		link = strings.Replace(link, "\u0026", "&", -1)
-		link = strings.Replace(link, "%2F", "/", -1)
-		link = strings.Replace(link, "%3B", ";", -1)
+	return resultFilesList, nil
+}
 
-		resultFilesList = append(resultFilesList, &webpojo.UserFile{Link: link, FileName: v.FileName, FileID: fmt.Sprint(fas.GetNewFileID(int(v.ID), sessID))})
+// SearchFileList return one page of user files matched to request filter
+func SearchFileList(userID string, sessID string, req *webpojo.FileListReq) (*webpojo.FileListResp, error) {
+	if userID == "" || userID == "0" {
+		return nil, errors.New("error while search user's file list: user id empty or zero")
 	}
 
-	return resultFilesList, nil
+	if req == nil {
+		return nil, errors.New("error while search user's file list: request is nil")
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultFileListLimit
+	}
+
+	if limit > maxFileListLimit {
+		limit = maxFileListLimit
+	}
+
+	page, err := newPage(limit, req.Cursor)
+	if err != nil {
+		log.Println("error while search user files: " + err.Error())
+		return nil, err
+	}
+
+	filter := &model.FileFilter{
+		Category: req.Category,
+		Tag:      req.Tag,
+		Query:    req.Query,
+		SortBy:   req.Sort,
+		Desc:     req.Desc,
+		Page:     page,
+	}
+
+	rawFileList, total, more, err := model.FilesSearch(userID, filter)
+	if err != nil {
+		log.Println("error while search user files: " + err.Error())
+		return nil, err
+	}
+
+	resp := &webpojo.FileListResp{Files: []*webpojo.UserFile{}, Total: total, Limit: limit}
+	for _, v := range rawFileList {
+		resp.Files = append(resp.Files, userFilePojo(v, sessID))
+	}
+
+	resp.PageCursors = *pageCursors(page, len(rawFileList), more, func(i int) (uint32, string) {
+		return rawFileList[i].ID, rawFileList[i].SortKey(req.Sort)
+	})
+
+	return resp, nil
+}
+
+// UpdateFileMeta changes category, description and tags of user file, missing fields are kept unchanged.
+// Metadata should be validated by caller.
+func UpdateFileMeta(userID string, fileID string, meta *webpojo.UserFileMetaPatch) error {
+	if fileID == "" {
+		return errors.New("error while update file metadata: fileID is zero")
+	}
+
+	if userID == "" || userID == "0" {
+		return errors.New("error while update file metadata: user id is empty or zero")
+	}
+
+	if meta == nil {
+		return errors.New("error while update file metadata: metadata is nil")
+	}
+
+	category := meta.Category
+	if category != nil && *category == "" {
+		defaultCategory := filecategory.Default()
+		category = &defaultCategory
+	}
+
+	err := model.FileMetaUpdate(userID, fileID, category, meta.Description, meta.Tags)
+	if err != nil {
+		log.Println("error while update file metadata: " + err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// GetFileCategories return list of allowed document categories
+func GetFileCategories() []string {
+	return filecategory.List()
 }
 
 // GetFile return user file struct
@@ -129,26 +306,11 @@ func GetFile(userID string, fileID string, sessID string) (*webpojo.UserFile, er
 		return nil, err
 	}
 
-	var userFile *webpojo.UserFile
-
-	link, err := getPresignLinkFromAWS(fmt.Sprint(fileInfo.ID) + filepath.Ext(fileInfo.FileName))
-	if err != nil {
-		log.Println("error while get presign link from AWSS3: " + err.Error())
-		return nil, err
-	}
-
-	// in some reason, app escaping  & and / symbols, so, should replase to righ state
-	link = strings.Replace(link, "\u0026", "&", -1)
-	link = strings.Replace(link, "%2F", "/", -1)
-	link = strings.Replace(link, "%3B", ";", -1)
-
-	userFile = &webpojo.UserFile{Link: link, FileName: fileInfo.FileName, FileID: fas.GetNewFileID(int(fileInfo.ID), sessID)}
-
-	return userFile, nil
+	return userFilePojo(fileInfo, sessID), nil
 }
 
-// FileDelete delete user file from database (WARNING: but not from storage. Storage contain all users files
-// after delete for backup)
+// FileDelete moves user file to trash. File stays in storage and can be restored until
+// it is purged after retention period (see PurgeDeletedFiles)
 func FileDelete(userID string, fileID string) error {
 	if fileID == "" {
 		return errors.New("error while delete user's file: fileID is zero")
@@ -160,7 +322,7 @@ func FileDelete(userID string, fileID string) error {
 
 	err := model.FileDelete(userID, fileID)
 	if err != nil {
-		log.Println("error while delete user file from DB: " + err.Error())
+		log.Println("error while move user file to trash: " + err.Error())
 		return err
 	}
 
@@ -177,3 +339,154 @@ func PhisicalFileDelete(filePath string) error {
 
 	return nil
 }
+
+// RotateFileKeys rewraps data keys of all files with active master key.
+// File contents are not touched, so nothing is re-uploaded. Returns count of rewrapped keys.
+func RotateFileKeys() (int, error) {
+	activeVersion, err := keyring.ActiveVersion()
+	if err != nil {
+		return 0, err
+	}
+
+	files, err := model.FilesByKeyVersionNot(activeVersion)
+	if err != nil {
+		log.Println("error while get files for key rotation: " + err.Error())
+		return 0, err
+	}
+
+	rotated := 0
+	for _, v := range files {
+		wrappedKey, err := keyring.RewrapDataKey(&keyring.WrappedKey{Version: v.KeyVersion, Ciphertext: v.WrappedKey})
+		if err != nil {
+			log.Println("error while rewrap data key of file ", v.ID, ": ", err)
+			return rotated, err
+		}
+
+		err = model.FileKeyUpdate(v.ID, wrappedKey.Version, wrappedKey.Ciphertext)
+		if err != nil {
+			log.Println("error while save rewrapped data key of file ", v.ID, ": ", err)
+			return rotated, err
+		}
+
+		rotated++
+	}
+
+	return rotated, nil
+}
+
+// EncryptLegacyFiles encrypts content of files stored before encryption and saves their wrapped data keys.
+// Stored object is replaced first, file row last, so run it while files are not downloaded.
+// Returns count of encrypted files.
+func EncryptLegacyFiles() (int, error) {
+	files, err := model.FilesByKeyVersion(model.FileKeyLegacy)
+	if err != nil {
+		log.Println("error while get legacy files for encryption: " + err.Error())
+		return 0, err
+	}
+
+	encrypted := 0
+	for _, v := range files {
+		data, err := downloadFileFromAWS(objectKey(v))
+		if err != nil {
+			log.Println("error while download legacy file ", v.ID, ": ", err)
+			return encrypted, err
+		}
+
+		_, wrappedKey, err := keyring.GenerateDataKey()
+		if err != nil {
+			return encrypted, err
+		}
+
+		v.KeyVersion = wrappedKey.Version
+		v.WrappedKey = wrappedKey.Ciphertext
+		sealed, err := encryptFileData(v, data)
+		if err != nil {
+			log.Println("error while encrypt legacy file ", v.ID, ": ", err)
+			return encrypted, err
+		}
+
+		_, err = uploadFileWithAWS(bytes.NewReader(sealed), objectKey(v))
+		if err != nil {
+			log.Println("error while upload encrypted legacy file ", v.ID, ": ", err)
+			return encrypted, err
+		}
+
+		err = model.FileKeyUpdate(v.ID, wrappedKey.Version, wrappedKey.Ciphertext)
+		if err != nil {
+			log.Println("error while save data key of legacy file ", v.ID, ": ", err)
+			return encrypted, err
+		}
+
+		encrypted++
+	}
+
+	return encrypted, nil
+}
+
+// storageKey return file key (name) in storage. It's authenticated with file content, so it never changes.
+func storageKey(fileInfo *model.UserFile) string {
+	return fmt.Sprint(fileInfo.ID) + filepath.Ext(fileInfo.FileName)
+}
+
+// objectKey return current location of file in storage: infected files are moved to quarantine
+func objectKey(fileInfo *model.UserFile) string {
+	if fileInfo.ScanStatus == model.FileScanInfected {
+		return quarantinePrefix + storageKey(fileInfo)
+	}
+
+	return storageKey(fileInfo)
+}
+
+// userFilePojo converts file DB row to frontend representation with fake file ID
+func userFilePojo(fileInfo *model.UserFile, sessID string) *webpojo.UserFile {
+	fakeID := fas.GetNewFileID(int(fileInfo.ID), sessID)
+
+	userFile := &webpojo.UserFile{
+		Link:        downloadLink(fakeID),
+		FileName:    fileInfo.FileName,
+		FileID:      fakeID,
+		Category:    fileInfo.Category,
+		Description: fileInfo.Description,
+		Tags:        fileInfo.TagList(),
+		Size:        fileInfo.Size,
+		Checksum:    fileInfo.Checksum,
+		ScanStatus:  fileInfo.ScanStatus,
+		CreatedAt:   fileInfo.CreatedAt.String(),
+	}
+
+	if fileInfo.DeletedAt != nil {
+		userFile.DeletedAt = fileInfo.DeletedAt.String()
+	}
+
+	return userFile
+}
+
+// downloadLink return link to decrypting download handler
+func downloadLink(fakeID string) string {
+	return fileDownloadPath + "?id=" + url.QueryEscape(fakeID)
+}
+
+// encryptFileData seal file content with file data key. Storage key is authenticated too,
+// so encrypted content can't be swapped between files.
+func encryptFileData(fileInfo *model.UserFile, data []byte) ([]byte, error) {
+	dataKey, err := keyring.UnwrapDataKey(&keyring.WrappedKey{Version: fileInfo.KeyVersion, Ciphertext: fileInfo.WrappedKey})
+	if err != nil {
+		return nil, err
+	}
+
+	return keyring.Seal(data, dataKey, []byte(storageKey(fileInfo)))
+}
+
+// decryptFileData open file content sealed with encryptFileData. Content of legacy file is plain.
+func decryptFileData(fileInfo *model.UserFile, data []byte) ([]byte, error) {
+	if fileInfo.KeyVersion == model.FileKeyLegacy {
+		return data, nil
+	}
+
+	dataKey, err := keyring.UnwrapDataKey(&keyring.WrappedKey{Version: fileInfo.KeyVersion, Ciphertext: fileInfo.WrappedKey})
+	if err != nil {
+		return nil, err
+	}
+
+	return keyring.Open(data, dataKey, []byte(storageKey(fileInfo)))
+}
@@ -0,0 +1,53 @@
+package provider
+
+import (
+	"fmt"
+	"log"
+
+	"app/model"
+	"app/shared/events"
+	"app/webpojo"
+)
+
+// publishEvent pushes event to users connections. Delivery is best effort so failure is only logged.
+func publishEvent(userIDs []uint32, eventType string, data interface{}) {
+	err := events.Publish(userIDs, eventType, data)
+	if err != nil {
+		log.Println("error while publish "+eventType+" event: ", err)
+	}
+}
+
+// publishThreadEvent pushes event to all participants of thread
+func publishThreadEvent(threadID uint32, eventType string, data interface{}) {
+	userIDs, err := model.ThreadParticipantIDs(threadID)
+	if err != nil {
+		log.Println("error while get participants for "+eventType+" event: ", err)
+		return
+	}
+
+	publishEvent(userIDs, eventType, data)
+}
+
+// publishMessageEvent loads message as seen by user and pushes it to thread participants
+func publishMessageEvent(eventType string, userID, messageID uint32, newThread bool) {
+	message, err := model.MessageByID(fmt.Sprint(userID), fmt.Sprint(messageID))
+	if err != nil {
+		log.Println("error while load message for "+eventType+" event: ", err)
+		return
+	}
+
+	users, err := model.ThreadParticipantIDs(message.ThreadID)
+	if err != nil {
+		log.Println("error while get participants for "+eventType+" event: ", err)
+		return
+	}
+
+	publishEvent(users, eventType, messagePojo(message, messageAttachments(message.ID)))
+	publishEvent(users, events.TypeThreadUpdate, &webpojo.ThreadUpdateEvent{
+		ThreadID:       message.ThreadID,
+		MessageID:      message.ID,
+		NewThread:      newThread,
+		FromUserID:     message.FromUserID,
+		ParticipantIDs: users,
+	})
+}
@@ -0,0 +1,260 @@
+package provider
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"app/model"
+	"app/webpojo"
+
+	"github.com/jasonlvhit/gocron"
+)
+
+const (
+	// fastQuoteClaimDays is how long anonymous fast quote can be attached to customer
+	fastQuoteClaimDays = 30
+	// fastQuoteRetentionDays is how long anonymous not claimed fast quote is kept for reports
+	fastQuoteRetentionDays = 366
+
+	// defaultQuoteReportDays is length of fast quote report without first day
+	defaultQuoteReportDays = 30
+	// maxQuoteReportDays is max length of fast quote report
+	maxQuoteReportDays = 3 * 366
+)
+
+var (
+	// ErrFastQuoteNotExist returns if fast quote is not found or it is too old to be claimed
+	ErrFastQuoteNotExist = errors.New("fast quote not exist")
+	// ErrFastQuoteClaimed returns if fast quote is attached to other customer
+	ErrFastQuoteClaimed = errors.New("fast quote is attached to other customer")
+)
+
+// CreateFastQuote return fast quote and stores it with inputs and returned rates. Quote of signed in customer
+// (non empty user ID) is attached to customer, anonymous quote can be claimed later by its quote ID.
+func CreateFastQuote(userID string, fastQuoteReq *webpojo.FastQuoteReq) ([]*webpojo.FastQuoteResp, error) {
+	quotes, err := GetFastQuote(fastQuoteReq)
+	if err != nil {
+		return nil, err
+	}
+
+	var uintUserID uint32
+	if userID != "" {
+		uintUserID, err = parseUserID(userID)
+		if err != nil {
+			log.Println("error while save fast quote: ", err)
+			return nil, err
+		}
+	}
+
+	token, err := newToken()
+	if err != nil {
+		log.Println("error while create fast quote token: ", err)
+		return nil, err
+	}
+
+	for _, v := range quotes {
+		v.QuoteID = token
+	}
+
+	rates, err := json.Marshal(quotes)
+	if err != nil {
+		log.Println("error while save fast quote: ", err)
+		return nil, err
+	}
+
+	_, err = model.FastQuoteCreate(&model.FastQuote{
+		Token:      token,
+		UserID:     uintUserID,
+		LoanAmount: strconv.FormatFloat(fastQuoteReq.LoanAmount, 'f', 2, 64),
+		TermYears:  fastQuoteReq.TermYears,
+		CreditBand: strings.ToLower(strings.TrimSpace(fastQuoteReq.CreditBand)),
+		Product:    strings.TrimSpace(fastQuoteReq.Product),
+		Rates:      string(rates),
+	})
+	if err != nil {
+		log.Println("error while save fast quote: ", err)
+		return nil, err
+	}
+
+	return quotes, nil
+}
+
+// ClaimFastQuote attaches anonymous fast quote to customer, claiming own quote again does nothing
+func ClaimFastQuote(userID string, quoteID string) error {
+	uintUserID, err := parseUserID(userID)
+	if err != nil {
+		log.Println("error while claim fast quote: ", err)
+		return err
+	}
+
+	quoteID = strings.TrimSpace(quoteID)
+	if quoteID == "" {
+		return ErrFastQuoteNotExist
+	}
+
+	err = model.FastQuoteClaim(quoteID, uintUserID, time.Now().AddDate(0, 0, -fastQuoteClaimDays))
+	if err != model.ErrNoResult {
+		if err != nil {
+			log.Println("error while claim fast quote: ", err)
+		}
+
+		return err
+	}
+
+	// Quote is not anonymous, too old or not exist
+	quote, err := model.FastQuoteByToken(quoteID)
+	if err == model.ErrNoResult {
+		return ErrFastQuoteNotExist
+	}
+	if err != nil {
+		log.Println("error while claim fast quote: ", err)
+		return err
+	}
+
+	switch {
+	case quote.UserID == uintUserID:
+		return nil
+	case quote.ClaimedAt != nil:
+		return ErrFastQuoteClaimed
+	default:
+		return ErrFastQuoteNotExist
+	}
+}
+
+// GetFastQuotes return customer's fast quotes, newest first
+func GetFastQuotes(userID string) ([]webpojo.FastQuotePojo, error) {
+	uintUserID, err := parseUserID(userID)
+	if err != nil {
+		log.Println("error while get fast quotes: ", err)
+		return nil, err
+	}
+
+	quotes, err := model.FastQuotesByUserID(uintUserID)
+	if err != nil {
+		log.Println("error while get fast quotes: ", err)
+		return nil, err
+	}
+
+	res := []webpojo.FastQuotePojo{}
+	for i := range quotes {
+		quote, err := fastQuotePojo(&quotes[i])
+		if err != nil {
+			log.Println("error while convert fast quote ", quotes[i].ID, ": ", err)
+			return nil, err
+		}
+
+		res = append(res, *quote)
+	}
+
+	return res, nil
+}
+
+// fastQuotePojo convert fast quote from database to response
+func fastQuotePojo(quote *model.FastQuote) (*webpojo.FastQuotePojo, error) {
+	amount, err := strconv.ParseFloat(quote.LoanAmount, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &webpojo.FastQuotePojo{
+		QuoteID:    quote.Token,
+		LoanAmount: amount,
+		TermYears:  quote.TermYears,
+		CreditBand: quote.CreditBand,
+		Product:    quote.Product,
+		Rates:      []webpojo.FastQuoteResp{},
+		CreatedAt:  quote.CreatedAt.String(),
+	}
+
+	err = json.Unmarshal([]byte(quote.Rates), &res.Rates)
+	if err != nil {
+		return nil, err
+	}
+
+	if quote.ClaimedAt != nil {
+		res.ClaimedAt = quote.ClaimedAt.String()
+	}
+
+	return res, nil
+}
+
+// GetFastQuoteReport return fast quote volume and conversion of every day of date range (YYYY-MM-DD) which has quotes.
+// Range is last 30 days if dates are empty. Days older than fastQuoteRetentionDays count claimed quotes only.
+func GetFastQuoteReport(fromDate, toDate string) (*webpojo.FastQuoteReportResp, error) {
+	to := time.Now()
+	if toDate != "" {
+		var err error
+		if to, err = time.Parse(model.RateDateLayout, toDate); err != nil {
+			return nil, ErrWrongRateDate
+		}
+	}
+
+	from := to.AddDate(0, 0, -defaultQuoteReportDays)
+	if fromDate != "" {
+		var err error
+		if from, err = time.Parse(model.RateDateLayout, fromDate); err != nil {
+			return nil, ErrWrongRateDate
+		}
+	}
+
+	if to.Before(from) || to.Sub(from) > maxQuoteReportDays*24*time.Hour {
+		return nil, ErrWrongRateRange
+	}
+
+	res := &webpojo.FastQuoteReportResp{
+		From: from.Format(model.RateDateLayout),
+		To:   to.Format(model.RateDateLayout),
+		Days: []webpojo.FastQuoteReportDay{},
+	}
+
+	days, err := model.FastQuoteReport(res.From, res.To)
+	if err != nil {
+		log.Println("error while get fast quote report: ", err)
+		return nil, err
+	}
+
+	for _, v := range days {
+		res.Quotes += v.Quotes
+		res.Claimed += v.Claimed
+		res.Applied += v.Applied
+		res.Days = append(res.Days, webpojo.FastQuoteReportDay{Date: v.Day, Quotes: v.Quotes, Claimed: v.Claimed, Applied: v.Applied})
+	}
+
+	if res.Quotes > 0 {
+		res.ClaimRate = roundTo(float64(res.Claimed)*100/float64(res.Quotes), 2)
+		res.ApplyRate = roundTo(float64(res.Applied)*100/float64(res.Quotes), 2)
+	}
+
+	return res, nil
+}
+
+// PurgeFastQuotes removes anonymous fast quotes which were not claimed and are older than retention period.
+// Return number of removed quotes.
+func PurgeFastQuotes(retention time.Duration) (int, error) {
+	n, err := model.FastQuotesPurge(time.Now().Add(-retention))
+	if err != nil {
+		log.Println("error while purge fast quotes: ", err)
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// StartFastQuotesPurgeSheduler starts daily removal of old anonymous fast quotes
+func StartFastQuotesPurgeSheduler() {
+	log.Println("start fast quotes purge sheduler")
+
+	gocron.Every(1).Day().Do(func() {
+		purged, err := PurgeFastQuotes(fastQuoteRetentionDays * 24 * time.Hour)
+		if err != nil {
+			log.Println("error while purge fast quotes: " + err.Error())
+			return
+		}
+
+		log.Println("fast quotes purge: purged=", purged)
+	})
+}
@@ -0,0 +1,309 @@
+package provider
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"app/model"
+	"app/shared/cache"
+	"app/shared/cursor"
+	"app/webpojo"
+)
+
+const (
+	// defaultRateListCount is page size of all lender rates list when request has no count
+	defaultRateListCount = 100
+	// maxRateListCount is max page size of all lender rates list
+	maxRateListCount = 500
+)
+
+var (
+	// ErrWrongRateSort returns if rate list sort field is unknown
+	ErrWrongRateSort = errors.New("sort should be apr or interest")
+	// ErrWrongRateValidity returns if rate list validity is unknown
+	ErrWrongRateValidity = errors.New("validity should be effective, upcoming, expired or any")
+	// ErrWrongRateField returns if rate list bounded field is unknown
+	ErrWrongRateField = errors.New("rate_field should be apr or interest")
+	// ErrWrongRateBounds returns if rate list bounds are out of range or min bound is above max bound
+	ErrWrongRateBounds = errors.New("min_rate and max_rate should be between 0 and 100, min_rate up to max_rate")
+	// ErrWrongRateGroup returns if rate list grouping is unknown
+	ErrWrongRateGroup = errors.New("group_by should be product")
+)
+
+// SearchLenderRates return best rates or page of all rates matched to request filter, sorted and grouped as requested.
+// Rates list is of any validity unless validity is requested. Best rates of today and page of rates
+// effective today are selected in memory from cache, other lists from database.
+func SearchLenderRates(req *webpojo.LenderRateListReq) (*webpojo.LenderRateListResp, error) {
+	filter, err := lenderRateFilter(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var rates []model.LenderRate
+	var more bool
+	res := &webpojo.LenderRateListResp{}
+
+	switch {
+	case req.Criteria == "best":
+		// Best rates are effective on day, validity is not checked
+		var best []model.LenderRate
+		best, err = GetBestLenderRatesAsOf(filter.Day)
+		if err != nil {
+			return nil, err
+		}
+
+		rates = filterRates(best, filter)
+		if filter.SortBy != "" {
+			sortRates(rates, filter.SortBy, filter.Desc)
+		}
+	case cacheInUse && filter.Validity == "effective" && filter.Day == today():
+		var effective []model.LenderRate
+		effective, err = getEffectiveRatesList()
+		if err != nil {
+			return nil, err
+		}
+
+		rates = filterRates(effective, filter)
+		sortRates(rates, filter.SortBy, filter.Desc)
+		rates, more, err = pageRates(rates, filter)
+	default:
+		rates, more, err = model.LenderRatesSearch(filter)
+		if err != nil {
+			log.Println("error while search lender rates: ", err)
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Criteria != "best" {
+		res.PageCursors = *pageCursors(filter.Page, len(rates), more, func(i int) (uint32, string) {
+			return rates[i].ID, rates[i].SortKey(filter.SortBy)
+		})
+	}
+
+	pojos, err := LenderRatesPojo(rates)
+	if err != nil {
+		return nil, err
+	}
+
+	res.LenderRates = pojos
+	if req.GroupBy == "product" {
+		res.LenderRates = []webpojo.LenderRatePojo{}
+		res.Groups = groupRatesByProduct(pojos)
+	}
+
+	return res, nil
+}
+
+// lenderRateFilter validates rate list request and return its filter. Page is set for all rates list only.
+func lenderRateFilter(req *webpojo.LenderRateListReq) (*model.LenderRateFilter, error) {
+	filter := &model.LenderRateFilter{
+		Product:   strings.TrimSpace(req.Product),
+		LenderID:  req.LenderID,
+		SortBy:    req.Sort,
+		MinRate:   req.MinRate,
+		MaxRate:   req.MaxRate,
+		RateField: req.RateField,
+		Validity:  req.Validity,
+		Day:       req.AsOf,
+		Desc:      req.Desc,
+	}
+
+	if filter.Day == "" {
+		filter.Day = today()
+	} else if _, err := time.Parse(model.RateDateLayout, filter.Day); err != nil {
+		return nil, ErrWrongRateDate
+	}
+
+	switch req.Sort {
+	case "", "apr", "interest":
+	default:
+		return nil, ErrWrongRateSort
+	}
+
+	switch req.Validity {
+	case "", "any":
+		filter.Validity = ""
+	case "effective", "upcoming", "expired":
+	default:
+		return nil, ErrWrongRateValidity
+	}
+
+	switch req.RateField {
+	case "", "apr", "interest":
+	default:
+		return nil, ErrWrongRateField
+	}
+
+	if req.MinRate < 0 || req.MinRate >= 100 || req.MaxRate < 0 || req.MaxRate >= 100 ||
+		(req.MaxRate != 0 && req.MinRate > req.MaxRate) {
+		return nil, ErrWrongRateBounds
+	}
+
+	switch req.GroupBy {
+	case "", "product":
+	default:
+		return nil, ErrWrongRateGroup
+	}
+
+	if req.Criteria == "best" {
+		return filter, nil
+	}
+
+	count := req.Count
+	if count <= 0 {
+		count = defaultRateListCount
+	}
+
+	if count > maxRateListCount {
+		count = maxRateListCount
+	}
+
+	page, err := newPage(count, req.Cursor)
+	if err != nil {
+		log.Println("error while search lender rates: ", err)
+		return nil, err
+	}
+
+	filter.Page = page
+	return filter, nil
+}
+
+// getEffectiveRatesList return rates effective today from cache, loads them from database on cache miss
+func getEffectiveRatesList() ([]model.LenderRate, error) {
+	day := today()
+	if cached, ok := cache.Get(cache.EffectiveLenderRateCacheKey); ok {
+		if effective, ok := cached.(*dayRates); ok && effective.day == day {
+			return effective.rates, nil
+		}
+	}
+
+	rates, err := model.LenderRatesEffectiveAsOf(day)
+	if err != nil {
+		log.Println("error while get effective lender rates: ", err)
+		return nil, err
+	}
+
+	cache.Put(cache.EffectiveLenderRateCacheKey, &dayRates{day: day, rates: rates})
+	return rates, nil
+}
+
+// rateValue return apr, or interest if field is interest, of rate. Wrong value is zero.
+func rateValue(rate *model.LenderRate, field string) float64 {
+	value := rate.Apr
+	if field == "interest" {
+		value = rate.Interest
+	}
+
+	res, _ := strconv.ParseFloat(value, 64)
+	return res
+}
+
+// filterRates return copy of rates matched to product, lender and rate bounds of filter, validity is not checked
+func filterRates(rates []model.LenderRate, filter *model.LenderRateFilter) []model.LenderRate {
+	res := []model.LenderRate{}
+	for i := range rates {
+		rate := &rates[i]
+		value := rateValue(rate, filter.RateField)
+
+		if (filter.Product != "" && rate.Product != filter.Product) ||
+			(filter.LenderID != 0 && rate.LenderID != filter.LenderID) ||
+			(filter.MinRate != 0 && value < filter.MinRate) ||
+			(filter.MaxRate != 0 && value > filter.MaxRate) {
+			continue
+		}
+
+		res = append(res, *rate)
+	}
+
+	return res
+}
+
+// sortRates sorts rates by sortBy value and ID in the same order as database list
+func sortRates(rates []model.LenderRate, sortBy string, desc bool) {
+	sort.SliceStable(rates, func(i, j int) bool {
+		return rateBefore(rateValue(&rates[i], sortBy), rates[i].ID, rateValue(&rates[j], sortBy), rates[j].ID, sortBy, desc)
+	})
+}
+
+// rateBefore tells rate with value and ID goes before other rate in list sorted by sortBy
+func rateBefore(value float64, id uint32, otherValue float64, otherID uint32, sortBy string, desc bool) bool {
+	if sortBy != "" && value != otherValue {
+		return (value < otherValue) != desc
+	}
+
+	return (id < otherID) != desc
+}
+
+// pageRates return page of sorted rates at filter page position and are there more rates in page direction.
+// Return cursor.ErrInvalid if cursor key is not a rate.
+func pageRates(rates []model.LenderRate, filter *model.LenderRateFilter) ([]model.LenderRate, bool, error) {
+	page := filter.Page
+	if page.Cursor == nil {
+		if len(rates) > page.Limit {
+			return rates[:page.Limit], true, nil
+		}
+
+		return rates, false, nil
+	}
+
+	var key float64
+	if filter.SortBy != "" {
+		var err error
+		if key, err = strconv.ParseFloat(page.Cursor.Key, 64); err != nil {
+			return nil, false, cursor.ErrInvalid
+		}
+	}
+
+	if page.Cursor.Before {
+		// Rates before position go before cursor row in list order
+		pos := sort.Search(len(rates), func(i int) bool {
+			return !rateBefore(rateValue(&rates[i], filter.SortBy), rates[i].ID, key, page.Cursor.ID, filter.SortBy, filter.Desc)
+		})
+
+		from := pos - page.Limit
+		if from < 0 {
+			from = 0
+		}
+
+		return rates[from:pos], from > 0, nil
+	}
+
+	// Rates from position go after cursor row in list order
+	pos := sort.Search(len(rates), func(i int) bool {
+		return rateBefore(key, page.Cursor.ID, rateValue(&rates[i], filter.SortBy), rates[i].ID, filter.SortBy, filter.Desc)
+	})
+
+	to := pos + page.Limit
+	if to > len(rates) {
+		to = len(rates)
+	}
+
+	return rates[pos:to], to < len(rates), nil
+}
+
+// groupRatesByProduct puts rates to groups of product ordered by product, rates keep list order
+func groupRatesByProduct(rates []webpojo.LenderRatePojo) []webpojo.LenderRateGroup {
+	res := []webpojo.LenderRateGroup{}
+	index := map[string]int{}
+
+	for _, v := range rates {
+		i, ok := index[v.Product]
+		if !ok {
+			i = len(res)
+			index[v.Product] = i
+			res = append(res, webpojo.LenderRateGroup{Product: v.Product, LenderRates: []webpojo.LenderRatePojo{}})
+		}
+
+		res[i].LenderRates = append(res[i].LenderRates, v)
+	}
+
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Product < res[j].Product })
+	return res
+}
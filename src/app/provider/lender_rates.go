@@ -0,0 +1,306 @@
+package provider
+
+import (
+	"errors"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"app/model"
+	"app/shared/cache"
+	"app/webpojo"
+)
+
+const (
+	// conformingLoanLimit is max loan amount without jumbo loan adjustment
+	conformingLoanLimit = 484350
+	// jumboLoanAdjustment is added to rate of loan above conforming limit
+	jumboLoanAdjustment = 0.25
+
+	// defaultRateHistoryDays is length of rate history without first day
+	defaultRateHistoryDays = 90
+	// maxRateHistoryDays is max length of rate history
+	maxRateHistoryDays = 3 * 366
+)
+
+// creditBandAdjustments contains percents added to rate for borrower's credit band
+var creditBandAdjustments = map[string]float64{
+	"excellent": 0,
+	"good":      0.125,
+	"fair":      0.5,
+	"poor":      1,
+}
+
+var (
+	// ErrWrongLoanAmount returns if loan amount is not positive or is above maxLoanAmount
+	ErrWrongLoanAmount = errors.New("loan amount should be positive and at most 100000000")
+	// ErrWrongCreditBand returns if fast quote credit band is unknown
+	ErrWrongCreditBand = errors.New("credit band should be excellent, good, fair or poor")
+	// ErrNoQuote returns if there is no rate for fast quote product and term
+	ErrNoQuote = errors.New("no rates for requested product and term")
+	// ErrWrongRateDate returns if rate date is not YYYY-MM-DD
+	ErrWrongRateDate = errors.New("date should be YYYY-MM-DD")
+	// ErrWrongRateRange returns if rate history starts after its end or is too long
+	ErrWrongRateRange = errors.New("from should be before to and range should be up to 3 years")
+	// ErrWrongRateProduct returns if rate history product is empty
+	ErrWrongRateProduct = errors.New("product is empty")
+)
+
+// dayRates is rates of day kept in cache, they are reloaded on the next day
+type dayRates struct {
+	day   string
+	rates []model.LenderRate
+}
+
+// GetBestLenderRatesList return best rate of every product effective today from cache,
+// loads it from database on cache miss
+func GetBestLenderRatesList() ([]model.LenderRate, error) {
+	if cacheInUse {
+		if cached, ok := cache.Get(cache.BestLenderRateCacheKey); ok {
+			if best, ok := cached.(*dayRates); ok && best.day == today() {
+				return best.rates, nil
+			}
+		}
+	}
+
+	return getBestRatesListFromDB()
+}
+
+// getBestRatesListFromDB loads best rate of every product effective today from database and puts it to cache.
+// Rates effective today are dropped from cache to be reloaded on the next use.
+func getBestRatesListFromDB() ([]model.LenderRate, error) {
+	day := today()
+	if cacheInUse {
+		cache.Put(cache.EffectiveLenderRateCacheKey, nil)
+	}
+
+	rates, err := model.LenderRatesListBestAsOf(day)
+	if err != nil {
+		log.Println("error while get best lender rates: ", err)
+		return nil, err
+	}
+
+	if cacheInUse {
+		cache.Put(cache.BestLenderRateCacheKey, &dayRates{day: day, rates: rates})
+	}
+
+	return rates, nil
+}
+
+// GetBestLenderRatesAsOf return best rate of every product effective on day (YYYY-MM-DD), empty day is today
+func GetBestLenderRatesAsOf(day string) ([]model.LenderRate, error) {
+	if day == "" || day == today() {
+		return GetBestLenderRatesList()
+	}
+
+	if _, err := time.Parse(model.RateDateLayout, day); err != nil {
+		return nil, ErrWrongRateDate
+	}
+
+	rates, err := model.LenderRatesListBestAsOf(day)
+	if err != nil {
+		log.Println("error while get best lender rates as of "+day+": ", err)
+		return nil, err
+	}
+
+	return rates, nil
+}
+
+// GetLenderRateHistory return best rate of product for every day of date range, days without effective
+// rates are skipped. Range is last 90 days if dates are empty.
+func GetLenderRateHistory(req *webpojo.LenderRateHistoryReq) (*webpojo.LenderRateHistoryResp, error) {
+	product := strings.TrimSpace(req.Product)
+	if product == "" {
+		return nil, ErrWrongRateProduct
+	}
+
+	to := time.Now()
+	if req.To != "" {
+		var err error
+		if to, err = time.Parse(model.RateDateLayout, req.To); err != nil {
+			return nil, ErrWrongRateDate
+		}
+	}
+
+	from := to.AddDate(0, 0, -defaultRateHistoryDays)
+	if req.From != "" {
+		var err error
+		if from, err = time.Parse(model.RateDateLayout, req.From); err != nil {
+			return nil, ErrWrongRateDate
+		}
+	}
+
+	if to.Before(from) || to.Sub(from) > maxRateHistoryDays*24*time.Hour {
+		return nil, ErrWrongRateRange
+	}
+
+	res := &webpojo.LenderRateHistoryResp{
+		Product: product,
+		From:    from.Format(model.RateDateLayout),
+		To:      to.Format(model.RateDateLayout),
+		History: []webpojo.LenderRateHistoryPoint{},
+	}
+
+	rates, err := model.LenderRatesByProductBetween(product, res.From, res.To)
+	if err != nil {
+		log.Println("error while get lender rate history: ", err)
+		return nil, err
+	}
+
+	pojos, err := LenderRatesPojo(rates)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rates are ordered from best to worst, so the first rate effective on day is the best one
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		day := d.Format(model.RateDateLayout)
+		for _, v := range pojos {
+			if v.BeginDate <= day && v.EndDate >= day {
+				res.History = append(res.History, webpojo.LenderRateHistoryPoint{Date: day, LenderRatePojo: v})
+				break
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// today return current date in rate dates layout
+func today() string {
+	return time.Now().Format(model.RateDateLayout)
+}
+
+// LenderRatesPojo convert lender rates from database to response
+func LenderRatesPojo(rates []model.LenderRate) ([]webpojo.LenderRatePojo, error) {
+	res := []webpojo.LenderRatePojo{}
+	for i := range rates {
+		rate, err := lenderRatePojo(&rates[i])
+		if err != nil {
+			log.Println("error while convert lender rate ", rates[i].ID, ": ", err)
+			return nil, err
+		}
+
+		res = append(res, *rate)
+	}
+
+	return res, nil
+}
+
+// lenderRatePojo convert lender rate from database to response
+func lenderRatePojo(rate *model.LenderRate) (*webpojo.LenderRatePojo, error) {
+	interest, err := strconv.ParseFloat(rate.Interest, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	apr, err := strconv.ParseFloat(rate.Apr, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &webpojo.LenderRatePojo{
+		ID:         rate.ID,
+		LenderID:   rate.LenderID,
+		LenderName: rate.LenderName,
+		Product:    rate.Product,
+		TermYears:  rate.TermYears,
+		Interest:   interest,
+		Apr:        apr,
+		BeginDate:  rate.BeginDate,
+		EndDate:    rate.EndDate,
+	}, nil
+}
+
+// maxLoanAmount is max loan amount of quotes, calculator and loan applications, so loan in cents fits int64
+const maxLoanAmount = 100000000
+
+// checkLoanAmount return ErrWrongLoanAmount if loan amount is not positive or is above maxLoanAmount
+func checkLoanAmount(amount float64) error {
+	if amount <= 0 || amount > maxLoanAmount || math.IsNaN(amount) {
+		return ErrWrongLoanAmount
+	}
+
+	return nil
+}
+
+// GetFastQuote return best rates of products matched to requested product and term,
+// adjusted for loan amount and credit band, with monthly payment of requested loan
+func GetFastQuote(fastQuoteReq *webpojo.FastQuoteReq) ([]*webpojo.FastQuoteResp, error) {
+	if err := checkLoanAmount(fastQuoteReq.LoanAmount); err != nil {
+		return nil, err
+	}
+
+	creditBand := strings.ToLower(strings.TrimSpace(fastQuoteReq.CreditBand))
+	adjustment, ok := creditBandAdjustments[creditBand]
+	if !ok {
+		return nil, ErrWrongCreditBand
+	}
+
+	if fastQuoteReq.LoanAmount > conformingLoanLimit {
+		adjustment += jumboLoanAdjustment
+	}
+
+	rates, err := GetBestLenderRatesList()
+	if err != nil {
+		return nil, err
+	}
+
+	product := strings.TrimSpace(fastQuoteReq.Product)
+
+	res := []*webpojo.FastQuoteResp{}
+	for i := range rates {
+		if product != "" && !strings.EqualFold(rates[i].Product, product) {
+			continue
+		}
+
+		if fastQuoteReq.TermYears != 0 && rates[i].TermYears != fastQuoteReq.TermYears {
+			continue
+		}
+
+		rate, err := lenderRatePojo(&rates[i])
+		if err != nil {
+			log.Println("error while get fast quote: bad rate ", rates[i].ID, ": ", err)
+			return nil, err
+		}
+
+		rate.Interest = roundTo(rate.Interest+adjustment, 3)
+		rate.Apr = roundTo(rate.Apr+adjustment, 3)
+
+		res = append(res, &webpojo.FastQuoteResp{
+			LenderRatePojo: *rate,
+			CreditBand:     creditBand,
+			LoanAmount:     fastQuoteReq.LoanAmount,
+			MonthlyPayment: monthlyPayment(fastQuoteReq.LoanAmount, rate.Interest, rate.TermYears),
+		})
+	}
+
+	if len(res) == 0 {
+		return nil, ErrNoQuote
+	}
+
+	return res, nil
+}
+
+// monthlyPayment return monthly principal and interest payment of fully amortized loan, rounded to cents
+func monthlyPayment(amount, interest float64, termYears int) float64 {
+	months := float64(termYears * 12)
+	if months == 0 {
+		return 0
+	}
+
+	r := interest / 100 / 12
+	if r == 0 {
+		return roundTo(amount/months, 2)
+	}
+
+	return roundTo(amount*r/(1-math.Pow(1+r, -months)), 2)
+}
+
+// roundTo rounds value to number of decimal places
+func roundTo(value float64, places int) float64 {
+	p := math.Pow(10, float64(places))
+	return math.Floor(value*p+0.5) / p
+}
@@ -0,0 +1,273 @@
+package provider
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"app/model"
+	"app/shared/cache"
+	"app/webpojo"
+)
+
+const (
+	maxLenderProductLen = 50
+	maxLenderTermYears  = 40
+)
+
+var (
+	// ErrLenderNotExist returns if lender not found by ID
+	ErrLenderNotExist = errors.New("lender not exist")
+	// ErrLenderNameTaken returns if other lender has the same name
+	ErrLenderNameTaken = errors.New("lender with this name already exists")
+	// ErrLenderInUse returns if lender to delete has loan applications
+	ErrLenderInUse = errors.New("lender has loan applications")
+	// ErrLenderRateNotExist returns if lender rate not found by ID
+	ErrLenderRateNotExist = errors.New("lender rate not exist")
+)
+
+// refreshBestRates reloads best rates cache after lender or rate change.
+// Cache is cleared if reload fails, so next request reads rates from database.
+func refreshBestRates() {
+	err := UpdateCache("update_best_rates")
+	if err != nil {
+		log.Println("error while refresh best rates cache: ", err)
+		cache.Put(cache.BestLenderRateCacheKey, nil)
+	}
+}
+
+// CheckLenderRate trims rate product and return text of rate validation error, empty text if rate is valid
+func CheckLenderRate(rate *webpojo.LenderRatePojo) string {
+	rate.Product = strings.TrimSpace(rate.Product)
+	if rate.LenderID == 0 {
+		return "lender_id is empty"
+	}
+
+	if rate.Product == "" {
+		return "product is empty"
+	}
+
+	if len(rate.Product) > maxLenderProductLen {
+		return "product is too long"
+	}
+
+	if rate.TermYears < 1 || rate.TermYears > maxLenderTermYears {
+		return "term_years should be from 1 to 40"
+	}
+
+	if rate.Interest <= 0 || rate.Interest >= 100 {
+		return "interest should be between 0 and 100"
+	}
+
+	if rate.Apr < rate.Interest || rate.Apr >= 100 {
+		return "apr should be between interest and 100"
+	}
+
+	begin, err := time.Parse(model.RateDateLayout, rate.BeginDate)
+	if err != nil {
+		return "begin_date should be YYYY-MM-DD"
+	}
+
+	end, err := time.Parse(model.RateDateLayout, rate.EndDate)
+	if err != nil {
+		return "end_date should be YYYY-MM-DD"
+	}
+
+	if end.Before(begin) {
+		return "end_date is before begin_date"
+	}
+
+	return ""
+}
+
+// lenderPojo convert lender from database to response
+func lenderPojo(lender *model.Lender) webpojo.LenderPojo {
+	return webpojo.LenderPojo{
+		ID:          lender.ID,
+		Name:        lender.Name,
+		Description: lender.Description,
+		Address:     lender.Address,
+		Email:       lender.Email,
+		Phone:       lender.Phone,
+		Contact:     lender.Contact,
+		StatusID:    lender.StatusID,
+		CreatedAt:   lender.CreatedAt.String(),
+	}
+}
+
+// lenderModel convert lender from request to database model
+func lenderModel(req *webpojo.LenderPojo) *model.Lender {
+	return &model.Lender{
+		ID:          req.ID,
+		Name:        req.Name,
+		Description: req.Description,
+		Address:     req.Address,
+		Email:       req.Email,
+		Phone:       req.Phone,
+		Contact:     req.Contact,
+		StatusID:    req.StatusID,
+	}
+}
+
+// lenderRateModel convert lender rate from request to database model
+func lenderRateModel(req *webpojo.LenderRatePojo) *model.LenderRate {
+	return &model.LenderRate{
+		ID:        req.ID,
+		LenderID:  req.LenderID,
+		Product:   req.Product,
+		TermYears: req.TermYears,
+		Interest:  strconv.FormatFloat(req.Interest, 'f', 3, 64),
+		Apr:       strconv.FormatFloat(req.Apr, 'f', 3, 64),
+		BeginDate: req.BeginDate,
+		EndDate:   req.EndDate,
+	}
+}
+
+// GetLenders return all lenders ordered by name
+func GetLenders() ([]webpojo.LenderPojo, error) {
+	lenders, err := model.LendersListAll()
+	if err != nil {
+		log.Println("error while get lenders: ", err)
+		return nil, err
+	}
+
+	res := []webpojo.LenderPojo{}
+	for i := range lenders {
+		res = append(res, lenderPojo(&lenders[i]))
+	}
+
+	return res, nil
+}
+
+// CreateLender adds a new lender and return its ID
+func CreateLender(req *webpojo.LenderPojo) (uint32, error) {
+	id, err := model.LenderCreate(lenderModel(req))
+	if err == model.ErrDuplicateEntry {
+		return 0, ErrLenderNameTaken
+	}
+	if err != nil {
+		log.Println("error while create lender: ", err)
+		return 0, err
+	}
+
+	refreshBestRates()
+	return id, nil
+}
+
+// UpdateLender changes lender found by ID
+func UpdateLender(req *webpojo.LenderPojo) error {
+	_, err := model.LenderByID(req.ID)
+	if err == model.ErrNoResult {
+		return ErrLenderNotExist
+	}
+	if err != nil {
+		log.Println("error while update lender: ", err)
+		return err
+	}
+
+	err = model.LenderUpdate(lenderModel(req))
+	if err == model.ErrDuplicateEntry {
+		return ErrLenderNameTaken
+	}
+	if err != nil {
+		log.Println("error while update lender: ", err)
+		return err
+	}
+
+	refreshBestRates()
+	return nil
+}
+
+// DeleteLender removes lender with all its rates. Lender with loan applications is kept, ErrLenderInUse returns.
+func DeleteLender(id uint32) error {
+	err := model.LenderDelete(id)
+	if err == model.ErrNoResult {
+		return ErrLenderNotExist
+	}
+	if err == model.ErrRowReferenced {
+		return ErrLenderInUse
+	}
+	if err != nil {
+		log.Println("error while delete lender: ", err)
+		return err
+	}
+
+	refreshBestRates()
+	return nil
+}
+
+// GetLenderRates return all rates of lender
+func GetLenderRates(lenderID uint32) ([]webpojo.LenderRatePojo, error) {
+	_, err := model.LenderByID(lenderID)
+	if err == model.ErrNoResult {
+		return nil, ErrLenderNotExist
+	}
+	if err != nil {
+		log.Println("error while get lender rates: ", err)
+		return nil, err
+	}
+
+	rates, err := model.LenderRatesByLenderID(lenderID)
+	if err != nil {
+		log.Println("error while get lender rates: ", err)
+		return nil, err
+	}
+
+	return LenderRatesPojo(rates)
+}
+
+// CreateLenderRate adds a new rate to lender's rate sheet and return its ID
+func CreateLenderRate(req *webpojo.LenderRatePojo) (uint32, error) {
+	id, err := model.LenderRateCreate(lenderRateModel(req))
+	if err == model.ErrConstraintFails {
+		return 0, ErrLenderNotExist
+	}
+	if err != nil {
+		log.Println("error while create lender rate: ", err)
+		return 0, err
+	}
+
+	refreshBestRates()
+	return id, nil
+}
+
+// UpdateLenderRate changes lender rate found by ID
+func UpdateLenderRate(req *webpojo.LenderRatePojo) error {
+	_, err := model.LenderRateByID(req.ID)
+	if err == model.ErrNoResult {
+		return ErrLenderRateNotExist
+	}
+	if err != nil {
+		log.Println("error while update lender rate: ", err)
+		return err
+	}
+
+	err = model.LenderRateUpdate(lenderRateModel(req))
+	if err == model.ErrConstraintFails {
+		return ErrLenderNotExist
+	}
+	if err != nil {
+		log.Println("error while update lender rate: ", err)
+		return err
+	}
+
+	refreshBestRates()
+	return nil
+}
+
+// DeleteLenderRate removes rate from lender's rate sheet
+func DeleteLenderRate(id uint32) error {
+	err := model.LenderRateDelete(id)
+	if err == model.ErrNoResult {
+		return ErrLenderRateNotExist
+	}
+	if err != nil {
+		log.Println("error while delete lender rate: ", err)
+		return err
+	}
+
+	refreshBestRates()
+	return nil
+}
@@ -0,0 +1,408 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	"app/constants"
+	"app/model"
+	"app/webpojo"
+)
+
+const (
+	// maxLoanDocuments is the biggest number of files attached to loan application
+	maxLoanDocuments = 50
+	// maxLoanNoteLen is max length of state change note
+	maxLoanNoteLen = 1024
+)
+
+var (
+	// ErrLoanApplicationNotExist returns if loan application is not found or caller can't see it
+	ErrLoanApplicationNotExist = errors.New("loan application not exist")
+	// ErrWrongLoanState returns if loan application state is unknown
+	ErrWrongLoanState = errors.New("state should be draft, submitted, in_review, approved, declined or funded")
+	// ErrWrongLoanTransition returns if application can't move to state from its current state by caller
+	ErrWrongLoanTransition = errors.New("application can't move to requested state")
+	// ErrLenderRateNotEffective returns if chosen lender rate is expired or its lender is inactive
+	ErrLenderRateNotEffective = errors.New("lender rate is not effective")
+	// ErrLoanNoteTooLong returns if state change note is longer than maxLoanNoteLen
+	ErrLoanNoteTooLong = errors.New("note is too long")
+)
+
+// loanTransitions contains allowed moves between application states, value tells if customer makes the move.
+// Customer submits draft or withdraws submitted application back to draft, staff makes the rest of moves.
+var loanTransitions = map[string]map[string]bool{
+	model.LoanDraft:     {model.LoanSubmitted: true},
+	model.LoanSubmitted: {model.LoanDraft: true, model.LoanInReview: false},
+	model.LoanInReview:  {model.LoanApproved: false, model.LoanDeclined: false},
+	model.LoanApproved:  {model.LoanFunded: false},
+}
+
+// loanStates contains all application states
+var loanStates = map[string]bool{
+	model.LoanDraft:     true,
+	model.LoanSubmitted: true,
+	model.LoanInReview:  true,
+	model.LoanApproved:  true,
+	model.LoanDeclined:  true,
+	model.LoanFunded:    true,
+}
+
+// loanApplicationPojo convert loan application from database to response
+func loanApplicationPojo(app *model.LoanApplication) (*webpojo.LoanApplicationPojo, error) {
+	interest, err := strconv.ParseFloat(app.Interest, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	apr, err := strconv.ParseFloat(app.Apr, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	amount, err := strconv.ParseFloat(app.LoanAmount, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &webpojo.LoanApplicationPojo{
+		ID:             app.ID,
+		CustomerID:     app.CustomerID,
+		StaffID:        app.StaffID,
+		LenderRateID:   app.LenderRateID,
+		LenderID:       app.LenderID,
+		LenderName:     app.LenderName,
+		Product:        app.Product,
+		TermYears:      app.TermYears,
+		Interest:       interest,
+		Apr:            apr,
+		LoanAmount:     amount,
+		MonthlyPayment: monthlyPayment(amount, interest, app.TermYears),
+		State:          app.State,
+		CreatedAt:      app.CreatedAt.String(),
+		UpdatedAt:      app.UpdatedAt.String(),
+	}
+
+	if app.SubmittedAt != nil {
+		res.SubmittedAt = app.SubmittedAt.String()
+	}
+
+	return res, nil
+}
+
+// loanApplicationModel return draft application of customer for request, chosen rate should be effective today
+func loanApplicationModel(customerID uint32, req *webpojo.LoanApplicationReq) (*model.LoanApplication, error) {
+	if err := checkLoanAmount(req.LoanAmount); err != nil {
+		return nil, err
+	}
+
+	rate, err := model.LenderRateByID(req.LenderRateID)
+	if err == model.ErrNoResult {
+		return nil, ErrLenderRateNotExist
+	}
+	if err != nil {
+		log.Println("error while get loan application rate: ", err)
+		return nil, err
+	}
+
+	day := today()
+	if rate.BeginDate > day || rate.EndDate < day {
+		return nil, ErrLenderRateNotEffective
+	}
+
+	lender, err := model.LenderByID(rate.LenderID)
+	if err != nil {
+		log.Println("error while get loan application lender: ", err)
+		return nil, err
+	}
+
+	if lender.StatusID != model.LenderActive {
+		return nil, ErrLenderRateNotEffective
+	}
+
+	return &model.LoanApplication{
+		ID:           req.ID,
+		CustomerID:   customerID,
+		LenderRateID: rate.ID,
+		LenderID:     rate.LenderID,
+		Product:      rate.Product,
+		TermYears:    rate.TermYears,
+		Interest:     rate.Interest,
+		Apr:          rate.Apr,
+		LoanAmount:   strconv.FormatFloat(req.LoanAmount, 'f', 2, 64),
+	}, nil
+}
+
+// CreateLoanApplication creates customer's draft application at lender rate with attached customer's files,
+// return application ID
+func CreateLoanApplication(userID string, req *webpojo.LoanApplicationReq) (uint32, error) {
+	customerID, err := parseUserID(userID)
+	if err != nil {
+		log.Println("error while create loan application: ", err)
+		return 0, err
+	}
+
+	app, err := loanApplicationModel(customerID, req)
+	if err != nil {
+		return 0, err
+	}
+
+	fileIDs, err := checkOwnFiles(customerID, req.FileIDs, maxLoanDocuments)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := model.LoanApplicationCreate(app, fileIDs, constants.CustomerRole)
+	if err != nil {
+		log.Println("error while create loan application: ", err)
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// UpdateLoanApplication changes rate, loan amount and files of customer's draft application
+func UpdateLoanApplication(userID string, req *webpojo.LoanApplicationReq) error {
+	customerID, err := parseUserID(userID)
+	if err != nil {
+		log.Println("error while update loan application: ", err)
+		return err
+	}
+
+	current, err := model.LoanApplicationByID(req.ID)
+	if err == model.ErrNoResult || (err == nil && current.CustomerID != customerID) {
+		return ErrLoanApplicationNotExist
+	}
+	if err != nil {
+		log.Println("error while update loan application: ", err)
+		return err
+	}
+
+	if current.State != model.LoanDraft {
+		return ErrWrongLoanTransition
+	}
+
+	app, err := loanApplicationModel(customerID, req)
+	if err != nil {
+		return err
+	}
+
+	fileIDs, err := checkOwnFiles(customerID, req.FileIDs, maxLoanDocuments)
+	if err != nil {
+		return err
+	}
+
+	err = model.LoanApplicationUpdate(app, fileIDs)
+	if err == model.ErrNoResult {
+		// Application is submitted after it was read
+		return ErrWrongLoanTransition
+	}
+	if err != nil {
+		log.Println("error while update loan application: ", err)
+		return err
+	}
+
+	return nil
+}
+
+// checkLoanApplicationAccess return application if user can see it: customers see their own applications,
+// staff sees applications of assigned customers, supervisors and admins see any application
+func checkLoanApplicationAccess(id, userID uint32, role int) (*model.LoanApplication, error) {
+	app, err := model.LoanApplicationByID(id)
+	if err == model.ErrNoResult {
+		return nil, ErrLoanApplicationNotExist
+	}
+	if err != nil {
+		log.Println("error while get loan application: ", err)
+		return nil, err
+	}
+
+	switch role {
+	case constants.SupervisorRole, constants.AdminRole:
+		return app, nil
+	case constants.StaffRole:
+		assigned, err := model.IsStaffAssigned(fmt.Sprint(userID), fmt.Sprint(app.CustomerID))
+		if err != nil {
+			log.Println("error while check staff assignment: ", err)
+			return nil, err
+		}
+
+		if !assigned {
+			return nil, model.ErrUnauthorized
+		}
+
+		return app, nil
+	default:
+		if app.CustomerID != userID {
+			return nil, ErrLoanApplicationNotExist
+		}
+
+		return app, nil
+	}
+}
+
+// GetLoanApplication return loan application with its documents and state history
+func GetLoanApplication(userID string, role int, id uint32) (*webpojo.LoanApplicationPojo, error) {
+	uintUserID, err := parseUserID(userID)
+	if err != nil {
+		log.Println("error while get loan application: ", err)
+		return nil, err
+	}
+
+	app, err := checkLoanApplicationAccess(id, uintUserID, role)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := loanApplicationPojo(app)
+	if err != nil {
+		log.Println("error while convert loan application ", app.ID, ": ", err)
+		return nil, err
+	}
+
+	documents, err := model.LoanApplicationDocuments(id)
+	if err != nil {
+		log.Println("error while get loan application documents: ", err)
+		return nil, err
+	}
+
+	res.Documents = []webpojo.LoanApplicationDocument{}
+	for _, v := range documents {
+		res.Documents = append(res.Documents, webpojo.LoanApplicationDocument{
+			FileID:     v.FileID,
+			FileName:   v.FileName,
+			Category:   v.Category,
+			Size:       v.Size,
+			ScanStatus: v.ScanStatus,
+		})
+	}
+
+	history, err := model.LoanApplicationHistory(id)
+	if err != nil {
+		log.Println("error while get loan application history: ", err)
+		return nil, err
+	}
+
+	res.History = []webpojo.LoanApplicationChange{}
+	for _, v := range history {
+		res.History = append(res.History, webpojo.LoanApplicationChange{
+			FromState: v.FromState,
+			ToState:   v.ToState,
+			ActorID:   v.ActorID,
+			ActorRole: v.ActorRole,
+			Note:      v.Note,
+			CreatedAt: v.CreatedAt.String(),
+		})
+	}
+
+	return res, nil
+}
+
+// GetLoanApplications return customer's loan applications, newest first
+func GetLoanApplications(userID string) ([]webpojo.LoanApplicationPojo, error) {
+	customerID, err := parseUserID(userID)
+	if err != nil {
+		log.Println("error while get loan applications: ", err)
+		return nil, err
+	}
+
+	return searchLoanApplications(&model.LoanApplicationFilter{CustomerID: customerID})
+}
+
+// StaffGetLoanApplications return loan applications in state (any state if empty) of customer (any customer if zero).
+// Staff gets applications of assigned customers only.
+func StaffGetLoanApplications(actorID string, actorRole int, state string, customerID uint32) ([]webpojo.LoanApplicationPojo, error) {
+	staffID, err := parseUserID(actorID)
+	if err != nil {
+		log.Println("error while get loan applications for staff: ", err)
+		return nil, err
+	}
+
+	if state != "" && !loanStates[state] {
+		return nil, ErrWrongLoanState
+	}
+
+	filter := &model.LoanApplicationFilter{CustomerID: customerID, State: state}
+	switch actorRole {
+	case constants.SupervisorRole, constants.AdminRole:
+	case constants.StaffRole:
+		filter.AssignedStaffID = staffID
+	default:
+		return nil, model.ErrUnauthorized
+	}
+
+	return searchLoanApplications(filter)
+}
+
+// searchLoanApplications return loan applications matched to filter
+func searchLoanApplications(filter *model.LoanApplicationFilter) ([]webpojo.LoanApplicationPojo, error) {
+	apps, err := model.LoanApplicationsSearch(filter)
+	if err != nil {
+		log.Println("error while get loan applications: ", err)
+		return nil, err
+	}
+
+	res := []webpojo.LoanApplicationPojo{}
+	for i := range apps {
+		app, err := loanApplicationPojo(&apps[i])
+		if err != nil {
+			log.Println("error while convert loan application ", apps[i].ID, ": ", err)
+			return nil, err
+		}
+
+		res = append(res, *app)
+	}
+
+	return res, nil
+}
+
+// ChangeLoanApplicationState moves application to requested state if the move is allowed for caller.
+// Staff member who takes application in review becomes its reviewer.
+func ChangeLoanApplicationState(actorID string, actorRole int, req *webpojo.LoanApplicationStateReq) error {
+	callerID, err := parseUserID(actorID)
+	if err != nil {
+		log.Println("error while change loan application state: ", err)
+		return err
+	}
+
+	if !loanStates[req.State] {
+		return ErrWrongLoanState
+	}
+
+	if len(req.Note) > maxLoanNoteLen {
+		return ErrLoanNoteTooLong
+	}
+
+	app, err := checkLoanApplicationAccess(req.ID, callerID, actorRole)
+	if err != nil {
+		return err
+	}
+
+	byCustomer, ok := loanTransitions[app.State][req.State]
+	if !ok || byCustomer != (actorRole == constants.CustomerRole || actorRole == constants.DefaultRole) {
+		return ErrWrongLoanTransition
+	}
+
+	var reviewerID uint32
+	if req.State == model.LoanInReview {
+		reviewerID = callerID
+	}
+
+	err = model.LoanApplicationTransition(app.ID, app.State, req.State, reviewerID, &model.LoanApplicationChange{
+		ActorID:   callerID,
+		ActorRole: actorRole,
+		Note:      req.Note,
+	})
+	if err == model.ErrNoResult {
+		// State is changed by somebody else after application was read
+		return ErrWrongLoanTransition
+	}
+	if err != nil {
+		log.Println("error while change loan application state: ", err)
+		return err
+	}
+
+	return nil
+}
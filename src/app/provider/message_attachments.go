@@ -0,0 +1,145 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"app/model"
+	"app/webpojo"
+)
+
+const (
+	messageAttachmentPath = "/api/user/message/attachment"
+
+	// maxMessageAttachments is the biggest number of files in one message
+	maxMessageAttachments = 10
+)
+
+var (
+	// ErrAttachmentNotFound returns if attached file is not sender's file or it is deleted
+	ErrAttachmentNotFound = errors.New("attached file not found")
+	// ErrTooManyAttachments returns if message has more than maxMessageAttachments files
+	ErrTooManyAttachments = errors.New("too many attachments")
+)
+
+// checkMessageAttachments return unique file IDs if all files belong to sender
+func checkMessageAttachments(senderID uint32, fileIDs []uint32) ([]uint32, error) {
+	return checkOwnFiles(senderID, fileIDs, maxMessageAttachments)
+}
+
+// checkOwnFiles return unique file IDs if all files belong to owner and there are up to max files
+func checkOwnFiles(ownerID uint32, fileIDs []uint32, max int) ([]uint32, error) {
+	unique := []uint32{}
+	seen := map[uint32]bool{}
+
+	for _, fileID := range fileIDs {
+		if seen[fileID] {
+			continue
+		}
+		seen[fileID] = true
+
+		_, err := model.FileByID(fmt.Sprint(ownerID), fmt.Sprint(fileID))
+		if err == model.ErrNoResult {
+			log.Println("error while check attached file: file", fileID, "not found for user", ownerID)
+			return nil, ErrAttachmentNotFound
+		}
+
+		if err != nil {
+			log.Println("error while check attached file: " + err.Error())
+			return nil, err
+		}
+
+		unique = append(unique, fileID)
+	}
+
+	if len(unique) > max {
+		return nil, ErrTooManyAttachments
+	}
+
+	return unique, nil
+}
+
+// attachmentPojo convert attachment from database to response with participant download link
+func attachmentPojo(a *model.MessageAttachment) *webpojo.MessageAttachment {
+	return &webpojo.MessageAttachment{
+		FileID:     a.FileID,
+		OwnerID:    a.OwnerID,
+		FileName:   a.FileName,
+		Category:   a.Category,
+		Size:       a.Size,
+		ScanStatus: a.ScanStatus,
+		Link:       fmt.Sprintf("%s?message_id=%d&file_id=%d", messageAttachmentPath, a.MessageID, a.FileID),
+	}
+}
+
+// messageAttachments return attachments of message, failure is logged and gives empty list
+func messageAttachments(messageID uint32) []*webpojo.MessageAttachment {
+	res := []*webpojo.MessageAttachment{}
+
+	attachments, err := model.MessageAttachmentsByMessageID(messageID)
+	if err != nil {
+		log.Println("error while get message attachments: " + err.Error())
+		return res
+	}
+
+	for _, v := range attachments {
+		res = append(res, attachmentPojo(v))
+	}
+
+	return res
+}
+
+// threadAttachments return attachments of thread messages grouped by message ID
+func threadAttachments(threadID uint32) (map[uint32][]*webpojo.MessageAttachment, error) {
+	attachments, err := model.MessageAttachmentsByThreadID(threadID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := map[uint32][]*webpojo.MessageAttachment{}
+	for _, v := range attachments {
+		res[v.MessageID] = append(res[v.MessageID], attachmentPojo(v))
+	}
+
+	return res, nil
+}
+
+// GetMessageAttachmentContent return decrypted content of file attached to message.
+// Any participant of message thread can download it, others get model.ErrUnauthorized.
+// Downloads and denied attempts of other user's file are written to file access log.
+func GetMessageAttachmentContent(reader *StaffActor, messageID, fileID uint32) ([]byte, *model.UserFile, error) {
+	if reader == nil {
+		return nil, nil, errors.New("error while get message attachment: reader is nil")
+	}
+
+	readerID, err := parseUserID(reader.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fileInfo, threadID, err := model.MessageAttachmentFile(messageID, fileID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ownerID := fmt.Sprint(fileInfo.UserID)
+	_, err = checkThreadParticipant(threadID, readerID)
+	if err == model.ErrUnauthorized && fileInfo.UserID != readerID {
+		if logErr := logFileAccess(reader, ownerID, fileInfo.ID, model.FileAccessDenied, "not thread participant"); logErr != nil {
+			return nil, nil, logErr
+		}
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if fileInfo.UserID != readerID {
+		err = logFileAccess(reader, ownerID, fileInfo.ID, model.FileAccessDownload, "message attachment")
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return GetFileContent(ownerID, fmt.Sprint(fileInfo.ID))
+}
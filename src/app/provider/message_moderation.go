@@ -0,0 +1,82 @@
+package provider
+
+import (
+	"app/model"
+	"app/shared/events"
+	"app/webpojo"
+	"log"
+)
+
+// deletedMessagePlaceholder is shown to thread participants instead of deleted message content
+const deletedMessagePlaceholder = "Message deleted"
+
+// GetMessageHistory return message with its actual content, deletion info and previous contents for moderation
+func GetMessageHistory(messageID uint32) (*webpojo.MessageHistoryResp, error) {
+	message, err := model.MessageModerationByID(messageID)
+	if err == model.ErrNoResult {
+		return nil, model.ErrMessageNotExist
+	}
+	if err != nil {
+		log.Println("error while get message history: ", err)
+		return nil, err
+	}
+
+	revisions, err := model.MessageRevisions(messageID)
+	if err != nil {
+		log.Println("error while get message history: ", err)
+		return nil, err
+	}
+
+	res := &webpojo.MessageHistoryResp{
+		Message:   messagePojo(message, nil),
+		DeletedBy: message.DeletedBy,
+		Revisions: []*webpojo.MessageRevision{},
+	}
+	res.Message.Content = message.Content
+
+	if message.DeletedAt != nil {
+		res.DeletedAt = message.DeletedAt.String()
+	}
+
+	for _, v := range revisions {
+		res.Revisions = append(res.Revisions, &webpojo.MessageRevision{
+			RevisionID:  v.ID,
+			Content:     v.Content,
+			EditedBy:    v.EditedBy,
+			EditorFirst: v.EditorFirstName,
+			EditorLast:  v.EditorLastName,
+			Created:     v.CreatedAt.String(),
+		})
+	}
+
+	return res, nil
+}
+
+// RestoreMessage undeletes message and optionally brings back content of its revision.
+// Message is restored by the authenticated moderator.
+func RestoreMessage(userID string, restoreReq *webpojo.MessageRestoreReq) error {
+	restoredBy, err := parseUserID(userID)
+	if err != nil {
+		log.Println("error while restore message: ", err)
+		return err
+	}
+
+	message, err := model.MessageModerationByID(restoreReq.MessageID)
+	if err == model.ErrNoResult {
+		return model.ErrMessageNotExist
+	}
+	if err != nil {
+		log.Println("error while restore message: ", err)
+		return err
+	}
+
+	err = model.MessageRestore(restoredBy, restoreReq.MessageID, restoreReq.RevisionID)
+	if err != nil {
+		log.Println("error while restore message: ", err)
+		return err
+	}
+
+	publishMessageEvent(events.TypeMessageUpdate, message.FromUserID, message.ID, false)
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package provider
+
+import (
+	"log"
+
+	"app/constants"
+	"app/model"
+	"app/shared/events"
+	"app/webpojo"
+)
+
+// GetThreadParticipants return participants of thread, caller must take part in it
+func GetThreadParticipants(userID string, threadID uint32) ([]*webpojo.ThreadParticipant, error) {
+	callerID, err := parseUserID(userID)
+	if err != nil {
+		log.Println("error while get thread participants: ", err)
+		return nil, err
+	}
+
+	_, err = checkThreadParticipant(threadID, callerID)
+	if err != nil {
+		return nil, err
+	}
+
+	participants, err := model.ThreadParticipants(threadID)
+	if err != nil {
+		log.Println("error while get thread participants: ", err)
+		return nil, err
+	}
+
+	res := []*webpojo.ThreadParticipant{}
+	for _, v := range participants {
+		res = append(res, &webpojo.ThreadParticipant{
+			UserID:    v.UserID,
+			FirstName: v.FirstName,
+			LastName:  v.LastName,
+			UserRole:  v.UserRole,
+			AddedBy:   v.AddedBy,
+			CreatedAt: v.CreatedAt.String(),
+		})
+	}
+
+	return res, nil
+}
+
+// AddThreadParticipant adds user to thread. Only staff taking part in thread can add participants.
+func AddThreadParticipant(actorID string, actorRole int, req *webpojo.ThreadParticipantReq) error {
+	callerID, err := parseUserID(actorID)
+	if err != nil {
+		log.Println("error while add thread participant: ", err)
+		return err
+	}
+
+	if actorRole == constants.CustomerRole {
+		return model.ErrUnauthorized
+	}
+
+	_, err = checkThreadParticipant(req.ThreadID, callerID)
+	if err != nil {
+		return err
+	}
+
+	err = CheckUserExist(req.UserID)
+	if err != nil {
+		return err
+	}
+
+	err = model.ThreadParticipantAdd(req.ThreadID, req.UserID, callerID)
+	if err != nil {
+		log.Println("error while add thread participant: ", err)
+		return err
+	}
+
+	publishParticipantsEvent(req.ThreadID)
+	return nil
+}
+
+// RemoveThreadParticipant removes user from thread.
+// Any participant can leave thread, only staff taking part in thread can remove others.
+func RemoveThreadParticipant(actorID string, actorRole int, req *webpojo.ThreadParticipantReq) error {
+	callerID, err := parseUserID(actorID)
+	if err != nil {
+		log.Println("error while remove thread participant: ", err)
+		return err
+	}
+
+	if callerID != req.UserID && actorRole == constants.CustomerRole {
+		return model.ErrUnauthorized
+	}
+
+	_, err = checkThreadParticipant(req.ThreadID, callerID)
+	if err != nil {
+		return err
+	}
+
+	err = model.ThreadParticipantRemove(req.ThreadID, req.UserID)
+	if err != nil {
+		log.Println("error while remove thread participant: ", err)
+		return err
+	}
+
+	// Removed user is notified too, so their clients can drop thread
+	publishEvent([]uint32{req.UserID}, events.TypeThreadUpdate, &webpojo.ThreadUpdateEvent{ThreadID: req.ThreadID, ParticipantIDs: []uint32{}})
+	publishParticipantsEvent(req.ThreadID)
+	return nil
+}
+
+// publishParticipantsEvent tells thread participants about changed participants list
+func publishParticipantsEvent(threadID uint32) {
+	userIDs, err := model.ThreadParticipantIDs(threadID)
+	if err != nil {
+		log.Println("error while get participants for thread event: ", err)
+		return
+	}
+
+	publishEvent(userIDs, events.TypeThreadUpdate, &webpojo.ThreadUpdateEvent{ThreadID: threadID, ParticipantIDs: userIDs})
+}
@@ -0,0 +1,75 @@
+package provider
+
+import (
+	"errors"
+	"log"
+	"strings"
+
+	"app/model"
+	"app/shared/snippet"
+	"app/webpojo"
+)
+
+const (
+	defaultMessageSearchLimit = 20
+	maxMessageSearchLimit     = 100
+
+	// messageSnippetWidth is length of message snippet in characters
+	messageSnippetWidth = 160
+)
+
+// ErrEmptySearchQuery returns if search query has no words
+var ErrEmptySearchQuery = errors.New("search query has no words")
+
+// SearchMessages finds messages by content and thread title in threads where user is a participant.
+// Every word of query should match a word of message or title beginning.
+func SearchMessages(userID string, query string, count int) (*webpojo.MessageSearchResp, error) {
+	callerID, err := parseUserID(userID)
+	if err != nil {
+		log.Println("error while search messages: ", err)
+		return nil, err
+	}
+
+	terms := snippet.Terms(query)
+	if len(terms) == 0 {
+		return nil, ErrEmptySearchQuery
+	}
+
+	if count <= 0 {
+		count = defaultMessageSearchLimit
+	}
+
+	if count > maxMessageSearchLimit {
+		count = maxMessageSearchLimit
+	}
+
+	found, err := model.MessagesSearch(callerID, booleanQuery(terms), count)
+	if err != nil {
+		log.Println("error while search messages: ", err)
+		return nil, err
+	}
+
+	res := &webpojo.MessageSearchResp{Query: query, Results: []*webpojo.MessageSearchResult{}}
+	for _, v := range found {
+		res.Results = append(res.Results, &webpojo.MessageSearchResult{
+			ThreadID:     v.ThreadID,
+			MessageID:    v.MessageID,
+			FromUserID:   v.FromUserID,
+			CreatedAt:    v.CreatedAt.String(),
+			TitleSnippet: snippet.Highlight(v.Title, terms, len(v.Title)),
+			Snippet:      snippet.Highlight(v.Content, terms, messageSnippetWidth),
+		})
+	}
+
+	return res, nil
+}
+
+// booleanQuery return MySQL boolean mode query requiring all terms as word prefixes
+func booleanQuery(terms []string) string {
+	parts := make([]string, 0, len(terms))
+	for _, v := range terms {
+		parts = append(parts, "+"+v+"*")
+	}
+
+	return strings.Join(parts, " ")
+}
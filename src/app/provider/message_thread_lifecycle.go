@@ -0,0 +1,259 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"app/constants"
+	"app/model"
+	"app/shared/events"
+	"app/webpojo"
+)
+
+var (
+	// ErrWrongThreadStatus returns if thread status is unknown
+	ErrWrongThreadStatus = errors.New("unknown thread status")
+	// ErrThreadArchived returns on posting to archived thread
+	ErrThreadArchived = errors.New("thread is archived")
+	// ErrThreadAssigned returns if staff member takes thread assigned to other staff member
+	ErrThreadAssigned = errors.New("thread is assigned to other staff member")
+)
+
+// threadStatuses contains allowed thread statuses
+var threadStatuses = map[string]bool{
+	model.ThreadOpen:            true,
+	model.ThreadWaitingCustomer: true,
+	model.ThreadClosed:          true,
+	model.ThreadArchived:        true,
+}
+
+// threadFilter convert threads filter from request, nil filter matches all threads
+func threadFilter(filter *webpojo.ThreadFilter) (*model.ThreadFilter, error) {
+	if filter == nil {
+		return nil, nil
+	}
+
+	if filter.Status != "" && !threadStatuses[filter.Status] {
+		return nil, ErrWrongThreadStatus
+	}
+
+	return &model.ThreadFilter{
+		Status:          filter.Status,
+		AssignedStaffID: filter.AssignedStaffID,
+		Unassigned:      filter.Unassigned,
+	}, nil
+}
+
+// threadPojo convert thread from database to response
+func threadPojo(t *model.MessageThread) *webpojo.MessagesThreadsResp {
+	content := t.Content
+	if t.LastDeleted {
+		content = deletedMessagePlaceholder
+	}
+
+	res := &webpojo.MessagesThreadsResp{
+		ID:         t.ID,
+		ToUserID:   t.ToUserID,
+		FromUserID: t.FromUserID,
+		Title:      t.Title,
+		Content:    content,
+		CreatedAt:  t.CreatedAt.String(),
+
+		ParticipantIDs: t.ParticipantIDs(),
+		Unread:         t.Unread,
+
+		Status:          t.Status,
+		AssignedStaffID: t.AssignedStaffID,
+	}
+
+	if t.FirstResponseAt != nil {
+		res.FirstResponseAt = t.FirstResponseAt.String()
+	}
+
+	if t.ResolvedAt != nil {
+		res.ResolvedAt = t.ResolvedAt.String()
+	}
+
+	return res
+}
+
+// checkThreadStaff return thread if staff member can manage it: supervisors and admins manage any thread,
+// staff manages threads they take part in. Customers can't manage threads.
+func checkThreadStaff(threadID, actorID uint32, actorRole int) (*model.MessageThread, error) {
+	switch actorRole {
+	case constants.SupervisorRole, constants.AdminRole:
+		thread, err := model.ThreadByID(threadID)
+		if err == model.ErrNoResult {
+			return nil, model.ErrThreadNotExist
+		}
+
+		return thread, err
+	case constants.StaffRole:
+		return checkThreadParticipant(threadID, actorID)
+	default:
+		return nil, model.ErrUnauthorized
+	}
+}
+
+// SetThreadStatus changes thread status, closing thread sets its resolution time
+func SetThreadStatus(actorID string, actorRole int, req *webpojo.ThreadStatusReq) error {
+	callerID, err := parseUserID(actorID)
+	if err != nil {
+		log.Println("error while set thread status: ", err)
+		return err
+	}
+
+	if !threadStatuses[req.Status] {
+		return ErrWrongThreadStatus
+	}
+
+	_, err = checkThreadStaff(req.ThreadID, callerID, actorRole)
+	if err != nil {
+		return err
+	}
+
+	err = model.ThreadStatusUpdate(req.ThreadID, req.Status)
+	if err != nil {
+		log.Println("error while set thread status: ", err)
+		return err
+	}
+
+	publishThreadChange(&webpojo.ThreadUpdateEvent{ThreadID: req.ThreadID, Status: req.Status})
+	return nil
+}
+
+// AssignThread assigns thread to staff member, zero staff ID returns thread to queue.
+// Staff can take unassigned thread of their assigned customer or give back their own one,
+// supervisors and admins assign any thread. Staff gets ErrThreadAssigned if other staff member took thread first.
+func AssignThread(actorID string, actorRole int, req *webpojo.ThreadAssignReq) error {
+	callerID, err := parseUserID(actorID)
+	if err != nil {
+		log.Println("error while assign thread: ", err)
+		return err
+	}
+
+	thread, err := model.ThreadByID(req.ThreadID)
+	if err == model.ErrNoResult {
+		return model.ErrThreadNotExist
+	}
+	if err != nil {
+		log.Println("error while assign thread: ", err)
+		return err
+	}
+
+	// Supervisors and admins reassign threads explicitly, staff can't take thread from other staff member
+	override := false
+	switch actorRole {
+	case constants.SupervisorRole, constants.AdminRole:
+		override = true
+	case constants.StaffRole:
+		if req.StaffID != 0 && req.StaffID != callerID {
+			return model.ErrUnauthorized
+		}
+
+		if thread.AssignedStaffID != 0 && thread.AssignedStaffID != callerID {
+			return ErrThreadAssigned
+		}
+
+		assigned, err := isThreadCustomerAssigned(callerID, thread)
+		if err != nil {
+			log.Println("error while assign thread: ", err)
+			return err
+		}
+
+		if !assigned {
+			return model.ErrUnauthorized
+		}
+	default:
+		return model.ErrUnauthorized
+	}
+
+	if req.StaffID != 0 {
+		staff, err := model.UserByID(fmt.Sprint(req.StaffID))
+		if err != nil {
+			return err
+		}
+
+		if staff.UserRole != constants.StaffRole && staff.UserRole != constants.SupervisorRole {
+			return ErrWrongUserRole
+		}
+	}
+
+	assigned, err := model.ThreadAssign(req.ThreadID, req.StaffID, callerID, override)
+	if err != nil {
+		log.Println("error while assign thread: ", err)
+		return err
+	}
+
+	if !assigned {
+		return ErrThreadAssigned
+	}
+
+	publishThreadChange(&webpojo.ThreadUpdateEvent{ThreadID: req.ThreadID, AssignedStaffID: req.StaffID})
+	return nil
+}
+
+// publishThreadChange tells thread participants about changed thread status or assignment
+func publishThreadChange(event *webpojo.ThreadUpdateEvent) {
+	userIDs, err := model.ThreadParticipantIDs(event.ThreadID)
+	if err != nil {
+		log.Println("error while get participants for thread event: ", err)
+		return
+	}
+
+	event.ParticipantIDs = userIDs
+	publishEvent(userIDs, events.TypeThreadUpdate, event)
+}
+
+// isThreadCustomerAssigned tells customer of thread, its sender or recipient, is assigned to staff member
+func isThreadCustomerAssigned(staffID uint32, thread *model.MessageThread) (bool, error) {
+	for _, customerID := range []uint32{thread.FromUserID, thread.ToUserID} {
+		assigned, err := model.IsStaffAssigned(fmt.Sprint(staffID), fmt.Sprint(customerID))
+		if err != nil || assigned {
+			return assigned, err
+		}
+	}
+
+	return false, nil
+}
+
+// GetThreadQueue return page of unassigned open and waiting threads, oldest first.
+// Staff see threads of their assigned customers only, supervisors and admins see all threads.
+// Page size is clamped to [1, maxThreadListCount].
+func GetThreadQueue(actorID string, actorRole int, count int, pageCursor string) ([]*webpojo.MessagesThreadsResp, *webpojo.PageCursors, error) {
+	callerID, err := parseUserID(actorID)
+	if err != nil {
+		log.Println("error while get thread queue: ", err)
+		return nil, nil, err
+	}
+
+	var staffID uint32
+	switch actorRole {
+	case constants.SupervisorRole, constants.AdminRole:
+	case constants.StaffRole:
+		staffID = callerID
+	default:
+		return nil, nil, model.ErrUnauthorized
+	}
+
+	page, err := newPage(pageLimit(count, maxThreadListCount), pageCursor)
+	if err != nil {
+		log.Println("error while get thread queue: ", err)
+		return nil, nil, err
+	}
+
+	threads, more, err := model.ThreadQueue(staffID, page)
+	if err != nil {
+		log.Println("error while get thread queue: ", err)
+		return nil, nil, err
+	}
+
+	res := []*webpojo.MessagesThreadsResp{}
+	for _, v := range threads {
+		res = append(res, threadPojo(v))
+	}
+
+	cursors := pageCursors(page, len(threads), more, func(i int) (uint32, string) { return threads[i].ID, "" })
+	return res, cursors, nil
+}
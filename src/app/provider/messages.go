@@ -1,87 +1,144 @@
 package provider
 
 import (
+	"app/constants"
 	"app/model"
+	"app/shared/events"
 	"app/webpojo"
 	"errors"
 	"log"
 	"strconv"
 )
 
-// ThreadsByUserID return list of user's threads
-func ThreadsByUserID(userID string, count, offset int) ([]*webpojo.MessagesThreadsResp, error) {
+const (
+	// maxThreadListCount is the biggest page of threads list
+	maxThreadListCount = 100
+	// maxMessageListCount is the biggest page of thread messages list
+	maxMessageListCount = 200
+)
+
+// ThreadsByUserID return list of user's threads matched to filter, nil filter matches all threads.
+// Page size is clamped to [1, maxThreadListCount].
+func ThreadsByUserID(userID string, filter *webpojo.ThreadFilter, count int, pageCursor string) ([]*webpojo.MessagesThreadsResp, *webpojo.PageCursors, error) {
 	if userID == "" {
-		return nil, errors.New("error while get user's threads: user ID is empty")
+		return nil, nil, errors.New("error while get user's threads: user ID is empty")
 	}
 
-	if count <= 0 {
-		return nil, errors.New("error while get user's threads: count is 0")
+	page, err := newPage(pageLimit(count, maxThreadListCount), pageCursor)
+	if err != nil {
+		log.Println("error while get user's threads: ", err)
+		return nil, nil, err
 	}
 
-	if offset < 0 {
-		return nil, errors.New("error while get user's threads: offset less than 0")
+	modelFilter, err := threadFilter(filter)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	threads, err := model.ThreadsByUserID(userID, count, offset)
+	threads, more, err := model.ThreadsByUserID(userID, modelFilter, page)
 	if err != nil {
 		log.Println("error while get user's threads: ", err)
-		return nil, err
+		return nil, nil, err
 	}
 
 	res := []*webpojo.MessagesThreadsResp{}
 
 	for _, v := range threads {
-		res = append(res, &webpojo.MessagesThreadsResp{
-			ID:         v.ID,
-			ToUserID:   v.ToUserID,
-			FromUserID: v.FromUserID,
-			Title:      v.Title,
-			Content:    v.Content,
-			CreatedAt:  v.CreatedAt.String(),
-		})
+		res = append(res, threadPojo(v))
 	}
 
-	return res, nil
+	cursors := pageCursors(page, len(threads), more, func(i int) (uint32, string) { return threads[i].ID, "" })
+	return res, cursors, nil
 }
 
-// PostNewMessage create new user's message in database
+// PostNewMessage create new user's message in database.
+// Sender is always the authenticated user, reply to existing thread goes to other thread participant.
+// Attached files must be sender's files, they become available to all thread participants.
 func PostNewMessage(userID string, messagePostReq *webpojo.MessagePostReq) (*webpojo.PostNewMessageResp, error) {
 	var newThreadCreated bool
 
-	err := CheckUserExist(messagePostReq.FromUserID)
+	senderID, err := parseUserID(userID)
 	if err != nil {
-		log.Println("error while check is user exist: ", err)
+		log.Println("error while post new message: ", err)
 		return nil, err
 	}
+	messagePostReq.FromUserID = senderID
 
-	err = CheckUserExist(messagePostReq.ToUserID)
+	fileIDs, err := checkMessageAttachments(senderID, messagePostReq.FileIDs)
 	if err != nil {
-		log.Println("error while check is user exist: ", err)
 		return nil, err
 	}
 
 	if messagePostReq.ThreadID != 0 {
-		err := CheckThreadExist(messagePostReq.ThreadID)
+		thread, err := checkThreadParticipant(messagePostReq.ThreadID, senderID)
 		if err != nil {
 			return nil, err
 		}
+
+		if thread.Status == model.ThreadArchived {
+			return nil, ErrThreadArchived
+		}
+
+		// Message is delivered to all participants, recipient column keeps thread counterpart of sender
+		messagePostReq.ToUserID = thread.ToUserID
+		if thread.ToUserID == senderID {
+			messagePostReq.ToUserID = thread.FromUserID
+		}
 	} else {
-		messagePostReq.ThreadID, err = CreateNewThread(messagePostReq.FromUserID, messagePostReq.ToUserID, messagePostReq.Subject)
+		err = CheckUserExist(messagePostReq.ToUserID)
+		if err != nil {
+			log.Println("error while check is user exist: ", err)
+			return nil, err
+		}
+
+		// Like AddThreadParticipant, customers can't pull other users into their threads
+		if len(messagePostReq.ParticipantIDs) != 0 {
+			sender, userErr := model.UserByID(userID)
+			if userErr != nil {
+				log.Println("error while get message sender: ", userErr)
+				return nil, userErr
+			}
+
+			if int(sender.UserRole) == constants.CustomerRole {
+				return nil, model.ErrUnauthorized
+			}
+		}
+
+		for _, participantID := range messagePostReq.ParticipantIDs {
+			err = CheckUserExist(participantID)
+			if err != nil {
+				log.Println("error while check is participant exist: ", err)
+				return nil, err
+			}
+		}
+
+		messagePostReq.ThreadID, err = CreateNewThread(senderID, messagePostReq.ToUserID, messagePostReq.Subject, messagePostReq.ParticipantIDs...)
 		if err != nil {
 			return nil, err
 		}
 		newThreadCreated = true
 	}
 
-	err = model.MessageCreate(messagePostReq.ToUserID, messagePostReq.FromUserID, messagePostReq.ThreadID, messagePostReq.Content)
+	messageID, err := model.MessageCreate(messagePostReq.ToUserID, messagePostReq.FromUserID, messagePostReq.ThreadID, messagePostReq.Content, fileIDs...)
 	if err != nil {
 		log.Println("error while create new user's message: " + err.Error())
 		return nil, err
 	}
 
+	if !newThreadCreated {
+		err = model.ThreadReplyUpdate(messagePostReq.ThreadID, senderID)
+		if err != nil {
+			log.Println("error while update thread after reply: " + err.Error())
+		}
+	}
+
+	publishMessageEvent(events.TypeMessageNew, messagePostReq.ToUserID, messageID, newThreadCreated)
+	notifyNewMessage(messagePostReq.ThreadID, messageID, senderID, messagePostReq.Content)
+
 	return &webpojo.PostNewMessageResp{
 		NewThreadCreated: newThreadCreated,
 		ThreadID:         messagePostReq.ThreadID,
+		MessageID:        messageID,
 	}, nil
 }
 
@@ -101,6 +158,43 @@ func CheckThreadExist(threadID uint32) error {
 	return nil
 }
 
+// checkThreadParticipant return thread if user takes part in it, model.ErrUnauthorized otherwise
+func checkThreadParticipant(threadID, userID uint32) (*model.MessageThread, error) {
+	thread, err := model.ThreadByID(threadID)
+	if err == model.ErrNoResult {
+		log.Println("thread not exist")
+		return nil, model.ErrThreadNotExist
+	}
+
+	if err != nil {
+		log.Println("error while get thread: " + err.Error())
+		return nil, err
+	}
+
+	participant, err := model.IsThreadParticipant(threadID, userID)
+	if err != nil {
+		log.Println("error while check thread participant: " + err.Error())
+		return nil, err
+	}
+
+	if !participant {
+		log.Println("user", userID, "is not participant of thread", threadID)
+		return nil, model.ErrUnauthorized
+	}
+
+	return thread, nil
+}
+
+// parseUserID convert session user ID to number
+func parseUserID(userID string) (uint32, error) {
+	id, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil || id == 0 {
+		return 0, errors.New("can't parse user ID")
+	}
+
+	return uint32(id), nil
+}
+
 // CheckMessageExist return error if thread not exist
 func CheckMessageExist(messageID uint32) error {
 	threadExist, err := model.CheckIsMessageExist(messageID)
@@ -134,7 +228,7 @@ func CheckUserExist(userID uint32) error {
 }
 
 // CreateNewThread chack param and create new thread witj messages
-func CreateNewThread(fromUserID, toUserID uint32, title string) (uint32, error) {
+func CreateNewThread(fromUserID, toUserID uint32, title string, participantIDs ...uint32) (uint32, error) {
 	log.Println(fromUserID, " - ", toUserID, " - ", title)
 	if fromUserID == 0 {
 		log.Println("error while create new thread: from user ID is 0")
@@ -146,7 +240,7 @@ func CreateNewThread(fromUserID, toUserID uint32, title string) (uint32, error)
 		return 0, errors.New("to user ID is 0")
 	}
 
-	threadID, err := model.ThreadCreate(title, toUserID, fromUserID)
+	threadID, err := model.ThreadCreate(title, toUserID, fromUserID, participantIDs...)
 	if err != nil {
 		log.Println("error while create new thread: ", err)
 		return 0, err
@@ -155,9 +249,16 @@ func CreateNewThread(fromUserID, toUserID uint32, title string) (uint32, error)
 	return threadID, nil
 }
 
-// PatchMessage path user's message in database
-func PatchMessage(messagePatchReq *webpojo.MessagePatchReq) error {
-	err := CheckUserExist(messagePatchReq.ToUserID)
+// PatchMessage replaces content of user's message, previous content is kept in revision history.
+// Editor is the authenticated moderator.
+func PatchMessage(editorID string, messagePatchReq *webpojo.MessagePatchReq) error {
+	uintEditorID, err := parseUserID(editorID)
+	if err != nil {
+		log.Println("error while patch user's message: ", err)
+		return err
+	}
+
+	err = CheckUserExist(messagePatchReq.ToUserID)
 	if err != nil {
 		log.Println("error while check is user exist: ", err)
 		return err
@@ -181,18 +282,31 @@ func PatchMessage(messagePatchReq *webpojo.MessagePatchReq) error {
 		return err
 	}
 
-	err = model.MessageUpdate(messagePatchReq.Content, messagePatchReq.FromUserID, messagePatchReq.MessageID, messagePatchReq.ThreadID)
+	err = model.MessageUpdate(messagePatchReq.Content, uintEditorID, messagePatchReq.FromUserID, messagePatchReq.MessageID, messagePatchReq.ThreadID)
+	if err == model.ErrNoResult {
+		log.Println("error while patch user's message: message not found in thread or deleted")
+		return model.ErrMessageNotExist
+	}
 	if err != nil {
 		log.Println("error while patch user's message: " + err.Error())
 		return err
 	}
 
+	publishMessageEvent(events.TypeMessageUpdate, messagePatchReq.FromUserID, messagePatchReq.MessageID, false)
+
 	return nil
 }
 
-// DeleteMessage delete users message that already send
-func DeleteMessage(fromUserID, messageID, threadID uint32) error {
-	err := CheckThreadExist(threadID)
+// DeleteMessage marks users message that already send deleted, participants see placeholder instead of it.
+// Message is deleted by the authenticated moderator.
+func DeleteMessage(userID string, fromUserID, messageID, threadID uint32) error {
+	deletedBy, err := parseUserID(userID)
+	if err != nil {
+		log.Println("error while delete message: ", err)
+		return err
+	}
+
+	err = CheckThreadExist(threadID)
 	if err != nil {
 		log.Println("error while delete message: error while check user exist: ", err)
 		return model.ErrThreadNotExist
@@ -210,21 +324,33 @@ func DeleteMessage(fromUserID, messageID, threadID uint32) error {
 		return model.ErrMessageNotExist
 	}
 
-	err = model.MessageDelete(fromUserID, messageID, threadID)
+	err = model.MessageDelete(deletedBy, fromUserID, messageID, threadID)
+	if err == model.ErrNoResult {
+		log.Println("error while delete message: message not found in thread or already deleted")
+		return model.ErrMessageNotExist
+	}
 	if err != nil {
-		log.Println("error while patch user's message: " + err.Error())
+		log.Println("error while delete message: " + err.Error())
 		return err
 	}
 
+	publishMessageEvent(events.TypeMessageDelete, fromUserID, messageID, false)
+
 	return nil
 }
 
 // MarkMessageReaded path user's message in database
 func MarkMessageReaded(userID string, messagePatchReq *webpojo.UserMessagePatchReq) error {
-	err := CheckThreadExist(messagePatchReq.ThreadID)
+	uintUserID, err := parseUserID(userID)
+	if err != nil {
+		log.Println("error while mark message readed: ", err)
+		return err
+	}
+
+	_, err = checkThreadParticipant(messagePatchReq.ThreadID, uintUserID)
 	if err != nil {
 		log.Println("error while mark message readed: " + err.Error())
-		return model.ErrThreadNotExist
+		return err
 	}
 
 	err = CheckMessageExist(messagePatchReq.MessageID)
@@ -233,70 +359,112 @@ func MarkMessageReaded(userID string, messagePatchReq *webpojo.UserMessagePatchR
 		return model.ErrMessageNotExist
 	}
 
-	iUserID, err := strconv.Atoi(userID)
+	err = model.MarkMessageReadedUpdate(messagePatchReq.Readed, uintUserID, messagePatchReq.MessageID, messagePatchReq.ThreadID)
 	if err != nil {
-		log.Println("error while mark message readed: can't parse user ID: ", err)
-		return errors.New("can't parse user ID")
+		log.Println("error while mark message readed: " + err.Error())
+		return err
 	}
 
-	uintUserID := uint32(iUserID)
+	publishThreadEvent(messagePatchReq.ThreadID, events.TypeMessageRead, &webpojo.MessageReadEvent{
+		ThreadID:  messagePatchReq.ThreadID,
+		MessageID: messagePatchReq.MessageID,
+		ReaderID:  uintUserID,
+		Readed:    messagePatchReq.Readed,
+	})
+
+	return nil
+}
 
-	err = CheckUserExist(uintUserID)
+// MarkThreadRead moves user's read cursor of thread up to message and return new cursor
+func MarkThreadRead(userID string, threadReadReq *webpojo.ThreadReadReq) (uint32, error) {
+	uintUserID, err := parseUserID(userID)
 	if err != nil {
-		log.Println("error while mark message readed: " + err.Error())
-		return err
+		log.Println("error while mark thread read: ", err)
+		return 0, err
 	}
 
-	err = model.MarkMessageReadedUpdate(messagePatchReq.Readed, uintUserID, messagePatchReq.MessageID, messagePatchReq.ThreadID)
+	_, err = checkThreadParticipant(threadReadReq.ThreadID, uintUserID)
 	if err != nil {
-		log.Println("error while mark message readed: " + err.Error())
-		return err
+		log.Println("error while mark thread read: " + err.Error())
+		return 0, err
 	}
 
-	return nil
+	cursor, err := model.ThreadReadCursorUpdate(threadReadReq.ThreadID, uintUserID, threadReadReq.MessageID)
+	if err != nil {
+		log.Println("error while mark thread read: " + err.Error())
+		return 0, err
+	}
+
+	publishThreadEvent(threadReadReq.ThreadID, events.TypeThreadRead, &webpojo.ThreadReadEvent{
+		ThreadID:          threadReadReq.ThreadID,
+		ReaderID:          uintUserID,
+		LastReadMessageID: cursor,
+	})
+
+	return cursor, nil
+}
+
+// GetUnreadCounts return numbers of user's unread messages, total and per thread
+func GetUnreadCounts(userID string) (*webpojo.UnreadCountResp, error) {
+	uintUserID, err := parseUserID(userID)
+	if err != nil {
+		log.Println("error while get unread counts: ", err)
+		return nil, err
+	}
+
+	counts, err := model.UnreadCounts(uintUserID)
+	if err != nil {
+		log.Println("error while get unread counts: " + err.Error())
+		return nil, err
+	}
+
+	res := &webpojo.UnreadCountResp{Threads: []*webpojo.ThreadUnread{}}
+	for _, v := range counts {
+		res.Total += v.Unread
+		res.Threads = append(res.Threads, &webpojo.ThreadUnread{ThreadID: v.ThreadID, Unread: v.Unread})
+	}
+
+	return res, nil
 }
 
-// GetCustomerMessagesList return slice with customers messages
-func GetCustomerMessagesList(threadID, userID uint32, count, offset int) ([]*webpojo.MessageListResp, error) {
+// GetCustomerMessagesList return slice with customers messages, page size is clamped to [1, maxMessageListCount]
+func GetCustomerMessagesList(threadID, userID uint32, count int, pageCursor string) ([]*webpojo.MessageListResp, *webpojo.PageCursors, error) {
 	if threadID == 0 {
-		return nil, errors.New("error while get customer messages list: thread ID is 0")
+		return nil, nil, errors.New("error while get customer messages list: thread ID is 0")
 	}
 
-	if count == 0 {
-		return nil, errors.New("error while get customer messages list: count is 0")
+	page, err := newPage(pageLimit(count, maxMessageListCount), pageCursor)
+	if err != nil {
+		log.Println("error while get customer messages list: " + err.Error())
+		return nil, nil, err
 	}
 
-	if offset < 0 {
-		return nil, errors.New("error while get customer messages list: offset lessa then 0")
+	_, err = checkThreadParticipant(threadID, userID)
+	if err != nil {
+		log.Println("error while get customer messages list: " + err.Error())
+		return nil, nil, err
 	}
 
-	usersMessage, err := model.MessagesByUserID(threadID, userID, count, offset)
+	usersMessage, more, err := model.MessagesByUserID(threadID, userID, page)
 	if err != nil {
 		log.Println("error while get customers messages list: " + err.Error())
-		return nil, err
+		return nil, nil, err
 	}
 
-	log.Println(usersMessage)
+	attachments, err := threadAttachments(threadID)
+	if err != nil {
+		log.Println("error while get customers messages attachments: " + err.Error())
+		return nil, nil, err
+	}
 
-	var res []*webpojo.MessageListResp
+	res := []*webpojo.MessageListResp{}
 
 	for _, v := range usersMessage {
-		res = append(res, &webpojo.MessageListResp{
-			ThreadID:   v.ThreadID,
-			MessageID:  v.ID,
-			FromUserID: v.FromUserID,
-			FromFirst:  v.FromUserFirstName,
-			FromLast:   v.FromUserLastName,
-			ToUserID:   v.ToUserID,
-			ToFirst:    v.ToUserFirstName,
-			ToLast:     v.ToUserLastName,
-			Content:    v.Content,
-			Readed:     v.Readed,
-			Created:    v.CreatedAt.String(),
-		})
+		res = append(res, messagePojo(v, attachments[v.ID]))
 	}
 
-	return res, nil
+	cursors := pageCursors(page, len(usersMessage), more, func(i int) (uint32, string) { return usersMessage[i].ID, "" })
+	return res, cursors, nil
 }
 
 // GetCustomerMessageByID return single object customers messages
@@ -315,17 +483,37 @@ func GetCustomerMessageByID(userID, messageID string) (*webpojo.MessageListResp,
 		return nil, err
 	}
 
+	return messagePojo(usersMessage, messageAttachments(usersMessage.ID)), nil
+}
+
+// messagePojo convert message from database to response, deleted message has placeholder content and no attachments
+func messagePojo(m *model.Message, attachments []*webpojo.MessageAttachment) *webpojo.MessageListResp {
+	content := m.Content
+	if m.Deleted != 0 {
+		content = deletedMessagePlaceholder
+		attachments = nil
+	}
+
+	if attachments == nil {
+		attachments = []*webpojo.MessageAttachment{}
+	}
+
 	return &webpojo.MessageListResp{
-		ThreadID:   usersMessage.ThreadID,
-		MessageID:  usersMessage.ID,
-		FromUserID: usersMessage.FromUserID,
-		FromFirst:  usersMessage.FromUserFirstName,
-		FromLast:   usersMessage.FromUserLastName,
-		ToUserID:   usersMessage.ToUserID,
-		ToFirst:    usersMessage.ToUserFirstName,
-		ToLast:     usersMessage.ToUserLastName,
-		Content:    usersMessage.Content,
-		Readed:     usersMessage.Readed,
-		Created:    usersMessage.CreatedAt.String(),
-	}, nil
+		ThreadID:   m.ThreadID,
+		MessageID:  m.ID,
+		FromUserID: m.FromUserID,
+		FromFirst:  m.FromUserFirstName,
+		FromLast:   m.FromUserLastName,
+		ToUserID:   m.ToUserID,
+		ToFirst:    m.ToUserFirstName,
+		ToLast:     m.ToUserLastName,
+		Content:    content,
+		Readed:     m.Readed,
+		ReadBy:     m.ReadByIDs(),
+		Created:    m.CreatedAt.String(),
+		Edited:     m.Edited,
+		Deleted:    m.Deleted != 0,
+
+		Attachments: attachments,
+	}
 }
@@ -0,0 +1,297 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"app/model"
+	"app/shared/email"
+	"app/shared/view"
+	"app/webpojo"
+
+	"github.com/jasonlvhit/gocron"
+)
+
+const (
+	// outboxBatchSize is the biggest number of emails sent by one delivery run
+	outboxBatchSize = 50
+	// outboxLease is time for sending of claimed email, after it other worker can retry
+	outboxLease = 5 * time.Minute
+	// maxEmailAttempts is number of delivery attempts before email gets failed status
+	maxEmailAttempts = 5
+
+	// emailContentWidth is the longest message content in notification email, in characters
+	emailContentWidth = 500
+)
+
+// ErrWrongNotificationMode returns if notification mode is not immediate, digest or off
+var ErrWrongNotificationMode = errors.New("wrong notification mode")
+
+// newMessageEmail is data of message_new email templates
+type newMessageEmail struct {
+	FirstName string
+	FromName  string
+	Title     string
+	Content   string
+}
+
+// digestEmail is data of message_digest email templates
+type digestEmail struct {
+	FirstName string
+	Messages  []*digestEmailMessage
+}
+
+// digestEmailMessage is message in digest email
+type digestEmailMessage struct {
+	Title     string
+	FromName  string
+	Content   string
+	CreatedAt string
+}
+
+// GetNotificationPreference return how user is notified about new messages
+func GetNotificationPreference(userID string) (*webpojo.NotificationPreference, error) {
+	uintUserID, err := parseUserID(userID)
+	if err != nil {
+		log.Println("error while get notification preference: ", err)
+		return nil, err
+	}
+
+	mode, err := model.NotificationMode(uintUserID)
+	if err != nil {
+		log.Println("error while get notification preference: ", err)
+		return nil, err
+	}
+
+	return &webpojo.NotificationPreference{Mode: mode}, nil
+}
+
+// SetNotificationPreference changes how user is notified about new messages
+func SetNotificationPreference(userID string, pref *webpojo.NotificationPreference) error {
+	uintUserID, err := parseUserID(userID)
+	if err != nil {
+		log.Println("error while set notification preference: ", err)
+		return err
+	}
+
+	switch pref.Mode {
+	case model.NotifyImmediate, model.NotifyDigest, model.NotifyOff:
+	default:
+		return ErrWrongNotificationMode
+	}
+
+	err = model.NotificationModeUpdate(uintUserID, pref.Mode)
+	if err != nil {
+		log.Println("error while set notification preference: ", err)
+		return err
+	}
+
+	return nil
+}
+
+// notifyNewMessage emails new message to thread participants or puts it to their digests.
+// Failures are logged, message is stored already.
+func notifyNewMessage(threadID, messageID, senderID uint32, content string) {
+	recipients, err := model.NotificationRecipients(threadID, senderID)
+	if err != nil {
+		log.Println("error while get new message notification recipients: ", err)
+		return
+	}
+
+	var data *newMessageEmail
+	for _, v := range recipients {
+		switch v.Mode {
+		case model.NotifyDigest:
+			err = model.DigestItemAdd(v.UserID, messageID)
+			if err != nil {
+				log.Println("error while add message to digest: ", err)
+			}
+		case model.NotifyImmediate:
+			if data == nil {
+				data, err = newMessageEmailData(threadID, senderID, content)
+				if err != nil {
+					log.Println("error while prepare new message email: ", err)
+					return
+				}
+			}
+
+			data.FirstName = v.FirstName
+			err = enqueueEmail(v.UserID, v.Email, "New message: "+data.Title, "email/message_new", data)
+			if err != nil {
+				log.Println("error while enqueue new message email: ", err)
+			}
+		}
+	}
+}
+
+// newMessageEmailData return thread title and sender name for new message email
+func newMessageEmailData(threadID, senderID uint32, content string) (*newMessageEmail, error) {
+	thread, err := model.ThreadByID(threadID)
+	if err != nil {
+		return nil, err
+	}
+
+	sender, err := model.UserByID(fmt.Sprint(senderID))
+	if err != nil {
+		return nil, err
+	}
+
+	return &newMessageEmail{
+		FromName: strings.TrimSpace(sender.FirstName + " " + sender.LastName),
+		Title:    thread.Title,
+		Content:  truncateText(content, emailContentWidth),
+	}, nil
+}
+
+// enqueueEmail renders text and HTML templates and puts email to outbox
+func enqueueEmail(userID uint32, to, subject, templateName string, data interface{}) error {
+	m, err := renderEmail(userID, to, subject, templateName, data)
+	if err != nil {
+		return err
+	}
+
+	_, err = model.OutboxEmailCreate(m.UserID, m.ToEmail, m.Subject, m.TextBody, m.HTMLBody)
+	return err
+}
+
+// renderEmail renders text and HTML templates of email for outbox
+func renderEmail(userID uint32, to, subject, templateName string, data interface{}) (*model.OutboxEmail, error) {
+	text, err := view.RenderText(templateName, data)
+	if err != nil {
+		return nil, err
+	}
+
+	html, err := view.RenderString(templateName, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.OutboxEmail{UserID: userID, ToEmail: to, Subject: subject, TextBody: text, HTMLBody: html}, nil
+}
+
+// SendMessageDigests puts digest of unread messages to outbox of every user waiting for it.
+// Return number of enqueued digests.
+func SendMessageDigests() (int, error) {
+	users, err := model.DigestUsers()
+	if err != nil {
+		return 0, err
+	}
+
+	enqueued := 0
+	for _, user := range users {
+		items, err := model.DigestItems(user.UserID, user.LastMessageID)
+		if err != nil {
+			return enqueued, err
+		}
+
+		// Messages read since they were posted need no digest
+		if len(items) != 0 {
+			data := &digestEmail{FirstName: user.FirstName}
+			for _, v := range items {
+				data.Messages = append(data.Messages, &digestEmailMessage{
+					Title:     v.Title,
+					FromName:  strings.TrimSpace(v.FromFirstName + " " + v.FromLastName),
+					Content:   truncateText(v.Content, emailContentWidth),
+					CreatedAt: v.CreatedAt.Format("3:04 PM 01/02/2006"),
+				})
+			}
+
+			subject := fmt.Sprintf("You have %d unread message(s)", len(items))
+			err = enqueueEmail(user.UserID, user.Email, subject, "email/message_digest", data)
+			if err != nil {
+				return enqueued, err
+			}
+			enqueued++
+		}
+
+		err = model.DigestItemsRemove(user.UserID, user.LastMessageID)
+		if err != nil {
+			return enqueued, err
+		}
+	}
+
+	return enqueued, nil
+}
+
+// DeliverOutbox sends due outbox emails. Failed emails are retried with growing delay
+// until maxEmailAttempts. Return number of sent emails.
+func DeliverOutbox() (int, error) {
+	emails, err := model.OutboxEmailsDue(outboxBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, v := range emails {
+		claimed, err := model.OutboxEmailClaim(v.ID, outboxLease)
+		if err != nil {
+			return sent, err
+		}
+
+		if !claimed {
+			continue
+		}
+
+		sendErr := email.Send(&email.Message{To: v.ToEmail, Subject: v.Subject, Text: v.TextBody, HTML: v.HTMLBody})
+		if sendErr == nil {
+			err = model.OutboxEmailSent(v.ID)
+			if err != nil {
+				return sent, err
+			}
+			sent++
+			continue
+		}
+
+		log.Println("error while send outbox email", v.ID, ":", sendErr)
+		attempts := v.Attempts + 1
+		err = model.OutboxEmailFailed(v.ID, sendErr.Error(), emailRetryDelay(attempts), attempts >= maxEmailAttempts)
+		if err != nil {
+			return sent, err
+		}
+	}
+
+	return sent, nil
+}
+
+// emailRetryDelay return delay before next delivery attempt: 1, 4, 9... minutes
+func emailRetryDelay(attempts int) time.Duration {
+	return time.Duration(attempts*attempts) * time.Minute
+}
+
+// truncateText cuts text to width characters
+func truncateText(text string, width int) string {
+	runes := []rune(text)
+	if len(runes) <= width {
+		return text
+	}
+
+	return string(runes[:width]) + "…"
+}
+
+// StartNotificationsSheduler start gocron with outbox delivery every minute and hourly digests
+func StartNotificationsSheduler() {
+	log.Println("start notifications sheduler")
+
+	gocron.Every(1).Minute().Do(func() {
+		sent, err := DeliverOutbox()
+		if err != nil {
+			log.Println("error while deliver outbox emails: " + err.Error())
+		}
+
+		if sent != 0 {
+			log.Println("outbox delivery: sent=", sent)
+		}
+	})
+
+	gocron.Every(1).Hour().Do(func() {
+		enqueued, err := SendMessageDigests()
+		if err != nil {
+			log.Println("error while send message digests: " + err.Error())
+		}
+
+		log.Println("message digests: enqueued=", enqueued)
+	})
+}
@@ -0,0 +1,60 @@
+package provider
+
+import (
+	"app/model"
+	"app/shared/cursor"
+	"app/webpojo"
+)
+
+// pageLimit return count clamped to page size range [1, max]
+func pageLimit(count, max int) int {
+	if count < 1 {
+		return 1
+	}
+
+	if count > max {
+		return max
+	}
+
+	return count
+}
+
+// newPage return model page of limit rows at position of encoded cursor
+func newPage(limit int, pageCursor string) (*model.Page, error) {
+	c, err := cursor.Decode(pageCursor)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.Page{Limit: limit, Cursor: c}, nil
+}
+
+// pageCursors return cursors of pages around page of n rows.
+// more tells there are more rows in page direction, rowKey return ID and sort key of i-th row.
+func pageCursors(page *model.Page, n int, more bool, rowKey func(i int) (uint32, string)) *webpojo.PageCursors {
+	res := &webpojo.PageCursors{}
+	before := page.Cursor != nil && page.Cursor.Before
+
+	if n == 0 {
+		// Keep client at the same position to poll for new rows
+		if page.Cursor != nil {
+			res.PrevCursor = cursor.Before(page.Cursor.ID, page.Cursor.Key)
+			if before {
+				res.NextCursor = cursor.After(page.Cursor.ID, page.Cursor.Key)
+			}
+		}
+
+		return res
+	}
+
+	id, key := rowKey(0)
+	res.PrevCursor = cursor.Before(id, key)
+
+	// Page before cursor is always followed by cursor row
+	if more || before {
+		id, key = rowKey(n - 1)
+		res.NextCursor = cursor.After(id, key)
+	}
+
+	return res
+}
@@ -8,9 +8,11 @@ package provider
 
 import (
 	"app/constants"
-	"app/shared/cache"
+	"app/shared/ratefeed"
 
 	"log"
+	"strings"
+	"time"
 
 	"github.com/jasonlvhit/gocron"
 )
@@ -19,12 +21,20 @@ const (
 	cacheInUse = true // enable/disable caching in app
 )
 
+// siteURL is public URL of site for links in emails, without trailing slash
+var siteURL string
+
 func init() {
 	if !cacheInUse {
 		log.Println("WARNING: cache disabled")
 	}
 }
 
+// ConfigureSiteURL sets public URL of site for links in emails
+func ConfigureSiteURL(url string) {
+	siteURL = strings.TrimRight(url, "/")
+}
+
 // InitAppCache add some init values to cache
 func InitAppCache() {
 	_, err := getBestRatesListFromDB()
@@ -39,12 +49,20 @@ func InitAppCache() {
 func UpdateCache(command string) error {
 	switch command {
 	case "update_best_rates":
-		rateList, err := getBestRatesListFromDB()
+		rates, err := getBestRatesListFromDB()
 		if err != nil {
 			return err
 		}
 
-		cache.Put(cache.BestLenderRateCacheKey, rateList)
+		// Failed alerts are retried on the next update, cache is updated already
+		enqueued, err := EvaluateRateAlerts(rates)
+		if err != nil {
+			log.Println("error while evaluate rate alerts: " + err.Error())
+		}
+
+		if enqueued != 0 {
+			log.Println("rate alerts: enqueued=", enqueued)
+		}
 
 	default:
 		return constants.NewErrorBadParams("command void or not implemented command")
@@ -58,6 +76,19 @@ func startUpdateCacheSheduler() {
 	log.Println("start cache update sheduler")
 
 	gocron.Every(1).Day().Do(func() {
-		InitAppCache()
+		err := UpdateCache("update_best_rates")
+		if err != nil {
+			log.Println("error while update best rates cache: " + err.Error())
+		}
 	})
+
+	if ratefeed.Enabled() {
+		log.Println("start rate feed sheduler, interval", ratefeed.Interval())
+		gocron.Every(uint64(ratefeed.Interval() / time.Minute)).Minutes().Do(ingestRateFeedScheduled)
+	}
+}
+
+// StartSheduler runs all sheduled tasks in background
+func StartSheduler() {
+	gocron.Start()
 }
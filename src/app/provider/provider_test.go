@@ -3,14 +3,29 @@ package provider
 import (
 	"app/shared/jsonconfig"
 	"app/shared/passhash"
+	"bytes"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"app/constants"
 	"app/model"
 	"app/shared/config"
+	"app/shared/cursor"
 	"app/shared/database"
+	"app/shared/email"
+	"app/shared/events"
+	"app/shared/keyring"
+	"app/shared/ratefeed"
+	"app/shared/ratesheet"
+	"app/shared/scanner"
+	"app/shared/view"
 	"app/webpojo"
 )
 
@@ -30,6 +45,13 @@ func TestProvider(t *testing.T) {
 	// Load the configuration file
 	jsonconfig.Load(constants.ConfigFilePath, config.Config)
 	database.Connect(config.Database())
+	configureTestKeyring(t)
+	scanner.SetInstance(&scanner.Fake{}) // tests don't need clamd
+	ConfigureSiteURL(config.SiteURL())
+
+	viewInfo := config.View()
+	viewInfo.Folder = constants.TemplateFolderPath
+	view.Configure(viewInfo)
 
 	RemoveCustomerByEmail(constants.TestUserEmail)
 
@@ -101,24 +123,24 @@ func TestProvider(t *testing.T) {
 			t.Error("fail TestBestLenderRates: wrong list length")
 		}
 
-		if list[0].Interest != "3.23" {
+		if list[0].Interest != "3.230" {
 			t.Error("fail TestBestLenderRates: wrong interest rate")
 			return
 		}
 
-		if list[1].Interest != "3.63" {
+		if list[1].Interest != "3.630" {
 			t.Error("fail TestBestLenderRates: wrong interest rate")
 			return
 		}
 
-		if list[2].Interest != "2.95" {
+		if list[2].Interest != "2.950" {
 			t.Error("fail TestBestLenderRates: wrong interest rate")
 			return
 		}
 	})
 
 	t.Run("TestSaveFileDataInDB", func(t *testing.T) {
-		id, err := SaveFileDataInDB("testFile", "raw", fmt.Sprint(testUser.ID))
+		id, err := SaveFileDataInDB("testFile", "raw", fmt.Sprint(testUser.ID), nil)
 		if err != nil {
 			t.Error("fail TestSaveFileDataInDB: " + err.Error())
 		}
@@ -139,6 +161,303 @@ func TestProvider(t *testing.T) {
 		}
 	})
 
+	t.Run("TestSearchFileList", func(t *testing.T) {
+		meta := &webpojo.UserFileMeta{Category: "w2", Description: "2017 W-2", Tags: []string{"employer", "y2017"}}
+		id, err := SaveFileDataInDB("w2_2017.pdf", "raw", fmt.Sprint(testUser.ID), meta)
+		if err != nil {
+			t.Error("fail TestSearchFileList: " + err.Error())
+			return
+		}
+
+		list, err := SearchFileList(fmt.Sprint(testUser.ID), "", &webpojo.FileListReq{Category: "w2"})
+		if err != nil {
+			t.Error("fail TestSearchFileList: " + err.Error())
+			return
+		}
+
+		if list.Total != 1 || len(list.Files) != 1 || list.Files[0].Description != "2017 W-2" || len(list.Files[0].Tags) != 2 {
+			t.Error("fail TestSearchFileList: wrong category filter result")
+			return
+		}
+
+		list, err = SearchFileList(fmt.Sprint(testUser.ID), "", &webpojo.FileListReq{Query: "employ"})
+		if err != nil {
+			t.Error("fail TestSearchFileList: " + err.Error())
+			return
+		}
+
+		if list.Total != 1 || list.Files[0].FileName != "w2_2017.pdf" {
+			t.Error("fail TestSearchFileList: file should be found by tag")
+			return
+		}
+
+		taxReturn, description := "tax_return", "IRS copy"
+		err = UpdateFileMeta(fmt.Sprint(testUser.ID), fmt.Sprint(id), &webpojo.UserFileMetaPatch{Category: &taxReturn, Tags: []string{"irs"}})
+		if err != nil {
+			t.Error("fail TestSearchFileList: " + err.Error())
+			return
+		}
+
+		list, err = SearchFileList(fmt.Sprint(testUser.ID), "", &webpojo.FileListReq{Tag: "irs"})
+		if err != nil {
+			t.Error("fail TestSearchFileList: " + err.Error())
+			return
+		}
+
+		if list.Total != 1 || list.Files[0].Category != "tax_return" {
+			t.Error("fail TestSearchFileList: file should be found by new tag")
+			return
+		}
+
+		// Missing tags are kept
+		err = UpdateFileMeta(fmt.Sprint(testUser.ID), fmt.Sprint(id), &webpojo.UserFileMetaPatch{Description: &description})
+		if err != nil {
+			t.Error("fail TestSearchFileList: " + err.Error())
+			return
+		}
+
+		list, err = SearchFileList(fmt.Sprint(testUser.ID), "", &webpojo.FileListReq{Tag: "irs"})
+		if err != nil || list.Total != 1 || list.Files[0].Description != "IRS copy" {
+			t.Error("fail TestSearchFileList: tags should be kept if they are missing in update")
+			return
+		}
+
+		// Missing category and description are kept
+		err = UpdateFileMeta(fmt.Sprint(testUser.ID), fmt.Sprint(id), &webpojo.UserFileMetaPatch{Tags: []string{"irs", "copy"}})
+		if err != nil {
+			t.Error("fail TestSearchFileList: " + err.Error())
+			return
+		}
+
+		list, err = SearchFileList(fmt.Sprint(testUser.ID), "", &webpojo.FileListReq{Tag: "copy"})
+		if err != nil || list.Total != 1 || list.Files[0].Category != "tax_return" || list.Files[0].Description != "IRS copy" {
+			t.Error("fail TestSearchFileList: category and description should be kept if they are missing in update")
+			return
+		}
+
+		list, err = SearchFileList(fmt.Sprint(testUser.ID), "", &webpojo.FileListReq{Sort: "name", Desc: true, Limit: 1})
+		if err != nil {
+			t.Error("fail TestSearchFileList: " + err.Error())
+			return
+		}
+
+		if len(list.Files) != 1 || list.Total < 2 || list.NextCursor == "" {
+			t.Error("fail TestSearchFileList: wrong page size or total")
+			return
+		}
+
+		next, err := SearchFileList(fmt.Sprint(testUser.ID), "", &webpojo.FileListReq{Sort: "name", Desc: true, Limit: 1, Cursor: list.NextCursor})
+		if err != nil {
+			t.Error("fail TestSearchFileList: " + err.Error())
+			return
+		}
+
+		if len(next.Files) != 1 || next.Files[0].FileName > list.Files[0].FileName {
+			t.Error("fail TestSearchFileList: next page should continue sort order")
+			return
+		}
+
+		prev, err := SearchFileList(fmt.Sprint(testUser.ID), "", &webpojo.FileListReq{Sort: "name", Desc: true, Limit: 1, Cursor: next.PrevCursor})
+		if err != nil || len(prev.Files) != 1 || prev.Files[0].FileName != list.Files[0].FileName || prev.Files[0].CreatedAt != list.Files[0].CreatedAt {
+			t.Error("fail TestSearchFileList: previous page should return first page")
+		}
+	})
+
+	t.Run("TestFileTrashRestore", func(t *testing.T) {
+		id, err := SaveFileDataInDB("trashFile", "raw", fmt.Sprint(testUser.ID), nil)
+		if err != nil {
+			t.Error("fail TestFileTrashRestore: " + err.Error())
+			return
+		}
+
+		err = FileDelete(fmt.Sprint(testUser.ID), fmt.Sprint(id))
+		if err != nil {
+			t.Error("fail TestFileTrashRestore: " + err.Error())
+			return
+		}
+
+		trash, err := GetTrashFileList(fmt.Sprint(testUser.ID), "")
+		if err != nil {
+			t.Error("fail TestFileTrashRestore: " + err.Error())
+			return
+		}
+
+		if len(trash) != 1 || trash[0].FileName != "trashFile" {
+			t.Error("fail TestFileTrashRestore: deleted file should be in trash")
+			return
+		}
+
+		if _, err = GetFile(fmt.Sprint(testUser.ID), fmt.Sprint(id), ""); err != model.ErrNoResult {
+			t.Error("fail TestFileTrashRestore: deleted file should not be available")
+			return
+		}
+
+		err = FileRestore(fmt.Sprint(testUser.ID), fmt.Sprint(id))
+		if err != nil {
+			t.Error("fail TestFileTrashRestore: " + err.Error())
+			return
+		}
+
+		if _, err = GetFile(fmt.Sprint(testUser.ID), fmt.Sprint(id), ""); err != nil {
+			t.Error("fail TestFileTrashRestore: restored file should be available: " + err.Error())
+			return
+		}
+
+		if err = FileRestore(fmt.Sprint(testUser.ID), fmt.Sprint(id)); err != model.ErrNoResult {
+			t.Error("fail TestFileTrashRestore: file not in trash can't be restored")
+		}
+	})
+
+	t.Run("TestRotateFileKeys", func(t *testing.T) {
+		oldKeys := config.Keyring()
+		newKeys := oldKeys
+		newKeys.MasterKeys = map[string]string{}
+		for version, key := range oldKeys.MasterKeys {
+			newKeys.MasterKeys[version] = key
+		}
+		newKeys.MasterKeys["2"] = "ZGVmZ2hpamtsbW5vcHFyc3R1dnd4eXp7fH1+f4CBgoM="
+		newKeys.ActiveVersion = 2
+
+		// Files are wrapped back with the config key for the next tests
+		defer func() {
+			rollback := newKeys
+			rollback.ActiveVersion = oldKeys.ActiveVersion
+			keyring.Configure(rollback)
+			RotateFileKeys()
+			keyring.Configure(oldKeys)
+		}()
+
+		id, err := SaveFileDataInDB("rotateFile", "raw", fmt.Sprint(testUser.ID), nil)
+		if err != nil {
+			t.Error("fail TestRotateFileKeys: " + err.Error())
+			return
+		}
+
+		oldInfo, err := model.FileByID(fmt.Sprint(testUser.ID), fmt.Sprint(id))
+		if err != nil {
+			t.Error("fail TestRotateFileKeys: " + err.Error())
+			return
+		}
+
+		encrypted, err := encryptFileData(oldInfo, []byte("rotate me"))
+		if err != nil {
+			t.Error("fail TestRotateFileKeys: " + err.Error())
+			return
+		}
+
+		if err = keyring.Configure(newKeys); err != nil {
+			t.Error("fail TestRotateFileKeys: " + err.Error())
+			return
+		}
+
+		rotated, err := RotateFileKeys()
+		if err != nil {
+			t.Error("fail TestRotateFileKeys: " + err.Error())
+			return
+		}
+
+		if rotated == 0 {
+			t.Error("fail TestRotateFileKeys: file of old key should be rotated")
+			return
+		}
+
+		files, err := model.FilesByKeyVersionNot(2)
+		if err != nil {
+			t.Error("fail TestRotateFileKeys: " + err.Error())
+			return
+		}
+
+		if len(files) != 0 {
+			t.Error("fail TestRotateFileKeys: all files should use active master key")
+			return
+		}
+
+		newInfo, err := model.FileByID(fmt.Sprint(testUser.ID), fmt.Sprint(id))
+		if err != nil {
+			t.Error("fail TestRotateFileKeys: " + err.Error())
+			return
+		}
+
+		if newInfo.KeyVersion != 2 || bytes.Equal(newInfo.WrappedKey, oldInfo.WrappedKey) {
+			t.Error("fail TestRotateFileKeys: data key should be wrapped with new master key")
+			return
+		}
+
+		// File written under old key is readable with new key only
+		onlyNew := newKeys
+		onlyNew.MasterKeys = map[string]string{"2": newKeys.MasterKeys["2"]}
+		if err = keyring.Configure(onlyNew); err != nil {
+			t.Error("fail TestRotateFileKeys: " + err.Error())
+			return
+		}
+
+		data, err := decryptFileData(newInfo, encrypted)
+		keyring.Configure(newKeys)
+		if err != nil || string(data) != "rotate me" {
+			t.Error("fail TestRotateFileKeys: file should be readable after rotation")
+		}
+	})
+
+	t.Run("TestScanFileData", func(t *testing.T) {
+		defer scanner.SetInstance(&scanner.Fake{})
+
+		id, err := SaveFileDataInDB("eicar.txt", "raw", fmt.Sprint(testUser.ID), nil)
+		if err != nil {
+			t.Error("fail TestScanFileData: " + err.Error())
+			return
+		}
+
+		fileInfo, err := model.FileByID(fmt.Sprint(testUser.ID), fmt.Sprint(id))
+		if err != nil {
+			t.Error("fail TestScanFileData: " + err.Error())
+			return
+		}
+
+		scanner.SetInstance(&scanner.Fake{Err: errors.New("scanner is unavailable")})
+		if err = scanFileData(fileInfo, []byte(scanner.EICAR)); err == nil {
+			t.Error("fail TestScanFileData: scanner error should be returned")
+			return
+		}
+
+		if _, _, err = GetFileContent(fmt.Sprint(testUser.ID), fmt.Sprint(id)); err != ErrFileNotScanned {
+			t.Error("fail TestScanFileData: not scanned file should not be available")
+			return
+		}
+
+		encrypted, err := encryptFileData(fileInfo, []byte(scanner.EICAR))
+		if err != nil {
+			t.Error("fail TestScanFileData: " + err.Error())
+			return
+		}
+
+		if _, err = uploadFileWithAWS(bytes.NewReader(encrypted), storageKey(fileInfo)); err != nil {
+			t.Error("fail TestScanFileData: " + err.Error())
+			return
+		}
+
+		scanner.SetInstance(&scanner.Fake{})
+		if err = scanFileData(fileInfo, []byte(scanner.EICAR)); err != nil {
+			t.Error("fail TestScanFileData: " + err.Error())
+			return
+		}
+		defer RemoveFileWithAWS(quarantinePrefix + storageKey(fileInfo))
+
+		if _, _, err = GetFileContent(fmt.Sprint(testUser.ID), fmt.Sprint(id)); err != ErrFileInfected {
+			t.Error("fail TestScanFileData: infected file should not be available")
+			return
+		}
+
+		file, err := GetFile(fmt.Sprint(testUser.ID), fmt.Sprint(id), "")
+		if err != nil {
+			t.Error("fail TestScanFileData: " + err.Error())
+			return
+		}
+
+		if file.ScanStatus != model.FileScanInfected {
+			t.Error("fail TestScanFileData: wrong scan status " + file.ScanStatus)
+		}
+	})
+
 	t.Run("TestPostNewMessage", func(t *testing.T) {
 		resp, err := PostNewMessage(fmt.Sprint(testUser.ID), &webpojo.MessagePostReq{
 			FromUserID: testUser.ID,
@@ -152,7 +471,7 @@ func TestProvider(t *testing.T) {
 			return
 		}
 
-		threads, err := ThreadsByUserID(fmt.Sprint(testUser.ID), 10, 0)
+		threads, _, err := ThreadsByUserID(fmt.Sprint(testUser.ID), nil, 10, "")
 		if err != nil {
 			t.Error("error while post new message: can't get threads by user ID: ", err)
 			return
@@ -195,7 +514,7 @@ func TestProvider(t *testing.T) {
 			return
 		}
 
-		messages, err := GetCustomerMessagesList(threads[0].ID, testUser.ID, 10, 0)
+		messages, _, err := GetCustomerMessagesList(threads[0].ID, testUser.ID, 10, "")
 		if err != nil {
 			t.Error("error while TestPostNewMessage: GetCustomerMessagesList: " + err.Error())
 			return
@@ -211,19 +530,19 @@ func TestProvider(t *testing.T) {
 	})
 
 	t.Run("TestPatchMessage", func(t *testing.T) {
-		threads, err := ThreadsByUserID(fmt.Sprint(testUser.ID), 10, 0)
+		threads, _, err := ThreadsByUserID(fmt.Sprint(testUser.ID), nil, 10, "")
 		if err != nil {
 			t.Error("error while TestPatchMessage: can't get threads by user ID: ", err)
 			return
 		}
 
-		messages, err := GetCustomerMessagesList(threads[0].ID, testUser.ID, 10, 0)
+		messages, _, err := GetCustomerMessagesList(threads[0].ID, testUser.ID, 10, "")
 		if err != nil {
 			t.Error("error while TestPatchMessage: GetCustomerMessagesList: " + err.Error())
 			return
 		}
 
-		err = PatchMessage(&webpojo.MessagePatchReq{
+		err = PatchMessage(fmt.Sprint(testUser.ID), &webpojo.MessagePatchReq{
 			ToUserID:   messages[0].ToUserID,
 			FromUserID: messages[0].FromUserID,
 			MessageID:  messages[0].MessageID,
@@ -236,7 +555,7 @@ func TestProvider(t *testing.T) {
 			return
 		}
 
-		messages, err = GetCustomerMessagesList(messages[0].ThreadID, testUser.ID, 10, 0)
+		messages, _, err = GetCustomerMessagesList(messages[0].ThreadID, testUser.ID, 10, "")
 		if err != nil {
 			t.Error("error while TestPatchMessage: GetCustomerMessagesList: " + err.Error())
 			return
@@ -251,13 +570,13 @@ func TestProvider(t *testing.T) {
 	})
 
 	t.Run("TestMarkMessageReaded", func(t *testing.T) {
-		threads, err := ThreadsByUserID(fmt.Sprint(testUser.ID), 10, 0)
+		threads, _, err := ThreadsByUserID(fmt.Sprint(testUser.ID), nil, 10, "")
 		if err != nil {
 			t.Error("error while TestMarkMessageReaded: can't get threads by user ID: ", err)
 			return
 		}
 
-		messages, err := GetCustomerMessagesList(threads[0].ID, testUser.ID, 10, 0)
+		messages, _, err := GetCustomerMessagesList(threads[0].ID, testUser.ID, 10, "")
 		if err != nil {
 			t.Error("error while TestMarkMessageReaded: GetCustomerMessagesList: " + err.Error())
 			return
@@ -273,7 +592,7 @@ func TestProvider(t *testing.T) {
 			return
 		}
 
-		messages, err = GetCustomerMessagesList(messages[0].ThreadID, testUser.ID, 10, 0)
+		messages, _, err = GetCustomerMessagesList(messages[0].ThreadID, testUser.ID, 10, "")
 		if err != nil {
 			t.Error("error while TestMarkMessageReaded: GetCustomerMessagesList: " + err.Error())
 			return
@@ -291,147 +610,1898 @@ func TestProvider(t *testing.T) {
 	})
 
 	t.Run("TestDeleteMessage", func(t *testing.T) {
-		threads, err := ThreadsByUserID(fmt.Sprint(testUser.ID), 10, 0)
+		threads, _, err := ThreadsByUserID(fmt.Sprint(testUser.ID), nil, 10, "")
 		if err != nil {
 			t.Error("error while TestDeleteMessage: can't get threads by user ID: ", err)
 			return
 		}
 
-		messages, err := GetCustomerMessagesList(threads[0].ID, testUser.ID, 10, 0)
+		messages, _, err := GetCustomerMessagesList(threads[0].ID, testUser.ID, 10, "")
 		if err != nil {
 			t.Error("error while TestDeleteMessage: GetCustomerMessagesList: " + err.Error())
 			return
 		}
 
-		err = DeleteMessage(testUser.ID, messages[0].MessageID, messages[0].ThreadID)
+		err = DeleteMessage(fmt.Sprint(testUser.ID), messages[0].FromUserID, messages[0].MessageID, messages[0].ThreadID)
 		if err != nil {
 			t.Error("error while TestDeleteMessage: DeleteMessage: " + err.Error())
 			return
 		}
 
-		messages, err = GetCustomerMessagesList(threads[0].ID, testUser.ID, 10, 0)
+		messages, _, err = GetCustomerMessagesList(threads[0].ID, testUser.ID, 10, "")
 		if err != nil {
 			t.Error("error while TestDeleteMessage: DeleteMessage: " + err.Error())
 			return
 		}
 
-		if len(messages) != 1 {
-			t.Error("error while TestDeleteMessage: DeleteMessage: messages list is not empty")
+		if len(messages) != 2 {
+			t.Error("error while TestDeleteMessage: DeleteMessage: deleted message should stay in list")
 			return
 		}
+
+		if !messages[0].Deleted || messages[0].Content != deletedMessagePlaceholder {
+			t.Error("error while TestDeleteMessage: DeleteMessage: deleted message should have placeholder content")
+		}
 	})
 
-	t.Run("TestGetCustomerProfile", func(t *testing.T) {
-		result, err := GetCustomerProfile(fmt.Sprint(testUser.ID))
+	t.Run("TestMessageModeration", func(t *testing.T) {
+		threads, _, err := ThreadsByUserID(fmt.Sprint(testUser.ID), nil, 10, "")
 		if err != nil {
-			t.Error(err)
+			t.Error("error while TestMessageModeration: can't get threads by user ID: ", err)
 			return
 		}
 
-		if result.FirstName != "John" || result.LastName != "Doe" {
-			t.Error("fail TestGetCustomerProfile: wrong user name")
+		messages, _, err := GetCustomerMessagesList(threads[0].ID, testUser.ID, 10, "")
+		if err != nil || len(messages) == 0 {
+			t.Error("error while TestMessageModeration: GetCustomerMessagesList: ", err)
 			return
 		}
-	})
 
-	t.Run("TestPatchCustomerProfileInfo", func(t *testing.T) {
-
-		password, _ := passhash.HashString("password")
-		patchInfo := &webpojo.CustomerInfoPojoPatchReq{
-			UserID:         fmt.Sprint(testUser.ID),
-			UserName:       "jdoe",
-			Password:       password,
-			Email:          "johndoecool@gmail.com",
-			MailingAddress: "laplandia",
-			Phone:          "911",
+		message := messages[len(messages)-1]
+		if message.Deleted {
+			t.Error("error while TestMessageModeration: oldest message should not be deleted")
+			return
 		}
 
-		err := PatchCustomerProfileInfo(patchInfo)
+		err = PatchMessage(fmt.Sprint(testUser.ID), &webpojo.MessagePatchReq{
+			ToUserID:   message.ToUserID,
+			FromUserID: message.FromUserID,
+			MessageID:  message.MessageID,
+			ThreadID:   message.ThreadID,
+			Content:    "moderated content",
+		})
 		if err != nil {
-			t.Error(err)
+			t.Error("error while TestMessageModeration: PatchMessage: " + err.Error())
 			return
 		}
 
-		result, err := GetCustomerProfile(fmt.Sprint(testUser.ID))
+		err = DeleteMessage(fmt.Sprint(testUser.ID), message.FromUserID, message.MessageID, message.ThreadID)
 		if err != nil {
-			t.Error(err)
+			t.Error("error while TestMessageModeration: DeleteMessage: " + err.Error())
 			return
 		}
 
-		if result == nil {
-			t.Error("get customer profile result is null")
+		err = PatchMessage(fmt.Sprint(testUser.ID), &webpojo.MessagePatchReq{
+			ToUserID:   message.ToUserID,
+			FromUserID: message.FromUserID,
+			MessageID:  message.MessageID,
+			ThreadID:   message.ThreadID,
+			Content:    "edit of deleted message",
+		})
+		if err != model.ErrMessageNotExist {
+			t.Error("error while TestMessageModeration: deleted message should not be edited")
+		}
+
+		history, err := GetMessageHistory(message.MessageID)
+		if err != nil {
+			t.Error("error while TestMessageModeration: GetMessageHistory: " + err.Error())
 			return
 		}
 
-		AccertEqual(t, "userName", result.UserName, patchInfo.UserName)
-		AccertEqual(t, "email", result.Email, patchInfo.Email)
-		AccertEqual(t, "mailAddress", result.MailingAddress, patchInfo.MailingAddress)
-		AccertEqual(t, "phone", result.Phone, patchInfo.Phone)
+		if !history.Message.Deleted || history.Message.Content != "moderated content" || history.DeletedBy != testUser.ID {
+			t.Error("error while TestMessageModeration: history should show actual content of deleted message")
+		}
 
-		user, err := GetUserByEmail("johndoecool@gmail.com")
+		if len(history.Revisions) == 0 || history.Revisions[len(history.Revisions)-1].Content != message.Content {
+			t.Error("error while TestMessageModeration: history should keep content before edit")
+			return
+		}
+
+		err = RestoreMessage(fmt.Sprint(testUser.ID), &webpojo.MessageRestoreReq{
+			MessageID:  message.MessageID,
+			RevisionID: history.Revisions[len(history.Revisions)-1].RevisionID,
+		})
 		if err != nil {
-			t.Error("fail TestGetUserByEmail: " + err.Error())
+			t.Error("error while TestMessageModeration: RestoreMessage: " + err.Error())
 			return
 		}
 
-		if user == nil {
-			t.Error("user model is nil")
+		restored, err := GetCustomerMessageByID(fmt.Sprint(testUser.ID), fmt.Sprint(message.MessageID))
+		if err != nil {
+			t.Error("error while TestMessageModeration: GetCustomerMessageByID: " + err.Error())
 			return
 		}
 
-		AccertEqual(t, "password", user.Password, patchInfo.Password)
+		if restored.Deleted || restored.Content != message.Content || !restored.Edited {
+			t.Error("error while TestMessageModeration: message should be restored with previous content")
+		}
+
+		if _, err = GetMessageHistory(0); err != model.ErrMessageNotExist {
+			t.Error("error while TestMessageModeration: history of unknown message should not be found")
+		}
 	})
 
-	t.Run("TestRemoveCustomerByEmail", func(t *testing.T) {
-		err := RemoveCustomerByEmail(constants.TestOtherUserEmail)
+	t.Run("TestThreadLifecycle", func(t *testing.T) {
+		model.UserRemoveByEmail(constants.TestStaffEmail)
+		defer model.UserRemoveByEmail(constants.TestStaffEmail)
+
+		err := model.UserCreateWithRole("Jane", "Doe", constants.TestStaffEmail, "1qazxsw2", constants.StaffRole)
 		if err != nil {
-			t.Error("fail TestRemoveCustomerByEmail: " + err.Error())
+			t.Error("fail TestThreadLifecycle: " + err.Error())
 			return
 		}
-	})
 
-	t.Run("TestGetAmazonCredentials", func(t *testing.T) {
-		c, err := getAmazonCredentials()
+		staff, err := GetUserByEmail(constants.TestStaffEmail)
 		if err != nil {
-			t.Error(err)
+			t.Error("fail TestThreadLifecycle: " + err.Error())
 			return
 		}
 
-		if c == nil {
-			t.Error(errors.New("error while get amazon credentials - credentials is nil"))
+		customerID := fmt.Sprint(testUser.ID)
+		resp, err := PostNewMessage(customerID, &webpojo.MessagePostReq{ToUserID: staff.ID, Subject: "Rate lock", Content: "Can I lock my rate?"})
+		if err != nil {
+			t.Error("fail TestThreadLifecycle: " + err.Error())
 			return
 		}
-	})
 
-	t.Run("TestGetAmazonSession", func(t *testing.T) {
-		s, err := getAmazonSession()
-		if err != nil {
-			t.Error(err)
+		inQueue := func() bool {
+			pageCursor := ""
+			for {
+				threads, cursors, err := GetThreadQueue(staff.UserID(), constants.StaffRole, 100, pageCursor)
+				if err != nil {
+					t.Error("fail TestThreadLifecycle: GetThreadQueue: " + err.Error())
+					return false
+				}
+
+				for _, v := range threads {
+					if v.ID == resp.ThreadID {
+						return true
+					}
+				}
+
+				if len(threads) == 0 || cursors.NextCursor == "" {
+					return false
+				}
+				pageCursor = cursors.NextCursor
+			}
+		}
+
+		if inQueue() {
+			t.Error("fail TestThreadLifecycle: thread of not assigned customer should not be in staff queue")
+		}
+
+		assignReq := &webpojo.ThreadAssignReq{ThreadID: resp.ThreadID, StaffID: staff.ID}
+		if err = AssignThread(staff.UserID(), constants.StaffRole, assignReq); err != model.ErrUnauthorized {
+			t.Error("fail TestThreadLifecycle: staff should not take thread of not assigned customer")
+		}
+
+		if err = AssignCustomerToStaff(staff.UserID(), customerID); err != nil {
+			t.Error("fail TestThreadLifecycle: " + err.Error())
 			return
 		}
 
-		if s == nil {
-			t.Error(errors.New("error while get amazon session - credentials is nil"))
+		if !inQueue() {
+			t.Error("fail TestThreadLifecycle: new thread should be in staff queue")
+		}
+
+		if _, _, err = GetThreadQueue(customerID, constants.CustomerRole, 10, ""); err != model.ErrUnauthorized {
+			t.Error("fail TestThreadLifecycle: customer should not see thread queue")
+		}
+
+		if err = AssignThread(customerID, constants.CustomerRole, assignReq); err != model.ErrUnauthorized {
+			t.Error("fail TestThreadLifecycle: customer should not assign threads")
+		}
+
+		if err = AssignThread(staff.UserID(), constants.StaffRole, assignReq); err != nil {
+			t.Error("fail TestThreadLifecycle: AssignThread: " + err.Error())
 			return
 		}
-	})
 
-	t.Run("TestGetFastQuote", func(t *testing.T) {
-		rate, err := GetFastQuote(&webpojo.FastQuoteReq{})
-		if err != nil {
-			t.Error(errors.New("fail TestGetFastQuote: " + err.Error()))
+		if inQueue() {
+			t.Error("fail TestThreadLifecycle: assigned thread should leave staff queue")
+		}
+
+		threads, _, err := ThreadsByUserID(staff.UserID(), &webpojo.ThreadFilter{AssignedStaffID: staff.ID}, 10, "")
+		if err != nil || len(threads) != 1 || threads[0].ID != resp.ThreadID || threads[0].FirstResponseAt != "" {
+			t.Error("fail TestThreadLifecycle: assigned thread should be found by filter without first response")
 			return
 		}
 
-		if rate == nil {
-			t.Error(errors.New("fail TestGetFastQuote: rate is nil"))
+		_, err = PostNewMessage(staff.UserID(), &webpojo.MessagePostReq{ThreadID: resp.ThreadID, Content: "Please send your pay stubs"})
+		if err != nil {
+			t.Error("fail TestThreadLifecycle: " + err.Error())
 			return
 		}
-	})
-}
 
-func AccertEqual(t *testing.T, name, first, next string) {
+		statusReq := &webpojo.ThreadStatusReq{ThreadID: resp.ThreadID, Status: model.ThreadWaitingCustomer}
+		if err = SetThreadStatus(customerID, constants.CustomerRole, statusReq); err != model.ErrUnauthorized {
+			t.Error("fail TestThreadLifecycle: customer should not change thread status")
+		}
+
+		if err = SetThreadStatus(staff.UserID(), constants.StaffRole, &webpojo.ThreadStatusReq{ThreadID: resp.ThreadID, Status: "pending"}); err != ErrWrongThreadStatus {
+			t.Error("fail TestThreadLifecycle: unknown status should be rejected")
+		}
+
+		if err = SetThreadStatus(staff.UserID(), constants.StaffRole, statusReq); err != nil {
+			t.Error("fail TestThreadLifecycle: SetThreadStatus: " + err.Error())
+			return
+		}
+
+		_, err = PostNewMessage(customerID, &webpojo.MessagePostReq{ThreadID: resp.ThreadID, Content: "Sent them"})
+		if err != nil {
+			t.Error("fail TestThreadLifecycle: " + err.Error())
+			return
+		}
+
+		threads, _, err = ThreadsByUserID(customerID, &webpojo.ThreadFilter{AssignedStaffID: staff.ID, Status: model.ThreadOpen}, 10, "")
+		if err != nil || len(threads) != 1 || threads[0].FirstResponseAt == "" {
+			t.Error("fail TestThreadLifecycle: customer reply should reopen thread, staff reply should be first response")
+			return
+		}
+
+		statusReq.Status = model.ThreadClosed
+		if err = SetThreadStatus(staff.UserID(), constants.StaffRole, statusReq); err != nil {
+			t.Error("fail TestThreadLifecycle: SetThreadStatus: " + err.Error())
+			return
+		}
+
+		threads, _, err = ThreadsByUserID(customerID, &webpojo.ThreadFilter{Status: model.ThreadClosed}, 10, "")
+		if err != nil || len(threads) != 1 || threads[0].ResolvedAt == "" {
+			t.Error("fail TestThreadLifecycle: closed thread should have resolution time")
+			return
+		}
+
+		statusReq.Status = model.ThreadArchived
+		if err = SetThreadStatus(staff.UserID(), constants.StaffRole, statusReq); err != nil {
+			t.Error("fail TestThreadLifecycle: SetThreadStatus: " + err.Error())
+			return
+		}
+
+		_, err = PostNewMessage(customerID, &webpojo.MessagePostReq{ThreadID: resp.ThreadID, Content: "One more question"})
+		if err != ErrThreadArchived {
+			t.Error("fail TestThreadLifecycle: archived thread should not accept messages")
+		}
+	})
+
+	t.Run("TestMessageEvents", func(t *testing.T) {
+		err := events.Configure(events.Info{Backend: events.BackendLocal})
+		if err != nil {
+			t.Error("error while TestMessageEvents: configure events: " + err.Error())
+			return
+		}
+
+		sub := events.Subscribe(testUser.ID)
+		defer events.Unsubscribe(sub)
+
+		threads, _, err := ThreadsByUserID(fmt.Sprint(testUser.ID), nil, 10, "")
+		if err != nil {
+			t.Error("error while TestMessageEvents: can't get threads by user ID: ", err)
+			return
+		}
+
+		resp, err := PostNewMessage(fmt.Sprint(testUser.ID), &webpojo.MessagePostReq{
+			FromUserID: testUser.ID,
+			ToUserID:   testUser.ID,
+			ThreadID:   threads[0].ID,
+			Content:    customerMessageContent,
+		})
+		if err != nil {
+			t.Error("error while TestMessageEvents: PostNewMessage: " + err.Error())
+			return
+		}
+
+		err = MarkMessageReaded(fmt.Sprint(testUser.ID), &webpojo.UserMessagePatchReq{
+			MessageID: resp.MessageID,
+			ThreadID:  resp.ThreadID,
+			Readed:    true,
+		})
+		if err != nil {
+			t.Error("error while TestMessageEvents: MarkMessageReaded: " + err.Error())
+			return
+		}
+
+		for _, eventType := range []string{events.TypeMessageNew, events.TypeThreadUpdate, events.TypeMessageRead} {
+			select {
+			case e := <-sub.Events():
+				AccertEqual(t, "TestMessageEvents - event type", eventType, e.Type)
+			default:
+				t.Error("error while TestMessageEvents: event is not delivered: " + eventType)
+				return
+			}
+		}
+	})
+
+	t.Run("TestThreadMembership", func(t *testing.T) {
+		model.UserRemoveByEmail(constants.TestStaffEmail)
+		defer model.UserRemoveByEmail(constants.TestStaffEmail)
+
+		err := model.UserCreateWithRole("Jane", "Doe", constants.TestStaffEmail, "1qazxsw2", constants.CustomerRole)
+		if err != nil {
+			t.Error("fail TestThreadMembership: " + err.Error())
+			return
+		}
+
+		outsider, err := GetUserByEmail(constants.TestStaffEmail)
+		if err != nil {
+			t.Error("fail TestThreadMembership: " + err.Error())
+			return
+		}
+
+		threads, _, err := ThreadsByUserID(fmt.Sprint(testUser.ID), nil, 10, "")
+		if err != nil {
+			t.Error("fail TestThreadMembership: " + err.Error())
+			return
+		}
+		threadID := threads[0].ID
+
+		_, err = PostNewMessage(outsider.UserID(), &webpojo.MessagePostReq{ThreadID: threadID, Content: customerMessageContent})
+		if err != model.ErrUnauthorized {
+			t.Error("fail TestThreadMembership: outsider should not post to thread")
+		}
+
+		if _, _, err = GetCustomerMessagesList(threadID, outsider.ID, 10, ""); err != model.ErrUnauthorized {
+			t.Error("fail TestThreadMembership: outsider should not read thread")
+		}
+
+		messages, _, err := GetCustomerMessagesList(threadID, testUser.ID, 10, "")
+		if err != nil || len(messages) == 0 {
+			t.Error("fail TestThreadMembership: participant should read thread")
+			return
+		}
+
+		err = MarkMessageReaded(outsider.UserID(), &webpojo.UserMessagePatchReq{MessageID: messages[0].MessageID, ThreadID: threadID, Readed: true})
+		if err != model.ErrUnauthorized {
+			t.Error("fail TestThreadMembership: outsider should not mark message readed")
+		}
+
+		if _, err = PostNewMessage(fmt.Sprint(testUser.ID), &webpojo.MessagePostReq{ThreadID: 1 << 31, Content: customerMessageContent}); err != model.ErrThreadNotExist {
+			t.Error("fail TestThreadMembership: post to not existing thread should fail")
+		}
+
+		// Sender from request body is ignored
+		resp, err := PostNewMessage(fmt.Sprint(testUser.ID), &webpojo.MessagePostReq{
+			ThreadID:   threadID,
+			FromUserID: outsider.ID,
+			Content:    customerSecondMessageContent,
+		})
+		if err != nil {
+			t.Error("fail TestThreadMembership: " + err.Error())
+			return
+		}
+
+		message, err := GetCustomerMessageByID(fmt.Sprint(testUser.ID), fmt.Sprint(resp.MessageID))
+		if err != nil {
+			t.Error("fail TestThreadMembership: " + err.Error())
+			return
+		}
+
+		if message.FromUserID != testUser.ID {
+			t.Error("fail TestThreadMembership: sender should be taken from session")
+		}
+	})
+
+	t.Run("TestGroupThread", func(t *testing.T) {
+		for _, email := range []string{constants.TestStaffEmail, constants.TestProcessorEmail} {
+			model.UserRemoveByEmail(email)
+			defer model.UserRemoveByEmail(email)
+
+			err := model.UserCreateWithRole("Jane", "Doe", email, "1qazxsw2", constants.StaffRole)
+			if err != nil {
+				t.Error("fail TestGroupThread: " + err.Error())
+				return
+			}
+		}
+
+		staff, err := GetUserByEmail(constants.TestStaffEmail)
+		if err != nil {
+			t.Error("fail TestGroupThread: " + err.Error())
+			return
+		}
+
+		processor, err := GetUserByEmail(constants.TestProcessorEmail)
+		if err != nil {
+			t.Error("fail TestGroupThread: " + err.Error())
+			return
+		}
+
+		resp, err := PostNewMessage(staff.UserID(), &webpojo.MessagePostReq{
+			ToUserID: testUser.ID,
+			Subject:  "loan file",
+			Content:  customerMessageContent,
+		})
+		if err != nil {
+			t.Error("fail TestGroupThread: " + err.Error())
+			return
+		}
+
+		_, err = PostNewMessage(fmt.Sprint(testUser.ID), &webpojo.MessagePostReq{
+			ToUserID:       staff.ID,
+			Subject:        "loan file",
+			Content:        customerMessageContent,
+			ParticipantIDs: []uint32{processor.ID},
+		})
+		if err != model.ErrUnauthorized {
+			t.Error("fail TestGroupThread: customer should not create thread with participants")
+		}
+
+		group, err := PostNewMessage(staff.UserID(), &webpojo.MessagePostReq{
+			ToUserID:       testUser.ID,
+			Subject:        "loan file group",
+			Content:        customerMessageContent,
+			ParticipantIDs: []uint32{processor.ID},
+		})
+		if err != nil {
+			t.Error("fail TestGroupThread: " + err.Error())
+			return
+		}
+
+		if participants, err := GetThreadParticipants(staff.UserID(), group.ThreadID); err != nil || len(participants) != 3 {
+			t.Error("fail TestGroupThread: staff should create thread with participants")
+		}
+
+		// Processor should see only the thread below
+		err = RemoveThreadParticipant(staff.UserID(), constants.StaffRole, &webpojo.ThreadParticipantReq{ThreadID: group.ThreadID, UserID: processor.ID})
+		if err != nil {
+			t.Error("fail TestGroupThread: " + err.Error())
+			return
+		}
+
+		req := &webpojo.ThreadParticipantReq{ThreadID: resp.ThreadID, UserID: processor.ID}
+		if err = AddThreadParticipant(fmt.Sprint(testUser.ID), constants.CustomerRole, req); err != model.ErrUnauthorized {
+			t.Error("fail TestGroupThread: customer should not add participants")
+		}
+
+		if _, _, err = GetCustomerMessagesList(resp.ThreadID, processor.ID, 10, ""); err != model.ErrUnauthorized {
+			t.Error("fail TestGroupThread: processor is not participant yet")
+		}
+
+		if err = AddThreadParticipant(staff.UserID(), constants.StaffRole, req); err != nil {
+			t.Error("fail TestGroupThread: " + err.Error())
+			return
+		}
+
+		participants, err := GetThreadParticipants(fmt.Sprint(testUser.ID), resp.ThreadID)
+		if err != nil || len(participants) != 3 {
+			t.Error("fail TestGroupThread: thread should have 3 participants")
+			return
+		}
+
+		threads, _, err := ThreadsByUserID(processor.UserID(), nil, 10, "")
+		if err != nil || len(threads) != 1 || len(threads[0].ParticipantIDs) != 3 {
+			t.Error("fail TestGroupThread: added participant should see thread")
+			return
+		}
+
+		err = MarkMessageReaded(processor.UserID(), &webpojo.UserMessagePatchReq{MessageID: resp.MessageID, ThreadID: resp.ThreadID, Readed: true})
+		if err != nil {
+			t.Error("fail TestGroupThread: " + err.Error())
+			return
+		}
+
+		messages, _, err := GetCustomerMessagesList(resp.ThreadID, testUser.ID, 10, "")
+		if err != nil || len(messages) != 1 {
+			t.Error("fail TestGroupThread: customer should read thread")
+			return
+		}
+
+		if messages[0].Readed || len(messages[0].ReadBy) != 1 || messages[0].ReadBy[0] != processor.ID {
+			t.Error("fail TestGroupThread: read state should be kept per participant")
+		}
+
+		if err = RemoveThreadParticipant(fmt.Sprint(testUser.ID), constants.CustomerRole, &webpojo.ThreadParticipantReq{ThreadID: resp.ThreadID, UserID: staff.ID}); err != model.ErrUnauthorized {
+			t.Error("fail TestGroupThread: customer should not remove other participants")
+		}
+
+		if err = RemoveThreadParticipant(processor.UserID(), constants.StaffRole, req); err != nil {
+			t.Error("fail TestGroupThread: participant should leave thread: " + err.Error())
+			return
+		}
+
+		if _, _, err = GetCustomerMessagesList(resp.ThreadID, processor.ID, 10, ""); err != model.ErrUnauthorized {
+			t.Error("fail TestGroupThread: removed participant should not read thread")
+		}
+	})
+
+	t.Run("TestMessageAttachments", func(t *testing.T) {
+		model.UserRemoveByEmail(constants.TestStaffEmail)
+		defer model.UserRemoveByEmail(constants.TestStaffEmail)
+
+		err := model.UserCreateWithRole("Jane", "Doe", constants.TestStaffEmail, "1qazxsw2", constants.CustomerRole)
+		if err != nil {
+			t.Error("fail TestMessageAttachments: " + err.Error())
+			return
+		}
+
+		outsider, err := GetUserByEmail(constants.TestStaffEmail)
+		if err != nil {
+			t.Error("fail TestMessageAttachments: " + err.Error())
+			return
+		}
+
+		fileID, err := SaveFileDataInDB("bank_statement.pdf", "raw", fmt.Sprint(testUser.ID), nil)
+		if err != nil {
+			t.Error("fail TestMessageAttachments: " + err.Error())
+			return
+		}
+
+		threads, _, err := ThreadsByUserID(fmt.Sprint(testUser.ID), nil, 10, "")
+		if err != nil {
+			t.Error("fail TestMessageAttachments: " + err.Error())
+			return
+		}
+
+		_, err = PostNewMessage(outsider.UserID(), &webpojo.MessagePostReq{
+			ToUserID: outsider.ID,
+			Content:  customerMessageContent,
+			FileIDs:  []uint32{uint32(fileID)},
+		})
+		if err != ErrAttachmentNotFound {
+			t.Error("fail TestMessageAttachments: only own files can be attached")
+		}
+
+		resp, err := PostNewMessage(fmt.Sprint(testUser.ID), &webpojo.MessagePostReq{
+			ThreadID: threads[0].ID,
+			Content:  customerMessageContent,
+			FileIDs:  []uint32{uint32(fileID), uint32(fileID)},
+		})
+		if err != nil {
+			t.Error("fail TestMessageAttachments: " + err.Error())
+			return
+		}
+
+		message, err := GetCustomerMessageByID(fmt.Sprint(testUser.ID), fmt.Sprint(resp.MessageID))
+		if err != nil {
+			t.Error("fail TestMessageAttachments: " + err.Error())
+			return
+		}
+
+		if len(message.Attachments) != 1 || message.Attachments[0].FileID != uint32(fileID) {
+			t.Error("fail TestMessageAttachments: message should have one attachment")
+			return
+		}
+
+		if _, _, err = GetMessageAttachmentContent(&StaffActor{UserID: outsider.UserID()}, resp.MessageID, uint32(fileID)); err != model.ErrUnauthorized {
+			t.Error("fail TestMessageAttachments: outsider should not download attachment")
+		}
+
+		accessLog, err := GetFileAccessLog(&webpojo.FileAccessLogReq{ActorID: outsider.UserID()})
+		if err != nil || accessLog.Total != 1 || accessLog.Records[0].Action != model.FileAccessDenied {
+			t.Error("fail TestMessageAttachments: denied attachment download should be in file access log")
+		}
+
+		// File has no content yet, so participant passes access check and stops on antivirus status
+		if _, _, err = GetMessageAttachmentContent(&StaffActor{UserID: fmt.Sprint(testUser.ID)}, resp.MessageID, uint32(fileID)); err != ErrFileNotScanned {
+			t.Error("fail TestMessageAttachments: participant should pass access check")
+		}
+
+		if _, _, err = GetMessageAttachmentContent(&StaffActor{UserID: fmt.Sprint(testUser.ID)}, resp.MessageID, 1<<31); err != model.ErrNoResult {
+			t.Error("fail TestMessageAttachments: not attached file should not be found")
+		}
+	})
+
+	t.Run("TestUnreadCounts", func(t *testing.T) {
+		model.UserRemoveByEmail(constants.TestStaffEmail)
+		defer model.UserRemoveByEmail(constants.TestStaffEmail)
+
+		err := model.UserCreateWithRole("Jane", "Doe", constants.TestStaffEmail, "1qazxsw2", constants.StaffRole)
+		if err != nil {
+			t.Error("fail TestUnreadCounts: " + err.Error())
+			return
+		}
+
+		staff, err := GetUserByEmail(constants.TestStaffEmail)
+		if err != nil {
+			t.Error("fail TestUnreadCounts: " + err.Error())
+			return
+		}
+
+		first, err := PostNewMessage(staff.UserID(), &webpojo.MessagePostReq{ToUserID: testUser.ID, Subject: "unread", Content: customerMessageContent})
+		if err != nil {
+			t.Error("fail TestUnreadCounts: " + err.Error())
+			return
+		}
+
+		second, err := PostNewMessage(staff.UserID(), &webpojo.MessagePostReq{ThreadID: first.ThreadID, Content: customerMessageContent})
+		if err != nil {
+			t.Error("fail TestUnreadCounts: " + err.Error())
+			return
+		}
+
+		threadUnread := func(userID string) int {
+			counts, err := GetUnreadCounts(userID)
+			if err != nil {
+				t.Error("fail TestUnreadCounts: " + err.Error())
+				return -1
+			}
+
+			for _, v := range counts.Threads {
+				if v.ThreadID == first.ThreadID {
+					return v.Unread
+				}
+			}
+
+			return 0
+		}
+
+		if n := threadUnread(fmt.Sprint(testUser.ID)); n != 2 {
+			t.Error("fail TestUnreadCounts: customer should have 2 unread messages, got", n)
+		}
+
+		if n := threadUnread(staff.UserID()); n != 0 {
+			t.Error("fail TestUnreadCounts: own messages should not be unread, got", n)
+		}
+
+		cursor, err := MarkThreadRead(fmt.Sprint(testUser.ID), &webpojo.ThreadReadReq{ThreadID: first.ThreadID, MessageID: first.MessageID})
+		if err != nil || cursor != first.MessageID {
+			t.Error("fail TestUnreadCounts: cursor should move to first message")
+			return
+		}
+
+		if n := threadUnread(fmt.Sprint(testUser.ID)); n != 1 {
+			t.Error("fail TestUnreadCounts: customer should have 1 unread message, got", n)
+		}
+
+		if _, err = MarkThreadRead(fmt.Sprint(testUser.ID), &webpojo.ThreadReadReq{ThreadID: first.ThreadID}); err != nil {
+			t.Error("fail TestUnreadCounts: " + err.Error())
+			return
+		}
+
+		cursor, err = MarkThreadRead(fmt.Sprint(testUser.ID), &webpojo.ThreadReadReq{ThreadID: first.ThreadID, MessageID: first.MessageID})
+		if err != nil || cursor != second.MessageID {
+			t.Error("fail TestUnreadCounts: cursor should not move back")
+		}
+
+		threads, _, err := ThreadsByUserID(fmt.Sprint(testUser.ID), nil, 100, "")
+		if err != nil {
+			t.Error("fail TestUnreadCounts: " + err.Error())
+			return
+		}
+
+		for _, v := range threads {
+			if v.ID == first.ThreadID && v.Unread != 0 {
+				t.Error("fail TestUnreadCounts: thread list should show read thread")
+			}
+		}
+
+		messages, _, err := GetCustomerMessagesList(first.ThreadID, staff.ID, 10, "")
+		if err != nil || len(messages) != 2 || !messages[0].Readed || !messages[1].Readed {
+			t.Error("fail TestUnreadCounts: sender should see messages readed by cursor")
+		}
+
+		err = MarkMessageReaded(fmt.Sprint(testUser.ID), &webpojo.UserMessagePatchReq{MessageID: second.MessageID, ThreadID: first.ThreadID, Readed: false})
+		if err != nil {
+			t.Error("fail TestUnreadCounts: " + err.Error())
+			return
+		}
+
+		if n := threadUnread(fmt.Sprint(testUser.ID)); n != 1 {
+			t.Error("fail TestUnreadCounts: unmarked message should be unread again, got", n)
+		}
+
+		if _, err = MarkThreadRead(fmt.Sprint(testUser.ID), &webpojo.ThreadReadReq{ThreadID: first.ThreadID, MessageID: 1 << 31}); err != model.ErrMessageNotExist {
+			t.Error("fail TestUnreadCounts: message from other thread should not be accepted")
+		}
+	})
+
+	t.Run("TestMessagesPagination", func(t *testing.T) {
+		resp, err := PostNewMessage(fmt.Sprint(testUser.ID), &webpojo.MessagePostReq{ToUserID: testUser.ID, Subject: "pages", Content: customerMessageContent})
+		if err != nil {
+			t.Error("fail TestMessagesPagination: " + err.Error())
+			return
+		}
+
+		ids := []uint32{resp.MessageID}
+		for i := 0; i < 2; i++ {
+			reply, err := PostNewMessage(fmt.Sprint(testUser.ID), &webpojo.MessagePostReq{ThreadID: resp.ThreadID, Content: customerMessageContent})
+			if err != nil {
+				t.Error("fail TestMessagesPagination: " + err.Error())
+				return
+			}
+
+			ids = append(ids, reply.MessageID)
+		}
+
+		first, cursors, err := GetCustomerMessagesList(resp.ThreadID, testUser.ID, 2, "")
+		if err != nil || len(first) != 2 || first[0].MessageID != ids[2] || first[1].MessageID != ids[1] || cursors.NextCursor == "" {
+			t.Error("fail TestMessagesPagination: first page should have 2 newest messages")
+			return
+		}
+
+		last, lastCursors, err := GetCustomerMessagesList(resp.ThreadID, testUser.ID, 2, cursors.NextCursor)
+		if err != nil || len(last) != 1 || last[0].MessageID != ids[0] || lastCursors.NextCursor != "" {
+			t.Error("fail TestMessagesPagination: last page should have the oldest message")
+			return
+		}
+
+		newer, _, err := GetCustomerMessagesList(resp.ThreadID, testUser.ID, 10, cursors.PrevCursor)
+		if err != nil || len(newer) != 0 {
+			t.Error("fail TestMessagesPagination: there should be no newer messages")
+			return
+		}
+
+		reply, err := PostNewMessage(fmt.Sprint(testUser.ID), &webpojo.MessagePostReq{ThreadID: resp.ThreadID, Content: customerMessageContent})
+		if err != nil {
+			t.Error("fail TestMessagesPagination: " + err.Error())
+			return
+		}
+
+		newer, _, err = GetCustomerMessagesList(resp.ThreadID, testUser.ID, 10, cursors.PrevCursor)
+		if err != nil || len(newer) != 1 || newer[0].MessageID != reply.MessageID {
+			t.Error("fail TestMessagesPagination: new message should be on previous page")
+		}
+
+		if _, _, err = GetCustomerMessagesList(resp.ThreadID, testUser.ID, 10, "bad"); err != cursor.ErrInvalid {
+			t.Error("fail TestMessagesPagination: bad cursor should be rejected")
+		}
+	})
+
+	t.Run("TestSearchMessages", func(t *testing.T) {
+		for _, email := range []string{constants.TestStaffEmail, constants.TestProcessorEmail} {
+			model.UserRemoveByEmail(email)
+			defer model.UserRemoveByEmail(email)
+
+			err := model.UserCreateWithRole("Jane", "Doe", email, "1qazxsw2", constants.StaffRole)
+			if err != nil {
+				t.Error("fail TestSearchMessages: " + err.Error())
+				return
+			}
+		}
+
+		staff, err := GetUserByEmail(constants.TestStaffEmail)
+		if err != nil {
+			t.Error("fail TestSearchMessages: " + err.Error())
+			return
+		}
+
+		outsider, err := GetUserByEmail(constants.TestProcessorEmail)
+		if err != nil {
+			t.Error("fail TestSearchMessages: " + err.Error())
+			return
+		}
+
+		resp, err := PostNewMessage(staff.UserID(), &webpojo.MessagePostReq{
+			ToUserID: testUser.ID,
+			Subject:  "Appraisal schedule",
+			Content:  "The appraiser will visit your property on Thursday morning",
+		})
+		if err != nil {
+			t.Error("fail TestSearchMessages: " + err.Error())
+			return
+		}
+
+		found, err := SearchMessages(fmt.Sprint(testUser.ID), "APPRAIS thursday", 0)
+		if err != nil {
+			t.Error("fail TestSearchMessages: " + err.Error())
+			return
+		}
+
+		if len(found.Results) == 0 || found.Results[0].MessageID != resp.MessageID {
+			t.Error("fail TestSearchMessages: message should be found by content and title words")
+			return
+		}
+
+		// Terms are split between title and content
+		split, err := SearchMessages(fmt.Sprint(testUser.ID), "schedule thursday", 0)
+		if err != nil || len(split.Results) == 0 || split.Results[0].MessageID != resp.MessageID {
+			t.Error("fail TestSearchMessages: message should be found by title word and content word together")
+			return
+		}
+
+		if !strings.Contains(found.Results[0].Snippet, "<mark>Thursday</mark>") || !strings.Contains(found.Results[0].TitleSnippet, "<mark>Appraisal</mark>") {
+			t.Error("fail TestSearchMessages: matches should be highlighted, got " + found.Results[0].Snippet)
+		}
+
+		found, err = SearchMessages(outsider.UserID(), "appraiser thursday", 0)
+		if err != nil || len(found.Results) != 0 {
+			t.Error("fail TestSearchMessages: messages of other threads should not be found")
+		}
+
+		if _, err = SearchMessages(fmt.Sprint(testUser.ID), " +* ", 0); err != ErrEmptySearchQuery {
+			t.Error("fail TestSearchMessages: query without words should be rejected")
+		}
+	})
+
+	t.Run("TestMessageNotifications", func(t *testing.T) {
+		fakeEmail := &email.Fake{}
+		email.SetSender(fakeEmail)
+		defer email.SetSender(nil)
+
+		model.UserRemoveByEmail(constants.TestStaffEmail)
+		defer model.UserRemoveByEmail(constants.TestStaffEmail)
+
+		err := model.UserCreateWithRole("Jane", "Doe", constants.TestStaffEmail, "1qazxsw2", constants.StaffRole)
+		if err != nil {
+			t.Error("fail TestMessageNotifications: " + err.Error())
+			return
+		}
+
+		staff, err := GetUserByEmail(constants.TestStaffEmail)
+		if err != nil {
+			t.Error("fail TestMessageNotifications: " + err.Error())
+			return
+		}
+
+		if err = SetNotificationPreference(fmt.Sprint(testUser.ID), &webpojo.NotificationPreference{Mode: "weekly"}); err != ErrWrongNotificationMode {
+			t.Error("fail TestMessageNotifications: unknown mode should be rejected")
+		}
+
+		err = SetNotificationPreference(fmt.Sprint(testUser.ID), &webpojo.NotificationPreference{Mode: model.NotifyDigest})
+		if err != nil {
+			t.Error("fail TestMessageNotifications: " + err.Error())
+			return
+		}
+		defer SetNotificationPreference(fmt.Sprint(testUser.ID), &webpojo.NotificationPreference{Mode: model.NotifyImmediate})
+
+		pref, err := GetNotificationPreference(fmt.Sprint(testUser.ID))
+		if err != nil || pref.Mode != model.NotifyDigest {
+			t.Error("fail TestMessageNotifications: preference should be saved")
+			return
+		}
+
+		resp, err := PostNewMessage(staff.UserID(), &webpojo.MessagePostReq{ToUserID: testUser.ID, Subject: "Closing date", Content: "Closing is set for Friday"})
+		if err != nil {
+			t.Error("fail TestMessageNotifications: " + err.Error())
+			return
+		}
+
+		_, err = PostNewMessage(fmt.Sprint(testUser.ID), &webpojo.MessagePostReq{ThreadID: resp.ThreadID, Content: "Friday works for me"})
+		if err != nil {
+			t.Error("fail TestMessageNotifications: " + err.Error())
+			return
+		}
+
+		if _, err = DeliverOutbox(); err != nil {
+			t.Error("fail TestMessageNotifications: " + err.Error())
+			return
+		}
+
+		sentTo := func(to string) []*email.Message {
+			res := []*email.Message{}
+			for _, v := range fakeEmail.Sent() {
+				if v.To == to {
+					res = append(res, v)
+				}
+			}
+			return res
+		}
+
+		if len(sentTo(constants.TestUserEmail)) != 0 {
+			t.Error("fail TestMessageNotifications: digest user should not get immediate email")
+		}
+
+		staffEmails := sentTo(constants.TestStaffEmail)
+		if len(staffEmails) != 1 || !strings.Contains(staffEmails[0].Subject, "Closing date") ||
+			!strings.Contains(staffEmails[0].Text, "Friday works for me") || !strings.Contains(staffEmails[0].HTML, "<strong>Closing date</strong>") {
+			t.Error("fail TestMessageNotifications: staff should get new message email")
+		}
+
+		if _, err = SendMessageDigests(); err != nil {
+			t.Error("fail TestMessageNotifications: " + err.Error())
+			return
+		}
+
+		if _, err = DeliverOutbox(); err != nil {
+			t.Error("fail TestMessageNotifications: " + err.Error())
+			return
+		}
+
+		digests := sentTo(constants.TestUserEmail)
+		if len(digests) != 1 || !strings.Contains(digests[0].Text, "Closing is set for Friday") {
+			t.Error("fail TestMessageNotifications: customer should get digest")
+		}
+
+		fakeEmail.Err = errors.New("smtp is down")
+		id, err := model.OutboxEmailCreate(staff.ID, constants.TestStaffEmail, "retry", "text", "")
+		if err != nil {
+			t.Error("fail TestMessageNotifications: " + err.Error())
+			return
+		}
+
+		if _, err = DeliverOutbox(); err != nil {
+			t.Error("fail TestMessageNotifications: " + err.Error())
+			return
+		}
+
+		failed, err := model.OutboxEmailByID(id)
+		if err != nil {
+			t.Error("fail TestMessageNotifications: " + err.Error())
+			return
+		}
+
+		if failed.Status != model.EmailPending || failed.Attempts != 1 || failed.LastError != "smtp is down" {
+			t.Error("fail TestMessageNotifications: failed email should wait for retry")
+		}
+	})
+
+	t.Run("TestGetCustomerProfile", func(t *testing.T) {
+		result, err := GetCustomerProfile(fmt.Sprint(testUser.ID))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if result.FirstName != "John" || result.LastName != "Doe" {
+			t.Error("fail TestGetCustomerProfile: wrong user name")
+			return
+		}
+	})
+
+	t.Run("TestPatchCustomerProfileInfo", func(t *testing.T) {
+
+		password, _ := passhash.HashString("password")
+		patchInfo := &webpojo.CustomerInfoPojoPatchReq{
+			UserID:         fmt.Sprint(testUser.ID),
+			UserName:       "jdoe",
+			Password:       password,
+			Email:          "johndoecool@gmail.com",
+			MailingAddress: "laplandia",
+			Phone:          "911",
+		}
+
+		err := PatchCustomerProfileInfo(patchInfo)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		result, err := GetCustomerProfile(fmt.Sprint(testUser.ID))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if result == nil {
+			t.Error("get customer profile result is null")
+			return
+		}
+
+		AccertEqual(t, "userName", result.UserName, patchInfo.UserName)
+		AccertEqual(t, "email", result.Email, patchInfo.Email)
+		AccertEqual(t, "mailAddress", result.MailingAddress, patchInfo.MailingAddress)
+		AccertEqual(t, "phone", result.Phone, patchInfo.Phone)
+
+		user, err := GetUserByEmail("johndoecool@gmail.com")
+		if err != nil {
+			t.Error("fail TestGetUserByEmail: " + err.Error())
+			return
+		}
+
+		if user == nil {
+			t.Error("user model is nil")
+			return
+		}
+
+		AccertEqual(t, "password", user.Password, patchInfo.Password)
+	})
+
+	t.Run("TestStaffFileAccess", func(t *testing.T) {
+		model.UserRemoveByEmail(constants.TestStaffEmail)
+		defer model.UserRemoveByEmail(constants.TestStaffEmail)
+
+		err := model.UserCreateWithRole("Jane", "Doe", constants.TestStaffEmail, "1qazxsw2", constants.StaffRole)
+		if err != nil {
+			t.Error("fail TestStaffFileAccess: " + err.Error())
+			return
+		}
+
+		staff, err := GetUserByEmail(constants.TestStaffEmail)
+		if err != nil {
+			t.Error("fail TestStaffFileAccess: " + err.Error())
+			return
+		}
+
+		actor := &StaffActor{UserID: staff.UserID(), Role: constants.StaffRole, RemoteAddr: "127.0.0.1"}
+		customerID := fmt.Sprint(testUser.ID)
+
+		if _, err = StaffGetCustomerFileList(actor, customerID, "", &webpojo.FileListReq{}); err != model.ErrUnauthorized {
+			t.Error("fail TestStaffFileAccess: not assigned customer files should not be available")
+			return
+		}
+
+		if err = AssignCustomerToStaff(customerID, customerID); err != ErrWrongUserRole {
+			t.Error("fail TestStaffFileAccess: customer can't be assigned to customer")
+			return
+		}
+
+		if err = AssignCustomerToStaff(staff.UserID(), customerID); err != nil {
+			t.Error("fail TestStaffFileAccess: " + err.Error())
+			return
+		}
+
+		list, err := StaffGetCustomerFileList(actor, customerID, "", &webpojo.FileListReq{})
+		if err != nil {
+			t.Error("fail TestStaffFileAccess: " + err.Error())
+			return
+		}
+
+		if list.Total == 0 || !strings.HasPrefix(list.Files[0].Link, staffFileDownloadPath) {
+			t.Error("fail TestStaffFileAccess: wrong staff file list")
+			return
+		}
+
+		requestID, err := StaffRequestDocument(actor, &webpojo.DocumentRequestReq{CustomerID: testUser.ID, Category: "pay_stub", Note: "last two months"})
+		if err != nil {
+			t.Error("fail TestStaffFileAccess: " + err.Error())
+			return
+		}
+
+		if _, err = GetPendingDocumentRequest(customerID, fmt.Sprint(requestID)); err != nil {
+			t.Error("fail TestStaffFileAccess: document request should be pending: " + err.Error())
+			return
+		}
+
+		fileID, err := SaveFileDataInDB("pay_stub.pdf", "raw", customerID, &webpojo.UserFileMeta{Category: "pay_stub"})
+		if err != nil {
+			t.Error("fail TestStaffFileAccess: " + err.Error())
+			return
+		}
+
+		if err = FulfillDocumentRequest(customerID, fmt.Sprint(requestID), fileID); err != nil {
+			t.Error("fail TestStaffFileAccess: " + err.Error())
+			return
+		}
+
+		if _, err = GetPendingDocumentRequest(customerID, fmt.Sprint(requestID)); err != model.ErrNoResult {
+			t.Error("fail TestStaffFileAccess: fulfilled document request should not be pending")
+			return
+		}
+
+		accessLog, err := GetFileAccessLog(&webpojo.FileAccessLogReq{ActorID: staff.UserID()})
+		if err != nil {
+			t.Error("fail TestStaffFileAccess: " + err.Error())
+			return
+		}
+
+		if accessLog.Total != 3 || accessLog.Records[0].Action != model.FileAccessRequest ||
+			accessLog.Records[1].Action != model.FileAccessList || accessLog.Records[2].Action != model.FileAccessDenied {
+			t.Error("fail TestStaffFileAccess: wrong file access log")
+			return
+		}
+
+		if err = UnassignCustomerFromStaff(staff.UserID(), customerID); err != nil {
+			t.Error("fail TestStaffFileAccess: " + err.Error())
+			return
+		}
+
+		if _, err = StaffGetDocumentRequests(actor, customerID, ""); err != model.ErrUnauthorized {
+			t.Error("fail TestStaffFileAccess: unassigned customer requests should not be available")
+		}
+	})
+
+	t.Run("TestRemoveCustomerByEmail", func(t *testing.T) {
+		err := RemoveCustomerByEmail(constants.TestOtherUserEmail)
+		if err != nil {
+			t.Error("fail TestRemoveCustomerByEmail: " + err.Error())
+			return
+		}
+	})
+
+	t.Run("TestGetAmazonCredentials", func(t *testing.T) {
+		c, err := getAmazonCredentials()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if c == nil {
+			t.Error(errors.New("error while get amazon credentials - credentials is nil"))
+			return
+		}
+	})
+
+	t.Run("TestGetAmazonSession", func(t *testing.T) {
+		s, err := getAmazonSession()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if s == nil {
+			t.Error(errors.New("error while get amazon session - credentials is nil"))
+			return
+		}
+	})
+
+	t.Run("TestGetFastQuote", func(t *testing.T) {
+		quotes, err := GetFastQuote(&webpojo.FastQuoteReq{LoanAmount: 300000, TermYears: 30, CreditBand: "good", Product: "30 year fixed"})
+		if err != nil {
+			t.Error(errors.New("fail TestGetFastQuote: " + err.Error()))
+			return
+		}
+
+		if len(quotes) != 1 {
+			t.Error(errors.New("fail TestGetFastQuote: one quote of requested product expected"))
+			return
+		}
+
+		// Best 30 year rate 3.63 plus good credit band adjustment
+		if quotes[0].Interest != 3.755 || quotes[0].MonthlyPayment != 1390.2 {
+			t.Error(fmt.Sprintf("fail TestGetFastQuote: wrong quote %v, payment %v", quotes[0].Interest, quotes[0].MonthlyPayment))
+		}
+
+		quotes, err = GetFastQuote(&webpojo.FastQuoteReq{LoanAmount: 600000, TermYears: 30, CreditBand: "excellent"})
+		if err != nil {
+			t.Error(errors.New("fail TestGetFastQuote: " + err.Error()))
+			return
+		}
+
+		if len(quotes) != 2 || quotes[0].Interest != 3.88 {
+			t.Error(errors.New("fail TestGetFastQuote: 30 year products with jumbo adjustment expected"))
+		}
+
+		if _, err = GetFastQuote(&webpojo.FastQuoteReq{LoanAmount: 300000, CreditBand: "unknown"}); err != ErrWrongCreditBand {
+			t.Error(errors.New("fail TestGetFastQuote: unknown credit band should be rejected"))
+		}
+
+		if _, err = GetFastQuote(&webpojo.FastQuoteReq{CreditBand: "good"}); err != ErrWrongLoanAmount {
+			t.Error(errors.New("fail TestGetFastQuote: empty loan amount should be rejected"))
+		}
+
+		if _, err = GetFastQuote(&webpojo.FastQuoteReq{LoanAmount: 300000, CreditBand: "good", TermYears: 40}); err != ErrNoQuote {
+			t.Error(errors.New("fail TestGetFastQuote: unknown term should have no quote"))
+		}
+	})
+
+	t.Run("TestCalculator", func(t *testing.T) {
+		payment, err := CalculatePayment(&webpojo.CalculatorReq{LoanAmount: 300000, TermYears: 30, Interest: 3.755})
+		if err != nil {
+			t.Error(errors.New("fail TestCalculator: " + err.Error()))
+			return
+		}
+
+		if payment.MonthlyPayment != 1390.2 || payment.TotalInterest != 200470.68 {
+			t.Error(fmt.Sprintf("fail TestCalculator: wrong payment %v, interest %v", payment.MonthlyPayment, payment.TotalInterest))
+		}
+
+		schedule, err := CalculateAmortization(&webpojo.CalculatorReq{LoanAmount: 300000, TermYears: 30, Interest: 3.755,
+			ExtraMonthly: 100, ExtraPayments: []webpojo.ExtraPayment{{Month: 12, Amount: 10000}}})
+		if err != nil {
+			t.Error(errors.New("fail TestCalculator: " + err.Error()))
+			return
+		}
+
+		last := schedule.Schedule[len(schedule.Schedule)-1]
+		if schedule.Months != 302 || schedule.MonthsSaved != 58 || schedule.InterestSaved != 41685.97 || last.Balance != 0 {
+			t.Error(fmt.Sprintf("fail TestCalculator: wrong schedule %v months, %v saved", schedule.Months, schedule.InterestSaved))
+		}
+
+		if _, err = CalculateAmortization(&webpojo.CalculatorReq{LoanAmount: 300000, TermYears: 30, Interest: 3.755,
+			ExtraPayments: []webpojo.ExtraPayment{{Month: 361, Amount: 100}}}); err != ErrWrongExtraPayment {
+			t.Error(errors.New("fail TestCalculator: extra payment after loan term should be rejected"))
+		}
+
+		if _, err = CalculatePayment(&webpojo.CalculatorReq{LoanAmount: 1e17, TermYears: 30, Interest: 3.755}); err != ErrWrongLoanAmount {
+			t.Error(errors.New("fail TestCalculator: loan amount above max should be rejected"))
+		}
+
+		best, err := GetBestLenderRatesList()
+		if err != nil {
+			t.Error(errors.New("fail TestCalculator: " + err.Error()))
+			return
+		}
+
+		// Term and rate are taken from lender rate
+		payment, err = CalculatePayment(&webpojo.CalculatorReq{LoanAmount: 300000, RateID: best[1].ID})
+		if err != nil || payment.TermYears != 30 || payment.Interest != 3.63 || payment.Apr != 3.71 {
+			t.Error(errors.New("fail TestCalculator: lender rate loan expected"))
+		}
+
+		comparison, err := CompareLenderRates(&webpojo.RateComparisonReq{LoanAmount: 300000, Product: "30 Year Fixed"})
+		if err != nil {
+			t.Error(errors.New("fail TestCalculator: " + err.Error()))
+			return
+		}
+
+		if len(comparison.Comparisons) != 3 || comparison.Comparisons[0].ID != best[1].ID || comparison.Comparisons[0].AboveBest != 0 ||
+			comparison.Comparisons[2].AboveBest <= 0 {
+			t.Error(errors.New("fail TestCalculator: effective 30 year rates ordered by APR cost expected"))
+		}
+	})
+
+	t.Run("TestGetLenderRateHistory", func(t *testing.T) {
+		history, err := GetLenderRateHistory(&webpojo.LenderRateHistoryReq{Product: "30 Year Fixed", From: "2016-12-31", To: "2017-07-01"})
+		if err != nil {
+			t.Error(errors.New("fail TestGetLenderRateHistory: " + err.Error()))
+			return
+		}
+
+		// No rates on 2016-12-31, Union Home Lending is best until First National Mortgage rate begins
+		points := history.History
+		if len(points) != 182 || points[0].Date != "2017-01-01" || points[0].LenderID != 2 ||
+			points[len(points)-1].LenderID != 1 || points[len(points)-1].Interest != 3.4 {
+			t.Error(errors.New("fail TestGetLenderRateHistory: wrong history"))
+		}
+
+		if _, err = GetLenderRateHistory(&webpojo.LenderRateHistoryReq{Product: "30 Year Fixed", From: "2017-07-01", To: "2017-01-01"}); err != ErrWrongRateRange {
+			t.Error(errors.New("fail TestGetLenderRateHistory: reversed range should be rejected"))
+		}
+
+		rates, err := GetBestLenderRatesAsOf("2017-06-30")
+		if err != nil || len(rates) != 1 || rates[0].Interest != "3.450" {
+			t.Error(errors.New("fail TestGetLenderRateHistory: best rate as of 2017 day expected"))
+		}
+
+		if _, err = GetBestLenderRatesAsOf("yesterday"); err != ErrWrongRateDate {
+			t.Error(errors.New("fail TestGetLenderRateHistory: bad date should be rejected"))
+		}
+	})
+
+	t.Run("TestSearchLenderRates", func(t *testing.T) {
+		best, err := SearchLenderRates(&webpojo.LenderRateListReq{Criteria: "best", Product: "30 Year Fixed"})
+		if err != nil {
+			t.Error(errors.New("fail TestSearchLenderRates: " + err.Error()))
+			return
+		}
+
+		if len(best.LenderRates) != 1 || best.LenderRates[0].LenderID != 2 || best.NextCursor != "" {
+			t.Error(errors.New("fail TestSearchLenderRates: best 30 year rate of Union Home Lending expected"))
+		}
+
+		grouped, err := SearchLenderRates(&webpojo.LenderRateListReq{Criteria: "best", GroupBy: "product"})
+		if err != nil || len(grouped.LenderRates) != 0 || len(grouped.Groups) < 3 || grouped.Groups[0].Product != "15 Year Fixed" ||
+			len(grouped.Groups[0].LenderRates) != 1 {
+			t.Error(errors.New("fail TestSearchLenderRates: best rates grouped by product expected"))
+		}
+
+		// Rates effective today are paged from cache
+		first, err := SearchLenderRates(&webpojo.LenderRateListReq{Product: "15 Year Fixed", Validity: "effective", Sort: "apr", Count: 2})
+		if err != nil {
+			t.Error(errors.New("fail TestSearchLenderRates: " + err.Error()))
+			return
+		}
+
+		if len(first.LenderRates) != 2 || first.LenderRates[0].Apr != 3.35 || first.LenderRates[1].Apr != 3.46 || first.NextCursor == "" {
+			t.Error(errors.New("fail TestSearchLenderRates: first page of 15 year rates by apr expected"))
+			return
+		}
+
+		next, err := SearchLenderRates(&webpojo.LenderRateListReq{Product: "15 Year Fixed", Validity: "effective", Sort: "apr", Count: 2,
+			Cursor: first.NextCursor})
+		if err != nil || len(next.LenderRates) != 1 || next.LenderRates[0].Apr != 3.52 || next.NextCursor != "" {
+			t.Error(errors.New("fail TestSearchLenderRates: next page should continue sort order"))
+			return
+		}
+
+		prev, err := SearchLenderRates(&webpojo.LenderRateListReq{Product: "15 Year Fixed", Validity: "effective", Sort: "apr", Count: 2,
+			Cursor: next.PrevCursor})
+		if err != nil || len(prev.LenderRates) != 2 || prev.LenderRates[0].ID != first.LenderRates[0].ID {
+			t.Error(errors.New("fail TestSearchLenderRates: previous page should be the first page"))
+		}
+
+		// Past day is searched in database
+		past, err := SearchLenderRates(&webpojo.LenderRateListReq{Product: "30 Year Fixed", Validity: "effective", AsOf: "2017-08-01",
+			Sort: "interest", Desc: true, MaxRate: 3.5, RateField: "interest"})
+		if err != nil || len(past.LenderRates) != 2 || past.LenderRates[0].Interest != 3.45 || past.LenderRates[1].Interest != 3.4 {
+			t.Error(errors.New("fail TestSearchLenderRates: 2017 rates by interest expected"))
+		}
+
+		expired, err := SearchLenderRates(&webpojo.LenderRateListReq{LenderID: 2, Validity: "expired"})
+		if err != nil || len(expired.LenderRates) == 0 {
+			t.Error(errors.New("fail TestSearchLenderRates: expired rates of lender expected"))
+			return
+		}
+
+		for _, v := range expired.LenderRates {
+			if v.LenderID != 2 || v.EndDate >= today() {
+				t.Error(errors.New("fail TestSearchLenderRates: only expired rates of lender expected"))
+			}
+		}
+
+		// Effective rates of today are listed from cache
+		effective, err := SearchLenderRates(&webpojo.LenderRateListReq{LenderID: 2, Validity: "effective"})
+		if err != nil || len(effective.LenderRates) == 0 {
+			t.Error(errors.New("fail TestSearchLenderRates: effective rates of lender expected"))
+			return
+		}
+
+		for _, v := range effective.LenderRates {
+			if v.BeginDate > today() || v.EndDate < today() {
+				t.Error(errors.New("fail TestSearchLenderRates: only effective rates expected"))
+			}
+		}
+
+		// Rates list without validity is list of rates of any validity
+		all, err := SearchLenderRates(&webpojo.LenderRateListReq{LenderID: 2, Count: maxRateListCount})
+		if err != nil || len(all.LenderRates) < len(effective.LenderRates)+len(expired.LenderRates) {
+			t.Error(errors.New("fail TestSearchLenderRates: rates of any validity expected by default"))
+		}
+
+		if _, err = SearchLenderRates(&webpojo.LenderRateListReq{Sort: "name"}); err != ErrWrongRateSort {
+			t.Error(errors.New("fail TestSearchLenderRates: unknown sort should be rejected"))
+		}
+
+		if _, err = SearchLenderRates(&webpojo.LenderRateListReq{MinRate: 3, RateField: "points"}); err != ErrWrongRateField {
+			t.Error(errors.New("fail TestSearchLenderRates: unknown rate field should be rejected"))
+		}
+
+		if _, err = SearchLenderRates(&webpojo.LenderRateListReq{MinRate: 4, MaxRate: 3}); err != ErrWrongRateBounds {
+			t.Error(errors.New("fail TestSearchLenderRates: reversed bounds should be rejected"))
+		}
+
+		if _, err = SearchLenderRates(&webpojo.LenderRateListReq{GroupBy: "lender"}); err != ErrWrongRateGroup {
+			t.Error(errors.New("fail TestSearchLenderRates: unknown grouping should be rejected"))
+		}
+	})
+
+	t.Run("TestLenderCRUD", func(t *testing.T) {
+		lenderID, err := CreateLender(&webpojo.LenderPojo{Name: "Test Lender", Email: "rates@testlender.example", StatusID: model.LenderActive})
+		if err != nil {
+			t.Error(errors.New("fail TestLenderCRUD: " + err.Error()))
+			return
+		}
+		defer DeleteLender(lenderID)
+
+		if _, err = CreateLender(&webpojo.LenderPojo{Name: "Test Lender", StatusID: model.LenderActive}); err != ErrLenderNameTaken {
+			t.Error(errors.New("fail TestLenderCRUD: duplicate lender name should be rejected"))
+		}
+
+		rateID, err := CreateLenderRate(&webpojo.LenderRatePojo{LenderID: lenderID, Product: "30 Year Fixed", TermYears: 30,
+			Interest: 3.1, Apr: 3.2, BeginDate: "2018-01-01", EndDate: "2099-12-31"})
+		if err != nil {
+			t.Error(errors.New("fail TestLenderCRUD: " + err.Error()))
+			return
+		}
+
+		rates, err := GetBestLenderRatesList()
+		if err != nil || !hasBestRate(rates, rateID) {
+			t.Error(errors.New("fail TestLenderCRUD: new rate should be best after cache refresh"))
+		}
+
+		err = UpdateLender(&webpojo.LenderPojo{ID: lenderID, Name: "Test Lender", StatusID: model.LenderInactive})
+		if err != nil {
+			t.Error(errors.New("fail TestLenderCRUD: " + err.Error()))
+			return
+		}
+
+		rates, err = GetBestLenderRatesList()
+		if err != nil || hasBestRate(rates, rateID) {
+			t.Error(errors.New("fail TestLenderCRUD: rate of inactive lender should not be best"))
+		}
+
+		err = UpdateLenderRate(&webpojo.LenderRatePojo{ID: rateID, LenderID: lenderID, Product: "30 Year Fixed", TermYears: 30,
+			Interest: 3.5, Apr: 3.6, BeginDate: "2018-01-01", EndDate: "2099-12-31"})
+		if err != nil {
+			t.Error(errors.New("fail TestLenderCRUD: " + err.Error()))
+			return
+		}
+
+		lenderRates, err := GetLenderRates(lenderID)
+		if err != nil || len(lenderRates) != 1 || lenderRates[0].Interest != 3.5 {
+			t.Error(errors.New("fail TestLenderCRUD: updated rate expected in rate sheet"))
+		}
+
+		if err = UpdateLenderRate(&webpojo.LenderRatePojo{ID: rateID, LenderID: 4294967295, Product: "30 Year Fixed", TermYears: 30,
+			Interest: 3.5, Apr: 3.6, BeginDate: "2018-01-01", EndDate: "2099-12-31"}); err != ErrLenderNotExist {
+			t.Error(errors.New("fail TestLenderCRUD: rate of unknown lender should be rejected"))
+		}
+
+		if err = DeleteLenderRate(rateID); err != nil {
+			t.Error(errors.New("fail TestLenderCRUD: " + err.Error()))
+		}
+
+		if err = DeleteLenderRate(rateID); err != ErrLenderRateNotExist {
+			t.Error(errors.New("fail TestLenderCRUD: deleted rate should not exist"))
+		}
+
+		if err = UpdateLender(&webpojo.LenderPojo{ID: 4294967295, Name: "Unknown Lender"}); err != ErrLenderNotExist {
+			t.Error(errors.New("fail TestLenderCRUD: unknown lender should not be updated"))
+		}
+	})
+
+	t.Run("TestImportRateSheet", func(t *testing.T) {
+		lenderID, err := CreateLender(&webpojo.LenderPojo{Name: "Test Sheet Lender", StatusID: model.LenderInactive})
+		if err != nil {
+			t.Error(errors.New("fail TestImportRateSheet: " + err.Error()))
+			return
+		}
+		defer DeleteLender(lenderID)
+
+		_, err = CreateLenderRate(&webpojo.LenderRatePojo{LenderID: lenderID, Product: "30 Year Fixed", TermYears: 30,
+			Interest: 3.9, Apr: 4, BeginDate: "2018-01-01", EndDate: "2099-12-31"})
+		if err != nil {
+			t.Error(errors.New("fail TestImportRateSheet: " + err.Error()))
+			return
+		}
+
+		sheet := []byte("product,term_years,interest,apr,begin_date,end_date\n" +
+			"30 Year Fixed,30,3.75%,3.85,2018-01-01,2099-12-31\n" +
+			"15 Year Fixed,15,3.25,3.3,2018-01-01,2099-12-31\n")
+
+		report, err := ImportRateSheet(lenderID, ratesheet.FormatCSV, sheet, true)
+		if err != nil {
+			t.Error(errors.New("fail TestImportRateSheet: " + err.Error()))
+			return
+		}
+
+		if report.Applied || len(report.Added) != 1 || len(report.Changed) != 1 || report.Changed[0].New.Interest != 3.75 {
+			t.Error(errors.New("fail TestImportRateSheet: dry run should report one added and one changed rate"))
+		}
+
+		report, err = ImportRateSheet(lenderID, ratesheet.FormatCSV, append(sheet, []byte("5/1 ARM,30,4.5,4.1,2018-01-01,2099-12-31\n")...), false)
+		if err != ErrRateSheetRejected || len(report.Rejected) != 1 || report.Rejected[0].Line != 4 || report.Applied {
+			t.Error(errors.New("fail TestImportRateSheet: sheet with apr below interest should be rejected"))
+		}
+
+		report, err = ImportRateSheet(lenderID, ratesheet.FormatCSV, append(sheet, []byte("5/1 ARM,30,3.6255,3.7,2018-01-01,2099-12-31\n")...), true)
+		if err != ErrRateSheetRejected || len(report.Rejected) != 1 || report.Rejected[0].Line != 4 {
+			t.Error(errors.New("fail TestImportRateSheet: rate with more decimals than stored should be rejected"))
+		}
+
+		if v, err := parseRatePercent("3.6250000000000004"); err != nil || v != 3.625 {
+			t.Error(errors.New("fail TestImportRateSheet: spreadsheet float noise should be accepted"))
+		}
+
+		report, err = ImportRateSheet(lenderID, ratesheet.FormatCSV, sheet, false)
+		if err != nil || !report.Applied {
+			t.Error(errors.New("fail TestImportRateSheet: sheet should be imported"))
+			return
+		}
+
+		// Replaced rate began before today, so it is ended yesterday and kept as history
+		yesterday := time.Now().UTC().AddDate(0, 0, -1).Format(model.RateDateLayout)
+		rates, err := GetLenderRates(lenderID)
+		if err != nil || len(rates) != 3 || rates[2].Product != "30 Year Fixed" || rates[2].Apr != 3.85 {
+			t.Error(errors.New("fail TestImportRateSheet: lender rates should be replaced with sheet rates"))
+			return
+		}
+
+		if rates[1].Apr != 4 || rates[1].EndDate != yesterday {
+			t.Error(errors.New("fail TestImportRateSheet: replaced rate should end yesterday"))
+		}
+
+		_, err = CreateLenderRate(&webpojo.LenderRatePojo{LenderID: lenderID, Product: "5/1 ARM", TermYears: 30,
+			Interest: 3.5, Apr: 3.6, BeginDate: "2099-01-01", EndDate: "2099-12-31"})
+		if err != nil {
+			t.Error(errors.New("fail TestImportRateSheet: " + err.Error()))
+			return
+		}
+
+		if _, err = ImportRateSheet(lenderID, ratesheet.FormatCSV, sheet, false); err != nil {
+			t.Error(errors.New("fail TestImportRateSheet: " + err.Error()))
+			return
+		}
+
+		rates, err = GetLenderRates(lenderID)
+		if err != nil {
+			t.Error(errors.New("fail TestImportRateSheet: " + err.Error()))
+			return
+		}
+
+		for _, v := range rates {
+			if v.Product == "5/1 ARM" {
+				t.Error(errors.New("fail TestImportRateSheet: replaced future rate should be deleted"))
+			}
+		}
+	})
+
+	t.Run("TestRateAlerts", func(t *testing.T) {
+		fakeEmail := &email.Fake{}
+		email.SetSender(fakeEmail)
+		defer email.SetSender(nil)
+
+		userID := fmt.Sprint(testUser.ID)
+		if _, err := CreateRateAlert(userID, &webpojo.RateAlertReq{Product: "30 Year Fixed", Threshold: 3.7, Direction: "sideways"}); err != ErrWrongAlertDirection {
+			t.Error(errors.New("fail TestRateAlerts: unknown direction should be rejected"))
+		}
+
+		if _, err := CreateRateAlert(userID, &webpojo.RateAlertReq{Product: "Balloon", Threshold: 3.7, Direction: model.AlertBelow}); err != ErrWrongAlertProduct {
+			t.Error(errors.New("fail TestRateAlerts: unknown product should be rejected"))
+		}
+
+		id, err := CreateRateAlert(userID, &webpojo.RateAlertReq{Product: "30 year fixed", Threshold: 3.7, Direction: model.AlertBelow})
+		if err != nil {
+			t.Error(errors.New("fail TestRateAlerts: " + err.Error()))
+			return
+		}
+		defer DeleteRateAlert(userID, id)
+
+		if _, err = CreateRateAlert(userID, &webpojo.RateAlertReq{Product: "30 Year Fixed", Threshold: 3.7, Direction: model.AlertBelow}); err != ErrRateAlertExists {
+			t.Error(errors.New("fail TestRateAlerts: the same alert should be rejected"))
+		}
+
+		alerts, err := GetRateAlerts(userID)
+		if err != nil || len(alerts) != 1 || alerts[0].Product != "30 Year Fixed" || alerts[0].Threshold != 3.7 {
+			t.Error(errors.New("fail TestRateAlerts: alert should be listed with product name of rates"))
+			return
+		}
+
+		rates := []model.LenderRate{{ID: 1, LenderName: "Test Lender", Product: "30 Year Fixed", TermYears: 30, Interest: "3.63", Apr: "3.71"}}
+		for i, expected := range []int{1, 0} {
+			sent, err := EvaluateRateAlerts(rates)
+			if err != nil || sent != expected {
+				t.Error(fmt.Sprintf("fail TestRateAlerts: evaluation %v sent %v alerts, %v expected", i, sent, expected))
+			}
+		}
+
+		// Concurrent evaluation that read alert before it was triggered enqueues nothing
+		if triggered, err := model.RateAlertTrigger(id, "3.63", &model.OutboxEmail{ToEmail: constants.TestUserEmail}); err != nil || triggered {
+			t.Error(errors.New("fail TestRateAlerts: triggered alert should not be triggered again"))
+		}
+
+		if _, err = DeliverOutbox(); err != nil {
+			t.Error(errors.New("fail TestRateAlerts: " + err.Error()))
+			return
+		}
+
+		sent := fakeEmail.Sent()
+		if len(sent) != 1 || sent[0].To != constants.TestUserEmail || !strings.Contains(sent[0].Text, "3.63") {
+			t.Error(errors.New("fail TestRateAlerts: alert email expected"))
+			return
+		}
+
+		// Rate rises back above threshold and re-arms alert, next drop alerts again
+		rates[0].Interest = "3.80"
+		if sent, err := EvaluateRateAlerts(rates); err != nil || sent != 0 {
+			t.Error(errors.New("fail TestRateAlerts: alert should not be sent above threshold"))
+		}
+
+		rates[0].Interest = "3.65"
+		if sent, err := EvaluateRateAlerts(rates); err != nil || sent != 1 {
+			t.Error(errors.New("fail TestRateAlerts: re-armed alert should be sent"))
+		}
+
+		token := sent[0].Text[strings.Index(sent[0].Text, "token=")+len("token="):]
+		token = strings.TrimSpace(strings.SplitN(token, "\n", 2)[0])
+		if err = UnsubscribeRateAlert(token); err != nil {
+			t.Error(errors.New("fail TestRateAlerts: unsubscribe link should remove alert"))
+		}
+
+		if err = UnsubscribeRateAlert(token); err != ErrRateAlertNotExist {
+			t.Error(errors.New("fail TestRateAlerts: alert should be unsubscribed already"))
+		}
+
+		if err = DeleteRateAlert(userID, id); err != ErrRateAlertNotExist {
+			t.Error(errors.New("fail TestRateAlerts: unsubscribed alert should not exist"))
+		}
+	})
+
+	t.Run("TestLoanApplications", func(t *testing.T) {
+		model.UserRemoveByEmail(constants.TestStaffEmail)
+		defer model.UserRemoveByEmail(constants.TestStaffEmail)
+
+		err := model.UserCreateWithRole("Jane", "Doe", constants.TestStaffEmail, "1qazxsw2", constants.StaffRole)
+		if err != nil {
+			t.Error(errors.New("fail TestLoanApplications: " + err.Error()))
+			return
+		}
+
+		staff, err := GetUserByEmail(constants.TestStaffEmail)
+		if err != nil {
+			t.Error(errors.New("fail TestLoanApplications: " + err.Error()))
+			return
+		}
+
+		rates, err := GetBestLenderRatesList()
+		if err != nil || len(rates) == 0 {
+			t.Error(errors.New("fail TestLoanApplications: effective rates expected"))
+			return
+		}
+
+		userID := fmt.Sprint(testUser.ID)
+		if _, err = CreateLoanApplication(userID, &webpojo.LoanApplicationReq{LenderRateID: rates[0].ID}); err != ErrWrongLoanAmount {
+			t.Error(errors.New("fail TestLoanApplications: zero loan amount should be rejected"))
+		}
+
+		id, err := CreateLoanApplication(userID, &webpojo.LoanApplicationReq{LenderRateID: rates[0].ID, LoanAmount: 250000})
+		if err != nil {
+			t.Error(errors.New("fail TestLoanApplications: " + err.Error()))
+			return
+		}
+
+		if err = DeleteLender(rates[0].LenderID); err != ErrLenderInUse {
+			t.Error(errors.New("fail TestLoanApplications: lender with applications should not be deleted"))
+		}
+
+		err = UpdateLoanApplication(userID, &webpojo.LoanApplicationReq{ID: id, LenderRateID: rates[0].ID, LoanAmount: 200000, FileIDs: []uint32{math.MaxUint32}})
+		if err != ErrAttachmentNotFound {
+			t.Error(errors.New("fail TestLoanApplications: file of other user should be rejected"))
+		}
+
+		err = UpdateLoanApplication(userID, &webpojo.LoanApplicationReq{ID: id, LenderRateID: rates[0].ID, LoanAmount: 200000})
+		if err != nil {
+			t.Error(errors.New("fail TestLoanApplications: " + err.Error()))
+			return
+		}
+
+		move := func(actorID string, role int, state string) error {
+			return ChangeLoanApplicationState(actorID, role, &webpojo.LoanApplicationStateReq{ID: id, State: state, Note: "moved to " + state})
+		}
+
+		if err = move(userID, constants.CustomerRole, model.LoanInReview); err != ErrWrongLoanTransition {
+			t.Error(errors.New("fail TestLoanApplications: customer should not review application"))
+		}
+
+		if err = move(userID, constants.CustomerRole, model.LoanSubmitted); err != nil {
+			t.Error(errors.New("fail TestLoanApplications: " + err.Error()))
+			return
+		}
+
+		err = UpdateLoanApplication(userID, &webpojo.LoanApplicationReq{ID: id, LenderRateID: rates[0].ID, LoanAmount: 100000})
+		if err != ErrWrongLoanTransition {
+			t.Error(errors.New("fail TestLoanApplications: submitted application should not be updated"))
+		}
+
+		if err = move(staff.UserID(), constants.StaffRole, model.LoanInReview); err != model.ErrUnauthorized {
+			t.Error(errors.New("fail TestLoanApplications: not assigned staff should not review application"))
+		}
+
+		if err = AssignCustomerToStaff(staff.UserID(), userID); err != nil {
+			t.Error(errors.New("fail TestLoanApplications: " + err.Error()))
+			return
+		}
+
+		for _, state := range []string{model.LoanInReview, model.LoanApproved, model.LoanFunded} {
+			if err = move(staff.UserID(), constants.StaffRole, state); err != nil {
+				t.Error(errors.New("fail TestLoanApplications: move to " + state + ": " + err.Error()))
+				return
+			}
+		}
+
+		if err = move(staff.UserID(), constants.StaffRole, model.LoanDeclined); err != ErrWrongLoanTransition {
+			t.Error(errors.New("fail TestLoanApplications: funded application should not be declined"))
+		}
+
+		apps, err := StaffGetLoanApplications(staff.UserID(), constants.StaffRole, model.LoanFunded, 0)
+		if err != nil || len(apps) == 0 || apps[0].ID != id {
+			t.Error(errors.New("fail TestLoanApplications: funded application of assigned customer should be listed"))
+		}
+
+		app, err := GetLoanApplication(userID, constants.CustomerRole, id)
+		if err != nil {
+			t.Error(errors.New("fail TestLoanApplications: " + err.Error()))
+			return
+		}
+
+		if app.State != model.LoanFunded || app.StaffID != staff.ID || app.LoanAmount != 200000 || len(app.History) != 5 ||
+			app.History[2].FromState != model.LoanSubmitted || app.History[2].ActorID != staff.ID {
+			t.Error(errors.New("fail TestLoanApplications: funded application with state history expected"))
+		}
+	})
+
+	t.Run("TestFastQuotePersistence", func(t *testing.T) {
+		quotes, err := CreateFastQuote("", &webpojo.FastQuoteReq{LoanAmount: 300000, TermYears: 30, CreditBand: "good"})
+		if err != nil {
+			t.Error(errors.New("fail TestFastQuotePersistence: " + err.Error()))
+			return
+		}
+
+		quoteID := quotes[0].QuoteID
+		if quoteID == "" || quotes[len(quotes)-1].QuoteID != quoteID {
+			t.Error(errors.New("fail TestFastQuotePersistence: all rates of quote should have quote id"))
+			return
+		}
+
+		userID := fmt.Sprint(testUser.ID)
+		if err = ClaimFastQuote(userID, "missing"); err != ErrFastQuoteNotExist {
+			t.Error(errors.New("fail TestFastQuotePersistence: unknown quote should not be claimed"))
+		}
+
+		for i := 0; i < 2; i++ {
+			if err = ClaimFastQuote(userID, quoteID); err != nil {
+				t.Error(errors.New("fail TestFastQuotePersistence: " + err.Error()))
+				return
+			}
+		}
+
+		if err = ClaimFastQuote(fmt.Sprint(math.MaxUint32), quoteID); err != ErrFastQuoteClaimed {
+			t.Error(errors.New("fail TestFastQuotePersistence: quote of other customer should not be claimed"))
+		}
+
+		saved, err := GetFastQuotes(userID)
+		if err != nil || len(saved) == 0 || saved[0].QuoteID != quoteID || len(saved[0].Rates) != len(quotes) ||
+			saved[0].LoanAmount != 300000 || saved[0].ClaimedAt == "" {
+			t.Error(errors.New("fail TestFastQuotePersistence: claimed quote with rates expected"))
+			return
+		}
+
+		_, err = CreateLoanApplication(userID, &webpojo.LoanApplicationReq{LenderRateID: saved[0].Rates[0].ID, LoanAmount: 300000})
+		if err != nil {
+			t.Error(errors.New("fail TestFastQuotePersistence: " + err.Error()))
+			return
+		}
+
+		report, err := GetFastQuoteReport("", "")
+		if err != nil || len(report.Days) == 0 {
+			t.Error(errors.New("fail TestFastQuotePersistence: report of today quotes expected"))
+			return
+		}
+
+		day := report.Days[len(report.Days)-1]
+		if day.Date != report.To || day.Claimed == 0 || day.Applied == 0 || report.ClaimRate <= 0 {
+			t.Error(fmt.Sprintf("fail TestFastQuotePersistence: wrong report %+v", report))
+		}
+
+		if _, err = GetFastQuoteReport("2018-02-01", "2018-01-01"); err != ErrWrongRateRange {
+			t.Error(errors.New("fail TestFastQuotePersistence: reversed range should be rejected"))
+		}
+
+		anonymous, err := CreateFastQuote("", &webpojo.FastQuoteReq{LoanAmount: 200000, TermYears: 30, CreditBand: "good"})
+		if err != nil {
+			t.Error(errors.New("fail TestFastQuotePersistence: " + err.Error()))
+			return
+		}
+
+		// Negative retention purges anonymous quotes created up to now
+		if purged, err := PurgeFastQuotes(-time.Hour); err != nil || purged == 0 {
+			t.Error(errors.New("fail TestFastQuotePersistence: anonymous quote should be purged"))
+		}
+
+		if err = ClaimFastQuote(userID, anonymous[0].QuoteID); err != ErrFastQuoteNotExist {
+			t.Error(errors.New("fail TestFastQuotePersistence: purged quote should not be claimed"))
+		}
+
+		if saved, err = GetFastQuotes(userID); err != nil || len(saved) == 0 || saved[0].QuoteID != quoteID {
+			t.Error(errors.New("fail TestFastQuotePersistence: claimed quote should not be purged"))
+		}
+	})
+
+	t.Run("TestRateFeed", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "ratefeed")
+		if err != nil {
+			t.Error(errors.New("fail TestRateFeed: " + err.Error()))
+			return
+		}
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "feed.json")
+		writeFeed := func(interest string) error {
+			return ioutil.WriteFile(path, []byte(`[
+				{"lender": "coastal-cu", "product": "7/1 ARM", "term_years": 30, "interest": `+interest+`, "apr": 3.58, "begin_date": "2018-01-01", "end_date": "2099-12-31"},
+				{"lender": "coastal-cu", "product": "15 Year Fixed", "term_years": 15, "interest": 3.45, "apr": 3.52, "begin_date": "2018-01-01", "end_date": "2099-12-31"},
+				{"lender": "nobody", "product": "15 Year Fixed", "term_years": 15, "interest": 3.45, "apr": 3.52, "begin_date": "2018-01-01", "end_date": "2099-12-31"},
+				{"lender": "3", "product": "30 Year Fixed", "term_years": 30, "interest": 3.7, "apr": 3.0, "begin_date": "2018-01-01", "end_date": "2099-12-31"}
+			]`), 0600)
+		}
+
+		if err = writeFeed("3.15"); err != nil {
+			t.Error(errors.New("fail TestRateFeed: " + err.Error()))
+			return
+		}
+
+		err = ratefeed.Configure(ratefeed.Info{Type: ratefeed.TypeFile, File: path, Lenders: map[string]uint32{"coastal-cu": 3}})
+		if err != nil {
+			t.Error(errors.New("fail TestRateFeed: " + err.Error()))
+			return
+		}
+		defer ratefeed.Configure(ratefeed.Info{})
+
+		run, rejected, err := IngestRateFeed()
+		if err != nil || run.Fetched != 4 || run.Inserted != 1 || run.Updated != 0 || len(rejected) != 2 || rejected[0].Item != 2 ||
+			rejected[1].Item != 3 || rejected[1].Error != "unknown lender" {
+			t.Error(fmt.Sprintf("fail TestRateFeed: wrong ingestion %+v, %+v, %v", run, rejected, err))
+			return
+		}
+
+		if err = writeFeed("3.20"); err != nil {
+			t.Error(errors.New("fail TestRateFeed: " + err.Error()))
+			return
+		}
+
+		if run, _, err = IngestRateFeed(); err != nil || run.Inserted != 0 || run.Updated != 1 {
+			t.Error(fmt.Sprintf("fail TestRateFeed: changed rate should be updated %+v, %v", run, err))
+		}
+
+		status, err := GetRateFeedStatus()
+		if err != nil || !status.Enabled || status.Stale || status.LastSuccess == nil || status.LastSuccess.Updated != 1 {
+			t.Error(errors.New("fail TestRateFeed: fresh feed status expected"))
+		}
+
+		rates, err := model.LenderRatesByLenderID(3)
+		if err != nil {
+			t.Error(errors.New("fail TestRateFeed: " + err.Error()))
+			return
+		}
+
+		for _, v := range rates {
+			if v.Product == "7/1 ARM" {
+				if v.Interest != "3.200" {
+					t.Error(errors.New("fail TestRateFeed: feed rate expected"))
+				}
+				model.LenderRateDelete(v.ID)
+			}
+		}
+
+		ratefeed.Configure(ratefeed.Info{})
+		report, err := RefreshRates()
+		if err != nil || report.Run != nil || report.BestRates == 0 {
+			t.Error(errors.New("fail TestRateFeed: best rates should be reloaded without disabled feed"))
+			return
+		}
+
+		best, _ := GetBestLenderRatesList()
+		for _, v := range best {
+			if v.Product == "7/1 ARM" {
+				t.Error(errors.New("fail TestRateFeed: deleted feed rate should not be best rate"))
+			}
+		}
+	})
+}
+
+// hasBestRate return true if rate is in best rates list
+// TestEmailTemplates renders every email template without database
+func TestEmailTemplates(t *testing.T) {
+	jsonconfig.Load(constants.ConfigFilePath, config.Config)
+	viewInfo := config.View()
+	viewInfo.Folder = constants.TemplateFolderPath
+	view.Configure(viewInfo)
+
+	emails := map[string]interface{}{
+		"email/message_new": &newMessageEmail{FirstName: "John", FromName: "Jane Doe", Title: "W2 form", Content: "<b>text</b>"},
+		"email/message_digest": &digestEmail{FirstName: "John", Messages: []*digestEmailMessage{
+			{Title: "W2 form", FromName: "Jane Doe", Content: "text", CreatedAt: "2017-01-02 15:04"},
+		}},
+		"email/rate_alert": &rateAlertEmail{FirstName: "John", Product: "30_year_fixed", Direction: "below",
+			Threshold: "4.250", Interest: "4.125", Apr: "4.200", LenderName: "Bank", UnsubscribeURL: "http://localhost/unsubscribe"},
+	}
+
+	files, err := filepath.Glob(filepath.Join(constants.TemplateFolderPath, "email", "*.txt"))
+	if err != nil || len(files) != len(emails) {
+		t.Error(errors.New("fail TestEmailTemplates: every email template should be tested"))
+		return
+	}
+
+	for name, data := range emails {
+		m, err := renderEmail(1, "john@example.com", "subject", name, data)
+		if err != nil {
+			t.Error(errors.New("fail TestEmailTemplates: " + name + ": " + err.Error()))
+			continue
+		}
+
+		if !strings.Contains(m.TextBody, "Hi John") || !strings.Contains(m.HTMLBody, "Hi John") {
+			t.Error(errors.New("fail TestEmailTemplates: " + name + " should be rendered with data"))
+		}
+
+		if strings.Contains(m.HTMLBody, "<b>text</b>") {
+			t.Error(errors.New("fail TestEmailTemplates: " + name + " HTML should escape content"))
+		}
+	}
+}
+
+func hasBestRate(rates []model.LenderRate, rateID uint32) bool {
+	for _, v := range rates {
+		if v.ID == rateID {
+			return true
+		}
+	}
+
+	return false
+}
+
+func AccertEqual(t *testing.T, name, first, next string) {
 	if first != next {
 		t.Error(name + " not equal")
 	}
 }
+
+// configureTestKeyring loads the configured keys, or random keys when the config ships none
+func configureTestKeyring(t *testing.T) {
+	if keyring.Configure(config.Keyring()) == nil {
+		return
+	}
+
+	masterKey, err := keyring.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileIDKey, err := keyring.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config.Config.Keyring.ActiveVersion = 1
+	config.Config.Keyring.MasterKeys = map[string]string{"1": masterKey}
+	config.Config.Keyring.FileIDKey = fileIDKey
+	if err = keyring.Configure(config.Keyring()); err != nil {
+		t.Fatal(err)
+	}
+}
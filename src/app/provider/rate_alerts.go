@@ -0,0 +1,295 @@
+package provider
+
+import (
+	"errors"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"app/model"
+	"app/webpojo"
+)
+
+const (
+	// maxRateAlerts is the biggest number of alerts of customer
+	maxRateAlerts = 20
+	// rateAlertUnsubscribePath is public API path of alert unsubscribe link
+	rateAlertUnsubscribePath = "/api/public/rate_alert/unsubscribe"
+)
+
+var (
+	// ErrWrongAlertDirection returns if alert direction is not below or above
+	ErrWrongAlertDirection = errors.New("direction should be below or above")
+	// ErrWrongAlertThreshold returns if alert threshold is out of range
+	ErrWrongAlertThreshold = errors.New("threshold should be between 0 and 100")
+	// ErrWrongAlertProduct returns if there are no rates of alert product
+	ErrWrongAlertProduct = errors.New("no rates of product")
+	// ErrRateAlertExists returns if customer has the same alert
+	ErrRateAlertExists = errors.New("the same alert exists")
+	// ErrTooManyRateAlerts returns if customer has maxRateAlerts already
+	ErrTooManyRateAlerts = errors.New("too many alerts")
+	// ErrRateAlertNotExist returns if alert is not found
+	ErrRateAlertNotExist = errors.New("rate alert not exist")
+)
+
+// rateAlertEmail is data of rate_alert email templates
+type rateAlertEmail struct {
+	FirstName      string
+	Product        string
+	Direction      string
+	Threshold      string
+	Interest       string
+	Apr            string
+	LenderName     string
+	UnsubscribeURL string
+}
+
+// rateAlertPojo convert rate alert from database to response
+func rateAlertPojo(alert *model.RateAlert) (*webpojo.RateAlertPojo, error) {
+	threshold, err := strconv.ParseFloat(alert.Threshold, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &webpojo.RateAlertPojo{
+		ID:        alert.ID,
+		Product:   alert.Product,
+		Threshold: threshold,
+		Direction: alert.Direction,
+		Triggered: alert.Triggered,
+		CreatedAt: alert.CreatedAt.String(),
+	}
+
+	if alert.NotifiedAt != nil {
+		res.NotifiedAt = alert.NotifiedAt.String()
+	}
+
+	return res, nil
+}
+
+// GetRateAlerts return customer's rate alerts
+func GetRateAlerts(userID string) ([]webpojo.RateAlertPojo, error) {
+	uintUserID, err := parseUserID(userID)
+	if err != nil {
+		log.Println("error while get rate alerts: ", err)
+		return nil, err
+	}
+
+	alerts, err := model.RateAlertsByUserID(uintUserID)
+	if err != nil {
+		log.Println("error while get rate alerts: ", err)
+		return nil, err
+	}
+
+	res := []webpojo.RateAlertPojo{}
+	for i := range alerts {
+		alert, err := rateAlertPojo(&alerts[i])
+		if err != nil {
+			log.Println("error while convert rate alert ", alerts[i].ID, ": ", err)
+			return nil, err
+		}
+
+		res = append(res, *alert)
+	}
+
+	return res, nil
+}
+
+// CreateRateAlert subscribes customer to alert on best rate of product crossing threshold, return alert ID.
+// Product should have rates effective today, its name is taken from rates.
+func CreateRateAlert(userID string, req *webpojo.RateAlertReq) (uint32, error) {
+	uintUserID, err := parseUserID(userID)
+	if err != nil {
+		log.Println("error while create rate alert: ", err)
+		return 0, err
+	}
+
+	if req.Direction != model.AlertBelow && req.Direction != model.AlertAbove {
+		return 0, ErrWrongAlertDirection
+	}
+
+	if req.Threshold <= 0 || req.Threshold >= 100 {
+		return 0, ErrWrongAlertThreshold
+	}
+
+	rates, err := GetBestLenderRatesList()
+	if err != nil {
+		return 0, err
+	}
+
+	product := ""
+	for _, v := range rates {
+		if strings.EqualFold(v.Product, strings.TrimSpace(req.Product)) {
+			product = v.Product
+		}
+	}
+
+	if product == "" {
+		return 0, ErrWrongAlertProduct
+	}
+
+	count, err := model.RateAlertsCount(uintUserID)
+	if err != nil {
+		log.Println("error while create rate alert: ", err)
+		return 0, err
+	}
+
+	if count >= maxRateAlerts {
+		return 0, ErrTooManyRateAlerts
+	}
+
+	token, err := newToken()
+	if err != nil {
+		log.Println("error while create rate alert token: ", err)
+		return 0, err
+	}
+
+	id, err := model.RateAlertCreate(&model.RateAlert{
+		UserID:    uintUserID,
+		Product:   product,
+		Threshold: strconv.FormatFloat(req.Threshold, 'f', 3, 64),
+		Direction: req.Direction,
+		Token:     token,
+	})
+	if err == model.ErrDuplicateEntry {
+		return 0, ErrRateAlertExists
+	}
+	if err != nil {
+		log.Println("error while create rate alert: ", err)
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// DeleteRateAlert removes customer's rate alert
+func DeleteRateAlert(userID string, alertID uint32) error {
+	uintUserID, err := parseUserID(userID)
+	if err != nil {
+		log.Println("error while delete rate alert: ", err)
+		return err
+	}
+
+	err = model.RateAlertDelete(alertID, uintUserID)
+	if err == model.ErrNoResult {
+		return ErrRateAlertNotExist
+	}
+	if err != nil {
+		log.Println("error while delete rate alert: ", err)
+		return err
+	}
+
+	return nil
+}
+
+// UnsubscribeRateAlert removes rate alert by token of unsubscribe link
+func UnsubscribeRateAlert(token string) error {
+	if token == "" {
+		return ErrRateAlertNotExist
+	}
+
+	err := model.RateAlertDeleteByToken(token)
+	if err == model.ErrNoResult {
+		return ErrRateAlertNotExist
+	}
+	if err != nil {
+		log.Println("error while unsubscribe rate alert: ", err)
+		return err
+	}
+
+	return nil
+}
+
+// EvaluateRateAlerts emails customers whose alerts are crossed by best rates. Alert is sent once and is
+// sent again only after rate crosses threshold back. Return number of enqueued emails.
+func EvaluateRateAlerts(rates []model.LenderRate) (int, error) {
+	best := map[string]*model.LenderRate{}
+	for i := range rates {
+		best[strings.ToLower(rates[i].Product)] = &rates[i]
+	}
+
+	alerts, err := model.RateAlertRecipients()
+	if err != nil {
+		return 0, err
+	}
+
+	enqueued := 0
+	for i := range alerts {
+		alert := &alerts[i]
+		rate, ok := best[strings.ToLower(alert.Product)]
+		if !ok {
+			continue
+		}
+
+		crossed, err := alertCrossed(&alert.RateAlert, rate)
+		if err != nil {
+			log.Println("error while evaluate rate alert ", alert.ID, ": ", err)
+			continue
+		}
+
+		switch {
+		case crossed && !alert.Triggered:
+			var m *model.OutboxEmail
+			m, err = rateAlertMessage(alert, rate)
+			if err != nil {
+				return enqueued, err
+			}
+
+			// Alert triggered by concurrent evaluation since it was read is skipped
+			var triggered bool
+			triggered, err = model.RateAlertTrigger(alert.ID, rate.Interest, m)
+			if err != nil {
+				return enqueued, err
+			}
+
+			if triggered {
+				enqueued++
+			}
+		case !crossed && alert.Triggered:
+			err = model.RateAlertRearm(alert.ID)
+			if err != nil {
+				return enqueued, err
+			}
+		}
+	}
+
+	return enqueued, nil
+}
+
+// alertCrossed return true if interest of rate is at or below alert threshold for below alerts,
+// at or above threshold for above alerts
+func alertCrossed(alert *model.RateAlert, rate *model.LenderRate) (bool, error) {
+	threshold, err := strconv.ParseFloat(alert.Threshold, 64)
+	if err != nil {
+		return false, err
+	}
+
+	interest, err := strconv.ParseFloat(rate.Interest, 64)
+	if err != nil {
+		return false, err
+	}
+
+	if alert.Direction == model.AlertAbove {
+		return interest >= threshold, nil
+	}
+
+	return interest <= threshold, nil
+}
+
+// rateAlertMessage renders rate alert email for outbox
+func rateAlertMessage(alert *model.RateAlertRecipient, rate *model.LenderRate) (*model.OutboxEmail, error) {
+	data := &rateAlertEmail{
+		FirstName:      alert.FirstName,
+		Product:        alert.Product,
+		Direction:      alert.Direction,
+		Threshold:      alert.Threshold,
+		Interest:       rate.Interest,
+		Apr:            rate.Apr,
+		LenderName:     rate.LenderName,
+		UnsubscribeURL: siteURL + rateAlertUnsubscribePath + "?token=" + url.QueryEscape(alert.Token),
+	}
+
+	subject := alert.Product + " rate is " + alert.Direction + " " + alert.Threshold + "%"
+	return renderEmail(alert.UserID, alert.Email, subject, "email/rate_alert", data)
+}
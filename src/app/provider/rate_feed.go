@@ -0,0 +1,212 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"app/model"
+	"app/shared/ratefeed"
+	"app/shared/ratesheet"
+	"app/webpojo"
+)
+
+var (
+	// ErrRateFeedEmpty returns if rate feed has no valid rates
+	ErrRateFeedEmpty = errors.New("rate feed has no valid rates")
+)
+
+// IngestRateFeed fetches rates of configured feed and saves them: rates are matched to lender rates by lender,
+// product, term and begin date, matched rates are updated and the rest are inserted. Invalid feed items are
+// skipped and reported. Every ingestion is logged, successful one makes rates fresh.
+func IngestRateFeed() (*webpojo.RateFeedRunPojo, []webpojo.RateFeedItemError, error) {
+	run := &model.RateFeedRun{Source: ratefeed.Type()}
+	rejected := []webpojo.RateFeedItemError{}
+
+	items, err := ratefeed.Fetch()
+	if err == ratefeed.ErrNotConfigured {
+		return nil, nil, err
+	}
+
+	if err == nil {
+		run.Fetched = len(items)
+
+		var rates []model.LenderRate
+		rates, rejected, err = rateFeedRates(items)
+		run.Rejected = len(rejected)
+
+		if err == nil && len(rates) == 0 {
+			err = ErrRateFeedEmpty
+		}
+
+		if err == nil {
+			run.Inserted, run.Updated, err = model.LenderRatesUpsert(rates)
+		}
+	}
+
+	run.Succeeded = err == nil
+	if err != nil {
+		log.Println("error while ingest rate feed: ", err)
+		run.Error = err.Error()
+	}
+
+	logErr := model.RateFeedRunCreate(run)
+	if logErr != nil {
+		log.Println("error while log rate feed run: ", logErr)
+	}
+
+	run.CreatedAt = time.Now()
+	return rateFeedRunPojo(run), rejected, err
+}
+
+// rateFeedRates convert feed items to lender rates, invalid items are rejected
+func rateFeedRates(items []ratefeed.Rate) ([]model.LenderRate, []webpojo.RateFeedItemError, error) {
+	rates := []model.LenderRate{}
+	rejected := []webpojo.RateFeedItemError{}
+
+	// Known lender IDs, rates of inactive lenders are saved too
+	lenders := map[uint32]bool{}
+	seen := map[string]int{}
+	layout := ratefeed.DateLayout()
+
+	for i := range items {
+		item := &items[i]
+		reject := func(errText string) {
+			rejected = append(rejected, webpojo.RateFeedItemError{Item: item.Item, Lender: item.Lender, Error: errText})
+		}
+
+		// Only lender codes of config are accepted, feed can't address lenders by database ID
+		lenderID, ok := ratefeed.LenderID(item.Lender)
+		if !ok {
+			reject("unknown lender")
+			continue
+		}
+
+		if _, ok := lenders[lenderID]; !ok {
+			_, err := model.LenderByID(lenderID)
+			if err != nil && err != model.ErrNoResult {
+				return nil, nil, err
+			}
+
+			lenders[lenderID] = err == nil
+		}
+
+		if !lenders[lenderID] {
+			reject("unknown lender")
+			continue
+		}
+
+		rate, errText := rateSheetRate(lenderID, &ratesheet.Row{
+			Product:   item.Product,
+			TermYears: item.TermYears,
+			Interest:  item.Interest,
+			Apr:       item.Apr,
+			BeginDate: item.BeginDate,
+			EndDate:   item.EndDate,
+		}, layout)
+		if errText != "" {
+			reject(errText)
+			continue
+		}
+
+		key := fmt.Sprintf("%d|%s", lenderID, rateSheetKey(rate))
+		if prev, ok := seen[key]; ok {
+			reject(fmt.Sprintf("duplicates rate of item %d", prev))
+			continue
+		}
+		seen[key] = item.Item
+
+		rates = append(rates, *lenderRateModel(rate))
+	}
+
+	return rates, rejected, nil
+}
+
+// rateFeedRunPojo convert rate feed run from database to response
+func rateFeedRunPojo(run *model.RateFeedRun) *webpojo.RateFeedRunPojo {
+	return &webpojo.RateFeedRunPojo{
+		Source:    run.Source,
+		Succeeded: run.Succeeded,
+		Fetched:   run.Fetched,
+		Inserted:  run.Inserted,
+		Updated:   run.Updated,
+		Rejected:  run.Rejected,
+		Error:     run.Error,
+		CreatedAt: run.CreatedAt.String(),
+	}
+}
+
+// RefreshRates ingests rate feed if it is enabled and reloads best rates with UpdateCache, so rate alerts
+// are evaluated too. Best rates are reloaded even if feed ingestion fails, its error is returned with report.
+func RefreshRates() (*webpojo.RateFeedRefreshResp, error) {
+	res := &webpojo.RateFeedRefreshResp{Rejected: []webpojo.RateFeedItemError{}}
+
+	var feedErr error
+	if ratefeed.Enabled() {
+		var rejected []webpojo.RateFeedItemError
+		res.Run, rejected, feedErr = IngestRateFeed()
+		if rejected != nil {
+			res.Rejected = rejected
+		}
+	}
+
+	err := UpdateCache("update_best_rates")
+	if err != nil {
+		log.Println("error while refresh rates: ", err)
+		return nil, err
+	}
+
+	rates, err := GetBestLenderRatesList()
+	if err != nil {
+		return nil, err
+	}
+
+	res.BestRates = len(rates)
+	return res, feedErr
+}
+
+// GetRateFeedStatus return the latest feed ingestions and tells if feed rates are stale
+func GetRateFeedStatus() (*webpojo.RateFeedStatusResp, error) {
+	res := &webpojo.RateFeedStatusResp{
+		Enabled:         ratefeed.Enabled(),
+		StaleAfterHours: int(ratefeed.StaleAfter() / time.Hour),
+	}
+
+	last, err := model.RateFeedRunLast(false)
+	if err != nil && err != model.ErrNoResult {
+		log.Println("error while get rate feed status: ", err)
+		return nil, err
+	}
+	if err == nil {
+		res.LastRun = rateFeedRunPojo(last)
+	}
+
+	success, err := model.RateFeedRunLast(true)
+	if err != nil && err != model.ErrNoResult {
+		log.Println("error while get rate feed status: ", err)
+		return nil, err
+	}
+	if err == nil {
+		res.LastSuccess = rateFeedRunPojo(success)
+	}
+
+	res.Stale = res.Enabled && (err == model.ErrNoResult || time.Since(success.CreatedAt) > ratefeed.StaleAfter())
+	return res, nil
+}
+
+// ingestRateFeedScheduled ingests rate feed, reloads best rates and warns if feed rates are stale
+func ingestRateFeedScheduled() {
+	_, _, err := IngestRateFeed()
+	if err == nil {
+		err = UpdateCache("update_best_rates")
+		if err != nil {
+			log.Println("error while update best rates cache: " + err.Error())
+		}
+	}
+
+	status, err := GetRateFeedStatus()
+	if err == nil && status.Stale {
+		log.Println("WARNING: rate feed rates are stale, no successful ingestion for", ratefeed.StaleAfter())
+	}
+}
@@ -0,0 +1,214 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+
+	"app/model"
+	"app/shared/ratesheet"
+	"app/webpojo"
+)
+
+var (
+	// ErrRateSheetEmpty returns if rate sheet has no rates, so it can't replace lender rates
+	ErrRateSheetEmpty = errors.New("rate sheet has no rates")
+	// ErrRateSheetRejected returns if rate sheet has invalid rows, import report lists them
+	ErrRateSheetRejected = errors.New("rate sheet has invalid rows")
+
+	// errRatePrecision returns if rate has more decimals than stored rates
+	errRatePrecision = errors.New("rate has too many decimals")
+)
+
+// rateDecimals is number of decimals of stored rates
+const rateDecimals = 3
+
+// ImportRateSheet reads lender rate sheet with lender's column mapping and compares its rates with
+// not expired rates of lender. Unless it is dry run, these rates are replaced with sheet rates at once.
+// Sheet with invalid rows is not imported, ErrRateSheetRejected returns with report of rejected rows.
+func ImportRateSheet(lenderID uint32, format string, data []byte, dryRun bool) (*webpojo.RateSheetImportResp, error) {
+	_, err := model.LenderByID(lenderID)
+	if err == model.ErrNoResult {
+		return nil, ErrLenderNotExist
+	}
+	if err != nil {
+		log.Println("error while import rate sheet: ", err)
+		return nil, err
+	}
+
+	mapping := ratesheet.MappingFor(lenderID)
+	rows, err := ratesheet.Read(format, data, &mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return nil, ErrRateSheetEmpty
+	}
+
+	report := &webpojo.RateSheetImportResp{
+		LenderID: lenderID,
+		DryRun:   dryRun,
+		Rows:     len(rows),
+		Added:    []webpojo.LenderRatePojo{},
+		Changed:  []webpojo.RateSheetChange{},
+		Removed:  []webpojo.LenderRatePojo{},
+		Rejected: []webpojo.RateSheetRowError{},
+	}
+
+	rates := []webpojo.LenderRatePojo{}
+	lines := map[string]int{}
+	for i := range rows {
+		rate, errText := rateSheetRate(lenderID, &rows[i], mapping.DateLayout)
+		if errText == "" {
+			key := rateSheetKey(rate)
+			if line, ok := lines[key]; ok {
+				errText = fmt.Sprintf("duplicates rate of line %d", line)
+			}
+			lines[key] = rows[i].Line
+		}
+
+		if errText != "" {
+			report.Rejected = append(report.Rejected, webpojo.RateSheetRowError{Line: rows[i].Line, Error: errText})
+			continue
+		}
+
+		rates = append(rates, *rate)
+	}
+
+	current, err := model.LenderRatesActiveByLenderID(lenderID)
+	if err != nil {
+		log.Println("error while import rate sheet: ", err)
+		return nil, err
+	}
+
+	currentRates, err := LenderRatesPojo(current)
+	if err != nil {
+		return nil, err
+	}
+
+	diffRateSheet(report, currentRates, rates)
+
+	if len(report.Rejected) > 0 {
+		return report, ErrRateSheetRejected
+	}
+
+	if dryRun {
+		return report, nil
+	}
+
+	newRates := []model.LenderRate{}
+	for i := range rates {
+		newRates = append(newRates, *lenderRateModel(&rates[i]))
+	}
+
+	err = model.LenderRatesReplace(lenderID, newRates)
+	if err != nil {
+		log.Println("error while import rate sheet: ", err)
+		return nil, err
+	}
+
+	refreshBestRates()
+	report.Applied = true
+	return report, nil
+}
+
+// rateSheetRate convert sheet row to lender rate, return text of row error if row is invalid
+func rateSheetRate(lenderID uint32, row *ratesheet.Row, dateLayout string) (*webpojo.LenderRatePojo, string) {
+	rate := &webpojo.LenderRatePojo{LenderID: lenderID, Product: row.Product}
+
+	term, err := strconv.ParseFloat(row.TermYears, 64)
+	if err != nil || term != math.Trunc(term) {
+		return nil, "term_years should be whole number of years"
+	}
+	rate.TermYears = int(term)
+
+	rate.Interest, err = parseRatePercent(row.Interest)
+	if err == errRatePrecision {
+		return nil, fmt.Sprintf("interest should have at most %d decimals", rateDecimals)
+	}
+	if err != nil {
+		return nil, "interest should be number"
+	}
+
+	rate.Apr, err = parseRatePercent(row.Apr)
+	if err == errRatePrecision {
+		return nil, fmt.Sprintf("apr should have at most %d decimals", rateDecimals)
+	}
+	if err != nil {
+		return nil, "apr should be number"
+	}
+
+	begin, err := ratesheet.ParseDate(row.BeginDate, dateLayout)
+	if err != nil {
+		return nil, "begin_date should be date like " + dateLayout
+	}
+	rate.BeginDate = begin.Format(model.RateDateLayout)
+
+	end, err := ratesheet.ParseDate(row.EndDate, dateLayout)
+	if err != nil {
+		return nil, "end_date should be date like " + dateLayout
+	}
+	rate.EndDate = end.Format(model.RateDateLayout)
+
+	if errText := CheckLenderRate(rate); errText != "" {
+		return nil, errText
+	}
+
+	return rate, ""
+}
+
+// parseRatePercent parses percent value like "3.625" or "3.625%". Return errRatePrecision if value
+// has more decimals than stored rates, spreadsheet float noise like "3.6250000000000004" is accepted.
+func parseRatePercent(value string) (float64, error) {
+	v, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(value, "%")), 64)
+	if err != nil {
+		return 0, err
+	}
+
+	rounded := roundTo(v, rateDecimals)
+	if math.Abs(v-rounded) > 1e-9 {
+		return 0, errRatePrecision
+	}
+
+	return rounded, nil
+}
+
+// rateSheetKey return key of rate in rate sheet
+func rateSheetKey(rate *webpojo.LenderRatePojo) string {
+	return fmt.Sprintf("%s|%d|%s", strings.ToLower(rate.Product), rate.TermYears, rate.BeginDate)
+}
+
+// diffRateSheet fills report with rates added, changed and removed by sheet
+func diffRateSheet(report *webpojo.RateSheetImportResp, current, rates []webpojo.LenderRatePojo) {
+	old := map[string]webpojo.LenderRatePojo{}
+	for _, v := range current {
+		old[rateSheetKey(&v)] = v
+	}
+
+	for _, v := range rates {
+		key := rateSheetKey(&v)
+		prev, ok := old[key]
+		if !ok {
+			report.Added = append(report.Added, v)
+			continue
+		}
+
+		delete(old, key)
+		if prev.Interest == v.Interest && prev.Apr == v.Apr && prev.EndDate == v.EndDate {
+			report.Unchanged++
+			continue
+		}
+
+		report.Changed = append(report.Changed, webpojo.RateSheetChange{Old: prev, New: v})
+	}
+
+	for _, v := range current {
+		if _, ok := old[rateSheetKey(&v)]; ok {
+			report.Removed = append(report.Removed, v)
+		}
+	}
+}
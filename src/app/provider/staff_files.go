@@ -0,0 +1,334 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+
+	"app/constants"
+	"app/model"
+	fas "app/shared/files_id_storage"
+	"app/webpojo"
+)
+
+const (
+	staffFileDownloadPath = "/api/staff/customer/file/download"
+
+	defaultAccessLogLimit = 100
+	maxAccessLogLimit     = 1000
+)
+
+var (
+	// ErrWrongUserRole returns if user can't be used in operation because of their role
+	ErrWrongUserRole = errors.New("user has wrong role")
+)
+
+// StaffActor identifies staff member, or other reader of customer files, for assignment checks and file access log
+type StaffActor struct {
+	UserID     string
+	Role       int
+	RemoteAddr string
+}
+
+// GetAssignedCustomers return list of customers assigned to staff member
+func GetAssignedCustomers(staffID string) ([]*webpojo.AssignedCustomer, error) {
+	if staffID == "" || staffID == "0" {
+		return nil, errors.New("error while get assigned customers: staff id is empty or zero")
+	}
+
+	customers, err := model.CustomersByStaffID(staffID)
+	if err != nil {
+		log.Println("error while get assigned customers: " + err.Error())
+		return nil, err
+	}
+
+	result := []*webpojo.AssignedCustomer{}
+	for _, v := range customers {
+		result = append(result, &webpojo.AssignedCustomer{
+			CustomerID: v.CustomerID,
+			FirstName:  v.FirstName,
+			LastName:   v.LastName,
+			Email:      v.Email,
+			AssignedAt: v.AssignedAt.String(),
+		})
+	}
+
+	return result, nil
+}
+
+// AssignCustomerToStaff assigns customer to staff or supervisor.
+// Returns model.ErrNoResult if any user not exist and ErrWrongUserRole if users have unexpected roles.
+func AssignCustomerToStaff(staffID, customerID string) error {
+	staff, err := model.UserByID(staffID)
+	if err != nil {
+		return err
+	}
+
+	if staff.UserRole != constants.StaffRole && staff.UserRole != constants.SupervisorRole {
+		return ErrWrongUserRole
+	}
+
+	customer, err := model.UserByID(customerID)
+	if err != nil {
+		return err
+	}
+
+	if customer.UserRole != constants.CustomerRole {
+		return ErrWrongUserRole
+	}
+
+	err = model.StaffAssignmentCreate(staffID, customerID)
+	if err != nil {
+		log.Println("error while assign customer to staff: " + err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// UnassignCustomerFromStaff removes customer assignment from staff member
+func UnassignCustomerFromStaff(staffID, customerID string) error {
+	err := model.StaffAssignmentDelete(staffID, customerID)
+	if err != nil {
+		log.Println("error while unassign customer from staff: " + err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// checkStaffAccess returns model.ErrUnauthorized if customer is not assigned to staff member.
+// Denied attempts are written to file access log too.
+func checkStaffAccess(actor *StaffActor, customerID string, fileID uint32) error {
+	if actor == nil || actor.UserID == "" || actor.UserID == "0" {
+		return errors.New("error while check staff access: staff id is empty or zero")
+	}
+
+	if customerID == "" || customerID == "0" {
+		return errors.New("error while check staff access: customer id is empty or zero")
+	}
+
+	assigned, err := model.IsStaffAssigned(actor.UserID, customerID)
+	if err != nil {
+		log.Println("error while check staff assignment: " + err.Error())
+		return err
+	}
+
+	if assigned {
+		return nil
+	}
+
+	err = logFileAccess(actor, customerID, fileID, model.FileAccessDenied, "customer is not assigned")
+	if err != nil {
+		return err
+	}
+
+	return model.ErrUnauthorized
+}
+
+// logFileAccess writes record to file access log. Access should not be granted if record wasn't saved.
+func logFileAccess(actor *StaffActor, customerID string, fileID uint32, action, detail string) error {
+	err := model.FileAccessLogCreate(actor.UserID, actor.Role, customerID, fileID, action, detail, actor.RemoteAddr)
+	if err != nil {
+		log.Println("error while write file access log: " + err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// StaffGetCustomerFileList return page of assigned customer files with staff download links
+func StaffGetCustomerFileList(actor *StaffActor, customerID string, sessID string, req *webpojo.FileListReq) (*webpojo.FileListResp, error) {
+	err := checkStaffAccess(actor, customerID, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	err = logFileAccess(actor, customerID, 0, model.FileAccessList, "")
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := SearchFileList(customerID, sessID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range list.Files {
+		v.Link = staffFileDownloadPath + "?customer_id=" + url.QueryEscape(customerID) + "&id=" + url.QueryEscape(v.FileID)
+	}
+
+	return list, nil
+}
+
+// StaffGetFileContent return decrypted content of assigned customer file
+func StaffGetFileContent(actor *StaffActor, customerID string, fileID string) ([]byte, *model.UserFile, error) {
+	id, err := strconv.ParseUint(fileID, 10, 32)
+	if err != nil {
+		return nil, nil, errors.New("error while get customer file content for staff: bad file id")
+	}
+
+	err = checkStaffAccess(actor, customerID, uint32(id))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = logFileAccess(actor, customerID, uint32(id), model.FileAccessDownload, "")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return GetFileContent(customerID, fileID)
+}
+
+// StaffRequestDocument asks assigned customer to upload document of category. Category should be validated by caller.
+func StaffRequestDocument(actor *StaffActor, req *webpojo.DocumentRequestReq) (int, error) {
+	if req == nil {
+		return 0, errors.New("error while request document: request is nil")
+	}
+
+	customerID := fmt.Sprint(req.CustomerID)
+	err := checkStaffAccess(actor, customerID, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	err = logFileAccess(actor, customerID, 0, model.FileAccessRequest, req.Category)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := model.DocumentRequestCreate(customerID, actor.UserID, req.Category, req.Note)
+	if err != nil {
+		log.Println("error while create document request: " + err.Error())
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// StaffGetDocumentRequests return document requests of assigned customer
+func StaffGetDocumentRequests(actor *StaffActor, customerID string, sessID string) ([]*webpojo.DocumentRequest, error) {
+	err := checkStaffAccess(actor, customerID, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return GetDocumentRequests(customerID, sessID)
+}
+
+// GetDocumentRequests return document requests of customer
+func GetDocumentRequests(customerID string, sessID string) ([]*webpojo.DocumentRequest, error) {
+	if customerID == "" || customerID == "0" {
+		return nil, errors.New("error while get document requests: customer id is empty or zero")
+	}
+
+	requests, err := model.DocumentRequestsByCustomerID(customerID)
+	if err != nil {
+		log.Println("error while get document requests: " + err.Error())
+		return nil, err
+	}
+
+	result := []*webpojo.DocumentRequest{}
+	for _, v := range requests {
+		request := &webpojo.DocumentRequest{
+			ID:         v.ID,
+			CustomerID: v.CustomerID,
+			StaffID:    v.StaffID,
+			Category:   v.Category,
+			Note:       v.Note,
+			Status:     v.Status,
+			CreatedAt:  v.CreatedAt.String(),
+		}
+
+		if v.FileID.Valid {
+			request.FileID = fas.GetNewFileID(int(v.FileID.Int64), sessID)
+		}
+
+		result = append(result, request)
+	}
+
+	return result, nil
+}
+
+// GetPendingDocumentRequest return customer's document request if it is waiting for upload.
+// Returns model.ErrNoResult if there is no pending request with ID.
+func GetPendingDocumentRequest(customerID string, requestID string) (*webpojo.DocumentRequest, error) {
+	requests, err := GetDocumentRequests(customerID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range requests {
+		if fmt.Sprint(v.ID) == requestID && v.Status == model.DocumentRequestPending {
+			return v, nil
+		}
+	}
+
+	return nil, model.ErrNoResult
+}
+
+// FulfillDocumentRequest links uploaded file to customer's pending document request
+func FulfillDocumentRequest(customerID string, requestID string, fileID int) error {
+	err := model.DocumentRequestFulfill(customerID, requestID, fileID)
+	if err != nil {
+		log.Println("error while fulfill document request: " + err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// GetFileAccessLog return page of file access log matched to request filter
+func GetFileAccessLog(req *webpojo.FileAccessLogReq) (*webpojo.FileAccessLogResp, error) {
+	if req == nil {
+		return nil, errors.New("error while get file access log: request is nil")
+	}
+
+	filter := &model.FileAccessFilter{
+		ActorID:    req.ActorID,
+		CustomerID: req.CustomerID,
+		Action:     req.Action,
+		From:       req.From,
+		To:         req.To,
+		Limit:      req.Limit,
+		Offset:     req.Offset,
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = defaultAccessLogLimit
+	}
+
+	if filter.Limit > maxAccessLogLimit {
+		filter.Limit = maxAccessLogLimit
+	}
+
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	records, total, err := model.FileAccessLogSearch(filter)
+	if err != nil {
+		log.Println("error while get file access log: " + err.Error())
+		return nil, err
+	}
+
+	resp := &webpojo.FileAccessLogResp{Records: []*webpojo.FileAccessRecord{}, Total: total, Limit: filter.Limit, Offset: filter.Offset}
+	for _, v := range records {
+		resp.Records = append(resp.Records, &webpojo.FileAccessRecord{
+			ID:         v.ID,
+			ActorID:    v.ActorID,
+			ActorRole:  v.ActorRole,
+			CustomerID: v.CustomerID,
+			FileID:     uint32(v.FileID.Int64),
+			Action:     v.Action,
+			Detail:     v.Detail,
+			RemoteAddr: v.RemoteAddr,
+			CreatedAt:  v.CreatedAt.String(),
+		})
+	}
+
+	return resp, nil
+}
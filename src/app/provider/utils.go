@@ -3,8 +3,10 @@ package provider
 import (
 	"crypto/hmac"
 	"crypto/md5"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"log"
 )
 
@@ -34,3 +36,14 @@ func CreateUsersFileKey(userName string) string {
 	log.Println("generate new usersFileKey: ", usersFileName)
 	return usersFileName
 }
+
+// newToken return random hex token for public links and IDs
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
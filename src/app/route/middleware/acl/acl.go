@@ -1,6 +1,7 @@
 package acl
 
 import (
+	"fmt"
 	"net/http"
 
 	"app/shared/session"
@@ -38,6 +39,30 @@ func DisallowAnon(h http.Handler) http.Handler {
 	})
 }
 
+// AllowRoles does not allow users without one of roles to access the page.
+// Use after DisallowAnon.
+func AllowRoles(roles ...int) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Get session
+			sess := session.Instance(r)
+
+			// Role type depends on the login handler, so compare as strings
+			role := fmt.Sprint(sess.Values["user_role"])
+			for _, v := range roles {
+				if role == fmt.Sprint(v) {
+					h.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("{\"statusCode\":403, \"message\": \"Forbidden\", \"error\": \"forbidden\"}"))
+		})
+	}
+}
+
 // AllowCORS allow Cross Side accessing
 func AllowCORS(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
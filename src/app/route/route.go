@@ -3,6 +3,7 @@ package route
 import (
 	"net/http"
 
+	"app/constants"
 	"app/controller"
 	"app/route/middleware/acl"
 	hr "app/route/middleware/httprouterwrapper"
@@ -114,10 +115,33 @@ func routes() *httprouter.Router {
 		New().
 		ThenFunc(controller.LenderRateList)))
 
+	// Public API: Best rate history of product
+	r.POST("/api/public/rate/history", hr.Handler(alice.
+		New().
+		ThenFunc(controller.LenderRateHistoryPost)))
+
 	r.POST("/api/public/fast_quote", hr.Handler(alice.
 		New().
 		ThenFunc(controller.FastQuotePost)))
 
+	// Public API: Mortgage calculator
+	r.POST("/api/public/calculator/payment", hr.Handler(alice.
+		New().
+		ThenFunc(controller.CalculatorPaymentPost)))
+
+	r.POST("/api/public/calculator/schedule", hr.Handler(alice.
+		New().
+		ThenFunc(controller.CalculatorSchedulePost)))
+
+	r.POST("/api/public/calculator/compare", hr.Handler(alice.
+		New().
+		ThenFunc(controller.CalculatorComparePost)))
+
+	// Public API: Unsubscribe from rate alert by link from alert email
+	r.GET("/api/public/rate_alert/unsubscribe", hr.Handler(alice.
+		New().
+		ThenFunc(controller.RateAlertUnsubscribeGet)))
+
 	//***************************************************************************
 	// Admin Rest APIs
 	//***************************************************************************
@@ -131,6 +155,101 @@ func routes() *httprouter.Router {
 		New(acl.AllowCORS).
 		ThenFunc(controller.UserLoginPost)))
 
+	// Admin API: Assign customer to staff member
+	r.POST("/api/admin/staff/assignment", hr.Handler(alice.
+		New(acl.DisallowAnon, acl.AllowRoles(constants.SupervisorRole, constants.AdminRole)).Append(acl.AllowCORS).
+		ThenFunc(controller.AdminStaffAssignmentPost)))
+
+	// Admin API: Remove customer assignment from staff member
+	r.DELETE("/api/admin/staff/assignment", hr.Handler(alice.
+		New(acl.DisallowAnon, acl.AllowRoles(constants.SupervisorRole, constants.AdminRole)).Append(acl.AllowCORS).
+		ThenFunc(controller.AdminStaffAssignmentDelete)))
+
+	// Admin API: Edit user's message, previous content is kept in history
+	r.PATCH("/api/admin/message", hr.Handler(alice.
+		New(acl.DisallowAnon, acl.AllowRoles(constants.SupervisorRole, constants.AdminRole)).Append(acl.AllowCORS).
+		ThenFunc(controller.AdminMessagePatch)))
+
+	// Admin API: Soft delete user's message
+	r.DELETE("/api/admin/message", hr.Handler(alice.
+		New(acl.DisallowAnon, acl.AllowRoles(constants.SupervisorRole, constants.AdminRole)).Append(acl.AllowCORS).
+		ThenFunc(controller.AdminMessageDelete)))
+
+	// Admin API: Get message edit history
+	r.GET("/api/admin/message/history", hr.Handler(alice.
+		New(acl.DisallowAnon, acl.AllowRoles(constants.SupervisorRole, constants.AdminRole)).Append(acl.AllowCORS).
+		ThenFunc(controller.AdminMessageHistoryGet)))
+
+	// Admin API: Restore deleted message or its previous content
+	r.POST("/api/admin/message/restore", hr.Handler(alice.
+		New(acl.DisallowAnon, acl.AllowRoles(constants.SupervisorRole, constants.AdminRole)).Append(acl.AllowCORS).
+		ThenFunc(controller.AdminMessageRestorePost)))
+
+	// Admin API: Get staff access log of customer files
+	r.GET("/api/admin/file/access_log", hr.Handler(alice.
+		New(acl.DisallowAnon, acl.AllowRoles(constants.AdminRole)).Append(acl.AllowCORS).
+		ThenFunc(controller.AdminFileAccessLogGet)))
+
+	// Admin API: Ingest external rate feed now and reload best rates
+	r.POST("/api/admin/rate_feed/refresh", hr.Handler(alice.
+		New(acl.DisallowAnon, acl.AllowRoles(constants.AdminRole)).Append(acl.AllowCORS).
+		ThenFunc(controller.AdminRateFeedRefreshPost)))
+
+	// Admin API: Get rate feed ingestion status and staleness
+	r.GET("/api/admin/rate_feed/status", hr.Handler(alice.
+		New(acl.DisallowAnon, acl.AllowRoles(constants.AdminRole)).Append(acl.AllowCORS).
+		ThenFunc(controller.AdminRateFeedStatusGet)))
+
+	// Admin API: Get fast quote volume and conversion report
+	r.GET("/api/admin/report/fast_quotes", hr.Handler(alice.
+		New(acl.DisallowAnon, acl.AllowRoles(constants.AdminRole)).Append(acl.AllowCORS).
+		ThenFunc(controller.AdminFastQuoteReportGet)))
+
+	// Admin API: Get all lenders
+	r.GET("/api/admin/lenders", hr.Handler(alice.
+		New(acl.DisallowAnon, acl.AllowRoles(constants.AdminRole)).Append(acl.AllowCORS).
+		ThenFunc(controller.AdminLendersGet)))
+
+	// Admin API: Add lender
+	r.POST("/api/admin/lender", hr.Handler(alice.
+		New(acl.DisallowAnon, acl.AllowRoles(constants.AdminRole)).Append(acl.AllowCORS).
+		ThenFunc(controller.AdminLenderPost)))
+
+	// Admin API: Update lender
+	r.PUT("/api/admin/lender", hr.Handler(alice.
+		New(acl.DisallowAnon, acl.AllowRoles(constants.AdminRole)).Append(acl.AllowCORS).
+		ThenFunc(controller.AdminLenderPut)))
+
+	// Admin API: Delete lender with its rates
+	r.DELETE("/api/admin/lender", hr.Handler(alice.
+		New(acl.DisallowAnon, acl.AllowRoles(constants.AdminRole)).Append(acl.AllowCORS).
+		ThenFunc(controller.AdminLenderDelete)))
+
+	// Admin API: Get lender's rate sheet
+	r.GET("/api/admin/lender/rates", hr.Handler(alice.
+		New(acl.DisallowAnon, acl.AllowRoles(constants.AdminRole)).Append(acl.AllowCORS).
+		ThenFunc(controller.AdminLenderRatesGet)))
+
+	// Admin API: Add lender rate
+	r.POST("/api/admin/lender/rate", hr.Handler(alice.
+		New(acl.DisallowAnon, acl.AllowRoles(constants.AdminRole)).Append(acl.AllowCORS).
+		ThenFunc(controller.AdminLenderRatePost)))
+
+	// Admin API: Update lender rate
+	r.PUT("/api/admin/lender/rate", hr.Handler(alice.
+		New(acl.DisallowAnon, acl.AllowRoles(constants.AdminRole)).Append(acl.AllowCORS).
+		ThenFunc(controller.AdminLenderRatePut)))
+
+	// Admin API: Delete lender rate
+	r.DELETE("/api/admin/lender/rate", hr.Handler(alice.
+		New(acl.DisallowAnon, acl.AllowRoles(constants.AdminRole)).Append(acl.AllowCORS).
+		ThenFunc(controller.AdminLenderRateDelete)))
+
+	// Admin API: Import lender rate sheet from CSV or XLSX file
+	r.POST("/api/admin/lender/rate_sheet", hr.Handler(alice.
+		New(acl.DisallowAnon, acl.AllowRoles(constants.AdminRole)).Append(acl.AllowCORS).
+		ThenFunc(controller.AdminRateSheetImportPost)))
+
 
 	//***************************************************************************
 	// Customer Rest APIs
@@ -170,16 +289,148 @@ func routes() *httprouter.Router {
 		New(acl.DisallowAnon).Append(acl.AllowCORS).
 		ThenFunc(controller.CustomerFilesListGet)))
 
+	// Customer API: Get document categories
+	r.GET("/api/customer/file/categories", hr.Handler(alice.
+		New(acl.DisallowAnon).Append(acl.AllowCORS).
+		ThenFunc(controller.CustomerFileCategoriesGet)))
+
+	// Customer API: Rate alerts on best rate of product crossing threshold
+	r.GET("/api/customer/rate_alert/list", hr.Handler(alice.
+		New(acl.DisallowAnon).Append(acl.AllowCORS).
+		ThenFunc(controller.RateAlertListGet)))
+
+	r.POST("/api/customer/rate_alert", hr.Handler(alice.
+		New(acl.DisallowAnon).Append(acl.AllowCORS).
+		ThenFunc(controller.RateAlertPost)))
+
+	r.DELETE("/api/customer/rate_alert", hr.Handler(alice.
+		New(acl.DisallowAnon).Append(acl.AllowCORS).
+		ThenFunc(controller.RateAlertDelete)))
+
+	// Customer API: Change file category, description and tags
+	r.PATCH("/api/customer/file", hr.Handler(alice.
+		New(acl.DisallowAnon).Append(acl.AllowCORS).
+		ThenFunc(controller.CustomerFilePatch)))
+
 	// Customer API: Get file by id
 	r.GET("/api/customer/file/get", hr.Handler(alice.
 		New(acl.DisallowAnon).Append(acl.AllowCORS).
 		ThenFunc(controller.CustomerServeFileGet)))
 
+	// Customer API: Download decrypted file content
+	r.GET("/api/customer/file/download", hr.Handler(alice.
+		New(acl.DisallowAnon).Append(acl.AllowCORS).
+		ThenFunc(controller.CustomerFileDownloadGet)))
+
 	// Customer API: Delete file
 	r.DELETE("/api/customer/file", hr.Handler(alice.
 		New(acl.DisallowAnon).Append(acl.AllowCORS).
 		ThenFunc(controller.CustomerFileDelete)))
 
+	// Customer API: Get list of deleted files
+	r.GET("/api/customer/file/trash", hr.Handler(alice.
+		New(acl.DisallowAnon).Append(acl.AllowCORS).
+		ThenFunc(controller.CustomerFilesTrashGet)))
+
+	// Customer API: Restore deleted file
+	r.POST("/api/customer/file/restore", hr.Handler(alice.
+		New(acl.DisallowAnon).Append(acl.AllowCORS).
+		ThenFunc(controller.CustomerFileRestorePost)))
+
+	// Customer API: Get documents requested by staff
+	r.GET("/api/customer/document/requests", hr.Handler(alice.
+		New(acl.DisallowAnon).Append(acl.AllowCORS).
+		ThenFunc(controller.CustomerDocumentRequestsGet)))
+
+	// Customer API: Get loan applications
+	r.GET("/api/customer/loan_application/list", hr.Handler(alice.
+		New(acl.DisallowAnon).Append(acl.AllowCORS).
+		ThenFunc(controller.LoanApplicationListGet)))
+
+	// Customer API: Get loan application with documents and history
+	r.GET("/api/customer/loan_application", hr.Handler(alice.
+		New(acl.DisallowAnon).Append(acl.AllowCORS).
+		ThenFunc(controller.LoanApplicationGet)))
+
+	// Customer API: Create draft loan application
+	r.POST("/api/customer/loan_application", hr.Handler(alice.
+		New(acl.DisallowAnon).Append(acl.AllowCORS).
+		ThenFunc(controller.LoanApplicationPost)))
+
+	// Customer API: Update draft loan application
+	r.PUT("/api/customer/loan_application", hr.Handler(alice.
+		New(acl.DisallowAnon).Append(acl.AllowCORS).
+		ThenFunc(controller.LoanApplicationPut)))
+
+	// Customer API: Submit or withdraw loan application
+	r.POST("/api/customer/loan_application/state", hr.Handler(alice.
+		New(acl.DisallowAnon).Append(acl.AllowCORS).
+		ThenFunc(controller.LoanApplicationStatePost)))
+
+	// Customer API: Get fast quotes attached to account
+	r.GET("/api/customer/fast_quote/list", hr.Handler(alice.
+		New(acl.DisallowAnon).Append(acl.AllowCORS).
+		ThenFunc(controller.FastQuoteListGet)))
+
+	// Customer API: Attach anonymous fast quote to account
+	r.POST("/api/customer/fast_quote/claim", hr.Handler(alice.
+		New(acl.DisallowAnon).Append(acl.AllowCORS).
+		ThenFunc(controller.FastQuoteClaimPost)))
+
+	//***************************************************************************
+	// Staff Rest APIs
+	//***************************************************************************
+
+	// Staff API: Get queue of unassigned message threads
+	r.GET("/api/staff/message/queue", hr.Handler(alice.
+		New(acl.DisallowAnon, acl.AllowRoles(constants.StaffRole, constants.SupervisorRole, constants.AdminRole)).Append(acl.AllowCORS).
+		ThenFunc(controller.StaffThreadQueueGet)))
+
+	// Staff API: Assign message thread to staff member
+	r.POST("/api/staff/message/thread/assign", hr.Handler(alice.
+		New(acl.DisallowAnon, acl.AllowRoles(constants.StaffRole, constants.SupervisorRole, constants.AdminRole)).Append(acl.AllowCORS).
+		ThenFunc(controller.StaffThreadAssignPost)))
+
+	// Staff API: Get assigned customers
+	r.GET("/api/staff/customers", hr.Handler(alice.
+		New(acl.DisallowAnon, acl.AllowRoles(constants.StaffRole, constants.SupervisorRole)).Append(acl.AllowCORS).
+		ThenFunc(controller.StaffCustomersGet)))
+
+	// Staff API: Get assigned customer file list
+	r.GET("/api/staff/customer/file/list", hr.Handler(alice.
+		New(acl.DisallowAnon, acl.AllowRoles(constants.StaffRole, constants.SupervisorRole)).Append(acl.AllowCORS).
+		ThenFunc(controller.StaffCustomerFilesListGet)))
+
+	// Staff API: Download assigned customer file
+	r.GET("/api/staff/customer/file/download", hr.Handler(alice.
+		New(acl.DisallowAnon, acl.AllowRoles(constants.StaffRole, constants.SupervisorRole)).Append(acl.AllowCORS).
+		ThenFunc(controller.StaffCustomerFileDownloadGet)))
+
+	// Staff API: Request document from assigned customer
+	r.POST("/api/staff/customer/document/request", hr.Handler(alice.
+		New(acl.DisallowAnon, acl.AllowRoles(constants.StaffRole, constants.SupervisorRole)).Append(acl.AllowCORS).
+		ThenFunc(controller.StaffDocumentRequestPost)))
+
+	// Staff API: Get documents requested from assigned customer
+	r.GET("/api/staff/customer/document/requests", hr.Handler(alice.
+		New(acl.DisallowAnon, acl.AllowRoles(constants.StaffRole, constants.SupervisorRole)).Append(acl.AllowCORS).
+		ThenFunc(controller.StaffDocumentRequestsGet)))
+
+	// Staff API: Get loan applications of assigned customers
+	r.GET("/api/staff/loan_application/list", hr.Handler(alice.
+		New(acl.DisallowAnon, acl.AllowRoles(constants.StaffRole, constants.SupervisorRole, constants.AdminRole)).Append(acl.AllowCORS).
+		ThenFunc(controller.StaffLoanApplicationListGet)))
+
+	// Staff API: Get loan application of assigned customer with documents and history
+	r.GET("/api/staff/loan_application", hr.Handler(alice.
+		New(acl.DisallowAnon, acl.AllowRoles(constants.StaffRole, constants.SupervisorRole, constants.AdminRole)).Append(acl.AllowCORS).
+		ThenFunc(controller.LoanApplicationGet)))
+
+	// Staff API: Review, approve, decline or fund loan application
+	r.POST("/api/staff/loan_application/state", hr.Handler(alice.
+		New(acl.DisallowAnon, acl.AllowRoles(constants.StaffRole, constants.SupervisorRole, constants.AdminRole)).Append(acl.AllowCORS).
+		ThenFunc(controller.LoanApplicationStatePost)))
+
 	//***************************************************************************
 	// Messaging Rest APIs
 	//***************************************************************************
@@ -203,6 +454,64 @@ func routes() *httprouter.Router {
 		New(acl.DisallowAnon).Append(acl.AllowCORS).
 		ThenFunc(controller.MarkMessageAsReaded)))
 
+	// Messaging API: search messages by content and thread title
+	r.GET("/api/user/message/search", hr.Handler(alice.
+		New(acl.DisallowAnon).Append(acl.AllowCORS).
+		ThenFunc(controller.MessageSearchGet)))
+
+	// Messaging API: numbers of unread messages, total and per thread
+	r.GET("/api/user/message/unread", hr.Handler(alice.
+		New(acl.DisallowAnon).Append(acl.AllowCORS).
+		ThenFunc(controller.MessageUnreadGet)))
+
+	// Messaging API: mark thread read up to message
+	r.POST("/api/user/message/thread/read", hr.Handler(alice.
+		New(acl.DisallowAnon).Append(acl.AllowCORS).
+		ThenFunc(controller.MessageThreadReadPost)))
+
+	// Messaging API: download file attached to message
+	r.GET("/api/user/message/attachment", hr.Handler(alice.
+		New(acl.DisallowAnon).Append(acl.AllowCORS).
+		ThenFunc(controller.MessageAttachmentGet)))
+
+	// Messaging API: get thread participants
+	r.GET("/api/user/message/thread/participants", hr.Handler(alice.
+		New(acl.DisallowAnon).Append(acl.AllowCORS).
+		ThenFunc(controller.MessageThreadParticipantsGet)))
+
+	// Messaging API: add thread participant
+	r.POST("/api/user/message/thread/participant", hr.Handler(alice.
+		New(acl.DisallowAnon).Append(acl.AllowCORS).
+		ThenFunc(controller.MessageThreadParticipantPost)))
+
+	// Messaging API: remove thread participant or leave thread
+	r.DELETE("/api/user/message/thread/participant", hr.Handler(alice.
+		New(acl.DisallowAnon).Append(acl.AllowCORS).
+		ThenFunc(controller.MessageThreadParticipantDelete)))
+
+	// Messaging API: change thread status
+	r.PATCH("/api/user/message/thread/status", hr.Handler(alice.
+		New(acl.DisallowAnon, acl.AllowRoles(constants.StaffRole, constants.SupervisorRole, constants.AdminRole)).Append(acl.AllowCORS).
+		ThenFunc(controller.MessageThreadStatusPatch)))
+
+	// Notifications API: how user is notified about new messages
+	r.GET("/api/user/notification/preference", hr.Handler(alice.
+		New(acl.DisallowAnon).Append(acl.AllowCORS).
+		ThenFunc(controller.NotificationPreferenceGet)))
+
+	r.PUT("/api/user/notification/preference", hr.Handler(alice.
+		New(acl.DisallowAnon).Append(acl.AllowCORS).
+		ThenFunc(controller.NotificationPreferencePut)))
+
+	//***************************************************************************
+	// Real-time Events APIs
+	//***************************************************************************
+
+	// Events API: stream of user's messages, read receipts and thread updates over WebSocket or SSE
+	r.GET("/api/v1/events", hr.Handler(alice.
+		New(acl.DisallowAnon).Append(acl.AllowCORS).
+		ThenFunc(controller.EventsGet)))
+
 
 	return r
 }
@@ -220,7 +529,9 @@ func middleware(h http.Handler) http.Handler {
 	cs.ExcludeRegexPaths([]string{"/api/admin(.*)"})
 	cs.ExcludeRegexPaths([]string{"/api/user(.*)"})
 	cs.ExcludeRegexPaths([]string{"/api/customer(.*)"})
+	cs.ExcludeRegexPaths([]string{"/api/staff(.*)"})
 	cs.ExcludeRegexPaths([]string{"/api/public(.*)"})
+	cs.ExcludeRegexPaths([]string{"/api/v1(.*)"})
 
 	csrfbanana.TokenLength = 32
 	csrfbanana.TokenName = "token"
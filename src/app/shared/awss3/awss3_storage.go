@@ -8,5 +8,5 @@ type AWSS3Config struct {
 	AWSBucketName           string
 	AWSAccessType           string
 	AWSPresignLifePeriodMin uint
+	TrashRetentionDays      uint // deleted files are removed from storage after this period
 }
-
@@ -8,8 +8,10 @@ import (
 )
 
 const (
-	// BestLenderRateCacheKey key for best lender lates from POST /api/admin/rate/list
+	// BestLenderRateCacheKey key for best lender rates from POST /api/public/rate/list
 	BestLenderRateCacheKey = "bestLenderRates"
+	// EffectiveLenderRateCacheKey key for lender rates effective today from POST /api/public/rate/list
+	EffectiveLenderRateCacheKey = "effectiveLenderRates"
 )
 
 var (
@@ -6,6 +6,12 @@ import (
 	"app/shared/awss3"
 	"app/shared/database"
 	"app/shared/email"
+	"app/shared/events"
+	"app/shared/filecategory"
+	"app/shared/keyring"
+	"app/shared/ratefeed"
+	"app/shared/ratesheet"
+	"app/shared/scanner"
 	"app/shared/server"
 	"app/shared/session"
 	"app/shared/view"
@@ -27,6 +33,13 @@ type Configuration struct {
 	Template view.Template     `json:"Template"`
 	View     view.View         `json:"View"`
 	AWSS3    awss3.AWSS3Config `json:"AWSS3Config"`
+	Keyring  keyring.Info      `json:"Keyring"`
+	Files    filecategory.Info `json:"FileCategories"`
+	Scanner  scanner.Info      `json:"Scanner"`
+	Events   events.Info       `json:"Events"`
+	Rates    ratesheet.Info    `json:"RateSheets"`
+	RateFeed ratefeed.Info     `json:"RateFeed"`
+	SiteURL  string            `json:"SiteURL"` // public URL of site for links in emails
 }
 
 // ParseJSON unmarshals bytes to structs
@@ -44,6 +57,11 @@ func Database() database.Info {
 	return Config.Database
 }
 
+// Email return SMTP settings
+func Email() email.SMTPInfo {
+	return Config.Email
+}
+
 // View return current view settings
 func View() view.View {
 	return Config.View
@@ -63,3 +81,38 @@ func Template() view.Template {
 func AWSS3() awss3.AWSS3Config {
 	return Config.AWSS3
 }
+
+// Keyring return encryption keys settings
+func Keyring() keyring.Info {
+	return Config.Keyring
+}
+
+// FileCategories return document categories settings
+func FileCategories() filecategory.Info {
+	return Config.Files
+}
+
+// Scanner return antivirus scanner settings
+func Scanner() scanner.Info {
+	return Config.Scanner
+}
+
+// Events return real-time events broker settings
+func Events() events.Info {
+	return Config.Events
+}
+
+// RateSheets return column mappings of lender rate sheets
+func RateSheets() ratesheet.Info {
+	return Config.Rates
+}
+
+// RateFeed return external rate feed settings
+func RateFeed() ratefeed.Info {
+	return Config.RateFeed
+}
+
+// SiteURL return public URL of site
+func SiteURL() string {
+	return Config.SiteURL
+}
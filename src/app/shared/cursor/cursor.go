@@ -0,0 +1,55 @@
+// Package cursor encodes opaque cursors for keyset pagination of lists.
+// Cursor points to a row of list and tells whether page goes after or before it in list order.
+package cursor
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// ErrInvalid returns if cursor can't be decoded
+var ErrInvalid = errors.New("invalid cursor")
+
+// Cursor is position in list sorted by key and ID
+type Cursor struct {
+	ID     uint32 `json:"i"`
+	Key    string `json:"k,omitempty"` // sort column value of row, empty for lists sorted by ID only
+	Before bool   `json:"b,omitempty"` // page goes before row in list order
+}
+
+// After return encoded cursor of page after row
+func After(id uint32, key string) string {
+	return Encode(&Cursor{ID: id, Key: key})
+}
+
+// Before return encoded cursor of page before row
+func Before(id uint32, key string) string {
+	return Encode(&Cursor{ID: id, Key: key, Before: true})
+}
+
+// Encode return URL safe cursor string
+func Encode(c *Cursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Decode parses cursor string, empty string gives nil cursor of the first page
+func Decode(s string) (*Cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalid
+	}
+
+	c := &Cursor{}
+	err = json.Unmarshal(b, c)
+	if err != nil || c.ID == 0 {
+		return nil, ErrInvalid
+	}
+
+	return c, nil
+}
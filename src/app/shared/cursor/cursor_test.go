@@ -0,0 +1,40 @@
+package cursor
+
+import (
+	"testing"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	c, err := Decode(Before(42, "report.pdf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.ID != 42 || c.Key != "report.pdf" || !c.Before {
+		t.Error("Decoded cursor differs from encoded, got", *c)
+	}
+
+	c, err = Decode(After(7, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.ID != 7 || c.Key != "" || c.Before {
+		t.Error("Decoded cursor differs from encoded, got", *c)
+	}
+}
+
+func TestDecodeEmpty(t *testing.T) {
+	c, err := Decode("")
+	if err != nil || c != nil {
+		t.Error("Empty cursor should be the first page")
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	for _, s := range []string{"%%%", "bm90IGpzb24", Encode(&Cursor{})} {
+		if _, err := Decode(s); err != ErrInvalid {
+			t.Error("Cursor", s, "should be invalid")
+		}
+	}
+}
@@ -1,17 +1,37 @@
+// Package email sends emails. Sender is selected in config: "smtp" delivers through SMTP server,
+// "fake" keeps messages in memory for dev and tests.
 package email
 
 import (
 	"encoding/base64"
+	"errors"
 	"fmt"
+	"math/rand"
+	"mime"
 	"net/smtp"
+	"strings"
+	"sync"
+)
+
+// Sender types in config
+const (
+	TypeSMTP = "smtp"
+	TypeFake = "fake"
 )
 
 var (
+	// ErrNotConfigured returns if email is sent before Configure
+	ErrNotConfigured = errors.New("email sender is not configured")
+
 	e SMTPInfo
+
+	mutex  sync.RWMutex
+	sender Sender
 )
 
 // SMTPInfo is the details for the SMTP server
 type SMTPInfo struct {
+	Type     string // smtp or fake, empty means smtp
 	Username string
 	Password string
 	Hostname string
@@ -19,9 +39,37 @@ type SMTPInfo struct {
 	From     string
 }
 
-// Configure adds the settings for the SMTP server
-func Configure(c SMTPInfo) {
-	e = c
+// Message is email with plain text and optional HTML bodies
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Sender delivers emails
+type Sender interface {
+	Send(m *Message) error
+}
+
+// Configure adds the settings for the SMTP server and creates sender.
+// Return error if SMTP server host or sender address is empty.
+func Configure(c SMTPInfo) error {
+	switch c.Type {
+	case TypeFake:
+		e = c
+		SetSender(&Fake{})
+	case "", TypeSMTP:
+		if c.Hostname == "" || c.From == "" {
+			return errors.New("error while configure email: smtp hostname or from address is empty")
+		}
+		e = c
+		SetSender(&SMTP{})
+	default:
+		return errors.New("error while configure email: unknown type " + c.Type)
+	}
+
+	return nil
 }
 
 // ReadConfig returns the SMTP information
@@ -29,32 +77,95 @@ func ReadConfig() SMTPInfo {
 	return e
 }
 
-// SendEmail sends an email
+// SetSender replaces current sender
+func SetSender(s Sender) {
+	mutex.Lock()
+	sender = s
+	mutex.Unlock()
+}
+
+// Send delivers message with configured sender
+func Send(m *Message) error {
+	mutex.RLock()
+	s := sender
+	mutex.RUnlock()
+
+	if s == nil {
+		return ErrNotConfigured
+	}
+
+	return s.Send(m)
+}
+
+// SendEmail sends a plain text email
 func SendEmail(to, subject, body string) error {
+	return Send(&Message{To: to, Subject: subject, Text: body})
+}
+
+// SMTP delivers emails through SMTP server from config
+type SMTP struct{}
+
+// Send sends message as multipart/alternative if it has HTML body
+func (s *SMTP) Send(m *Message) error {
 	auth := smtp.PlainAuth("", e.Username, e.Password, e.Hostname)
 
 	header := make(map[string]string)
-	header["From"] = e.From
-	header["To"] = to
-	header["Subject"] = subject
+	header["From"] = headerValue(e.From)
+	header["To"] = headerValue(m.To)
+	header["Subject"] = headerValue(m.Subject)
 	header["MIME-Version"] = "1.0"
-	header["Content-Type"] = `text/plain; charset="utf-8"`
-	header["Content-Transfer-Encoding"] = "base64"
+
+	body := ""
+	if m.HTML == "" {
+		header["Content-Type"] = `text/plain; charset="utf-8"`
+		header["Content-Transfer-Encoding"] = "base64"
+		body = base64.StdEncoding.EncodeToString([]byte(m.Text))
+	} else {
+		boundary := fmt.Sprintf("%x", rand.Int63())
+		header["Content-Type"] = `multipart/alternative; boundary="` + boundary + `"`
+		body = part(boundary, "text/plain", m.Text) + part(boundary, "text/html", m.HTML) + "--" + boundary + "--\r\n"
+	}
 
 	message := ""
 	for k, v := range header {
 		message += fmt.Sprintf("%s: %s\r\n", k, v)
 	}
-	message += "\r\n" + base64.StdEncoding.EncodeToString([]byte(body))
+	message += "\r\n" + body
 
 	// Send the email
 	err := smtp.SendMail(
 		fmt.Sprintf("%s:%d", e.Hostname, e.Port),
 		auth,
 		e.From,
-		[]string{to},
+		[]string{m.To},
 		[]byte(message),
 	)
 
 	return err
 }
+
+// headerValue removes line breaks from header value, so it can't add headers, and Q-encodes non-ASCII value
+func headerValue(v string) string {
+	v = strings.Map(func(r rune) rune {
+		if r == '\r' || r == '\n' {
+			return -1
+		}
+		return r
+	}, v)
+
+	for _, r := range v {
+		if r >= 0x80 {
+			return mime.QEncoding.Encode("utf-8", v)
+		}
+	}
+
+	return v
+}
+
+// part return base64 encoded part of multipart body
+func part(boundary, contentType, content string) string {
+	return "--" + boundary + "\r\n" +
+		"Content-Type: " + contentType + `; charset="utf-8"` + "\r\n" +
+		"Content-Transfer-Encoding: base64\r\n\r\n" +
+		base64.StdEncoding.EncodeToString([]byte(content)) + "\r\n"
+}
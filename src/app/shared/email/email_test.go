@@ -0,0 +1,83 @@
+package email
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestConfigure(t *testing.T) {
+	defer SetSender(nil)
+
+	if err := Configure(SMTPInfo{Type: TypeFake}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := sender.(*Fake); !ok {
+		t.Error("Fake sender should be configured")
+	}
+
+	if err := Configure(SMTPInfo{Hostname: "localhost", Port: 25, From: "noreply@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := sender.(*SMTP); !ok {
+		t.Error("SMTP sender should be default")
+	}
+
+	if err := Configure(SMTPInfo{}); err == nil {
+		t.Error("SMTP sender without hostname should fail")
+	}
+
+	if err := Configure(SMTPInfo{Type: "sendmail", Hostname: "localhost"}); err == nil {
+		t.Error("Unknown sender type should fail")
+	}
+}
+
+func TestHeaderValue(t *testing.T) {
+	if got := headerValue("Hello\r\nBcc: eve@example.com"); got != "HelloBcc: eve@example.com" {
+		t.Errorf("Line breaks should be removed: %q", got)
+	}
+
+	if got := headerValue("Plain subject"); got != "Plain subject" {
+		t.Errorf("ASCII value should be kept: %q", got)
+	}
+
+	if got := headerValue("Résumé"); got != "=?utf-8?q?R=C3=A9sum=C3=A9?=" {
+		t.Errorf("Non-ASCII value should be Q-encoded: %q", got)
+	}
+}
+
+func TestSendNotConfigured(t *testing.T) {
+	SetSender(nil)
+
+	if err := SendEmail("john@example.com", "subject", "body"); err != ErrNotConfigured {
+		t.Error("Send without sender should fail, got", err)
+	}
+}
+
+func TestFakeSend(t *testing.T) {
+	fake := &Fake{}
+	SetSender(fake)
+	defer SetSender(nil)
+
+	err := Send(&Message{To: "john@example.com", Subject: "Hello", Text: "text", HTML: "<p>html</p>"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sent := fake.Sent()
+	if len(sent) != 1 || sent[0].To != "john@example.com" || sent[0].HTML != "<p>html</p>" {
+		t.Error("Fake should keep sent message, got", sent)
+	}
+
+	fake.Err = errors.New("smtp is down")
+	if err = Send(&Message{To: "john@example.com"}); err != fake.Err {
+		t.Error("Fake should return configured error, got", err)
+	}
+}
+
+func TestPart(t *testing.T) {
+	got := part("b1", "text/plain", "hi")
+	want := "--b1\r\nContent-Type: text/plain; charset=\"utf-8\"\r\nContent-Transfer-Encoding: base64\r\n\r\naGk=\r\n"
+	if got != want {
+		t.Errorf("Wrong part: %q", got)
+	}
+}
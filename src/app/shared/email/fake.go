@@ -0,0 +1,36 @@
+package email
+
+import (
+	"log"
+	"sync"
+)
+
+// Fake keeps sent messages in memory instead of delivery. For dev and tests.
+type Fake struct {
+	Err error // returned from Send if set, e.g. to emulate unavailable SMTP server
+
+	mutex sync.Mutex
+	sent  []*Message
+}
+
+// Send stores message
+func (f *Fake) Send(m *Message) error {
+	if f.Err != nil {
+		return f.Err
+	}
+
+	log.Println("fake email to", m.To+":", m.Subject)
+
+	f.mutex.Lock()
+	f.sent = append(f.sent, m)
+	f.mutex.Unlock()
+	return nil
+}
+
+// Sent return stored messages
+func (f *Fake) Sent() []*Message {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return append([]*Message{}, f.sent...)
+}
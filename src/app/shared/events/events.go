@@ -0,0 +1,160 @@
+// Package events delivers real-time notifications to connected users.
+// Events are published through a broker so every application instance receives them,
+// then fanned out by local hub to all connections of the target users.
+// Broker is selected in config: "local" keeps events in process, "redis" uses redis pub/sub.
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+)
+
+// Broker types in config
+const (
+	BackendLocal = "local"
+	BackendRedis = "redis"
+)
+
+// Event types pushed to clients
+const (
+	TypeMessageNew    = "message.new"
+	TypeMessageRead   = "message.read"
+	TypeMessageUpdate = "message.updated"
+	TypeMessageDelete = "message.deleted"
+	TypeThreadUpdate  = "thread.updated"
+	TypeThreadRead    = "thread.read"
+)
+
+const defaultChannel = "app-events"
+
+var (
+	// ErrNotConfigured returns if events published before Configure
+	ErrNotConfigured = errors.New("events broker is not configured")
+
+	mutex  sync.RWMutex
+	broker Broker
+	hub    = NewHub()
+)
+
+// Event is a notification sent to client
+type Event struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// envelope is a broker message with event recipients
+type envelope struct {
+	UserIDs []uint32 `json:"user_ids"`
+	Event   *Event   `json:"event"`
+}
+
+// Broker shares published events between application instances
+type Broker interface {
+	Publish(payload []byte) error
+	Subscribe(handler func(payload []byte)) error
+	Close() error
+}
+
+// Info contains events settings
+type Info struct {
+	Backend       string `json:"Backend"`       // local or redis
+	RedisAddress  string `json:"RedisAddress"`  // redis host:port
+	RedisPassword string `json:"RedisPassword"` // redis AUTH password, optional
+	Channel       string `json:"Channel"`       // redis pub/sub channel
+}
+
+// Configure creates broker from settings and starts dispatching its events to hub
+func Configure(i Info) error {
+	var b Broker
+
+	switch i.Backend {
+	case BackendLocal, "":
+		b = NewLocalBroker()
+	case BackendRedis:
+		if i.RedisAddress == "" {
+			return errors.New("error while configure events: redis address is empty")
+		}
+		channel := i.Channel
+		if channel == "" {
+			channel = defaultChannel
+		}
+		b = NewRedisBroker(i.RedisAddress, i.RedisPassword, channel)
+	default:
+		return errors.New("error while configure events: unknown backend " + i.Backend)
+	}
+
+	return SetBroker(b)
+}
+
+// SetBroker replaces current broker, previous one is closed
+func SetBroker(b Broker) error {
+	if err := b.Subscribe(dispatch); err != nil {
+		return errors.New("error while subscribe to events broker: " + err.Error())
+	}
+
+	mutex.Lock()
+	prev := broker
+	broker = b
+	mutex.Unlock()
+
+	if prev != nil {
+		prev.Close()
+	}
+	return nil
+}
+
+// Publish sends event with data encoded as JSON to all connections of users
+func Publish(userIDs []uint32, eventType string, data interface{}) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	mutex.RLock()
+	b := broker
+	mutex.RUnlock()
+
+	if b == nil {
+		return ErrNotConfigured
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return errors.New("error while encode event data: " + err.Error())
+	}
+
+	payload, err := json.Marshal(&envelope{UserIDs: userIDs, Event: &Event{Type: eventType, Data: raw}})
+	if err != nil {
+		return errors.New("error while encode event: " + err.Error())
+	}
+
+	return b.Publish(payload)
+}
+
+// Subscribe registers new connection of user
+func Subscribe(userID uint32) *Subscription {
+	return hub.Subscribe(userID)
+}
+
+// Unsubscribe removes connection of user
+func Unsubscribe(s *Subscription) {
+	hub.Unsubscribe(s)
+}
+
+// dispatch delivers broker payload to local connections
+func dispatch(payload []byte) {
+	env := &envelope{}
+	if err := json.Unmarshal(payload, env); err != nil {
+		log.Println("error while decode event:", err)
+		return
+	}
+
+	if env.Event == nil {
+		return
+	}
+
+	for _, userID := range env.UserIDs {
+		hub.Send(userID, env.Event)
+	}
+}
@@ -0,0 +1,112 @@
+package events
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"time"
+)
+
+func receive(t *testing.T, s *Subscription) *Event {
+	select {
+	case e, ok := <-s.Events():
+		if !ok {
+			t.Fatal("Subscription is closed")
+		}
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("Event is not delivered")
+	}
+	return nil
+}
+
+func TestPublish(t *testing.T) {
+	if err := Configure(Info{Backend: BackendLocal}); err != nil {
+		t.Fatal(err)
+	}
+
+	first := Subscribe(1)
+	second := Subscribe(1)
+	other := Subscribe(2)
+	defer Unsubscribe(first)
+	defer Unsubscribe(second)
+	defer Unsubscribe(other)
+
+	err := Publish([]uint32{1}, TypeMessageNew, map[string]uint32{"id": 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range []*Subscription{first, second} {
+		e := receive(t, s)
+		if e.Type != TypeMessageNew || string(e.Data) != `{"id":10}` {
+			t.Error("Wrong event delivered", e.Type, string(e.Data))
+		}
+	}
+
+	select {
+	case e := <-other.Events():
+		t.Error("Event delivered to other user", e.Type)
+	default:
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	if err := Configure(Info{Backend: "unknown"}); err == nil {
+		t.Error("Unknown backend should be rejected")
+	}
+
+	if err := Configure(Info{Backend: BackendRedis}); err == nil {
+		t.Error("Redis without address should be rejected")
+	}
+}
+
+func TestHubDropsSlowSubscription(t *testing.T) {
+	h := NewHub()
+	s := h.Subscribe(5)
+
+	for i := 0; i <= subscriptionBuffer; i++ {
+		h.Send(5, &Event{Type: TypeThreadUpdate})
+	}
+
+	if h.Count(5) != 0 {
+		t.Error("Slow subscription should be dropped")
+	}
+
+	count := 0
+	for range s.Events() {
+		count++
+	}
+	if count != subscriptionBuffer {
+		t.Error("Buffered events should stay readable, got", count)
+	}
+
+	// Unsubscribe of dropped subscription must not panic
+	h.Unsubscribe(s)
+}
+
+func TestReadRESP(t *testing.T) {
+	reader := bufio.NewReader(strings.NewReader(
+		"*3\r\n$7\r\nmessage\r\n$4\r\nchan\r\n$7\r\npay\r\nld\r\n+OK\r\n-ERR wrong\r\n:3\r\n"))
+
+	reply, err := readRESP(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts, ok := reply.([]interface{})
+	if !ok || len(parts) != 3 || string(parts[2].([]byte)) != "pay\r\nld" {
+		t.Error("Wrong array reply", reply)
+	}
+
+	if reply, _ = readRESP(reader); reply != "OK" {
+		t.Error("Wrong simple string reply", reply)
+	}
+
+	if reply, _ = readRESP(reader); reply.(error).Error() != "ERR wrong" {
+		t.Error("Wrong error reply", reply)
+	}
+
+	if reply, _ = readRESP(reader); reply != int64(3) {
+		t.Error("Wrong integer reply", reply)
+	}
+}
@@ -0,0 +1,111 @@
+package events
+
+import (
+	"sync"
+)
+
+// subscriptionBuffer is a number of events waiting for slow connection before it is dropped
+const subscriptionBuffer = 64
+
+// Subscription is a single user connection receiving events
+type Subscription struct {
+	UserID uint32
+	events chan *Event
+	once   sync.Once
+}
+
+// Events return channel with events, it is closed when subscription is removed
+func (s *Subscription) Events() <-chan *Event {
+	return s.events
+}
+
+func (s *Subscription) close() {
+	s.once.Do(func() {
+		close(s.events)
+	})
+}
+
+// Hub fans out events to all connections of user
+type Hub struct {
+	mutex sync.RWMutex
+	users map[uint32]map[*Subscription]struct{}
+}
+
+// NewHub return empty hub
+func NewHub() *Hub {
+	return &Hub{users: make(map[uint32]map[*Subscription]struct{})}
+}
+
+// Subscribe registers new connection of user
+func (h *Hub) Subscribe(userID uint32) *Subscription {
+	s := &Subscription{UserID: userID, events: make(chan *Event, subscriptionBuffer)}
+
+	h.mutex.Lock()
+	subs, ok := h.users[userID]
+	if !ok {
+		subs = make(map[*Subscription]struct{})
+		h.users[userID] = subs
+	}
+	subs[s] = struct{}{}
+	h.mutex.Unlock()
+
+	return s
+}
+
+// Unsubscribe removes connection and closes its channel
+func (h *Hub) Unsubscribe(s *Subscription) {
+	h.mutex.Lock()
+	h.remove(s)
+	h.mutex.Unlock()
+}
+
+// Send delivers event to every connection of user.
+// Connection which does not read its events is dropped so it can't block others.
+func (h *Hub) Send(userID uint32, e *Event) {
+	var slow []*Subscription
+
+	h.mutex.RLock()
+	for s := range h.users[userID] {
+		select {
+		case s.events <- e:
+		default:
+			slow = append(slow, s)
+		}
+	}
+	h.mutex.RUnlock()
+
+	if len(slow) == 0 {
+		return
+	}
+
+	h.mutex.Lock()
+	for _, s := range slow {
+		h.remove(s)
+	}
+	h.mutex.Unlock()
+}
+
+// Count return number of connections of user
+func (h *Hub) Count(userID uint32) int {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	return len(h.users[userID])
+}
+
+// remove must be called with write lock held
+func (h *Hub) remove(s *Subscription) {
+	subs, ok := h.users[s.UserID]
+	if !ok {
+		return
+	}
+
+	if _, ok := subs[s]; !ok {
+		return
+	}
+
+	delete(subs, s)
+	if len(subs) == 0 {
+		delete(h.users, s.UserID)
+	}
+	s.close()
+}
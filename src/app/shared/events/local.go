@@ -0,0 +1,44 @@
+package events
+
+import (
+	"sync"
+)
+
+// LocalBroker delivers events inside current process only
+type LocalBroker struct {
+	mutex    sync.RWMutex
+	handlers []func(payload []byte)
+}
+
+// NewLocalBroker return in process broker
+func NewLocalBroker() *LocalBroker {
+	return &LocalBroker{}
+}
+
+// Publish passes payload to all handlers
+func (b *LocalBroker) Publish(payload []byte) error {
+	b.mutex.RLock()
+	handlers := b.handlers
+	b.mutex.RUnlock()
+
+	for _, handler := range handlers {
+		handler(payload)
+	}
+	return nil
+}
+
+// Subscribe adds handler for published payloads
+func (b *LocalBroker) Subscribe(handler func(payload []byte)) error {
+	b.mutex.Lock()
+	b.handlers = append(b.handlers, handler)
+	b.mutex.Unlock()
+	return nil
+}
+
+// Close removes all handlers
+func (b *LocalBroker) Close() error {
+	b.mutex.Lock()
+	b.handlers = nil
+	b.mutex.Unlock()
+	return nil
+}
@@ -0,0 +1,270 @@
+package events
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	redisDialTimeout    = 5 * time.Second
+	redisWriteTimeout   = 5 * time.Second
+	redisReconnectDelay = 2 * time.Second
+)
+
+// RedisBroker shares events between instances with redis PUBLISH/SUBSCRIBE
+type RedisBroker struct {
+	address  string
+	password string
+	channel  string
+
+	mutex   sync.Mutex // guards publish connection
+	conn    net.Conn
+	reader  *bufio.Reader
+	closed  chan struct{}
+	closing sync.Once
+	subConn net.Conn
+	subLock sync.Mutex // guards subscribe connection
+}
+
+// NewRedisBroker return broker for redis at address using pub/sub channel
+func NewRedisBroker(address, password, channel string) *RedisBroker {
+	return &RedisBroker{address: address, password: password, channel: channel, closed: make(chan struct{})}
+}
+
+// Publish sends payload to redis channel, connection is reopened once on failure
+func (b *RedisBroker) Publish(payload []byte) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if b.conn == nil {
+			b.conn, b.reader, err = b.dial()
+			if err != nil {
+				continue
+			}
+		}
+
+		_, err = b.command(b.conn, b.reader, []byte("PUBLISH"), []byte(b.channel), payload)
+		if err == nil {
+			return nil
+		}
+
+		b.conn.Close()
+		b.conn = nil
+	}
+
+	return errors.New("error while publish event to redis: " + err.Error())
+}
+
+// Subscribe listens redis channel in background and passes payloads to handler until broker is closed
+func (b *RedisBroker) Subscribe(handler func(payload []byte)) error {
+	conn, reader, err := b.subscribe()
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for {
+			err := b.receive(reader, handler)
+
+			select {
+			case <-b.closed:
+				return
+			default:
+			}
+
+			log.Println("error while receive events from redis:", err)
+			conn.Close()
+
+			for {
+				select {
+				case <-b.closed:
+					return
+				case <-time.After(redisReconnectDelay):
+				}
+
+				conn, reader, err = b.subscribe()
+				if err == nil {
+					break
+				}
+				log.Println("error while resubscribe to redis:", err)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Close stops subscription and closes connections
+func (b *RedisBroker) Close() error {
+	b.closing.Do(func() {
+		close(b.closed)
+	})
+
+	b.subLock.Lock()
+	if b.subConn != nil {
+		b.subConn.Close()
+		b.subConn = nil
+	}
+	b.subLock.Unlock()
+
+	b.mutex.Lock()
+	if b.conn != nil {
+		b.conn.Close()
+		b.conn = nil
+	}
+	b.mutex.Unlock()
+
+	return nil
+}
+
+// subscribe opens connection in subscriber mode
+func (b *RedisBroker) subscribe() (net.Conn, *bufio.Reader, error) {
+	conn, reader, err := b.dial()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if _, err = b.command(conn, reader, []byte("SUBSCRIBE"), []byte(b.channel)); err != nil {
+		conn.Close()
+		return nil, nil, errors.New("error while subscribe to redis channel: " + err.Error())
+	}
+
+	// Reading blocks until message arrives
+	conn.SetReadDeadline(time.Time{})
+
+	b.subLock.Lock()
+	b.subConn = conn
+	b.subLock.Unlock()
+
+	return conn, reader, nil
+}
+
+// receive reads subscription messages until connection fails
+func (b *RedisBroker) receive(reader *bufio.Reader, handler func(payload []byte)) error {
+	for {
+		reply, err := readRESP(reader)
+		if err != nil {
+			return err
+		}
+
+		// Pushed messages look like ["message", channel, payload]
+		parts, ok := reply.([]interface{})
+		if !ok || len(parts) != 3 {
+			continue
+		}
+
+		kind, _ := parts[0].([]byte)
+		payload, _ := parts[2].([]byte)
+		if string(kind) == "message" && payload != nil {
+			handler(payload)
+		}
+	}
+}
+
+// dial connects to redis and authenticates when password is set
+func (b *RedisBroker) dial() (net.Conn, *bufio.Reader, error) {
+	conn, err := net.DialTimeout("tcp", b.address, redisDialTimeout)
+	if err != nil {
+		return nil, nil, errors.New("error while connect to redis: " + err.Error())
+	}
+
+	reader := bufio.NewReader(conn)
+	if b.password != "" {
+		if _, err = b.command(conn, reader, []byte("AUTH"), []byte(b.password)); err != nil {
+			conn.Close()
+			return nil, nil, errors.New("error while authenticate in redis: " + err.Error())
+		}
+	}
+
+	return conn, reader, nil
+}
+
+// command writes command as RESP array and reads single reply
+func (b *RedisBroker) command(conn net.Conn, reader *bufio.Reader, args ...[]byte) (interface{}, error) {
+	buf := make([]byte, 0, 64)
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(args)), 10)
+	buf = append(buf, '\r', '\n')
+	for _, arg := range args {
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(arg)), 10)
+		buf = append(buf, '\r', '\n')
+		buf = append(buf, arg...)
+		buf = append(buf, '\r', '\n')
+	}
+
+	conn.SetDeadline(time.Now().Add(redisWriteTimeout))
+	if _, err := conn.Write(buf); err != nil {
+		return nil, err
+	}
+
+	reply, err := readRESP(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	if replyErr, ok := reply.(error); ok {
+		return nil, replyErr
+	}
+	return reply, nil
+}
+
+// readRESP parses one redis reply: simple string, error, integer, bulk string or array
+func readRESP(reader *bufio.Reader) (interface{}, error) {
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, errors.New("malformed redis reply")
+	}
+	body := line[1 : len(line)-2]
+
+	switch line[0] {
+	case '+':
+		return string(body), nil
+	case '-':
+		return errors.New(string(body)), nil
+	case ':':
+		return strconv.ParseInt(string(body), 10, 64)
+	case '$':
+		size, err := strconv.Atoi(string(body))
+		if err != nil {
+			return nil, err
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		data := make([]byte, size+2)
+		if _, err = io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		return data[:size], nil
+	case '*':
+		count, err := strconv.Atoi(string(body))
+		if err != nil {
+			return nil, err
+		}
+		if count < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, count)
+		for i := range items {
+			if items[i], err = readRESP(reader); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+
+	return nil, errors.New("unknown redis reply type " + string(line[0]))
+}
@@ -0,0 +1,92 @@
+// Package filecategory keeps the list of document categories which customers can assign to uploaded files.
+package filecategory
+
+import (
+	"strings"
+	"sync"
+)
+
+var (
+	// defaultCategories is used when config has no categories
+	defaultCategories = []string{"other", "w2", "pay_stub", "bank_statement", "tax_return", "id_document"}
+
+	mutex      sync.RWMutex
+	categories = defaultCategories
+	fallback   = defaultCategories[0]
+)
+
+// Info contains document categories settings
+type Info struct {
+	Categories []string `json:"Categories"` // allowed categories
+	Default    string   `json:"Default"`    // category of files uploaded without category, first category if empty
+}
+
+// Configure sets allowed categories. Category names are trimmed and lowercased.
+func Configure(i Info) {
+	list := []string{}
+	seen := map[string]bool{}
+	for _, v := range i.Categories {
+		name := Normalize(v)
+		if name == "" || seen[name] {
+			continue
+		}
+
+		seen[name] = true
+		list = append(list, name)
+	}
+
+	if len(list) == 0 {
+		list = defaultCategories
+	}
+
+	def := Normalize(i.Default)
+	if def == "" || !contains(list, def) {
+		def = list[0]
+	}
+
+	mutex.Lock()
+	categories = list
+	fallback = def
+	mutex.Unlock()
+}
+
+// List return allowed categories
+func List() []string {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	result := make([]string, len(categories))
+	copy(result, categories)
+	return result
+}
+
+// Default return category for files uploaded without category
+func Default() string {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	return fallback
+}
+
+// IsValid return true if category is allowed
+func IsValid(name string) bool {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	return contains(categories, Normalize(name))
+}
+
+// Normalize trims and lowercases category name
+func Normalize(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+func contains(list []string, name string) bool {
+	for _, v := range list {
+		if v == name {
+			return true
+		}
+	}
+
+	return false
+}
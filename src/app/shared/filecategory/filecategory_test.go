@@ -0,0 +1,39 @@
+package filecategory
+
+import (
+	"testing"
+)
+
+func TestConfigure(t *testing.T) {
+	Configure(Info{Categories: []string{" W2 ", "pay_stub", "w2", ""}, Default: "Pay_Stub"})
+	defer Configure(Info{})
+
+	list := List()
+	if len(list) != 2 || list[0] != "w2" || list[1] != "pay_stub" {
+		t.Error("Categories should be normalized and deduplicated, got", list)
+	}
+
+	if Default() != "pay_stub" {
+		t.Error("Default category should be pay_stub, got", Default())
+	}
+
+	if !IsValid("W2") {
+		t.Error("W2 should be valid category")
+	}
+
+	if IsValid("other") {
+		t.Error("other should not be valid category")
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	Configure(Info{Default: "unknown"})
+
+	if len(List()) != len(defaultCategories) {
+		t.Error("Default categories should be used for empty config")
+	}
+
+	if Default() != defaultCategories[0] {
+		t.Error("Unknown default category should be replaced with first category")
+	}
+}
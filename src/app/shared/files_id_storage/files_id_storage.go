@@ -1,7 +1,7 @@
 package files_id_storage
 
 import (
-	"app/constants"
+	"app/shared/keyring"
 	"encoding/base64"
 	"fmt"
 	"log"
@@ -11,7 +11,13 @@ import (
 // GetNewFileID storing real file ID in cache, and return fail ID for frontend
 func GetNewFileID(realID int, sessID string) string {
 	log.Println("get new file ID with real ID=", realID, " and sess ID=", sessID, " len of info=", len([]byte(sessID+fmt.Sprint(realID))))
-	failID, err := Encrypt([]byte(sessID+fmt.Sprint(realID)), constants.AESEncryptKey)
+	key, err := keyring.FileIDKey()
+	if err != nil {
+		log.Println("error while get file ID key: " + err.Error())
+		panic(err)
+	}
+
+	failID, err := Encrypt([]byte(sessID+fmt.Sprint(realID)), key)
 	if err != nil {
 		log.Println("error while encrypt new fail ID key: " + err.Error())
 		panic(err)
@@ -32,7 +38,13 @@ func GetRealID(failID string, sessID string) (string, error) {
 	}
 
 	log.Println("bytes fake ID: ", []byte(decoded))
-	realID, err := Decrypt([]byte(decoded), constants.AESEncryptKey)
+	key, err := keyring.FileIDKey()
+	if err != nil {
+		log.Println("error while get file ID key: " + err.Error())
+		return "", err
+	}
+
+	realID, err := Decrypt([]byte(decoded), key)
 	if err != nil {
 		log.Println("error while decrypt new file ID key: " + err.Error())
 		return "", err
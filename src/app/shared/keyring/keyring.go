@@ -0,0 +1,395 @@
+// Package keyring keeps the application encryption keys.
+// Keys are never stored in source: they are loaded from the config file, an optional JSON key file
+// and environment variables (in that order, later sources override earlier ones).
+// Uploaded files use envelope encryption: every file is sealed with its own random data key,
+// and the data key is stored in DB wrapped (encrypted) by a versioned master key. Rotating
+// the master key only needs re-wrapping of data keys, file contents stay untouched.
+package keyring
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// KeySize is the size of master and data keys (AES-256)
+	KeySize = 32
+
+	envKeyFile       = "APP_KEYRING_FILE"
+	envActiveVersion = "APP_KEYRING_ACTIVE_VERSION"
+	envMasterKeys    = "APP_KEYRING_MASTER_KEYS" // "1:base64key,2:base64key"
+	envFileIDKey     = "APP_KEYRING_FILE_ID_KEY"
+	envSSEKMSKeyID   = "APP_KEYRING_SSE_KMS_KEY_ID"
+)
+
+var (
+	// ErrNotConfigured returns if keyring used before Configure
+	ErrNotConfigured = errors.New("keyring is not configured")
+	// ErrUnknownVersion returns if master key with requested version not loaded
+	ErrUnknownVersion = errors.New("unknown master key version")
+
+	mutex  sync.RWMutex
+	loaded *ring
+)
+
+// Info contains keyring settings. Keys are base64 encoded 32 bytes values.
+type Info struct {
+	KeyFile       string            `json:"KeyFile"`       // optional JSON file with the same fields
+	ActiveVersion uint32            `json:"ActiveVersion"` // master key version for new data keys
+	MasterKeys    map[string]string `json:"MasterKeys"`    // master key version -> key
+	FileIDKey     string            `json:"FileIDKey"`     // key for fake file IDs which sends to frontend
+	SSEKMSKeyID   string            `json:"SSEKMSKeyID"`   // AWS KMS key ID for server side encryption
+}
+
+// WrappedKey is a data key encrypted with master key
+type WrappedKey struct {
+	Version    uint32
+	Ciphertext []byte
+}
+
+type ring struct {
+	activeVersion uint32
+	masterKeys    map[uint32][]byte
+	fileIDKey     []byte
+	sseKMSKeyID   string
+}
+
+// Configure loads keys from config, key file and environment
+func Configure(i Info) error {
+	err := mergeKeyFile(&i)
+	if err != nil {
+		return err
+	}
+
+	err = mergeEnv(&i)
+	if err != nil {
+		return err
+	}
+
+	r, err := newRing(i)
+	if err != nil {
+		return err
+	}
+
+	mutex.Lock()
+	loaded = r
+	mutex.Unlock()
+
+	return nil
+}
+
+// mergeKeyFile read key file (if set) over config values
+func mergeKeyFile(i *Info) error {
+	path := i.KeyFile
+	if env := os.Getenv(envKeyFile); env != "" {
+		path = env
+	}
+
+	if path == "" {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.New("error while read key file: " + err.Error())
+	}
+
+	fileInfo := Info{}
+	err = json.Unmarshal(data, &fileInfo)
+	if err != nil {
+		return errors.New("error while parse key file: " + err.Error())
+	}
+
+	if fileInfo.ActiveVersion != 0 {
+		i.ActiveVersion = fileInfo.ActiveVersion
+	}
+
+	if len(fileInfo.MasterKeys) != 0 {
+		i.MasterKeys = fileInfo.MasterKeys
+	}
+
+	if fileInfo.FileIDKey != "" {
+		i.FileIDKey = fileInfo.FileIDKey
+	}
+
+	if fileInfo.SSEKMSKeyID != "" {
+		i.SSEKMSKeyID = fileInfo.SSEKMSKeyID
+	}
+
+	return nil
+}
+
+// mergeEnv read environment variables over config and key file values
+func mergeEnv(i *Info) error {
+	if env := os.Getenv(envActiveVersion); env != "" {
+		v, err := strconv.ParseUint(env, 10, 32)
+		if err != nil {
+			return errors.New("error while parse " + envActiveVersion + ": " + err.Error())
+		}
+		i.ActiveVersion = uint32(v)
+	}
+
+	if env := os.Getenv(envMasterKeys); env != "" {
+		i.MasterKeys = map[string]string{}
+		for _, pair := range strings.Split(env, ",") {
+			parts := strings.SplitN(strings.TrimSpace(pair), ":", 2)
+			if len(parts) != 2 {
+				return errors.New("error while parse " + envMasterKeys + ": expect version:key pairs")
+			}
+			i.MasterKeys[parts[0]] = parts[1]
+		}
+	}
+
+	if env := os.Getenv(envFileIDKey); env != "" {
+		i.FileIDKey = env
+	}
+
+	if env := os.Getenv(envSSEKMSKeyID); env != "" {
+		i.SSEKMSKeyID = env
+	}
+
+	return nil
+}
+
+func newRing(i Info) (*ring, error) {
+	r := &ring{
+		activeVersion: i.ActiveVersion,
+		masterKeys:    map[uint32][]byte{},
+		sseKMSKeyID:   i.SSEKMSKeyID,
+	}
+
+	if len(i.MasterKeys) == 0 {
+		return nil, errors.New("error while load master keys: no keys, set KeyFile, " + envKeyFile + " or " + envMasterKeys)
+	}
+
+	for version, encoded := range i.MasterKeys {
+		v, err := strconv.ParseUint(version, 10, 32)
+		if err != nil || v == 0 {
+			return nil, errors.New("error while load master keys: bad version " + version)
+		}
+
+		key, err := decodeKey(encoded)
+		if err != nil {
+			return nil, errors.New("error while load master key " + version + ": " + err.Error())
+		}
+
+		r.masterKeys[uint32(v)] = key
+	}
+
+	if _, ok := r.masterKeys[r.activeVersion]; !ok {
+		return nil, fmt.Errorf("error while load master keys: active version %d not found", r.activeVersion)
+	}
+
+	if i.FileIDKey == "" {
+		return nil, errors.New("error while load file ID key: no key, set KeyFile, " + envKeyFile + " or " + envFileIDKey)
+	}
+
+	key, err := decodeKey(i.FileIDKey)
+	if err != nil {
+		return nil, errors.New("error while load file ID key: " + err.Error())
+	}
+	r.fileIDKey = key
+
+	return r, nil
+}
+
+func decodeKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("key should be %d bytes, got %d", KeySize, len(key))
+	}
+
+	return key, nil
+}
+
+func current() (*ring, error) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	if loaded == nil {
+		return nil, ErrNotConfigured
+	}
+
+	return loaded, nil
+}
+
+// ActiveVersion return master key version used for new data keys
+func ActiveVersion() (uint32, error) {
+	r, err := current()
+	if err != nil {
+		return 0, err
+	}
+
+	return r.activeVersion, nil
+}
+
+// FileIDKey return key for fake file IDs
+func FileIDKey() ([]byte, error) {
+	r, err := current()
+	if err != nil {
+		return nil, err
+	}
+
+	return r.fileIDKey, nil
+}
+
+// SSEKMSKeyID return AWS KMS key ID for server side encryption
+func SSEKMSKeyID() string {
+	r, err := current()
+	if err != nil {
+		return ""
+	}
+
+	return r.sseKMSKeyID
+}
+
+// GenerateKey return new random base64 encoded key for master keys and file ID key
+func GenerateKey() (string, error) {
+	key := make([]byte, KeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return "", errors.New("error while generate key: " + err.Error())
+	}
+
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// GenerateDataKey return new random data key and the same key wrapped with active master key
+func GenerateDataKey() ([]byte, *WrappedKey, error) {
+	r, err := current()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dataKey := make([]byte, KeySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, nil, errors.New("error while generate data key: " + err.Error())
+	}
+
+	ciphertext, err := Seal(dataKey, r.masterKeys[r.activeVersion], versionAAD(r.activeVersion))
+	if err != nil {
+		return nil, nil, errors.New("error while wrap data key: " + err.Error())
+	}
+
+	return dataKey, &WrappedKey{Version: r.activeVersion, Ciphertext: ciphertext}, nil
+}
+
+// UnwrapDataKey decrypt data key with master key of wrapped key version
+func UnwrapDataKey(wrapped *WrappedKey) ([]byte, error) {
+	if wrapped == nil {
+		return nil, errors.New("error while unwrap data key: wrapped key is nil")
+	}
+
+	r, err := current()
+	if err != nil {
+		return nil, err
+	}
+
+	masterKey, ok := r.masterKeys[wrapped.Version]
+	if !ok {
+		return nil, ErrUnknownVersion
+	}
+
+	dataKey, err := Open(wrapped.Ciphertext, masterKey, versionAAD(wrapped.Version))
+	if err != nil {
+		return nil, errors.New("error while unwrap data key: " + err.Error())
+	}
+
+	return dataKey, nil
+}
+
+// RewrapDataKey wrap data key with active master key. Returns the same key if it already uses active version.
+func RewrapDataKey(wrapped *WrappedKey) (*WrappedKey, error) {
+	r, err := current()
+	if err != nil {
+		return nil, err
+	}
+
+	if wrapped != nil && wrapped.Version == r.activeVersion {
+		return wrapped, nil
+	}
+
+	dataKey, err := UnwrapDataKey(wrapped)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext, err := Seal(dataKey, r.masterKeys[r.activeVersion], versionAAD(r.activeVersion))
+	if err != nil {
+		return nil, errors.New("error while rewrap data key: " + err.Error())
+	}
+
+	return &WrappedKey{Version: r.activeVersion, Ciphertext: ciphertext}, nil
+}
+
+// Seal encrypt and authenticate input with AES-GCM. Nonce is stored at the beginning of output.
+func Seal(input, key, additionalData []byte) ([]byte, error) {
+	if input == nil {
+		return nil, errors.New("error while seal data: input is nil")
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(input)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, errors.New("error while seal data: can't read nonce: " + err.Error())
+	}
+
+	return aead.Seal(nonce, nonce, input, additionalData), nil
+}
+
+// Open verify and decrypt data sealed with Seal
+func Open(input, key, additionalData []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(input) < aead.NonceSize() {
+		return nil, errors.New("error while open data: data is too short")
+	}
+
+	nonce := input[:aead.NonceSize()]
+	output, err := aead.Open(nil, nonce, input[aead.NonceSize():], additionalData)
+	if err != nil {
+		return nil, errors.New("error while open data: " + err.Error())
+	}
+
+	return output, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.New("can't create AES cipher: " + err.Error())
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.New("can't create GCM: " + err.Error())
+	}
+
+	return aead, nil
+}
+
+// versionAAD binds wrapped key to master key version
+func versionAAD(version uint32) []byte {
+	return []byte("master-key-v" + strconv.FormatUint(uint64(version), 10))
+}
@@ -0,0 +1,146 @@
+package keyring
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"testing"
+)
+
+var (
+	testKeyV1  = base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, KeySize))
+	testKeyV2  = base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{2}, KeySize))
+	testFileID = base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{3}, KeySize))
+)
+
+func TestSealOpen(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, KeySize)
+	plainText := []byte("This is a test.")
+
+	sealed, err := Seal(plainText, key, []byte("1.pdf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opened, err := Open(sealed, key, []byte("1.pdf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(opened, plainText) {
+		t.Error("Opened data does not match")
+	}
+
+	if _, err := Open(sealed, key, []byte("2.pdf")); err == nil {
+		t.Error("Open should fail with another additional data")
+	}
+
+	sealed[len(sealed)-1] ^= 1
+	if _, err := Open(sealed, key, []byte("1.pdf")); err == nil {
+		t.Error("Open should fail on modified data")
+	}
+}
+
+func TestRotation(t *testing.T) {
+	err := Configure(Info{ActiveVersion: 1, MasterKeys: map[string]string{"1": testKeyV1}, FileIDKey: testFileID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dataKey, wrapped, err := GenerateDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if wrapped.Version != 1 {
+		t.Error("Data key should be wrapped with version 1")
+	}
+
+	err = Configure(Info{ActiveVersion: 2, MasterKeys: map[string]string{"1": testKeyV1, "2": testKeyV2}, FileIDKey: testFileID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rewrapped, err := RewrapDataKey(wrapped)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rewrapped.Version != 2 {
+		t.Error("Data key should be rewrapped with version 2")
+	}
+
+	unwrapped, err := UnwrapDataKey(rewrapped)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(unwrapped, dataKey) {
+		t.Error("Rewrapped data key does not match")
+	}
+
+	// version 1 retired: old wrapped key can't be opened anymore, rewrapped one still can
+	err = Configure(Info{ActiveVersion: 2, MasterKeys: map[string]string{"2": testKeyV2}, FileIDKey: testFileID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := UnwrapDataKey(wrapped); err != ErrUnknownVersion {
+		t.Error("Unwrap with retired version should fail")
+	}
+
+	if _, err := UnwrapDataKey(rewrapped); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEnvOverride(t *testing.T) {
+	os.Setenv(envActiveVersion, "2")
+	os.Setenv(envMasterKeys, "1:"+testKeyV1+",2:"+testKeyV2)
+	defer os.Unsetenv(envActiveVersion)
+	defer os.Unsetenv(envMasterKeys)
+
+	err := Configure(Info{ActiveVersion: 1, MasterKeys: map[string]string{"1": testKeyV1}, FileIDKey: testFileID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	version, err := ActiveVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if version != 2 {
+		t.Error("Active version should be taken from environment")
+	}
+}
+
+func TestBadKeys(t *testing.T) {
+	err := Configure(Info{ActiveVersion: 1, MasterKeys: map[string]string{"1": "c2hvcnQ="}, FileIDKey: testFileID})
+	if err == nil {
+		t.Error("Short master key should be rejected")
+	}
+
+	err = Configure(Info{ActiveVersion: 3, MasterKeys: map[string]string{"1": testKeyV1}, FileIDKey: testFileID})
+	if err == nil {
+		t.Error("Missing active master key should be rejected")
+	}
+}
+
+func TestMissingKeys(t *testing.T) {
+	if err := Configure(Info{ActiveVersion: 1}); err == nil {
+		t.Error("Config without master keys should be rejected")
+	}
+
+	if err := Configure(Info{ActiveVersion: 1, MasterKeys: map[string]string{"1": testKeyV1}}); err == nil {
+		t.Error("Config without file ID key should be rejected")
+	}
+
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = Configure(Info{ActiveVersion: 1, MasterKeys: map[string]string{"1": key}, FileIDKey: testFileID}); err != nil {
+		t.Error("Generated key should be accepted: ", err)
+	}
+}
@@ -0,0 +1,22 @@
+package ratefeed
+
+import (
+	"fmt"
+	"io/ioutil"
+)
+
+// FileSource reads JSON rate feed from local file. For dev and tests.
+type FileSource struct {
+	Path    string
+	Mapping Mapping
+}
+
+// Fetch reads feed file and parses its rates
+func (s *FileSource) Fetch() ([]Rate, error) {
+	data, err := ioutil.ReadFile(s.Path)
+	if err != nil {
+		return nil, fmt.Errorf("error while read rate feed file: %v", err)
+	}
+
+	return Parse(data, &s.Mapping)
+}
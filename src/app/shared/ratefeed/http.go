@@ -0,0 +1,70 @@
+package ratefeed
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// maxFeedSize is the biggest accepted feed response
+const maxFeedSize = 10 << 20
+
+// HTTPSource reads JSON rate feed from URL
+type HTTPSource struct {
+	URL        string
+	AuthHeader string
+	AuthToken  string
+	Mapping    Mapping
+	Client     *http.Client
+}
+
+// NewHTTPSource creates source of feed at URL, token is sent in auth header (Authorization if empty)
+func NewHTTPSource(url, authHeader, authToken string, timeoutSec uint, m Mapping) *HTTPSource {
+	if authHeader == "" {
+		authHeader = "Authorization"
+	}
+
+	if timeoutSec == 0 {
+		timeoutSec = 30
+	}
+
+	return &HTTPSource{
+		URL:        url,
+		AuthHeader: authHeader,
+		AuthToken:  authToken,
+		Mapping:    m,
+		Client:     &http.Client{Timeout: time.Duration(timeoutSec) * time.Second},
+	}
+}
+
+// Fetch requests feed and parses its rates
+func (s *HTTPSource) Fetch() ([]Rate, error) {
+	req, err := http.NewRequest(http.MethodGet, s.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if s.AuthToken != "" {
+		req.Header.Set(s.AuthHeader, s.AuthToken)
+	}
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error while request rate feed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error while request rate feed: status %d", resp.StatusCode)
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxFeedSize))
+	if err != nil {
+		return nil, fmt.Errorf("error while read rate feed: %v", err)
+	}
+
+	return Parse(data, &s.Mapping)
+}
@@ -0,0 +1,287 @@
+// Package ratefeed fetches lender rates from external rate feeds.
+// Source is selected in config: "http" reads JSON feed from URL, "file" reads the same JSON from local file
+// and is a stub for dev and tests. Empty type disables feed.
+package ratefeed
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Source types in config
+const (
+	TypeHTTP = "http"
+	TypeFile = "file"
+
+	// DefaultDateLayout is layout of feed dates if mapping has no layout
+	DefaultDateLayout = "2006-01-02"
+
+	defaultIntervalMin     = 60
+	defaultStaleAfterHours = 48
+)
+
+var (
+	// ErrNotConfigured returns if feed is used before Configure or feed is disabled
+	ErrNotConfigured = errors.New("rate feed is not configured")
+
+	mutex    sync.RWMutex
+	instance RateSource
+	settings Info
+)
+
+// RateSource fetches rates from feed
+type RateSource interface {
+	Fetch() ([]Rate, error)
+}
+
+// Rate contains values of mapped fields of feed item
+type Rate struct {
+	Item      int    // index of item in feed, from 0
+	Lender    string // lender code, it is mapped to lender ID by config
+	Product   string
+	TermYears string
+	Interest  string
+	Apr       string
+	BeginDate string
+	EndDate   string
+}
+
+// Mapping contains names of feed item fields with rate fields
+type Mapping struct {
+	Items      string `json:"Items"` // field of object with array of items, feed is array of items if empty
+	Lender     string `json:"Lender"`
+	Product    string `json:"Product"`
+	TermYears  string `json:"TermYears"`
+	Interest   string `json:"Interest"`
+	Apr        string `json:"Apr"`
+	BeginDate  string `json:"BeginDate"`
+	EndDate    string `json:"EndDate"`
+	DateLayout string `json:"DateLayout"` // Go layout of dates, DefaultDateLayout if empty
+}
+
+// Info contains rate feed settings
+type Info struct {
+	Type            string            `json:"Type"`            // http, file or empty to disable feed
+	URL             string            `json:"URL"`             // http feed URL
+	AuthHeader      string            `json:"AuthHeader"`      // header with auth token, Authorization if empty
+	AuthToken       string            `json:"AuthToken"`       // value of auth header, no auth if empty
+	TimeoutSec      uint              `json:"TimeoutSec"`      // http request timeout
+	File            string            `json:"File"`            // file feed path
+	Mapping         Mapping           `json:"Mapping"`         // empty fields are the same as rate fields in snake case
+	Lenders         map[string]uint32 `json:"Lenders"`         // lender IDs by feed lender codes
+	IntervalMin     uint              `json:"IntervalMin"`     // how often feed is ingested, 60 if zero
+	StaleAfterHours uint              `json:"StaleAfterHours"` // rates are stale if feed was not ingested for this time, 48 if zero
+}
+
+// defaultMapping is used for empty fields of configured mapping
+var defaultMapping = Mapping{
+	Lender:     "lender",
+	Product:    "product",
+	TermYears:  "term_years",
+	Interest:   "interest",
+	Apr:        "apr",
+	BeginDate:  "begin_date",
+	EndDate:    "end_date",
+	DateLayout: DefaultDateLayout,
+}
+
+// Configure creates rate source from settings, empty type disables feed
+func Configure(i Info) error {
+	var s RateSource
+
+	i.Mapping = merge(i.Mapping)
+	if i.IntervalMin == 0 {
+		i.IntervalMin = defaultIntervalMin
+	}
+	if i.StaleAfterHours == 0 {
+		i.StaleAfterHours = defaultStaleAfterHours
+	}
+
+	switch i.Type {
+	case "":
+	case TypeHTTP:
+		if i.URL == "" {
+			return errors.New("error while configure rate feed: URL is empty")
+		}
+		s = NewHTTPSource(i.URL, i.AuthHeader, i.AuthToken, i.TimeoutSec, i.Mapping)
+	case TypeFile:
+		if i.File == "" {
+			return errors.New("error while configure rate feed: file is empty")
+		}
+		s = &FileSource{Path: i.File, Mapping: i.Mapping}
+	default:
+		return errors.New("error while configure rate feed: unknown type " + i.Type)
+	}
+
+	mutex.Lock()
+	instance = s
+	settings = i
+	mutex.Unlock()
+	return nil
+}
+
+// merge fills empty fields of mapping from default mapping
+func merge(m Mapping) Mapping {
+	fields := []struct{ value, base *string }{
+		{&m.Lender, &defaultMapping.Lender},
+		{&m.Product, &defaultMapping.Product},
+		{&m.TermYears, &defaultMapping.TermYears},
+		{&m.Interest, &defaultMapping.Interest},
+		{&m.Apr, &defaultMapping.Apr},
+		{&m.BeginDate, &defaultMapping.BeginDate},
+		{&m.EndDate, &defaultMapping.EndDate},
+		{&m.DateLayout, &defaultMapping.DateLayout},
+	}
+
+	for _, f := range fields {
+		*f.value = strings.TrimSpace(*f.value)
+		if *f.value == "" {
+			*f.value = *f.base
+		}
+	}
+
+	m.Items = strings.TrimSpace(m.Items)
+	return m
+}
+
+// SetSource replaces current rate source, nil disables feed
+func SetSource(s RateSource) {
+	mutex.Lock()
+	instance = s
+	mutex.Unlock()
+}
+
+// Enabled return true if rate source is configured
+func Enabled() bool {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	return instance != nil
+}
+
+// Fetch return rates of configured source
+func Fetch() ([]Rate, error) {
+	mutex.RLock()
+	s := instance
+	mutex.RUnlock()
+
+	if s == nil {
+		return nil, ErrNotConfigured
+	}
+
+	return s.Fetch()
+}
+
+// Type return type of configured rate source, empty if feed is disabled
+func Type() string {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	return settings.Type
+}
+
+// LenderID return lender ID of feed lender code, ok is false for unknown code
+func LenderID(code string) (uint32, bool) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	id, ok := settings.Lenders[strings.TrimSpace(code)]
+	return id, ok
+}
+
+// DateLayout return layout of feed dates
+func DateLayout() string {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	if settings.Mapping.DateLayout == "" {
+		return DefaultDateLayout
+	}
+
+	return settings.Mapping.DateLayout
+}
+
+// Interval return how often feed is ingested
+func Interval() time.Duration {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	if settings.IntervalMin == 0 {
+		return defaultIntervalMin * time.Minute
+	}
+
+	return time.Duration(settings.IntervalMin) * time.Minute
+}
+
+// StaleAfter return how long rates stay fresh after feed ingestion
+func StaleAfter() time.Duration {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	if settings.StaleAfterHours == 0 {
+		return defaultStaleAfterHours * time.Hour
+	}
+
+	return time.Duration(settings.StaleAfterHours) * time.Hour
+}
+
+// Parse return rates of JSON feed. Feed is array of item objects or object with items array in mapped field.
+// Numbers and strings are accepted as values.
+func Parse(data []byte, m *Mapping) ([]Rate, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+
+	var feed interface{}
+	if err := d.Decode(&feed); err != nil {
+		return nil, fmt.Errorf("can't parse rate feed: %v", err)
+	}
+
+	if m.Items != "" {
+		obj, ok := feed.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("rate feed should be object with %q field", m.Items)
+		}
+		feed = obj[m.Items]
+	}
+
+	items, ok := feed.([]interface{})
+	if !ok {
+		return nil, errors.New("rate feed has no array of rates")
+	}
+
+	rates := []Rate{}
+	for i, v := range items {
+		item, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("rate feed item %d is not object", i)
+		}
+
+		rates = append(rates, Rate{
+			Item:      i,
+			Lender:    value(item, m.Lender),
+			Product:   value(item, m.Product),
+			TermYears: value(item, m.TermYears),
+			Interest:  value(item, m.Interest),
+			Apr:       value(item, m.Apr),
+			BeginDate: value(item, m.BeginDate),
+			EndDate:   value(item, m.EndDate),
+		})
+	}
+
+	return rates, nil
+}
+
+// value return field of item as string, empty if field is missing or null
+func value(item map[string]interface{}, field string) string {
+	v, ok := item[field]
+	if !ok || v == nil {
+		return ""
+	}
+
+	return strings.TrimSpace(fmt.Sprint(v))
+}
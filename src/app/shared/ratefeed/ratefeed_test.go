@@ -0,0 +1,127 @@
+package ratefeed
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testFeed = `{"rates": [
+	{"bank": "ACME", "name": "30 Year Fixed", "years": 30, "rate": 3.625, "apr": "3.71", "from": "01/02/2018", "to": "12/31/2099"},
+	{"bank": "ACME", "name": "15 Year Fixed", "years": "15", "rate": 3.1, "apr": 3.2, "from": "01/02/2018", "to": null}
+]}`
+
+var testMapping = Mapping{Items: "rates", Lender: "bank", Product: "name", TermYears: "years", Interest: "rate",
+	BeginDate: "from", EndDate: "to", DateLayout: "01/02/2006"}
+
+func TestParse(t *testing.T) {
+	m := merge(testMapping)
+	rates, err := Parse([]byte(testFeed), &m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rates) != 2 {
+		t.Fatal("Two rates expected")
+	}
+
+	expected := Rate{Item: 0, Lender: "ACME", Product: "30 Year Fixed", TermYears: "30", Interest: "3.625", Apr: "3.71",
+		BeginDate: "01/02/2018", EndDate: "12/31/2099"}
+	if rates[0] != expected {
+		t.Errorf("Wrong rate %+v", rates[0])
+	}
+
+	if rates[1].TermYears != "15" || rates[1].EndDate != "" {
+		t.Errorf("Wrong rate %+v", rates[1])
+	}
+
+	if _, err = Parse([]byte(`{"items": []}`), &m); err == nil {
+		t.Error("Feed without mapped items field should be rejected")
+	}
+
+	d := merge(Mapping{})
+	if rates, err = Parse([]byte(`[{"lender": "1", "product": "5/1 ARM"}]`), &d); err != nil || len(rates) != 1 || rates[0].Product != "5/1 ARM" {
+		t.Error("Array feed with default mapping expected")
+	}
+}
+
+func TestHTTPSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.Write([]byte(testFeed))
+	}))
+	defer server.Close()
+
+	err := Configure(Info{Type: TypeHTTP, URL: server.URL, AuthHeader: "X-Api-Key", AuthToken: "secret", Mapping: testMapping,
+		Lenders: map[string]uint32{"ACME": 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Configure(Info{})
+
+	rates, err := Fetch()
+	if err != nil || len(rates) != 2 {
+		t.Fatal("Rates of HTTP feed expected: ", err)
+	}
+
+	if id, ok := LenderID(rates[0].Lender); !ok || id != 2 {
+		t.Error("Feed lender should be mapped to lender ID")
+	}
+
+	if DateLayout() != "01/02/2006" || Interval() != defaultIntervalMin*time.Minute {
+		t.Error("Configured date layout and default interval expected")
+	}
+
+	SetSource(NewHTTPSource(server.URL, "", "", 0, merge(testMapping)))
+	if _, err = Fetch(); err == nil {
+		t.Error("Unauthorized feed request should fail")
+	}
+}
+
+func TestFileSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ratefeed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "feed.json")
+	if err = ioutil.WriteFile(path, []byte(testFeed), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = Configure(Info{Type: TypeFile, File: path, Mapping: testMapping}); err != nil {
+		t.Fatal(err)
+	}
+	defer Configure(Info{})
+
+	if rates, err := Fetch(); err != nil || len(rates) != 2 {
+		t.Error("Rates of file feed expected: ", err)
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	if err := Configure(Info{Type: "ftp"}); err == nil {
+		t.Error("Unknown feed type should be rejected")
+	}
+
+	if err := Configure(Info{Type: TypeHTTP}); err == nil {
+		t.Error("HTTP feed without URL should be rejected")
+	}
+
+	if err := Configure(Info{}); err != nil || Enabled() {
+		t.Error("Empty type should disable feed")
+	}
+
+	if _, err := Fetch(); err != ErrNotConfigured {
+		t.Error("Disabled feed should not fetch rates")
+	}
+}
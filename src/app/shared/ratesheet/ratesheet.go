@@ -0,0 +1,262 @@
+// Package ratesheet reads lender rate sheets from CSV and XLSX spreadsheets.
+// Sheet columns are mapped to rate fields by header names, every lender can have own mapping.
+package ratesheet
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// FormatCSV is comma separated values sheet
+	FormatCSV = "csv"
+	// FormatXLSX is Office Open XML workbook
+	FormatXLSX = "xlsx"
+
+	// DefaultDateLayout is layout of dates in sheet if mapping has no layout
+	DefaultDateLayout = "2006-01-02"
+
+	// maxSheetRows is max number of sheet rows including header
+	maxSheetRows = 10000
+	// maxSheetColumns is max number of sheet columns, the XLSX limit
+	maxSheetColumns = 16384
+	// maxXLSXPartSize is max size of unpacked XLSX part
+	maxXLSXPartSize = 50 << 20
+)
+
+// SheetError returns if sheet can't be read or has no mapped columns
+type SheetError struct {
+	msg string
+}
+
+func (e *SheetError) Error() string {
+	return e.msg
+}
+
+func sheetError(format string, args ...interface{}) error {
+	return &SheetError{msg: fmt.Sprintf(format, args...)}
+}
+
+// Mapping contains header names of sheet columns with rate fields, names are case insensitive
+type Mapping struct {
+	Product    string `json:"Product"`
+	TermYears  string `json:"TermYears"`
+	Interest   string `json:"Interest"`
+	Apr        string `json:"Apr"`
+	BeginDate  string `json:"BeginDate"`
+	EndDate    string `json:"EndDate"`
+	DateLayout string `json:"DateLayout"` // Go layout of date columns, DefaultDateLayout if empty
+	Sheet      string `json:"Sheet"`      // XLSX worksheet name, the first worksheet if empty
+}
+
+// Info contains rate sheet mappings
+type Info struct {
+	Default Mapping            `json:"Default"` // mapping of lenders without own mapping
+	Lenders map[string]Mapping `json:"Lenders"` // mappings by lender ID
+}
+
+// defaultMapping is used when config has no default mapping
+var defaultMapping = Mapping{
+	Product:    "product",
+	TermYears:  "term_years",
+	Interest:   "interest",
+	Apr:        "apr",
+	BeginDate:  "begin_date",
+	EndDate:    "end_date",
+	DateLayout: DefaultDateLayout,
+}
+
+var (
+	mutex    sync.RWMutex
+	fallback = defaultMapping
+	lenders  = map[string]Mapping{}
+)
+
+// Configure sets rate sheet mappings, empty fields of mappings are taken from the default mapping
+func Configure(i Info) {
+	def := merge(i.Default, defaultMapping)
+
+	list := map[string]Mapping{}
+	for k, v := range i.Lenders {
+		list[strings.TrimSpace(k)] = merge(v, def)
+	}
+
+	mutex.Lock()
+	fallback = def
+	lenders = list
+	mutex.Unlock()
+}
+
+// merge fills empty fields of mapping from base mapping
+func merge(m, base Mapping) Mapping {
+	fields := []struct{ value, base *string }{
+		{&m.Product, &base.Product},
+		{&m.TermYears, &base.TermYears},
+		{&m.Interest, &base.Interest},
+		{&m.Apr, &base.Apr},
+		{&m.BeginDate, &base.BeginDate},
+		{&m.EndDate, &base.EndDate},
+		{&m.DateLayout, &base.DateLayout},
+		{&m.Sheet, &base.Sheet},
+	}
+
+	for _, f := range fields {
+		*f.value = strings.TrimSpace(*f.value)
+		if *f.value == "" {
+			*f.value = *f.base
+		}
+	}
+
+	return m
+}
+
+// MappingFor return mapping of lender
+func MappingFor(lenderID uint32) Mapping {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	if m, ok := lenders[strconv.FormatUint(uint64(lenderID), 10)]; ok {
+		return m
+	}
+
+	return fallback
+}
+
+// FormatByName return sheet format by file extension
+func FormatByName(name string) (string, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return FormatCSV, nil
+	case ".xlsx":
+		return FormatXLSX, nil
+	}
+
+	return "", sheetError("unsupported rate sheet format, use .csv or .xlsx")
+}
+
+// Row contains values of mapped columns of sheet row
+type Row struct {
+	Line      int // line number in sheet, header is line 1
+	Product   string
+	TermYears string
+	Interest  string
+	Apr       string
+	BeginDate string
+	EndDate   string
+}
+
+// Read parses sheet and return its non-empty rows with values of mapped columns
+func Read(format string, data []byte, m *Mapping) ([]Row, error) {
+	var records [][]string
+	var err error
+
+	switch format {
+	case FormatCSV:
+		records, err = readCSV(data)
+	case FormatXLSX:
+		records, err = readXLSX(data, m.Sheet)
+	default:
+		return nil, sheetError("unsupported rate sheet format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) == 0 {
+		return nil, sheetError("rate sheet is empty")
+	}
+
+	if len(records) > maxSheetRows {
+		return nil, sheetError("rate sheet has more than %d rows", maxSheetRows)
+	}
+
+	header := map[string]int{}
+	for i, v := range records[0] {
+		header[strings.ToLower(strings.TrimSpace(v))] = i
+	}
+
+	columns := []string{m.Product, m.TermYears, m.Interest, m.Apr, m.BeginDate, m.EndDate}
+	index := make([]int, len(columns))
+	for i, name := range columns {
+		n, ok := header[strings.ToLower(name)]
+		if !ok {
+			return nil, sheetError("rate sheet has no column %q", name)
+		}
+
+		index[i] = n
+	}
+
+	rows := []Row{}
+	for line, record := range records[1:] {
+		values := make([]string, len(index))
+		empty := true
+		for i, n := range index {
+			if n < len(record) {
+				values[i] = strings.TrimSpace(record[n])
+			}
+
+			if values[i] != "" {
+				empty = false
+			}
+		}
+
+		if empty {
+			continue
+		}
+
+		rows = append(rows, Row{
+			Line:      line + 2,
+			Product:   values[0],
+			TermYears: values[1],
+			Interest:  values[2],
+			Apr:       values[3],
+			BeginDate: values[4],
+			EndDate:   values[5],
+		})
+	}
+
+	return rows, nil
+}
+
+// readCSV return records of CSV sheet, rows may have different number of fields
+func readCSV(data []byte) ([][]string, error) {
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	r.FieldsPerRecord = -1
+
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, sheetError("can't read CSV rate sheet: %v", err)
+	}
+
+	return records, nil
+}
+
+// excelEpoch is day zero of spreadsheet date serial numbers
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// ParseDate parses date value of sheet. Spreadsheets store dates as serial numbers of days,
+// so number is accepted too.
+func ParseDate(value, layout string) (time.Time, error) {
+	if layout == "" {
+		layout = DefaultDateLayout
+	}
+
+	t, err := time.Parse(layout, value)
+	if err == nil {
+		return t, nil
+	}
+
+	serial, serialErr := strconv.ParseFloat(value, 64)
+	if serialErr != nil || serial < 1 {
+		return time.Time{}, err
+	}
+
+	return excelEpoch.AddDate(0, 0, int(math.Floor(serial))), nil
+}
@@ -0,0 +1,165 @@
+package ratesheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+)
+
+func TestReadCSV(t *testing.T) {
+	m := MappingFor(1)
+	data := []byte("\xef\xbb\xbfProduct,Term_Years,Interest,APR,Begin_Date,End_Date,Note\n" +
+		"30 Year Fixed,30,3.63,3.71,2018-01-01,2099-12-31,best\n" +
+		",,,,,,\n" +
+		"15 Year Fixed,15,3.23\n")
+
+	rows, err := Read(FormatCSV, data, &m)
+	if err != nil {
+		t.Fatal("CSV sheet should be read:", err)
+	}
+
+	if len(rows) != 2 {
+		t.Fatal("Empty rows should be skipped, got", len(rows))
+	}
+
+	if rows[0].Line != 2 || rows[0].Product != "30 Year Fixed" || rows[0].Apr != "3.71" || rows[0].EndDate != "2099-12-31" {
+		t.Error("Wrong first row", rows[0])
+	}
+
+	if rows[1].Line != 4 || rows[1].Interest != "3.23" || rows[1].Apr != "" {
+		t.Error("Short row should have empty missing values", rows[1])
+	}
+}
+
+func TestReadMissingColumn(t *testing.T) {
+	m := MappingFor(1)
+	_, err := Read(FormatCSV, []byte("product,interest\n30 Year Fixed,3.63\n"), &m)
+	if _, ok := err.(*SheetError); !ok {
+		t.Error("Sheet without mapped columns should be rejected, got", err)
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	Configure(Info{Lenders: map[string]Mapping{"7": {Interest: "Note Rate", DateLayout: "01/02/2006"}}})
+	defer Configure(Info{})
+
+	m := MappingFor(7)
+	if m.Interest != "Note Rate" || m.Apr != "apr" || m.DateLayout != "01/02/2006" {
+		t.Error("Lender mapping should be merged with default mapping", m)
+	}
+
+	if MappingFor(8).Interest != "interest" {
+		t.Error("Lender without mapping should use default mapping")
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	d, err := ParseDate("03/15/2019", "01/02/2006")
+	if err != nil || d.Format(DefaultDateLayout) != "2019-03-15" {
+		t.Error("Date should be parsed by layout", d, err)
+	}
+
+	d, err = ParseDate("43101", "")
+	if err != nil || d.Format(DefaultDateLayout) != "2018-01-01" {
+		t.Error("Serial date should be parsed", d, err)
+	}
+
+	if _, err = ParseDate("soon", ""); err == nil {
+		t.Error("Bad date should be rejected")
+	}
+}
+
+func TestFormatByName(t *testing.T) {
+	if f, err := FormatByName("rates.XLSX"); err != nil || f != FormatXLSX {
+		t.Error("xlsx format expected", f, err)
+	}
+
+	if _, err := FormatByName("rates.xls"); err == nil {
+		t.Error("xls format should be unsupported")
+	}
+}
+
+func TestReadXLSX(t *testing.T) {
+	parts := map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+			xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+			<sheets><sheet name="Notes" sheetId="1" r:id="rId1"/><sheet name="Rates" sheetId="2" r:id="rId2"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+			<Relationship Id="rId1" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Target="worksheets/sheet2.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+			<si><t>product</t></si><si><r><t>5/1 </t></r><r><t>ARM</t></r></si></sst>`,
+		"xl/worksheets/sheet1.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData/></worksheet>`,
+		"xl/worksheets/sheet2.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+			<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="inlineStr"><is><t>term_years</t></is></c>
+				<c r="C1" t="inlineStr"><is><t>interest</t></is></c><c r="D1" t="inlineStr"><is><t>apr</t></is></c>
+				<c r="E1" t="inlineStr"><is><t>begin_date</t></is></c><c r="F1" t="inlineStr"><is><t>end_date</t></is></c></row>
+			<row r="3"><c r="A3" t="s"><v>1</v></c><c r="B3"><v>30</v></c><c r="C3"><v>2.95</v></c>
+				<c r="E3"><v>43101</v></c><c r="F3"><v>73050</v></c></row>
+			</sheetData></worksheet>`,
+	}
+
+	data := zipParts(t, parts)
+
+	m := MappingFor(1)
+	m.Sheet = "rates"
+
+	rows, err := Read(FormatXLSX, data, &m)
+	if err != nil {
+		t.Fatal("XLSX sheet should be read:", err)
+	}
+
+	if len(rows) != 1 {
+		t.Fatal("One row expected, got", len(rows))
+	}
+
+	r := rows[0]
+	if r.Line != 3 || r.Product != "5/1 ARM" || r.TermYears != "30" || r.Interest != "2.95" || r.Apr != "" || r.BeginDate != "43101" {
+		t.Error("Wrong XLSX row", r)
+	}
+
+	m.Sheet = "Missing"
+	if _, err = Read(FormatXLSX, data, &m); err == nil {
+		t.Error("Unknown worksheet should be rejected")
+	}
+
+	bad := map[string]string{
+		"cell without column": `<row r="1"><c r="12"><v>1</v></c></row>`,
+		"too many rows":       `<row r="1"><c r="A1"><v>1</v></c></row><row r="1048576"><c r="A1048576"><v>1</v></c></row>`,
+	}
+
+	m.Sheet = ""
+	for name, sheetData := range bad {
+		parts["xl/worksheets/sheet1.xml"] = `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` +
+			sheetData + `</sheetData></worksheet>`
+
+		if _, err = Read(FormatXLSX, zipParts(t, parts), &m); err == nil {
+			t.Error("XLSX sheet should be rejected:", name)
+		} else if _, ok := err.(*SheetError); !ok {
+			t.Error("SheetError expected for", name, "got", err)
+		}
+	}
+}
+
+func TestColumnIndex(t *testing.T) {
+	for ref, want := range map[string]int{"A1": 0, "z9": 25, "AB12": 27, "12": -1, "": -1} {
+		if got := columnIndex(ref); got != want {
+			t.Errorf("Wrong column of %q: %d, want %d", ref, got, want)
+		}
+	}
+}
+
+// zipParts return XLSX package of parts
+func zipParts(t *testing.T, parts map[string]string) []byte {
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	for name, content := range parts {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	zw.Close()
+
+	return buf.Bytes()
+}
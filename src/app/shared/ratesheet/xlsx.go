@@ -0,0 +1,212 @@
+package ratesheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Minimal XLSX reader: cell values of one worksheet as text, formulas give their cached values
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// xlsxText is shared or inline string, rich text is split into runs
+type xlsxText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t *xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+
+	s := ""
+	for _, r := range t.Runs {
+		s += r.Text
+	}
+
+	return s
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxText `xml:"si"`
+}
+
+type xlsxWorksheet struct {
+	Rows []struct {
+		Num   int `xml:"r,attr"`
+		Cells []struct {
+			Ref    string   `xml:"r,attr"`
+			Type   string   `xml:"t,attr"`
+			Value  string   `xml:"v"`
+			Inline xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// readXLSX return records of worksheet with name, the first worksheet if name is empty
+func readXLSX(data []byte, sheet string) ([][]string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, sheetError("can't read XLSX rate sheet: %v", err)
+	}
+
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	workbook := xlsxWorkbook{}
+	if err = readXMLPart(files, "xl/workbook.xml", &workbook); err != nil {
+		return nil, err
+	}
+
+	rels := xlsxRelationships{}
+	if err = readXMLPart(files, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+
+	rid := ""
+	for _, s := range workbook.Sheets {
+		if sheet == "" || strings.EqualFold(s.Name, sheet) {
+			rid = s.RID
+			break
+		}
+	}
+
+	if rid == "" {
+		return nil, sheetError("XLSX rate sheet has no worksheet %q", sheet)
+	}
+
+	sheetPath := ""
+	for _, r := range rels.Relationships {
+		if r.ID == rid {
+			sheetPath = r.Target
+			if strings.HasPrefix(sheetPath, "/") {
+				sheetPath = strings.TrimPrefix(sheetPath, "/")
+			} else {
+				sheetPath = path.Join("xl", sheetPath)
+			}
+		}
+	}
+
+	shared := xlsxSharedStrings{}
+	if _, ok := files["xl/sharedStrings.xml"]; ok {
+		if err = readXMLPart(files, "xl/sharedStrings.xml", &shared); err != nil {
+			return nil, err
+		}
+	}
+
+	ws := xlsxWorksheet{}
+	if err = readXMLPart(files, sheetPath, &ws); err != nil {
+		return nil, err
+	}
+
+	records := [][]string{}
+	for _, row := range ws.Rows {
+		if row.Num > maxSheetRows || len(records) >= maxSheetRows {
+			return nil, sheetError("rate sheet has more than %d rows", maxSheetRows)
+		}
+
+		// Empty rows are not stored, keep line numbers of sheet
+		for row.Num > len(records)+1 {
+			records = append(records, nil)
+		}
+
+		record := []string{}
+		for i, c := range row.Cells {
+			col := i
+			if c.Ref != "" {
+				col = columnIndex(c.Ref)
+			}
+
+			if col < 0 || col >= maxSheetColumns {
+				return nil, sheetError("XLSX rate sheet cell %q has bad reference", c.Ref)
+			}
+
+			for len(record) <= col {
+				record = append(record, "")
+			}
+
+			switch c.Type {
+			case "s":
+				n, err := strconv.Atoi(c.Value)
+				if err != nil || n < 0 || n >= len(shared.Items) {
+					return nil, sheetError("XLSX rate sheet cell %s has bad shared string", c.Ref)
+				}
+				record[col] = shared.Items[n].String()
+			case "inlineStr":
+				record[col] = c.Inline.String()
+			default:
+				record[col] = c.Value
+			}
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// readXMLPart unmarshals XML part of XLSX package
+func readXMLPart(files map[string]*zip.File, name string, v interface{}) error {
+	f, ok := files[name]
+	if !ok {
+		return sheetError("XLSX rate sheet has no part %s", name)
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return sheetError("can't read XLSX rate sheet part %s: %v", name, err)
+	}
+	defer rc.Close()
+
+	// Compressed part can unpack to much more than upload size
+	b, err := ioutil.ReadAll(io.LimitReader(rc, maxXLSXPartSize+1))
+	if err != nil {
+		return sheetError("can't read XLSX rate sheet part %s: %v", name, err)
+	}
+
+	if len(b) > maxXLSXPartSize {
+		return sheetError("XLSX rate sheet part %s is too large", name)
+	}
+
+	if err = xml.Unmarshal(b, v); err != nil {
+		return sheetError("can't parse XLSX rate sheet part %s: %v", name, err)
+	}
+
+	return nil
+}
+
+// columnIndex return zero based column of cell reference like "AB12", -1 if reference has no column letters
+func columnIndex(ref string) int {
+	n := 0
+	for _, r := range strings.ToUpper(ref) {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		n = n*26 + int(r-'A') + 1
+	}
+
+	return n - 1
+}
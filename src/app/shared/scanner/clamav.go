@@ -0,0 +1,104 @@
+package scanner
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	clamAVChunkSize      = 64 * 1024
+	defaultClamAVTimeout = 60
+)
+
+// ClamAV scans content with clamd INSTREAM command
+type ClamAV struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+// NewClamAV return scanner for clamd listening on network address. Network defaults to unix.
+func NewClamAV(network, address string, timeoutSec uint) *ClamAV {
+	if network == "" {
+		network = "unix"
+	}
+
+	if timeoutSec == 0 {
+		timeoutSec = defaultClamAVTimeout
+	}
+
+	return &ClamAV{network: network, address: address, timeout: time.Duration(timeoutSec) * time.Second}
+}
+
+// Scan sends content to clamd and parses verdict
+func (c *ClamAV) Scan(content io.Reader) (*Result, error) {
+	conn, err := net.DialTimeout(c.network, c.address, c.timeout)
+	if err != nil {
+		return nil, errors.New("error while connect to clamd: " + err.Error())
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(c.timeout))
+
+	_, err = conn.Write([]byte("zINSTREAM\x00"))
+	if err != nil {
+		return nil, errors.New("error while send command to clamd: " + err.Error())
+	}
+
+	// Stream is sent in chunks prefixed with big endian length, zero length chunk ends stream
+	buf := make([]byte, clamAVChunkSize)
+	size := make([]byte, 4)
+	for {
+		n, readErr := content.Read(buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size, uint32(n))
+			if _, err = conn.Write(size); err != nil {
+				return nil, errors.New("error while send content to clamd: " + err.Error())
+			}
+
+			if _, err = conn.Write(buf[:n]); err != nil {
+				return nil, errors.New("error while send content to clamd: " + err.Error())
+			}
+		}
+
+		if readErr == io.EOF {
+			break
+		}
+
+		if readErr != nil {
+			return nil, errors.New("error while read content for clamd: " + readErr.Error())
+		}
+	}
+
+	binary.BigEndian.PutUint32(size, 0)
+	if _, err = conn.Write(size); err != nil {
+		return nil, errors.New("error while send content to clamd: " + err.Error())
+	}
+
+	reply, err := ioutil.ReadAll(conn)
+	if err != nil {
+		return nil, errors.New("error while read clamd reply: " + err.Error())
+	}
+
+	return parseClamAVReply(string(bytes.TrimRight(reply, "\x00\n")))
+}
+
+// parseClamAVReply parses "stream: OK", "stream: <signature> FOUND" or "<message> ERROR"
+func parseClamAVReply(reply string) (*Result, error) {
+	reply = strings.TrimPrefix(reply, "stream: ")
+
+	switch {
+	case reply == "OK":
+		return &Result{}, nil
+	case strings.HasSuffix(reply, " FOUND"):
+		return &Result{Infected: true, Signature: strings.TrimSuffix(reply, " FOUND")}, nil
+	default:
+		return nil, errors.New("error while scan content with clamd: " + reply)
+	}
+}
@@ -0,0 +1,34 @@
+package scanner
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+)
+
+// EICAR is the standard antivirus test file content
+const EICAR = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// Fake reports content containing EICAR test string as infected. For dev and tests.
+type Fake struct {
+	Err error // returned from Scan if set, e.g. to emulate unavailable scanner
+}
+
+// Scan checks content for EICAR test string
+func (f *Fake) Scan(content io.Reader) (*Result, error) {
+	if f.Err != nil {
+		return nil, f.Err
+	}
+
+	data, err := ioutil.ReadAll(content)
+	if err != nil {
+		return nil, errors.New("error while read content for fake scanner: " + err.Error())
+	}
+
+	if bytes.Contains(data, []byte(EICAR)) {
+		return &Result{Infected: true, Signature: "Eicar-Test-Signature"}, nil
+	}
+
+	return &Result{}, nil
+}
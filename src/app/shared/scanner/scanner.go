@@ -0,0 +1,82 @@
+// Package scanner checks uploaded content for viruses.
+// Scanner implementation is selected in config: "clamav" talks to clamd socket, "fake" is for dev and tests.
+package scanner
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+// Scanner types in config
+const (
+	TypeClamAV = "clamav"
+	TypeFake   = "fake"
+)
+
+var (
+	// ErrNotConfigured returns if scanner used before Configure
+	ErrNotConfigured = errors.New("scanner is not configured")
+
+	mutex    sync.RWMutex
+	instance Scanner
+)
+
+// Scanner checks content for viruses
+type Scanner interface {
+	Scan(content io.Reader) (*Result, error)
+}
+
+// Result contains scan verdict
+type Result struct {
+	Infected  bool
+	Signature string // name of found virus
+}
+
+// Info contains scanner settings
+type Info struct {
+	Type       string `json:"Type"`       // clamav or fake
+	Network    string `json:"Network"`    // clamd network: unix or tcp
+	Address    string `json:"Address"`    // clamd socket path or host:port
+	TimeoutSec uint   `json:"TimeoutSec"` // clamd connection timeout
+}
+
+// Configure creates scanner from settings
+func Configure(i Info) error {
+	var s Scanner
+
+	switch i.Type {
+	case TypeClamAV:
+		if i.Address == "" {
+			return errors.New("error while configure scanner: clamd address is empty")
+		}
+		s = NewClamAV(i.Network, i.Address, i.TimeoutSec)
+	case TypeFake:
+		s = &Fake{}
+	default:
+		return errors.New("error while configure scanner: unknown type " + i.Type)
+	}
+
+	SetInstance(s)
+	return nil
+}
+
+// SetInstance replaces current scanner
+func SetInstance(s Scanner) {
+	mutex.Lock()
+	instance = s
+	mutex.Unlock()
+}
+
+// Scan checks content with configured scanner
+func Scan(content io.Reader) (*Result, error) {
+	mutex.RLock()
+	s := instance
+	mutex.RUnlock()
+
+	if s == nil {
+		return nil, ErrNotConfigured
+	}
+
+	return s.Scan(content)
+}
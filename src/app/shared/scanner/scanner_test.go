@@ -0,0 +1,122 @@
+package scanner
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestFake(t *testing.T) {
+	err := Configure(Info{Type: TypeFake})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Scan(strings.NewReader("This is a test."))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Infected {
+		t.Error("Clean content reported as infected")
+	}
+
+	result, err = Scan(strings.NewReader("prefix " + EICAR))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !result.Infected || result.Signature == "" {
+		t.Error("EICAR content should be reported as infected")
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	if err := Configure(Info{Type: "unknown"}); err == nil {
+		t.Error("Unknown scanner type should be rejected")
+	}
+
+	if err := Configure(Info{Type: TypeClamAV}); err == nil {
+		t.Error("ClamAV without address should be rejected")
+	}
+}
+
+// serveClamd emulates one clamd INSTREAM session and replies with verdict for received content
+func serveClamd(t *testing.T, listener net.Listener) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	command := make([]byte, len("zINSTREAM\x00"))
+	if _, err = io.ReadFull(conn, command); err != nil || string(command) != "zINSTREAM\x00" {
+		t.Error("Wrong clamd command", string(command))
+		return
+	}
+
+	content := &bytes.Buffer{}
+	size := make([]byte, 4)
+	for {
+		if _, err = io.ReadFull(conn, size); err != nil {
+			t.Error(err)
+			return
+		}
+
+		n := binary.BigEndian.Uint32(size)
+		if n == 0 {
+			break
+		}
+
+		if _, err = io.CopyN(content, conn, int64(n)); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	if bytes.Contains(content.Bytes(), []byte(EICAR)) {
+		conn.Write([]byte("stream: Eicar-Signature FOUND\x00"))
+		return
+	}
+
+	conn.Write([]byte("stream: OK\x00"))
+}
+
+func TestClamAV(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	clamAV := NewClamAV("tcp", listener.Addr().String(), 5)
+
+	go serveClamd(t, listener)
+	result, err := clamAV.Scan(bytes.NewReader(bytes.Repeat([]byte("a"), clamAVChunkSize*2+1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Infected {
+		t.Error("Clean content reported as infected")
+	}
+
+	go serveClamd(t, listener)
+	result, err = clamAV.Scan(strings.NewReader(EICAR))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !result.Infected || result.Signature != "Eicar-Signature" {
+		t.Error("EICAR content should be reported as infected, got", result)
+	}
+}
+
+func TestParseClamAVReply(t *testing.T) {
+	if _, err := parseClamAVReply("INSTREAM size limit exceeded. ERROR"); err == nil {
+		t.Error("clamd error should be returned")
+	}
+}
@@ -0,0 +1,135 @@
+// Package snippet splits search queries to terms and cuts highlighted snippets of found text.
+package snippet
+
+import (
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// MarkOpen and MarkClose wrap matched terms in snippet
+	MarkOpen  = "<mark>"
+	MarkClose = "</mark>"
+
+	// Ellipsis marks cut text at snippet edges
+	Ellipsis = "…"
+)
+
+// Terms return unique lowercased words of query, punctuation and search operators are dropped
+func Terms(query string) []string {
+	terms := []string{}
+	seen := map[string]bool{}
+
+	for _, v := range strings.FieldsFunc(strings.ToLower(query), isSeparator) {
+		if !seen[v] {
+			seen[v] = true
+			terms = append(terms, v)
+		}
+	}
+
+	return terms
+}
+
+// isSeparator tells rune is not part of word
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// Highlight return HTML escaped part of text around the first match of terms, about width runes long.
+// Words starting with any term are wrapped in MarkOpen and MarkClose.
+// Text without matches gives its beginning.
+func Highlight(text string, terms []string, width int) string {
+	words := wordSpans(text)
+
+	first := -1
+	matched := make([]bool, len(words))
+	for i, w := range words {
+		word := strings.ToLower(text[w[0]:w[1]])
+		for _, term := range terms {
+			if strings.HasPrefix(word, term) {
+				matched[i] = true
+				break
+			}
+		}
+
+		if matched[i] && first < 0 {
+			first = i
+		}
+	}
+
+	// Snippet starts a few words before the first match
+	start := 0
+	if first > 0 {
+		start = words[first][0]
+		for i := first - 1; i >= 0 && utf8.RuneCountInString(text[words[i][0]:words[first][0]]) < width/3; i-- {
+			start = words[i][0]
+		}
+	}
+
+	end := len(text)
+	if utf8.RuneCountInString(text[start:]) > width {
+		end = start
+		for _, w := range words {
+			if w[0] >= start && utf8.RuneCountInString(text[start:w[1]]) <= width {
+				end = w[1]
+			}
+		}
+
+		if end == start {
+			end = start + len(string([]rune(text[start:])[:width]))
+		}
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString(Ellipsis)
+	}
+
+	pos := start
+	for i, w := range words {
+		if !matched[i] || w[0] < start || w[1] > end {
+			continue
+		}
+
+		b.WriteString(html.EscapeString(text[pos:w[0]]))
+		b.WriteString(MarkOpen)
+		b.WriteString(html.EscapeString(text[w[0]:w[1]]))
+		b.WriteString(MarkClose)
+		pos = w[1]
+	}
+
+	b.WriteString(html.EscapeString(text[pos:end]))
+	if end < len(text) {
+		b.WriteString(Ellipsis)
+	}
+
+	return b.String()
+}
+
+// wordSpans return byte offsets of words in text
+func wordSpans(text string) [][2]int {
+	spans := [][2]int{}
+
+	start := -1
+	for i, r := range text {
+		if isSeparator(r) {
+			if start >= 0 {
+				spans = append(spans, [2]int{start, i})
+				start = -1
+			}
+			continue
+		}
+
+		if start < 0 {
+			start = i
+		}
+	}
+
+	if start >= 0 {
+		spans = append(spans, [2]int{start, len(text)})
+	}
+
+	return spans
+}
@@ -0,0 +1,55 @@
+package snippet
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTerms(t *testing.T) {
+	terms := Terms(`+Loan "approval" -loan* W2`)
+	if strings.Join(terms, ",") != "loan,approval,w2" {
+		t.Error("Terms should be lowercased and unique, got", terms)
+	}
+
+	if len(Terms(" +-*() ")) != 0 {
+		t.Error("Query without words should give no terms")
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	got := Highlight("Please upload your W2 & pay stubs", []string{"w2", "pay"}, 100)
+	want := "Please upload your <mark>W2</mark> &amp; <mark>pay</mark> stubs"
+	if got != want {
+		t.Error("Wrong highlight, got", got)
+	}
+
+	got = Highlight("Your loan application was approved", []string{"approv"}, 100)
+	if got != "Your loan application was <mark>approved</mark>" {
+		t.Error("Term should match word prefix, got", got)
+	}
+}
+
+func TestHighlightCut(t *testing.T) {
+	text := strings.Repeat("filler ", 20) + "closing date is Friday " + strings.Repeat("tail ", 20)
+	got := Highlight(text, []string{"closing"}, 40)
+
+	if !strings.HasPrefix(got, Ellipsis) || !strings.HasSuffix(got, Ellipsis) {
+		t.Error("Cut snippet should have ellipsis on both sides, got", got)
+	}
+
+	if !strings.Contains(got, "<mark>closing</mark> date") {
+		t.Error("Snippet should contain match, got", got)
+	}
+}
+
+func TestHighlightNoMatch(t *testing.T) {
+	got := Highlight("short text", []string{"missing"}, 100)
+	if got != "short text" {
+		t.Error("Text without match should be returned from beginning, got", got)
+	}
+
+	got = Highlight(strings.Repeat("a", 50), nil, 10)
+	if got != strings.Repeat("a", 10)+Ellipsis {
+		t.Error("Long word should be cut, got", got)
+	}
+}
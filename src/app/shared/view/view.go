@@ -1,6 +1,7 @@
 package view
 
 import (
+	"bytes"
 	"encoding/gob"
 	"encoding/json"
 	"fmt"
@@ -11,6 +12,7 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	textTemplate "text/template"
 
 	"app/shared/session"
 )
@@ -35,6 +37,7 @@ var (
 	childTemplates     []string
 	rootTemplate       string
 	templateCollection = make(map[string]*template.Template)
+	textCollection     = make(map[string]*textTemplate.Template)
 	pluginCollection   = make(template.FuncMap)
 	mutex              sync.RWMutex
 	mutexPlugins       sync.RWMutex
@@ -308,6 +311,72 @@ func (v *View) Render(w http.ResponseWriter) {
 	}
 }
 
+// RenderString renders single HTML template from views folder to string, e.g. email body
+func RenderString(name string, vars interface{}) (string, error) {
+	mutex.RLock()
+	tc, ok := templateCollection[name]
+	mutex.RUnlock()
+
+	mutexPlugins.RLock()
+	pc := pluginCollection
+	mutexPlugins.RUnlock()
+
+	fileName := name + "." + viewInfo.Extension
+	if !ok || !viewInfo.Caching {
+		path, err := filepath.Abs(viewInfo.Folder + string(os.PathSeparator) + fileName)
+		if err != nil {
+			return "", err
+		}
+
+		tc, err = template.New(name).Funcs(pc).ParseFiles(path)
+		if err != nil {
+			return "", err
+		}
+
+		mutex.Lock()
+		templateCollection[name] = tc
+		mutex.Unlock()
+	}
+
+	// ParseFiles names template by base name of file, without folder of name
+	b := &bytes.Buffer{}
+	err := tc.ExecuteTemplate(b, filepath.Base(fileName), vars)
+	return b.String(), err
+}
+
+// RenderText renders single plain text template name.txt from views folder to string, e.g. email body
+func RenderText(name string, vars interface{}) (string, error) {
+	mutex.RLock()
+	tc, ok := textCollection[name]
+	mutex.RUnlock()
+
+	mutexPlugins.RLock()
+	pc := pluginCollection
+	mutexPlugins.RUnlock()
+
+	fileName := name + ".txt"
+	if !ok || !viewInfo.Caching {
+		path, err := filepath.Abs(viewInfo.Folder + string(os.PathSeparator) + fileName)
+		if err != nil {
+			return "", err
+		}
+
+		tc, err = textTemplate.New(name).Funcs(textTemplate.FuncMap(pc)).ParseFiles(path)
+		if err != nil {
+			return "", err
+		}
+
+		mutex.Lock()
+		textCollection[name] = tc
+		mutex.Unlock()
+	}
+
+	// ParseFiles names template by base name of file, without folder of name
+	b := &bytes.Buffer{}
+	err := tc.ExecuteTemplate(b, filepath.Base(fileName), vars)
+	return b.String(), err
+}
+
 // Validate returns true if all the required form values are passed
 func Validate(req *http.Request, required []string) (bool, string) {
 	for _, v := range required {
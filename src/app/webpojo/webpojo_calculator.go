@@ -0,0 +1,80 @@
+package webpojo
+
+// CalculatorReq contains loan params of mortgage calculator. Rate is taken from lender rate with rate_id,
+// or interest is used if rate_id is zero. Zero term of lender rate loan is the rate term.
+type CalculatorReq struct {
+	LoanAmount    float64        `json:"loan_amount"`
+	TermYears     int            `json:"term_years"`
+	Interest      float64        `json:"interest"` // percents
+	RateID        uint32         `json:"rate_id"`
+	ExtraMonthly  float64        `json:"extra_monthly"`  // extra principal paid every month, schedule only
+	ExtraPayments []ExtraPayment `json:"extra_payments"` // one-time extra principal payments, schedule only
+}
+
+// ExtraPayment is one-time extra principal payment in month of loan, the first month is 1
+type ExtraPayment struct {
+	Month  int     `json:"month"`
+	Amount float64 `json:"amount"`
+}
+
+// CalculatorPaymentResp contains payment of fully amortized loan without extra payments
+type CalculatorPaymentResp struct {
+	StatusCode     uint16  `json:"statusCode"`
+	Message        string  `json:"message"`
+	LoanAmount     float64 `json:"loan_amount"`
+	TermYears      int     `json:"term_years"`
+	Interest       float64 `json:"interest"`
+	Apr            float64 `json:"apr,omitempty"` // of lender rate only
+	RateID         uint32  `json:"rate_id,omitempty"`
+	MonthlyPayment float64 `json:"monthly_payment"` // principal and interest
+	TotalPayment   float64 `json:"total_payment"`
+	TotalInterest  float64 `json:"total_interest"`
+}
+
+// AmortizationResp contains amortization schedule of loan with extra payments. Payment totals are
+// of loan without extra payments, savings show what extra payments give.
+type AmortizationResp struct {
+	CalculatorPaymentResp
+	Months        int               `json:"months"` // months until loan is paid off
+	PaidTotal     float64           `json:"paid_total"`
+	PaidInterest  float64           `json:"paid_interest"`
+	InterestSaved float64           `json:"interest_saved"`
+	MonthsSaved   int               `json:"months_saved"`
+	Schedule      []AmortizationRow `json:"schedule"`
+}
+
+// AmortizationRow is payment of loan month, balance is left after payment
+type AmortizationRow struct {
+	Month     int     `json:"month"`
+	Payment   float64 `json:"payment"` // principal, interest and extra
+	Principal float64 `json:"principal"`
+	Interest  float64 `json:"interest"`
+	Extra     float64 `json:"extra"`
+	Balance   float64 `json:"balance"`
+}
+
+// RateComparisonReq contains loan params for comparison of lender rates effective today.
+// Empty product and zero term match any product and term.
+type RateComparisonReq struct {
+	LoanAmount float64 `json:"loan_amount"`
+	TermYears  int     `json:"term_years"`
+	Product    string  `json:"product"`
+}
+
+// RateComparisonResp contains lender rates ordered from the cheapest by APR cost
+type RateComparisonResp struct {
+	StatusCode  uint16           `json:"statusCode"`
+	Message     string           `json:"message"`
+	LoanAmount  float64          `json:"loan_amount"`
+	Comparisons []RateComparison `json:"comparisons"`
+}
+
+// RateComparison contains loan cost with lender rate. APR cost is finance charge of loan at APR,
+// so it includes lender fees and is comparable across lenders.
+type RateComparison struct {
+	LenderRatePojo
+	MonthlyPayment float64 `json:"monthly_payment"` // principal and interest at interest rate
+	TotalInterest  float64 `json:"total_interest"`
+	AprCost        float64 `json:"apr_cost"`
+	AboveBest      float64 `json:"above_best"` // APR cost above the cheapest rate of the same product and term
+}
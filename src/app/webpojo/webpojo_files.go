@@ -2,12 +2,66 @@ package webpojo
 
 // UserFile represents file to frontend
 type UserFile struct {
-	Link     string `json:"link"`
-	FileName string `json:"file_name"`
-	FileID   string `json:"id"`
+	Link        string   `json:"link"`
+	FileName    string   `json:"file_name"`
+	FileID      string   `json:"id"`
+	Category    string   `json:"category"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+	Size        int64    `json:"size"`
+	Checksum    string   `json:"checksum"`    // hex SHA-256 of file content
+	ScanStatus  string   `json:"scan_status"` // pending, clean or infected: only clean file can be downloaded
+	CreatedAt   string   `json:"created_at"`
+	DeletedAt   string   `json:"deleted_at,omitempty"`
+}
+
+// UserFileMeta contains customer's description of a file
+type UserFileMeta struct {
+	Category    string   `json:"category"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"` // missing tags are kept on update, empty list removes them
+}
+
+// UserFileMetaPatch contains changed fields of file metadata, missing fields are kept unchanged
+type UserFileMetaPatch struct {
+	Category    *string  `json:"category"`
+	Description *string  `json:"description"`
+	Tags        []string `json:"tags"` // empty list removes tags
+}
+
+// UserFilePatchReq changes file metadata
+type UserFilePatchReq struct {
+	ID string `json:"id"`
+	UserFileMetaPatch
+}
+
+// FileListReq contains filter, sort and pagination params of file list
+type FileListReq struct {
+	Category string // filter by category
+	Tag      string // filter by tag
+	Query    string // search in file names and tags
+	Sort     string // created, name, size or category
+	Desc     bool
+	Limit    int
+	Cursor   string // next_cursor or prev_cursor of other page, empty for the first page
+}
+
+// FileListResp contains one page of user files
+type FileListResp struct {
+	Files []*UserFile `json:"files"`
+	Total int         `json:"total"`
+	Limit int         `json:"limit"`
+	PageCursors
 }
 
 // FileIDResponse contains fake ID of uploaded file
 type FileIDResponse struct {
 	FileID string `json:"file_id"`
 }
+
+// FilesPurgeReport contains result of deleted files purge
+type FilesPurgeReport struct {
+	PurgedFiles     int      `json:"purged_files"`
+	FailedFiles     []string `json:"failed_files,omitempty"`
+	OrphanedObjects []string `json:"orphaned_objects,omitempty"` // storage objects without DB rows
+}
@@ -0,0 +1,73 @@
+package webpojo
+
+// LoanApplicationReq contains chosen lender rate, loan amount and customer's files of draft loan application.
+// ID is empty for new application.
+type LoanApplicationReq struct {
+	ID           uint32   `json:"id"`
+	LenderRateID uint32   `json:"lender_rate_id"`
+	LoanAmount   float64  `json:"loan_amount"`
+	FileIDs      []uint32 `json:"file_ids"`
+}
+
+// LoanApplicationStateReq moves loan application to state, note is kept in application history
+type LoanApplicationStateReq struct {
+	ID    uint32 `json:"id"`
+	State string `json:"state"`
+	Note  string `json:"note"`
+}
+
+// LoanApplicationPojo represents loan application, state is draft, submitted, in_review, approved,
+// declined or funded. Interest and apr are percents of chosen rate.
+type LoanApplicationPojo struct {
+	ID             uint32                    `json:"id"`
+	CustomerID     uint32                    `json:"customer_id"`
+	StaffID        uint32                    `json:"staff_id"`
+	LenderRateID   uint32                    `json:"lender_rate_id"`
+	LenderID       uint32                    `json:"lender_id"`
+	LenderName     string                    `json:"lender_name"`
+	Product        string                    `json:"product"`
+	TermYears      int                       `json:"term_years"`
+	Interest       float64                   `json:"interest"`
+	Apr            float64                   `json:"apr"`
+	LoanAmount     float64                   `json:"loan_amount"`
+	MonthlyPayment float64                   `json:"monthly_payment"` // principal and interest
+	State          string                    `json:"state"`
+	SubmittedAt    string                    `json:"submitted_at,omitempty"`
+	CreatedAt      string                    `json:"created_at"`
+	UpdatedAt      string                    `json:"updated_at"`
+	Documents      []LoanApplicationDocument `json:"documents,omitempty"`
+	History        []LoanApplicationChange   `json:"history,omitempty"`
+}
+
+// LoanApplicationDocument is customer's file attached to loan application
+type LoanApplicationDocument struct {
+	FileID     uint32 `json:"file_id"`
+	FileName   string `json:"file_name"`
+	Category   string `json:"category"`
+	Size       int64  `json:"size"`
+	ScanStatus string `json:"scan_status"`
+}
+
+// LoanApplicationChange is loan application state change, from_state is empty for created application
+type LoanApplicationChange struct {
+	FromState string `json:"from_state"`
+	ToState   string `json:"to_state"`
+	ActorID   uint32 `json:"actor_id"`
+	ActorRole int    `json:"actor_role"`
+	Note      string `json:"note"`
+	CreatedAt string `json:"created_at"`
+}
+
+// LoanApplicationResp contains loan application with its documents and history
+type LoanApplicationResp struct {
+	StatusCode  uint16              `json:"statusCode"`
+	Message     string              `json:"message"`
+	Application LoanApplicationPojo `json:"application"`
+}
+
+// LoanApplicationListResp contains loan applications without documents and history
+type LoanApplicationListResp struct {
+	StatusCode   uint16                `json:"statusCode"`
+	Message      string                `json:"message"`
+	Applications []LoanApplicationPojo `json:"applications"`
+}
@@ -4,12 +4,27 @@ package webpojo
 type PostNewMessageResp struct {
 	NewThreadCreated bool   `json:"new_thread_created"`
 	ThreadID         uint32 `json:"thread_id"`
+	MessageID        uint32 `json:"message_id"`
 }
 
 // MessagesThreadsPostReq contains messages threads request param
 type MessagesThreadsPostReq struct {
-	Count  int `json:"count"`
-	Offset int `json:"offseet"`
+	Count  int    `json:"count"`
+	Cursor string `json:"cursor"` // next_cursor or prev_cursor of other page, empty for the first page
+	ThreadFilter
+}
+
+// ThreadFilter selects threads by lifecycle state, empty fields match any thread
+type ThreadFilter struct {
+	Status          string `json:"status,omitempty"`
+	AssignedStaffID uint32 `json:"assigned_staff_id,omitempty"`
+	Unassigned      bool   `json:"unassigned,omitempty"` // only threads in staff queue
+}
+
+// MessagesThreadsListResp contains page of user's messages threads
+type MessagesThreadsListResp struct {
+	Threads []*MessagesThreadsResp `json:"threads"`
+	PageCursors
 }
 
 // MessagesThreadsResp represents user's messages threads
@@ -20,13 +35,39 @@ type MessagesThreadsResp struct {
 	Title      string `json:"title"`
 	Content    string `json:"content"`
 	CreatedAt  string `json:"created_at"`
+
+	ParticipantIDs []uint32 `json:"participant_ids"`
+	Unread         int      `json:"unread"`
+
+	Status          string `json:"status"`
+	AssignedStaffID uint32 `json:"assigned_staff_id"`
+	FirstResponseAt string `json:"first_response_at,omitempty"`
+	ResolvedAt      string `json:"resolved_at,omitempty"`
+}
+
+// ThreadStatusReq changes thread status: open, waiting_customer, closed or archived
+type ThreadStatusReq struct {
+	ThreadID uint32 `json:"thread_id"`
+	Status   string `json:"status"`
+}
+
+// ThreadAssignReq assigns thread to staff member, zero staff ID returns thread to queue
+type ThreadAssignReq struct {
+	ThreadID uint32 `json:"thread_id"`
+	StaffID  uint32 `json:"staff_id"`
 }
 
 // MessagesListPostReq contains params for read messages
 type MessagesListPostReq struct {
 	ThreadID uint32 `json:"thread_id"`
 	Count    int    `json:"count"`
-	Offset   int    `json:"offseet"`
+	Cursor   string `json:"cursor"` // next_cursor or prev_cursor of other page, empty for the first page
+}
+
+// MessagesListResp contains page of thread messages
+type MessagesListResp struct {
+	Messages []*MessageListResp `json:"messages"`
+	PageCursors
 }
 
 // MessagePostReq contain info for creating new message for user
@@ -36,6 +77,11 @@ type MessagePostReq struct {
 	Subject    string `json:"subject"`
 	Content    string `json:"content"`
 	ThreadID   uint32 `json:"thread_id"`
+
+	// Additional participants of new group thread
+	ParticipantIDs []uint32 `json:"participant_ids,omitempty"`
+	// Sender's uploaded files attached to message
+	FileIDs []uint32 `json:"file_ids,omitempty"`
 }
 
 // MessagePatchReq patch user's message
@@ -64,16 +110,139 @@ type MessageDeleteReq struct {
 
 // MessageListResp contain info for one user's message
 type MessageListResp struct {
-	ThreadID   uint32 `json:"thread_id"`
+	ThreadID   uint32   `json:"thread_id"`
+	MessageID  uint32   `json:"message_id"`
+	FromUserID uint32   `json:"from_id"`
+	FromFirst  string   `json:"from_first"`
+	FromLast   string   `json:"from_last"`
+	ToUserID   uint32   `json:"to_id"`
+	ToFirst    string   `json:"to_first"`
+	ToLast     string   `json:"to_last"`
+	Subject    string   `json:"subject,omitempty"`
+	Content    string   `json:"content"`
+	Readed     bool     `json:"readed"`
+	ReadBy     []uint32 `json:"read_by"` // users who read message
+	Created    string   `json:"created"`
+	Edited     bool     `json:"edited"`
+	Deleted    bool     `json:"deleted"` // content of deleted message is replaced with placeholder
+
+	Attachments []*MessageAttachment `json:"attachments"`
+}
+
+// MessageRestoreReq undeletes message, not zero revision ID also brings back revision content
+type MessageRestoreReq struct {
 	MessageID  uint32 `json:"message_id"`
-	FromUserID uint32 `json:"from_id"`
-	FromFirst  string `json:"from_first"`
-	FromLast   string `json:"from_last"`
-	ToUserID   uint32 `json:"to_id"`
-	ToFirst    string `json:"to_first"`
-	ToLast     string `json:"to_last"`
-	Subject    string `json:"subject,omitempty"`
-	Content    string `json:"content"`
-	Readed     bool   `json:"readed"`
-	Created    string `json:"created"`
+	RevisionID uint32 `json:"revision_id,omitempty"`
+}
+
+// MessageRevision is previous content of edited message
+type MessageRevision struct {
+	RevisionID  uint32 `json:"revision_id"`
+	Content     string `json:"content"`
+	EditedBy    uint32 `json:"edited_by"`
+	EditorFirst string `json:"editor_first"`
+	EditorLast  string `json:"editor_last"`
+	Created     string `json:"created"`
+}
+
+// MessageHistoryResp contains current message with its original content and revision history, oldest first
+type MessageHistoryResp struct {
+	Message   *MessageListResp   `json:"message"`
+	DeletedBy uint32             `json:"deleted_by,omitempty"`
+	DeletedAt string             `json:"deleted_at,omitempty"`
+	Revisions []*MessageRevision `json:"revisions"`
+}
+
+// MessageAttachment is uploaded file attached to message, available to all thread participants
+type MessageAttachment struct {
+	FileID     uint32 `json:"file_id"`
+	OwnerID    uint32 `json:"owner_id"`
+	FileName   string `json:"file_name"`
+	Category   string `json:"category"`
+	Size       int64  `json:"size"`
+	ScanStatus string `json:"scan_status"`
+	Link       string `json:"link"`
+}
+
+// MessageReadEvent is pushed to thread participants when participant marks message readed
+type MessageReadEvent struct {
+	ThreadID  uint32 `json:"thread_id"`
+	MessageID uint32 `json:"message_id"`
+	ReaderID  uint32 `json:"reader_id"`
+	Readed    bool   `json:"readed"`
+}
+
+// ThreadUpdateEvent is pushed to thread participants when thread changes
+type ThreadUpdateEvent struct {
+	ThreadID       uint32   `json:"thread_id"`
+	MessageID      uint32   `json:"message_id,omitempty"` // new or changed message
+	NewThread      bool     `json:"new_thread"`
+	FromUserID     uint32   `json:"from_user_id,omitempty"` // message sender
+	ParticipantIDs []uint32 `json:"participant_ids"`
+
+	Status          string `json:"status,omitempty"` // changed thread status
+	AssignedStaffID uint32 `json:"assigned_staff_id,omitempty"`
+}
+
+// ThreadParticipantReq adds or removes thread participant
+type ThreadParticipantReq struct {
+	ThreadID uint32 `json:"thread_id"`
+	UserID   uint32 `json:"user_id"`
+}
+
+// ThreadParticipant represents user taking part in thread
+type ThreadParticipant struct {
+	UserID    uint32 `json:"user_id"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	UserRole  uint8  `json:"user_role"`
+	AddedBy   uint32 `json:"added_by"`
+	CreatedAt string `json:"created_at"`
+}
+
+// ThreadReadReq moves user's read cursor of thread up to message, zero message_id means the last message
+type ThreadReadReq struct {
+	ThreadID  uint32 `json:"thread_id"`
+	MessageID uint32 `json:"message_id"`
+}
+
+// ThreadReadResp contains user's read cursor of thread
+type ThreadReadResp struct {
+	ThreadID          uint32 `json:"thread_id"`
+	LastReadMessageID uint32 `json:"last_read_message_id"`
+}
+
+// ThreadReadEvent is pushed to thread participants when participant reads thread up to message
+type ThreadReadEvent struct {
+	ThreadID          uint32 `json:"thread_id"`
+	ReaderID          uint32 `json:"reader_id"`
+	LastReadMessageID uint32 `json:"last_read_message_id"`
+}
+
+// ThreadUnread represents number of unread messages in thread
+type ThreadUnread struct {
+	ThreadID uint32 `json:"thread_id"`
+	Unread   int    `json:"unread"`
+}
+
+// UnreadCountResp represents user's unread messages, total and per thread
+type UnreadCountResp struct {
+	Total   int             `json:"total"`
+	Threads []*ThreadUnread `json:"threads"`
+}
+
+// MessageSearchResult represents message found by search with highlighted snippets
+type MessageSearchResult struct {
+	ThreadID     uint32 `json:"thread_id"`
+	MessageID    uint32 `json:"message_id"`
+	FromUserID   uint32 `json:"from_id"`
+	CreatedAt    string `json:"created_at"`
+	TitleSnippet string `json:"title_snippet"` // HTML escaped thread title, matches are wrapped in <mark>
+	Snippet      string `json:"snippet"`       // HTML escaped part of message around matches
+}
+
+// MessageSearchResp contains most relevant messages first
+type MessageSearchResp struct {
+	Query   string                 `json:"query"`
+	Results []*MessageSearchResult `json:"results"`
 }
@@ -0,0 +1,6 @@
+package webpojo
+
+// NotificationPreference tells how user is notified about new messages: immediate, digest or off
+type NotificationPreference struct {
+	Mode string `json:"mode"`
+}
@@ -0,0 +1,9 @@
+package webpojo
+
+// PageCursors contains opaque cursors of pages around current page of list.
+// Next cursor is empty at the end of list. Previous cursor is set for non empty page
+// and lets client poll for rows added to the beginning of list.
+type PageCursors struct {
+	NextCursor string `json:"next_cursor"`
+	PrevCursor string `json:"prev_cursor"`
+}
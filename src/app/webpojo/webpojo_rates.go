@@ -0,0 +1,254 @@
+package webpojo
+
+// LenderRateListReq contains lender rates list request params.
+// Criteria "best" returns best rate of every product effective on as_of day (today if empty),
+// any other value returns page of all rates. Both lists can be filtered, sorted and grouped,
+// unsorted best rates are ordered by product.
+type LenderRateListReq struct {
+	Criteria  string  `json:"criteria"`
+	AsOf      string  `json:"as_of"` // YYYY-MM-DD
+	Product   string  `json:"product"`
+	LenderID  uint32  `json:"lender_id"`
+	MinRate   float64 `json:"min_rate"` // percents, bounds rate_field
+	MaxRate   float64 `json:"max_rate"`
+	RateField string  `json:"rate_field"` // apr or interest bounded by min_rate and max_rate, apr if empty
+	Validity  string  `json:"validity"`   // effective, upcoming or expired on as_of day, any if empty
+	Sort      string  `json:"sort"`       // apr or interest, empty for order rates were added
+	Desc      bool    `json:"desc"`
+	GroupBy   string  `json:"group_by"` // product or empty
+	Count     int     `json:"count"`
+	Cursor    string  `json:"cursor"` // next_cursor or prev_cursor of other page, empty for the first page
+}
+
+// LenderRateListResp contains lender rates, page cursors are empty for best rates.
+// Rates of page are put to groups instead of lender_rates if request is grouped.
+type LenderRateListResp struct {
+	StatusCode  uint16            `json:"statusCode"`
+	Message     string            `json:"message"`
+	LenderRates []LenderRatePojo  `json:"lender_rates"`
+	Groups      []LenderRateGroup `json:"groups,omitempty"`
+	PageCursors
+}
+
+// LenderRateGroup contains rates of product in list order
+type LenderRateGroup struct {
+	Product     string           `json:"product"`
+	LenderRates []LenderRatePojo `json:"lender_rates"`
+}
+
+// LenderRatePojo represents rate of lender's loan product, interest and apr are percents
+type LenderRatePojo struct {
+	ID         uint32  `json:"id"`
+	LenderID   uint32  `json:"lender_id"`
+	LenderName string  `json:"lender_name"`
+	Product    string  `json:"product"`
+	TermYears  int     `json:"term_years"`
+	Interest   float64 `json:"interest"`
+	Apr        float64 `json:"apr"`
+	BeginDate  string  `json:"begin_date"`
+	EndDate    string  `json:"end_date"`
+}
+
+// FastQuoteReq contains loan params for fast quote.
+// Empty product and zero term match any product and term.
+type FastQuoteReq struct {
+	LoanAmount float64 `json:"loan_amount"`
+	TermYears  int     `json:"term_years"`
+	CreditBand string  `json:"credit_band"` // excellent, good, fair or poor
+	Product    string  `json:"product"`
+}
+
+// FastQuoteResp is best lender rate of product adjusted for requested loan.
+// Quote ID is the same for all rates of fast quote, customer attaches quote to account with it.
+type FastQuoteResp struct {
+	QuoteID string `json:"quote_id"`
+	LenderRatePojo
+	CreditBand     string  `json:"credit_band"`
+	LoanAmount     float64 `json:"loan_amount"`
+	MonthlyPayment float64 `json:"monthly_payment"` // principal and interest
+}
+
+// FastQuoteClaimReq attaches fast quote to signed in customer
+type FastQuoteClaimReq struct {
+	QuoteID string `json:"quote_id"`
+}
+
+// FastQuotePojo represents stored fast quote with its inputs and returned rates
+type FastQuotePojo struct {
+	QuoteID    string          `json:"quote_id"`
+	LoanAmount float64         `json:"loan_amount"`
+	TermYears  int             `json:"term_years"`
+	CreditBand string          `json:"credit_band"`
+	Product    string          `json:"product"`
+	Rates      []FastQuoteResp `json:"rates"`
+	ClaimedAt  string          `json:"claimed_at,omitempty"`
+	CreatedAt  string          `json:"created_at"`
+}
+
+// FastQuoteListResp contains customer's fast quotes
+type FastQuoteListResp struct {
+	StatusCode uint16          `json:"statusCode"`
+	Message    string          `json:"message"`
+	Quotes     []FastQuotePojo `json:"quotes"`
+}
+
+// FastQuoteReportResp contains fast quote volume and conversion to registered customers and loan applications
+// of date range (YYYY-MM-DD), rates are percents of quotes
+type FastQuoteReportResp struct {
+	StatusCode uint16               `json:"statusCode"`
+	Message    string               `json:"message"`
+	From       string               `json:"from"`
+	To         string               `json:"to"`
+	Quotes     int                  `json:"quotes"`
+	Claimed    int                  `json:"claimed"`
+	Applied    int                  `json:"applied"`
+	ClaimRate  float64              `json:"claim_rate"`
+	ApplyRate  float64              `json:"apply_rate"`
+	Days       []FastQuoteReportDay `json:"days"`
+}
+
+// FastQuoteReportDay contains fast quote volume and conversion of day
+type FastQuoteReportDay struct {
+	Date    string `json:"date"`
+	Quotes  int    `json:"quotes"`
+	Claimed int    `json:"claimed"`
+	Applied int    `json:"applied"`
+}
+
+// LenderPojo represents lender, status_id is 1 for active and 2 for inactive lender
+type LenderPojo struct {
+	ID          uint32 `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Address     string `json:"address"`
+	Email       string `json:"email"`
+	Phone       string `json:"phone"`
+	Contact     string `json:"contact"`
+	StatusID    int    `json:"status_id"`
+	CreatedAt   string `json:"created_at"`
+}
+
+// LenderListResp contains all lenders
+type LenderListResp struct {
+	StatusCode uint16       `json:"statusCode"`
+	Message    string       `json:"message"`
+	Lenders    []LenderPojo `json:"lenders"`
+}
+
+// RateSheetImportResp is report of lender rate sheet import. Sheet rates are compared with not expired
+// rates of lender by product, term and begin date. Sheet with rejected rows is not imported.
+type RateSheetImportResp struct {
+	StatusCode uint16              `json:"statusCode"`
+	Message    string              `json:"message"`
+	LenderID   uint32              `json:"lender_id"`
+	DryRun     bool                `json:"dry_run"`
+	Applied    bool                `json:"applied"` // lender rates are replaced with sheet rates
+	Rows       int                 `json:"rows"`
+	Added      []LenderRatePojo    `json:"added"`
+	Changed    []RateSheetChange   `json:"changed"`
+	Removed    []LenderRatePojo    `json:"removed"`
+	Unchanged  int                 `json:"unchanged"`
+	Rejected   []RateSheetRowError `json:"rejected"`
+}
+
+// RateSheetChange contains current lender rate and sheet rate which replaces it
+type RateSheetChange struct {
+	Old LenderRatePojo `json:"old"`
+	New LenderRatePojo `json:"new"`
+}
+
+// RateSheetRowError tells why sheet row is rejected, header is line 1
+type RateSheetRowError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// LenderRateHistoryReq contains product and date range (YYYY-MM-DD) of best rate history,
+// empty to is today and empty from is 90 days before to
+type LenderRateHistoryReq struct {
+	Product string `json:"product"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+}
+
+// LenderRateHistoryResp contains best rate of product for every day of range which has effective rates
+type LenderRateHistoryResp struct {
+	StatusCode uint16                   `json:"statusCode"`
+	Message    string                   `json:"message"`
+	Product    string                   `json:"product"`
+	From       string                   `json:"from"`
+	To         string                   `json:"to"`
+	History    []LenderRateHistoryPoint `json:"history"`
+}
+
+// LenderRateHistoryPoint is best rate of day
+type LenderRateHistoryPoint struct {
+	Date string `json:"date"`
+	LenderRatePojo
+}
+
+// RateAlertReq contains customer's alert on best interest of product: direction "below" alerts when
+// rate drops to threshold or below, "above" when it rises to threshold or above
+type RateAlertReq struct {
+	Product   string  `json:"product"`
+	Threshold float64 `json:"threshold"` // percents
+	Direction string  `json:"direction"`
+}
+
+// RateAlertPojo represents customer's rate alert, triggered alert waits for rate to cross threshold back
+type RateAlertPojo struct {
+	ID         uint32  `json:"id"`
+	Product    string  `json:"product"`
+	Threshold  float64 `json:"threshold"`
+	Direction  string  `json:"direction"`
+	Triggered  bool    `json:"triggered"`
+	NotifiedAt string  `json:"notified_at,omitempty"`
+	CreatedAt  string  `json:"created_at"`
+}
+
+// RateAlertListResp contains customer's rate alerts
+type RateAlertListResp struct {
+	StatusCode uint16          `json:"statusCode"`
+	Message    string          `json:"message"`
+	Alerts     []RateAlertPojo `json:"alerts"`
+}
+
+// RateFeedRunPojo represents result of rate feed ingestion
+type RateFeedRunPojo struct {
+	Source    string `json:"source"`
+	Succeeded bool   `json:"succeeded"`
+	Fetched   int    `json:"fetched"`
+	Inserted  int    `json:"inserted"`
+	Updated   int    `json:"updated"`
+	Rejected  int    `json:"rejected"`
+	Error     string `json:"error,omitempty"`
+	CreatedAt string `json:"created_at"`
+}
+
+// RateFeedItemError tells why feed item is not ingested, item is index in feed from 0
+type RateFeedItemError struct {
+	Item   int    `json:"item"`
+	Lender string `json:"lender"`
+	Error  string `json:"error"`
+}
+
+// RateFeedRefreshResp is report of manual rates refresh: feed ingestion if feed is enabled and best rates reload
+type RateFeedRefreshResp struct {
+	StatusCode uint16              `json:"statusCode"`
+	Message    string              `json:"message"`
+	Run        *RateFeedRunPojo    `json:"run,omitempty"` // empty if feed is disabled
+	Rejected   []RateFeedItemError `json:"rejected"`
+	BestRates  int                 `json:"best_rates"` // number of products with best rate
+}
+
+// RateFeedStatusResp tells when feed rates were ingested, rates are stale if feed was not ingested
+// successfully for stale_after_hours
+type RateFeedStatusResp struct {
+	StatusCode      uint16           `json:"statusCode"`
+	Message         string           `json:"message"`
+	Enabled         bool             `json:"enabled"`
+	Stale           bool             `json:"stale"`
+	StaleAfterHours int              `json:"stale_after_hours"`
+	LastRun         *RateFeedRunPojo `json:"last_run,omitempty"`
+	LastSuccess     *RateFeedRunPojo `json:"last_success,omitempty"`
+}
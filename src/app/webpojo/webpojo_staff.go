@@ -0,0 +1,69 @@
+package webpojo
+
+import "time"
+
+// AssignedCustomer represents customer assigned to staff member
+type AssignedCustomer struct {
+	CustomerID uint32 `json:"customer_id"`
+	FirstName  string `json:"first_name"`
+	LastName   string `json:"last_name"`
+	Email      string `json:"email"`
+	AssignedAt string `json:"assigned_at"`
+}
+
+// StaffAssignmentReq assigns customer to staff member or removes assignment
+type StaffAssignmentReq struct {
+	StaffID    uint32 `json:"staff_id"`
+	CustomerID uint32 `json:"customer_id"`
+}
+
+// DocumentRequestReq asks customer to upload document
+type DocumentRequestReq struct {
+	CustomerID uint32 `json:"customer_id"`
+	Category   string `json:"category"`
+	Note       string `json:"note"`
+}
+
+// DocumentRequest represents document requested from customer
+type DocumentRequest struct {
+	ID         uint32 `json:"id"`
+	CustomerID uint32 `json:"customer_id"`
+	StaffID    uint32 `json:"staff_id"`
+	Category   string `json:"category"`
+	Note       string `json:"note"`
+	Status     string `json:"status"`
+	FileID     string `json:"file_id,omitempty"` // fake ID of uploaded file
+	CreatedAt  string `json:"created_at"`
+}
+
+// FileAccessLogReq contains filter and pagination params of file access log
+type FileAccessLogReq struct {
+	ActorID    string
+	CustomerID string
+	Action     string
+	From       time.Time
+	To         time.Time
+	Limit      int
+	Offset     int
+}
+
+// FileAccessRecord represents one file access log record
+type FileAccessRecord struct {
+	ID         uint32 `json:"id"`
+	ActorID    uint32 `json:"actor_id"`
+	ActorRole  uint8  `json:"actor_role"`
+	CustomerID uint32 `json:"customer_id"`
+	FileID     uint32 `json:"file_id,omitempty"`
+	Action     string `json:"action"`
+	Detail     string `json:"detail,omitempty"`
+	RemoteAddr string `json:"remote_addr"`
+	CreatedAt  string `json:"created_at"`
+}
+
+// FileAccessLogResp contains one page of file access log
+type FileAccessLogResp struct {
+	Records []*FileAccessRecord `json:"records"`
+	Total   int                 `json:"total"`
+	Limit   int                 `json:"limit"`
+	Offset  int                 `json:"offset"`
+}
@@ -45,6 +45,11 @@ type IDRequestString struct {
 	ID string `json:"id"`
 }
 
+// IDResponse contains ID of created entity
+type IDResponse struct {
+	ID uint32 `json:"id"`
+}
+
 //UserCreateResp for admin user registration's response
 type UserCreateResp struct {
 	StatusCode uint16 `json:"statusCode"`